@@ -0,0 +1,305 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/siliconcatalyst/officeforge/docx"
+	"github.com/siliconcatalyst/officeforge/pptx"
+	"github.com/siliconcatalyst/officeforge/xlsx"
+)
+
+var (
+	// deckRangePattern matches the slide range suffix of "deck.pptx:3-7"
+	deckRangePattern = regexp.MustCompile(`:([\d,\s-]+)$`)
+	// workbookSheetsPattern splits "book.xlsx:Summary,Detail" into the path and sheet names
+	workbookSheetsPattern = regexp.MustCompile(`(?i)^(.+\.xlsx):(.+)$`)
+)
+
+func handleDocxConcat(args []string) {
+	if len(args) < 4 {
+		fmt.Println("Error: Missing required arguments")
+		fmt.Println("\nUsage:")
+		fmt.Println("  officeforge docx-concat --output <file> --input <file> --input <file> [...]")
+		fmt.Println("  officeforge docx-concat --output <file> <file> <file> [...]")
+		os.Exit(1)
+	}
+
+	var outputPath string
+	var inputPaths []string
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--input", "-i":
+			if i+1 < len(args) {
+				inputPaths = append(inputPaths, args[i+1])
+				i++
+			}
+		case "--output", "-o":
+			if i+1 < len(args) {
+				outputPath = args[i+1]
+				i++
+			}
+		default:
+			inputPaths = append(inputPaths, args[i])
+		}
+	}
+
+	if outputPath == "" || len(inputPaths) < 2 {
+		fmt.Println("Error: --output and at least two input documents are required")
+		os.Exit(1)
+	}
+
+	inputs := make([]io.Reader, len(inputPaths))
+	for i, inputPath := range inputPaths {
+		file, err := os.Open(inputPath)
+		if err != nil {
+			fmt.Printf("Error opening input file: %v\n", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		inputs[i] = file
+	}
+
+	outputFile, err := os.Create(outputPath)
+	if err != nil {
+		fmt.Printf("Error creating output file: %v\n", err)
+		os.Exit(1)
+	}
+	if err := docx.Concat(outputFile, inputs...); err != nil {
+		outputFile.Close()
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := outputFile.Close(); err != nil {
+		fmt.Printf("Error writing output file: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✓ Concatenated %d documents into %s\n", len(inputPaths), outputPath)
+}
+
+func handleDocxSplit(args []string) {
+	if len(args) < 4 {
+		fmt.Println("Error: Missing required arguments")
+		fmt.Println("\nUsage:")
+		fmt.Println("  officeforge docx-split --input <file> --output <directory> [--by section|heading]")
+		os.Exit(1)
+	}
+
+	var inputPath, outputDir string
+	by := "section"
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--input", "-i":
+			if i+1 < len(args) {
+				inputPath = args[i+1]
+				i++
+			}
+		case "--output", "-o":
+			if i+1 < len(args) {
+				outputDir = args[i+1]
+				i++
+			}
+		case "--by":
+			if i+1 < len(args) {
+				by = args[i+1]
+				i++
+			}
+		}
+	}
+
+	if inputPath == "" || outputDir == "" {
+		fmt.Println("Error: All flags (--input, --output) are required")
+		os.Exit(1)
+	}
+
+	var splitBy docx.SplitBy
+	switch by {
+	case "section", "sections":
+		splitBy = docx.SplitSections
+	case "heading", "headings":
+		splitBy = docx.SplitHeadings
+	default:
+		fmt.Printf("Error: Unknown split mode: %s (use section or heading)\n", by)
+		os.Exit(1)
+	}
+
+	inputFile, err := os.Open(inputPath)
+	if err != nil {
+		fmt.Printf("Error opening input file: %v\n", err)
+		os.Exit(1)
+	}
+	defer inputFile.Close()
+
+	parts, err := docx.Split(inputFile, splitBy)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		fmt.Printf("Error creating output directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	baseName := strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))
+	for i, part := range parts {
+		partPath := filepath.Join(outputDir, fmt.Sprintf("%s_%d.docx", baseName, i+1))
+		if err := os.WriteFile(partPath, part, 0644); err != nil {
+			fmt.Printf("Error writing %s: %v\n", partPath, err)
+			os.Exit(1)
+		}
+	}
+
+	fmt.Printf("✓ Split %s into %d documents in: %s\n", inputPath, len(parts), outputDir)
+}
+
+func handlePptxConcat(args []string) {
+	if len(args) < 4 {
+		fmt.Println("Error: Missing required arguments")
+		fmt.Println("\nUsage:")
+		fmt.Println("  officeforge pptx-concat --output <file> <deck.pptx>[:<slides>] <deck.pptx>[:<slides>] [...]")
+		fmt.Println("\nSlide examples:")
+		fmt.Println("  intro.pptx          All slides")
+		fmt.Println("  library.pptx:3-7    Slides 3 to 7")
+		fmt.Println("  library.pptx:1,9-   Slide 1 and slides 9 to the end")
+		os.Exit(1)
+	}
+
+	var outputPath string
+	var inputs []string
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--input", "-i":
+			if i+1 < len(args) {
+				inputs = append(inputs, args[i+1])
+				i++
+			}
+		case "--output", "-o":
+			if i+1 < len(args) {
+				outputPath = args[i+1]
+				i++
+			}
+		default:
+			inputs = append(inputs, args[i])
+		}
+	}
+
+	if outputPath == "" || len(inputs) == 0 {
+		fmt.Println("Error: --output and at least one input presentation are required")
+		os.Exit(1)
+	}
+
+	decks := make([]pptx.Deck, len(inputs))
+	for i, input := range inputs {
+		inputPath, slides := input, ""
+		if match := deckRangePattern.FindStringSubmatchIndex(input); match != nil {
+			inputPath, slides = input[:match[0]], input[match[2]:match[3]]
+		}
+
+		file, err := os.Open(inputPath)
+		if err != nil {
+			fmt.Printf("Error opening input file: %v\n", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		decks[i] = pptx.Deck{Input: file, Slides: slides}
+	}
+
+	outputFile, err := os.Create(outputPath)
+	if err != nil {
+		fmt.Printf("Error creating output file: %v\n", err)
+		os.Exit(1)
+	}
+	if err := pptx.ConcatDecks(outputFile, decks...); err != nil {
+		outputFile.Close()
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := outputFile.Close(); err != nil {
+		fmt.Printf("Error writing output file: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✓ Concatenated %d presentations into %s\n", len(inputs), outputPath)
+}
+
+func handleXlsxConcat(args []string) {
+	if len(args) < 4 {
+		fmt.Println("Error: Missing required arguments")
+		fmt.Println("\nUsage:")
+		fmt.Println("  officeforge xlsx-concat --output <file> <book.xlsx>[:<sheets>] <book.xlsx>[:<sheets>] [...]")
+		fmt.Println("\nSheet examples:")
+		fmt.Println("  north.xlsx                 All sheets")
+		fmt.Println("  south.xlsx:Summary         The Summary sheet")
+		fmt.Println("  south.xlsx:Summary,Detail  The Summary and Detail sheets")
+		os.Exit(1)
+	}
+
+	var outputPath string
+	var inputs []string
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--input", "-i":
+			if i+1 < len(args) {
+				inputs = append(inputs, args[i+1])
+				i++
+			}
+		case "--output", "-o":
+			if i+1 < len(args) {
+				outputPath = args[i+1]
+				i++
+			}
+		default:
+			inputs = append(inputs, args[i])
+		}
+	}
+
+	if outputPath == "" || len(inputs) == 0 {
+		fmt.Println("Error: --output and at least one input workbook are required")
+		os.Exit(1)
+	}
+
+	books := make([]xlsx.Workbook, len(inputs))
+	for i, input := range inputs {
+		inputPath := input
+		var sheets []string
+		if match := workbookSheetsPattern.FindStringSubmatch(input); match != nil {
+			inputPath = match[1]
+			sheets = strings.Split(match[2], ",")
+		}
+
+		file, err := os.Open(inputPath)
+		if err != nil {
+			fmt.Printf("Error opening input file: %v\n", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		books[i] = xlsx.Workbook{Input: file, Sheets: sheets}
+	}
+
+	outputFile, err := os.Create(outputPath)
+	if err != nil {
+		fmt.Printf("Error creating output file: %v\n", err)
+		os.Exit(1)
+	}
+	if err := xlsx.ConcatWorkbooks(outputFile, books...); err != nil {
+		outputFile.Close()
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := outputFile.Close(); err != nil {
+		fmt.Printf("Error writing output file: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✓ Concatenated %d workbooks into %s\n", len(inputs), outputPath)
+}
@@ -17,11 +17,11 @@ func handleDocxSingle(args []string) {
 	if len(args) < 8 {
 		fmt.Println("Error: Missing required arguments")
 		fmt.Println("\nUsage:")
-		fmt.Println("  officeforge docx-single --input <template> --output <file> --key <keyword> --value <replacement>")
+		fmt.Println("  officeforge docx-single --input <template> --output <file> --key <keyword> --value <replacement> [--delims \"<left> <right>\"] [--locale <tag>]")
 		os.Exit(1)
 	}
 
-	var inputPath, outputPath, keyword, replacement string
+	var inputPath, outputPath, keyword, replacement, delims, locale string
 
 	for i := 0; i < len(args); i++ {
 		switch args[i] {
@@ -45,6 +45,16 @@ func handleDocxSingle(args []string) {
 				replacement = args[i+1]
 				i++
 			}
+		case "--delims":
+			if i+1 < len(args) {
+				delims = args[i+1]
+				i++
+			}
+		case "--locale":
+			if i+1 < len(args) {
+				locale = args[i+1]
+				i++
+			}
 		}
 	}
 
@@ -53,9 +63,10 @@ func handleDocxSingle(args []string) {
 		os.Exit(1)
 	}
 
-	keyword = internal.NormalizeKey(keyword)
+	syntax := parseDelims(delims)
+	keyword = syntax.NormalizeKey(keyword)
 
-	err := docx.ProcessDocxSingle(inputPath, outputPath, keyword, replacement)
+	err := docx.ProcessDocxMultiWithOptions(inputPath, outputPath, map[string]string{keyword: replacement}, docx.Options{Syntax: syntax, Locale: locale})
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
@@ -68,11 +79,13 @@ func handleDocxMulti(args []string) {
 	if len(args) < 6 {
 		fmt.Println("Error: Missing required arguments")
 		fmt.Println("\nUsage:")
-		fmt.Println("  officeforge docx-multi --input <template> --output <file> --data <json_file>")
+		fmt.Println("  officeforge docx-multi --input <template> --output <file> --data <json_file> [--delims \"<left> <right>\"] [--locale <tag>] [--include-dir <dir> [--include-outside-dir]] [--altchunk] [--revisions accept|reject] [--track-changes [--revision-author <name>] [--revision-date <date>]] [--set-prop <name>=<value>]")
 		os.Exit(1)
 	}
 
-	var inputPath, outputPath, dataPath string
+	var inputPath, outputPath, dataPath, delims, locale, includeDir, revisions, revisionAuthor, revisionDate string
+	var altChunk, includeOutsideDir, trackChanges bool
+	props := make(map[string]string)
 
 	for i := 0; i < len(args); i++ {
 		switch args[i] {
@@ -91,6 +104,47 @@ func handleDocxMulti(args []string) {
 				dataPath = args[i+1]
 				i++
 			}
+		case "--set-prop":
+			if i+1 < len(args) {
+				parseSetProp(args[i+1], props)
+				i++
+			}
+		case "--delims":
+			if i+1 < len(args) {
+				delims = args[i+1]
+				i++
+			}
+		case "--locale":
+			if i+1 < len(args) {
+				locale = args[i+1]
+				i++
+			}
+		case "--include-dir":
+			if i+1 < len(args) {
+				includeDir = args[i+1]
+				i++
+			}
+		case "--altchunk":
+			altChunk = true
+		case "--include-outside-dir":
+			includeOutsideDir = true
+		case "--revisions":
+			if i+1 < len(args) {
+				revisions = args[i+1]
+				i++
+			}
+		case "--track-changes":
+			trackChanges = true
+		case "--revision-author":
+			if i+1 < len(args) {
+				revisionAuthor = args[i+1]
+				i++
+			}
+		case "--revision-date":
+			if i+1 < len(args) {
+				revisionDate = args[i+1]
+				i++
+			}
 		}
 	}
 
@@ -100,22 +154,19 @@ func handleDocxMulti(args []string) {
 	}
 
 	// Read JSON file
-	data, err := os.ReadFile(dataPath)
+	data, err := readJSONData(dataPath)
 	if err != nil {
 		fmt.Printf("Error reading data file: %v\n", err)
 		os.Exit(1)
 	}
 
-	var replacements map[string]string
-	err = json.Unmarshal(data, &replacements)
-	if err != nil {
-		fmt.Printf("Error parsing JSON: %v\n", err)
-		os.Exit(1)
-	}
+	syntax := parseDelims(delims)
+	replacements := data.Flatten(syntax)
 
-	replacements = internal.NormalizeReplacements(replacements)
-
-	err = docx.ProcessDocxMulti(inputPath, outputPath, replacements)
+	err = docx.ProcessDocxMultiWithOptions(inputPath, outputPath, replacements, docx.Options{
+		Syntax: syntax, Locale: locale, IncludeDir: includeDir, IncludeOutsideDir: includeOutsideDir, IncludeAltChunk: altChunk, Revisions: revisions, Properties: props,
+		TrackChanges: trackChanges, RevisionAuthor: revisionAuthor, RevisionDate: parseRevisionDate(revisionDate),
+	})
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
@@ -129,7 +180,8 @@ func handleDocxBatch(args []string) {
 	if len(args) < 6 {
 		fmt.Println("Error: Missing required arguments")
 		fmt.Println("\nUsage:")
-		fmt.Println("  officeforge docx-batch --input <template> --output <directory> --data <csv_or_json_file> [--pattern <pattern>]")
+		fmt.Println("  officeforge docx-batch --input <template> --output <directory> --data <csv_json_or_xlsx_file> [--pattern <pattern>] [--delims \"<left> <right>\"] [--locale <tag>] [--locale-field <field>] [--include-dir <dir> [--include-outside-dir]] [--altchunk] [--revisions accept|reject] [--track-changes [--revision-author <name>] [--revision-date <date>]] [--set-prop <name>=<value>]")
+		fmt.Println("  officeforge docx-batch --input <template> --merge <output_file> --data <csv_json_or_xlsx_file> [--page-breaks] [...]")
 		fmt.Println("\nPattern examples:")
 		fmt.Printf("  --pattern \"contract_%%d.docx\"           Sequential: contract_1.docx, contract_2.docx\n")
 		fmt.Println("  --pattern \"{{NAME}}_contract.docx\"      From data: Alice_contract.docx, Bob_contract.docx")
@@ -138,7 +190,9 @@ func handleDocxBatch(args []string) {
 		os.Exit(1)
 	}
 
-	var inputPath, outputDir, dataPath, pattern string
+	var inputPath, outputDir, dataPath, pattern, delims, locale, localeField, includeDir, mergePath, revisions, revisionAuthor, revisionDate string
+	var altChunk, includeOutsideDir, pageBreaks, trackChanges bool
+	props := make(map[string]string)
 
 	for i := 0; i < len(args); i++ {
 		switch args[i] {
@@ -162,32 +216,91 @@ func handleDocxBatch(args []string) {
 				pattern = args[i+1]
 				i++
 			}
+		case "--set-prop":
+			if i+1 < len(args) {
+				parseSetProp(args[i+1], props)
+				i++
+			}
+		case "--delims":
+			if i+1 < len(args) {
+				delims = args[i+1]
+				i++
+			}
+		case "--locale":
+			if i+1 < len(args) {
+				locale = args[i+1]
+				i++
+			}
+		case "--locale-field":
+			if i+1 < len(args) {
+				localeField = args[i+1]
+				i++
+			}
+		case "--include-dir":
+			if i+1 < len(args) {
+				includeDir = args[i+1]
+				i++
+			}
+		case "--altchunk":
+			altChunk = true
+		case "--include-outside-dir":
+			includeOutsideDir = true
+		case "--revisions":
+			if i+1 < len(args) {
+				revisions = args[i+1]
+				i++
+			}
+		case "--track-changes":
+			trackChanges = true
+		case "--revision-author":
+			if i+1 < len(args) {
+				revisionAuthor = args[i+1]
+				i++
+			}
+		case "--revision-date":
+			if i+1 < len(args) {
+				revisionDate = args[i+1]
+				i++
+			}
+		case "--merge":
+			if i+1 < len(args) {
+				mergePath = args[i+1]
+				i++
+			}
+		case "--page-breaks":
+			pageBreaks = true
 		}
 	}
 
-	if inputPath == "" || outputDir == "" || dataPath == "" {
-		fmt.Println("Error: All flags (--input, --output, --data) are required")
+	if inputPath == "" || (outputDir == "" && mergePath == "") || dataPath == "" {
+		fmt.Println("Error: All flags (--input, --output or --merge, --data) are required")
 		os.Exit(1)
 	}
 
-	// Create output directory if it doesn't exist
-	err := os.MkdirAll(outputDir, 0755)
-	if err != nil {
-		fmt.Printf("Error creating output directory: %v\n", err)
-		os.Exit(1)
+	var err error
+	if mergePath == "" {
+		// Create output directory if it doesn't exist
+		err = os.MkdirAll(outputDir, 0755)
+		if err != nil {
+			fmt.Printf("Error creating output directory: %v\n", err)
+			os.Exit(1)
+		}
 	}
 
 	// Determine file type and read data
+	dataPath, sheet := splitSheetPath(dataPath)
 	ext := strings.ToLower(filepath.Ext(dataPath))
-	var records []map[string]string
+	var records []internal.Data
 
 	switch ext {
 	case ".json":
 		records, err = readJSONRecords(dataPath)
 	case ".csv":
 		records, err = readCSVRecords(dataPath)
+	case ".xlsx":
+		records, err = readXLSXRecords(dataPath, sheet)
 	default:
-		fmt.Printf("Error: Unsupported data file format: %s (use .json or .csv)\n", ext)
+		fmt.Printf("Error: Unsupported data file format: %s (use .json, .csv or .xlsx)\n", ext)
 		os.Exit(1)
 	}
 
@@ -201,31 +314,51 @@ func handleDocxBatch(args []string) {
 		os.Exit(1)
 	}
 
+	syntax := parseDelims(delims)
+	opts := docx.Options{
+		Syntax: syntax, Locale: locale, LocaleField: localeField, IncludeDir: includeDir, IncludeOutsideDir: includeOutsideDir, IncludeAltChunk: altChunk,
+		MergePageBreaks: pageBreaks, Revisions: revisions, Properties: props,
+		TrackChanges: trackChanges, RevisionAuthor: revisionAuthor, RevisionDate: parseRevisionDate(revisionDate),
+	}
+	if err := opts.Validate(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if mergePath != "" {
+		if err := docx.RenderMerged(inputPath, mergePath, records, opts); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ Merged %d records into %s\n", len(records), mergePath)
+		return
+	}
+
 	normalizedRecords := make([]map[string]string, len(records))
 	for i, record := range records {
-		normalizedRecords[i] = internal.NormalizeReplacements(record)
+		normalizedRecords[i] = record.Flatten(syntax)
 	}
 
 	// Validate pattern if provided
 	if pattern != "" {
-		if err := internal.ValidatePattern(pattern, normalizedRecords[0]); err != nil {
+		if err := internal.ValidatePattern(pattern, normalizedRecords[0], opts); err != nil {
 			fmt.Printf("Error: Invalid pattern - %v\n", err)
 			os.Exit(1)
 		}
 
 		// Show what pattern type is being used
-		patternType := internal.DetectPatternType(pattern)
+		patternType := internal.DetectPatternType(pattern, syntax)
 		switch patternType {
 		case internal.PatternTypeSequential:
 			fmt.Printf("Using sequential pattern: %s\n", pattern)
 		case internal.PatternTypeData:
-			placeholders := internal.ExtractPlaceholders(pattern)
+			placeholders := internal.ExtractPlaceholders(pattern, syntax)
 			fmt.Printf("Using data-based pattern with fields: %v\n", placeholders)
 		}
 	}
 
 	// Process documents using the pattern
-	err = docx.ProcessDocxMultipleRecords(inputPath, outputDir, normalizedRecords, pattern)
+	err = docx.ProcessDocxMultipleRecordsWithOptions(inputPath, outputDir, normalizedRecords, pattern, opts)
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
@@ -238,11 +371,11 @@ func handleDocxCheck(args []string) {
 	if len(args) < 4 {
 		fmt.Println("Error: Missing required arguments")
 		fmt.Println("\nUsage:")
-		fmt.Println("  officeforge docx-check --input <file> [--keys \"K1,K2\" | --data <json_file>] [--json]")
+		fmt.Println("  officeforge docx-check --input <file> [--keys \"K1,K2\" | --data <json_file>] [--json] [--delims \"<left> <right>\"]")
 		os.Exit(1)
 	}
 
-	var inputPath, keysString, dataPath string
+	var inputPath, keysString, dataPath, delims string
 	var outputJson bool
 
 	for i := 0; i < len(args); i++ {
@@ -264,6 +397,11 @@ func handleDocxCheck(args []string) {
 			}
 		case "--json":
 			outputJson = true
+		case "--delims":
+			if i+1 < len(args) {
+				delims = args[i+1]
+				i++
+			}
 		}
 	}
 
@@ -272,6 +410,7 @@ func handleDocxCheck(args []string) {
 		os.Exit(1)
 	}
 
+	syntax := parseDelims(delims)
 	var keywords []string
 
 	// Load keywords from JSON or Comma Separated String
@@ -296,17 +435,13 @@ func handleDocxCheck(args []string) {
 			var dataList []map[string]any
 			if err := json.Unmarshal(data, &dataList); err == nil {
 				if len(dataList) > 0 {
-					for k := range dataList[0] {
-						keywords = append(keywords, k)
-					}
+					keywords = internal.DataKeys(dataList[0], syntax)
 				}
 			} else {
 				// Try parsing as a single object (starts with {)
 				var keyMap map[string]any
 				if err := json.Unmarshal(data, &keyMap); err == nil {
-					for k := range keyMap {
-						keywords = append(keywords, k)
-					}
+					keywords = internal.DataKeys(keyMap, syntax)
 				} else {
 					// Try parsing as a simple array of strings (starts with ["str"])
 					var keyList []string
@@ -331,11 +466,11 @@ func handleDocxCheck(args []string) {
 	}
 
 	for i := range keywords {
-		keywords[i] = internal.NormalizeKey(keywords[i])
+		keywords[i] = syntax.NormalizeKey(keywords[i])
 	}
 
 	// Call the validation function
-	results, err := internal.ValidateDocxKeywords(inputPath, keywords)
+	results, err := internal.ValidateDocxKeywords(inputPath, keywords, syntax)
 	if err != nil {
 		if outputJson {
 			fmt.Printf(`{"error": "%v"}`, err)
@@ -376,3 +511,87 @@ func handleDocxCheck(args []string) {
 		os.Exit(1)
 	}
 }
+
+func handleDocxPDF(args []string) {
+	if len(args) < 4 {
+		fmt.Println("Error: Missing required arguments")
+		fmt.Println("\nUsage:")
+		fmt.Println("  officeforge docx-pdf --input <file> --output <file.pdf>")
+		os.Exit(1)
+	}
+
+	var inputPath, outputPath string
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--input", "-i":
+			if i+1 < len(args) {
+				inputPath = args[i+1]
+				i++
+			}
+		case "--output", "-o":
+			if i+1 < len(args) {
+				outputPath = args[i+1]
+				i++
+			}
+		}
+	}
+
+	if inputPath == "" || outputPath == "" {
+		fmt.Println("Error: All flags (--input, --output) are required")
+		os.Exit(1)
+	}
+
+	if err := docx.ConvertToPDF(inputPath, outputPath); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✓ PDF created: %s\n", outputPath)
+}
+
+func handleDocxRevisions(args []string) {
+	if len(args) < 4 {
+		fmt.Println("Error: Missing required arguments")
+		fmt.Println("\nUsage:")
+		fmt.Println("  officeforge docx-revisions --input <file> --output <file> [--reject]")
+		os.Exit(1)
+	}
+
+	var inputPath, outputPath string
+	var reject bool
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--input", "-i":
+			if i+1 < len(args) {
+				inputPath = args[i+1]
+				i++
+			}
+		case "--output", "-o":
+			if i+1 < len(args) {
+				outputPath = args[i+1]
+				i++
+			}
+		case "--reject":
+			reject = true
+		}
+	}
+
+	if inputPath == "" || outputPath == "" {
+		fmt.Println("Error: All flags (--input, --output) are required")
+		os.Exit(1)
+	}
+
+	resolve, action := docx.AcceptAllRevisions, "accepted"
+	if reject {
+		resolve, action = docx.RejectAllRevisions, "rejected"
+	}
+	count, err := resolve(inputPath, outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✓ %d tracked changes %s: %s\n", count, action, outputPath)
+}
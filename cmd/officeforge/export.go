@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/siliconcatalyst/officeforge/docx"
+	"github.com/siliconcatalyst/officeforge/pptx"
+)
+
+func handleExport(args []string) {
+	if len(args) < 2 {
+		fmt.Println("Error: Missing required arguments")
+		fmt.Println("\nUsage:")
+		fmt.Println("  officeforge export --input <file> [--format txt|md|html] [--output <file>]")
+		os.Exit(1)
+	}
+
+	var inputPath, outputPath string
+	format := "txt"
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--input", "-i":
+			if i+1 < len(args) {
+				inputPath = args[i+1]
+				i++
+			}
+		case "--output", "-o":
+			if i+1 < len(args) {
+				outputPath = args[i+1]
+				i++
+			}
+		case "--format", "-f":
+			if i+1 < len(args) {
+				format = args[i+1]
+				i++
+			}
+		}
+	}
+
+	if inputPath == "" {
+		fmt.Println("Error: --input is required")
+		os.Exit(1)
+	}
+
+	var extractText, exportMarkdown, exportHTML func(string) (string, error)
+	ext := strings.ToLower(filepath.Ext(inputPath))
+	switch ext {
+	case ".docx":
+		extractText, exportMarkdown, exportHTML = docx.ExtractText, docx.ExportMarkdown, docx.ExportHTML
+	case ".pptx":
+		extractText, exportMarkdown, exportHTML = pptx.ExtractText, pptx.ExportMarkdown, pptx.ExportHTML
+	default:
+		fmt.Printf("Error: Unsupported input format: %s (use .docx or .pptx)\n", ext)
+		os.Exit(1)
+	}
+
+	var export func(string) (string, error)
+	switch strings.ToLower(format) {
+	case "txt", "text":
+		export = extractText
+	case "md", "markdown":
+		export = exportMarkdown
+	case "html", "htm":
+		export = exportHTML
+	default:
+		fmt.Printf("Error: Unknown format: %s (use txt, md or html)\n", format)
+		os.Exit(1)
+	}
+
+	text, err := export(inputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if outputPath == "" {
+		fmt.Print(text)
+		return
+	}
+	if err := os.WriteFile(outputPath, []byte(text), 0644); err != nil {
+		fmt.Printf("Error: failed to write output file: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Exported %s to %s\n", inputPath, outputPath)
+}
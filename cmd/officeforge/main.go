@@ -23,6 +23,14 @@ func main() {
 		handleDocxBatch(os.Args[2:])
 	case "docx-check":
 		handleDocxCheck(os.Args[2:])
+	case "docx-concat":
+		handleDocxConcat(os.Args[2:])
+	case "docx-split":
+		handleDocxSplit(os.Args[2:])
+	case "docx-pdf":
+		handleDocxPDF(os.Args[2:])
+	case "docx-revisions":
+		handleDocxRevisions(os.Args[2:])
 
 	// XLSX commands
 	case "xlsx-single":
@@ -33,6 +41,10 @@ func main() {
 		handleXlsxBatch(os.Args[2:])
 	case "xlsx-check":
 		handleXlsxCheck(os.Args[2:])
+	case "xlsx-concat":
+		handleXlsxConcat(os.Args[2:])
+	case "xlsx-export":
+		handleXlsxExport(os.Args[2:])
 
 	// PPTX commands
 	case "pptx-single":
@@ -43,8 +55,20 @@ func main() {
 		handlePptxBatch(os.Args[2:])
 	case "pptx-check":
 		handlePptxCheck(os.Args[2:])
+	case "pptx-concat":
+		handlePptxConcat(os.Args[2:])
+
+	// Template commands
+	case "normalize":
+		handleNormalize(os.Args[2:])
 
 	// Other commands
+	case "export":
+		handleExport(os.Args[2:])
+	case "props":
+		handleProps(os.Args[2:])
+	case "scrub":
+		handleScrub(os.Args[2:])
 	case "version":
 		printVersion()
 	case "help", "-h", "--help":
@@ -68,20 +92,33 @@ Commands:
     docx-multi       Replace multiple keywords in a template
     docx-batch       Generate multiple documents from a template
     docx-check       Check if keywords exist in a document
+    docx-concat      Join documents into one
+    docx-split       Split a document at section breaks or headings
+    docx-pdf         Convert a document to PDF
+    docx-revisions   Accept or reject all tracked changes
 
   XLSX (Excel Spreadsheets):
     xlsx-single      Replace a single keyword in a template
     xlsx-multi       Replace multiple keywords in a template
     xlsx-batch       Generate multiple spreadsheets from a template
     xlsx-check       Check if keywords exist in a spreadsheet
+    xlsx-concat      Combine sheets of several workbooks into one
+    xlsx-export      Export a sheet as CSV or JSON
 
   PPTX (PowerPoint Presentations):
     pptx-single      Replace a single keyword in a template
     pptx-multi       Replace multiple keywords in a template
     pptx-batch       Generate multiple presentations from a template
     pptx-check       Check if keywords exist in a presentation
+    pptx-concat      Join slides of several presentations into one
+
+  Templates:
+    normalize        Merge fragmented runs so placeholders are contiguous
 
   Other:
+    export           Export a document or presentation as text, Markdown or HTML
+    props            Show or set document properties (title, author, custom...)
+    scrub            Remove comments, tracked changes and personal metadata
     version          Show version
     help             Show this help message
 
@@ -98,6 +135,60 @@ Examples:
   # Generate documents with custom naming pattern
   officeforge docx-batch --input template.docx --output ./output --data records.csv --pattern "{name}_{id}.docx"
 
+  # Use a different placeholder syntax, e.g. [[NAME]] or ${NAME}
+  officeforge docx-multi --input template.docx --output result.docx --data data.json --delims "[[ ]]"
+
+  # Format values in filenames with filters (templates support them too, e.g. {{AMOUNT | currency:"EUR"}})
+  officeforge docx-batch --input template.docx --output ./output --data records.csv --pattern "{{NAME | upper}}_{{INDEX}}.docx"
+
+  # Format {{TOTAL | currency}} and dates per customer, using each record's LANG column
+  officeforge docx-batch --input invoice.docx --output ./output --data customers.csv --locale en-GB --locale-field LANG
+
+  # Pull in standard clauses from {{include:clauses/liability.docx}} paragraphs
+  officeforge docx-multi --input contract.docx --output result.docx --data data.json --include-dir ./legal
+
+  # Render all letters into one document for printing
+  officeforge docx-batch --input letter.docx --merge letters.docx --data customers.csv
+
+  # Generate contracts straight to PDF, or convert a finished document
+  officeforge docx-batch --input contract.docx --output ./contracts --data clients.csv --pattern "{{NAME}}.pdf"
+  officeforge docx-pdf --input contract.docx --output contract.pdf
+
+  # Accept the tracked changes left in a template by reviewers, or render as if they were rejected
+  officeforge docx-revisions --input template.docx --output template_clean.docx
+  officeforge docx-multi --input template.docx --output result.docx --data data.json --revisions reject
+
+  # Write the values into renewal contracts as tracked changes for legal to review
+  officeforge docx-batch --input renewal.docx --output ./renewals --data clients.csv --track-changes --revision-author "Contracts Team"
+
+  # Join a cover letter, contract and annex, then split a long document per chapter
+  officeforge docx-concat --output bundle.docx cover.docx contract.docx annex.docx
+  officeforge docx-split --input report.docx --output ./chapters --by heading
+
+  # Mail-merge from a sheet of an Excel workbook, and export another sheet as JSON
+  officeforge docx-batch --input letter.docx --output ./letters --data contacts.xlsx:Customers
+  officeforge xlsx-export --input contacts.xlsx:Suppliers --format json --output suppliers.json
+
+  # Consolidate the regional reports, taking only the Summary sheet of south.xlsx
+  officeforge xlsx-concat --output all.xlsx north.xlsx south.xlsx:Summary
+
+  # Build a deck from the slide library: all of intro.pptx, then slides 3-7 of products.pptx
+  officeforge pptx-concat --output pitch.pptx intro.pptx products.pptx:3-7
+
+  # Clean up a template edited in Word
+  officeforge normalize --input template.docx --output template_clean.docx
+
+  # Export a report as Markdown, or print the text of a presentation
+  officeforge export --input report.docx --format md --output report.md
+  officeforge export --input deck.pptx
+
+  # Set the title and a custom ContractId of each contract, and show the properties of a file
+  officeforge docx-batch --input contract.docx --output ./contracts --data clients.csv --set-prop title="Contract {{ID}}" --set-prop ContractId="{{ID}}"
+  officeforge props --input contracts/Acme.docx
+
+  # Strip comments, tracked changes (accepted) and author details before sending a document out
+  officeforge scrub --input contract.docx --output contract_clean.docx
+
   # Check for specific keywords
   officeforge docx-check --input doc.docx --keys "TOTAL_COST,DATE,SIGNATURE"
 
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/siliconcatalyst/officeforge/docx"
+	"github.com/siliconcatalyst/officeforge/pptx"
+)
+
+func handleNormalize(args []string) {
+	if len(args) < 4 {
+		fmt.Println("Error: Missing required arguments")
+		fmt.Println("\nUsage:")
+		fmt.Println("  officeforge normalize --input <template> --output <file> [--delims \"<left> <right>\"]")
+		os.Exit(1)
+	}
+
+	var inputPath, outputPath, delims string
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--input", "-i":
+			if i+1 < len(args) {
+				inputPath = args[i+1]
+				i++
+			}
+		case "--output", "-o":
+			if i+1 < len(args) {
+				outputPath = args[i+1]
+				i++
+			}
+		case "--delims":
+			if i+1 < len(args) {
+				delims = args[i+1]
+				i++
+			}
+		}
+	}
+
+	if inputPath == "" || outputPath == "" {
+		fmt.Println("Error: All flags (--input, --output) are required")
+		os.Exit(1)
+	}
+
+	opts := docx.Options{Syntax: parseDelims(delims)}
+	var split []string
+	var err error
+
+	ext := strings.ToLower(filepath.Ext(inputPath))
+	switch ext {
+	case ".docx":
+		split, err = docx.NormalizeWithOptions(inputPath, outputPath, opts)
+	case ".pptx":
+		split, err = pptx.NormalizeWithOptions(inputPath, outputPath, opts)
+	default:
+		fmt.Printf("Error: Unsupported template format: %s (use .docx or .pptx)\n", ext)
+		os.Exit(1)
+	}
+
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✓ Template normalized: %s\n", outputPath)
+	if len(split) == 0 {
+		fmt.Println("  No split placeholders found")
+		return
+	}
+
+	fmt.Printf("  Merged %d split placeholders:\n", len(split))
+	for _, placeholder := range split {
+		fmt.Printf("    %s\n", placeholder)
+	}
+}
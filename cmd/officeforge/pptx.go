@@ -17,11 +17,11 @@ func handlePptxSingle(args []string) {
 	if len(args) < 8 {
 		fmt.Println("Error: Missing required arguments")
 		fmt.Println("\nUsage:")
-		fmt.Println("  officeforge pptx-single --input <template> --output <file> --key <keyword> --value <replacement>")
+		fmt.Println("  officeforge pptx-single --input <template> --output <file> --key <keyword> --value <replacement> [--delims \"<left> <right>\"] [--locale <tag>]")
 		os.Exit(1)
 	}
 
-	var inputPath, outputPath, keyword, replacement string
+	var inputPath, outputPath, keyword, replacement, delims, locale string
 
 	for i := 0; i < len(args); i++ {
 		switch args[i] {
@@ -45,6 +45,16 @@ func handlePptxSingle(args []string) {
 				replacement = args[i+1]
 				i++
 			}
+		case "--delims":
+			if i+1 < len(args) {
+				delims = args[i+1]
+				i++
+			}
+		case "--locale":
+			if i+1 < len(args) {
+				locale = args[i+1]
+				i++
+			}
 		}
 	}
 
@@ -53,9 +63,10 @@ func handlePptxSingle(args []string) {
 		os.Exit(1)
 	}
 
-	keyword = internal.NormalizeKey(keyword)
+	syntax := parseDelims(delims)
+	keyword = syntax.NormalizeKey(keyword)
 
-	err := pptx.ProcessPptxSingle(inputPath, outputPath, keyword, replacement)
+	err := pptx.ProcessPptxMultiWithOptions(inputPath, outputPath, map[string]string{keyword: replacement}, pptx.Options{Syntax: syntax, Locale: locale})
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
@@ -68,11 +79,12 @@ func handlePptxMulti(args []string) {
 	if len(args) < 6 {
 		fmt.Println("Error: Missing required arguments")
 		fmt.Println("\nUsage:")
-		fmt.Println("  officeforge pptx-multi --input <template> --output <file> --data <json_file>")
+		fmt.Println("  officeforge pptx-multi --input <template> --output <file> --data <json_file> [--delims \"<left> <right>\"] [--locale <tag>] [--set-prop <name>=<value>]")
 		os.Exit(1)
 	}
 
-	var inputPath, outputPath, dataPath string
+	var inputPath, outputPath, dataPath, delims, locale string
+	props := make(map[string]string)
 
 	for i := 0; i < len(args); i++ {
 		switch args[i] {
@@ -91,6 +103,21 @@ func handlePptxMulti(args []string) {
 				dataPath = args[i+1]
 				i++
 			}
+		case "--set-prop":
+			if i+1 < len(args) {
+				parseSetProp(args[i+1], props)
+				i++
+			}
+		case "--delims":
+			if i+1 < len(args) {
+				delims = args[i+1]
+				i++
+			}
+		case "--locale":
+			if i+1 < len(args) {
+				locale = args[i+1]
+				i++
+			}
 		}
 	}
 
@@ -100,22 +127,16 @@ func handlePptxMulti(args []string) {
 	}
 
 	// Read JSON file
-	data, err := os.ReadFile(dataPath)
+	data, err := readJSONData(dataPath)
 	if err != nil {
 		fmt.Printf("Error reading data file: %v\n", err)
 		os.Exit(1)
 	}
 
-	var replacements map[string]string
-	err = json.Unmarshal(data, &replacements)
-	if err != nil {
-		fmt.Printf("Error parsing JSON: %v\n", err)
-		os.Exit(1)
-	}
+	syntax := parseDelims(delims)
+	replacements := data.Flatten(syntax)
 
-	replacements = internal.NormalizeReplacements(replacements)
-
-	err = pptx.ProcessPptxMulti(inputPath, outputPath, replacements)
+	err = pptx.ProcessPptxMultiWithOptions(inputPath, outputPath, replacements, pptx.Options{Syntax: syntax, Locale: locale, Properties: props})
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
@@ -129,7 +150,7 @@ func handlePptxBatch(args []string) {
 	if len(args) < 6 {
 		fmt.Println("Error: Missing required arguments")
 		fmt.Println("\nUsage:")
-		fmt.Println("  officeforge pptx-batch --input <template> --output <directory> --data <csv_or_json_file> [--pattern <pattern>]")
+		fmt.Println("  officeforge pptx-batch --input <template> --output <directory> --data <csv_json_or_xlsx_file> [--pattern <pattern>] [--delims \"<left> <right>\"] [--locale <tag>] [--locale-field <field>] [--set-prop <name>=<value>]")
 		fmt.Println("\nPattern examples:")
 		fmt.Printf("  --pattern \"presentation_%%d.pptx\"       Sequential: presentation_1.pptx, presentation_2.pptx\n")
 		fmt.Println("  --pattern \"{{NAME}}_slides.pptx\"        From data: Alice_slides.pptx, Bob_slides.pptx")
@@ -138,7 +159,8 @@ func handlePptxBatch(args []string) {
 		os.Exit(1)
 	}
 
-	var inputPath, outputDir, dataPath, pattern string
+	var inputPath, outputDir, dataPath, pattern, delims, locale, localeField string
+	props := make(map[string]string)
 
 	for i := 0; i < len(args); i++ {
 		switch args[i] {
@@ -162,6 +184,26 @@ func handlePptxBatch(args []string) {
 				pattern = args[i+1]
 				i++
 			}
+		case "--set-prop":
+			if i+1 < len(args) {
+				parseSetProp(args[i+1], props)
+				i++
+			}
+		case "--delims":
+			if i+1 < len(args) {
+				delims = args[i+1]
+				i++
+			}
+		case "--locale":
+			if i+1 < len(args) {
+				locale = args[i+1]
+				i++
+			}
+		case "--locale-field":
+			if i+1 < len(args) {
+				localeField = args[i+1]
+				i++
+			}
 		}
 	}
 
@@ -178,16 +220,19 @@ func handlePptxBatch(args []string) {
 	}
 
 	// Determine file type and read data
+	dataPath, sheet := splitSheetPath(dataPath)
 	ext := strings.ToLower(filepath.Ext(dataPath))
-	var records []map[string]string
+	var records []internal.Data
 
 	switch ext {
 	case ".json":
 		records, err = readJSONRecords(dataPath)
 	case ".csv":
 		records, err = readCSVRecords(dataPath)
+	case ".xlsx":
+		records, err = readXLSXRecords(dataPath, sheet)
 	default:
-		fmt.Printf("Error: Unsupported data file format: %s (use .json or .csv)\n", ext)
+		fmt.Printf("Error: Unsupported data file format: %s (use .json, .csv or .xlsx)\n", ext)
 		os.Exit(1)
 	}
 
@@ -201,31 +246,38 @@ func handlePptxBatch(args []string) {
 		os.Exit(1)
 	}
 
+	syntax := parseDelims(delims)
+	opts := pptx.Options{Syntax: syntax, Locale: locale, LocaleField: localeField, Properties: props}
+	if err := opts.Validate(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	normalizedRecords := make([]map[string]string, len(records))
 	for i, record := range records {
-		normalizedRecords[i] = internal.NormalizeReplacements(record)
+		normalizedRecords[i] = record.Flatten(syntax)
 	}
 
 	// Validate pattern if provided
 	if pattern != "" {
-		if err := internal.ValidatePattern(pattern, normalizedRecords[0]); err != nil {
+		if err := internal.ValidatePattern(pattern, normalizedRecords[0], opts); err != nil {
 			fmt.Printf("Error: Invalid pattern - %v\n", err)
 			os.Exit(1)
 		}
 
 		// Show what pattern type is being used
-		patternType := internal.DetectPatternType(pattern)
+		patternType := internal.DetectPatternType(pattern, syntax)
 		switch patternType {
 		case internal.PatternTypeSequential:
 			fmt.Printf("Using sequential pattern: %s\n", pattern)
 		case internal.PatternTypeData:
-			placeholders := internal.ExtractPlaceholders(pattern)
+			placeholders := internal.ExtractPlaceholders(pattern, syntax)
 			fmt.Printf("Using data-based pattern with fields: %v\n", placeholders)
 		}
 	}
 
 	// Process presentations using the pattern
-	err = pptx.ProcessPptxMultipleRecords(inputPath, outputDir, normalizedRecords, pattern)
+	err = pptx.ProcessPptxMultipleRecordsWithOptions(inputPath, outputDir, normalizedRecords, pattern, opts)
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
@@ -238,11 +290,11 @@ func handlePptxCheck(args []string) {
 	if len(args) < 4 {
 		fmt.Println("Error: Missing required arguments")
 		fmt.Println("\nUsage:")
-		fmt.Println("  officeforge pptx-check --input <file> [--keys \"K1,K2\" | --data <json_file>] [--json]")
+		fmt.Println("  officeforge pptx-check --input <file> [--keys \"K1,K2\" | --data <json_file>] [--json] [--delims \"<left> <right>\"]")
 		os.Exit(1)
 	}
 
-	var inputPath, keysString, dataPath string
+	var inputPath, keysString, dataPath, delims string
 	var outputJson bool
 
 	for i := 0; i < len(args); i++ {
@@ -264,6 +316,11 @@ func handlePptxCheck(args []string) {
 			}
 		case "--json":
 			outputJson = true
+		case "--delims":
+			if i+1 < len(args) {
+				delims = args[i+1]
+				i++
+			}
 		}
 	}
 
@@ -272,6 +329,7 @@ func handlePptxCheck(args []string) {
 		os.Exit(1)
 	}
 
+	syntax := parseDelims(delims)
 	var keywords []string
 
 	// Load keywords from JSON or Comma Separated String
@@ -296,17 +354,13 @@ func handlePptxCheck(args []string) {
 			var dataList []map[string]any
 			if err := json.Unmarshal(data, &dataList); err == nil {
 				if len(dataList) > 0 {
-					for k := range dataList[0] {
-						keywords = append(keywords, k)
-					}
+					keywords = internal.DataKeys(dataList[0], syntax)
 				}
 			} else {
 				// Try parsing as a single object (starts with {)
 				var keyMap map[string]any
 				if err := json.Unmarshal(data, &keyMap); err == nil {
-					for k := range keyMap {
-						keywords = append(keywords, k)
-					}
+					keywords = internal.DataKeys(keyMap, syntax)
 				} else {
 					// Try parsing as a simple array of strings (starts with ["str"])
 					var keyList []string
@@ -331,11 +385,11 @@ func handlePptxCheck(args []string) {
 	}
 
 	for i := range keywords {
-		keywords[i] = internal.NormalizeKey(keywords[i])
+		keywords[i] = syntax.NormalizeKey(keywords[i])
 	}
 
 	// Call the validation function
-	results, err := internal.ValidatePptxKeywords(inputPath, keywords)
+	results, err := internal.ValidatePptxKeywords(inputPath, keywords, syntax)
 	if err != nil {
 		if outputJson {
 			fmt.Printf(`{"error": "%v"}`, err)
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/siliconcatalyst/officeforge/docx"
+	"github.com/siliconcatalyst/officeforge/internal"
+	"github.com/siliconcatalyst/officeforge/pptx"
+	"github.com/siliconcatalyst/officeforge/xlsx"
+)
+
+func handleProps(args []string) {
+	if len(args) < 2 {
+		fmt.Println("Error: Missing required arguments")
+		fmt.Println("\nUsage:")
+		fmt.Println("  officeforge props --input <file> [--json]")
+		fmt.Println("  officeforge props --input <file> --output <file> --set-prop <name>=<value> [--set-prop ...]")
+		os.Exit(1)
+	}
+
+	var inputPath, outputPath string
+	var outputJson bool
+	props := make(map[string]string)
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--input", "-i":
+			if i+1 < len(args) {
+				inputPath = args[i+1]
+				i++
+			}
+		case "--output", "-o":
+			if i+1 < len(args) {
+				outputPath = args[i+1]
+				i++
+			}
+		case "--set-prop":
+			if i+1 < len(args) {
+				parseSetProp(args[i+1], props)
+				i++
+			}
+		case "--json":
+			outputJson = true
+		}
+	}
+
+	if inputPath == "" {
+		fmt.Println("Error: --input is required")
+		os.Exit(1)
+	}
+
+	var readProperties func(string) (internal.Properties, error)
+	var setProperties func(string, string, map[string]string) error
+	ext := strings.ToLower(filepath.Ext(inputPath))
+	switch ext {
+	case ".docx":
+		readProperties, setProperties = docx.ReadProperties, docx.SetProperties
+	case ".pptx":
+		readProperties, setProperties = pptx.ReadProperties, pptx.SetProperties
+	case ".xlsx":
+		readProperties, setProperties = xlsx.ReadProperties, xlsx.SetProperties
+	default:
+		fmt.Printf("Error: Unsupported input format: %s (use .docx, .pptx or .xlsx)\n", ext)
+		os.Exit(1)
+	}
+
+	if len(props) > 0 {
+		if outputPath == "" {
+			fmt.Println("Error: --output is required with --set-prop")
+			os.Exit(1)
+		}
+		if err := setProperties(inputPath, outputPath, props); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ Set %d properties: %s\n", len(props), outputPath)
+		return
+	}
+
+	properties, err := readProperties(inputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if outputJson {
+		output, _ := json.MarshalIndent(map[string]map[string]string{
+			"core":   properties.Core,
+			"app":    properties.App,
+			"custom": properties.Custom,
+		}, "", "  ")
+		fmt.Println(string(output))
+		return
+	}
+
+	for _, group := range []struct {
+		title  string
+		values map[string]string
+	}{{"Core", properties.Core}, {"App", properties.App}, {"Custom", properties.Custom}} {
+		if len(group.values) == 0 {
+			continue
+		}
+		fmt.Printf("%s properties:\n", group.title)
+		names := make([]string, 0, len(group.values))
+		for name := range group.values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("  %-22s %s\n", name, group.values[name])
+		}
+	}
+}
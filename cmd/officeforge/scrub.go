@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/siliconcatalyst/officeforge/docx"
+	"github.com/siliconcatalyst/officeforge/internal"
+	"github.com/siliconcatalyst/officeforge/pptx"
+	"github.com/siliconcatalyst/officeforge/xlsx"
+)
+
+func handleScrub(args []string) {
+	if len(args) < 4 {
+		fmt.Println("Error: Missing required arguments")
+		fmt.Println("\nUsage:")
+		fmt.Println("  officeforge scrub --input <file> --output <file> [--reject-revisions] [--keep-personal] [--keep-comments] [--keep-revisions] [--keep-hidden] [--keep-custom-xml] [--keep-thumbnail]")
+		os.Exit(1)
+	}
+
+	var inputPath, outputPath string
+	var opts internal.ScrubOptions
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--input", "-i":
+			if i+1 < len(args) {
+				inputPath = args[i+1]
+				i++
+			}
+		case "--output", "-o":
+			if i+1 < len(args) {
+				outputPath = args[i+1]
+				i++
+			}
+		case "--reject-revisions":
+			opts.RejectRevisions = true
+		case "--keep-personal":
+			opts.KeepPersonalInfo = true
+		case "--keep-comments":
+			opts.KeepComments = true
+		case "--keep-revisions":
+			opts.KeepRevisions = true
+		case "--keep-hidden":
+			opts.KeepHiddenText = true
+		case "--keep-custom-xml":
+			opts.KeepCustomXML = true
+		case "--keep-thumbnail":
+			opts.KeepThumbnail = true
+		}
+	}
+
+	if inputPath == "" || outputPath == "" {
+		fmt.Println("Error: All flags (--input, --output) are required")
+		os.Exit(1)
+	}
+
+	var scrub func(string, string, internal.ScrubOptions) (internal.ScrubReport, error)
+	ext := strings.ToLower(filepath.Ext(inputPath))
+	switch ext {
+	case ".docx":
+		scrub = docx.Scrub
+	case ".pptx":
+		scrub = pptx.Scrub
+	case ".xlsx":
+		scrub = xlsx.Scrub
+	default:
+		fmt.Printf("Error: Unsupported input format: %s (use .docx, .pptx or .xlsx)\n", ext)
+		os.Exit(1)
+	}
+
+	report, err := scrub(inputPath, outputPath, opts)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✓ Scrubbed file created: %s\n", outputPath)
+	if len(report.Items) == 0 {
+		fmt.Println("  Nothing to remove")
+	}
+	for _, item := range report.Items {
+		fmt.Printf("  Removed %s: %d\n", item.What, item.Count)
+	}
+}
@@ -2,19 +2,23 @@ package main
 
 import (
 	"encoding/csv"
-	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
+	"time"
+
+	"github.com/siliconcatalyst/officeforge/internal"
+	"github.com/siliconcatalyst/officeforge/xlsx"
 )
 
-func readJSONRecords(path string) ([]map[string]string, error) {
+func readJSONRecords(path string) ([]internal.Data, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
-	var records []map[string]string
-	err = json.Unmarshal(data, &records)
+	var records []internal.Data
+	err = internal.DecodeJSON(data, &records)
 	if err != nil {
 		return nil, err
 	}
@@ -22,7 +26,23 @@ func readJSONRecords(path string) ([]map[string]string, error) {
 	return records, nil
 }
 
-func readCSVRecords(path string) ([]map[string]string, error) {
+// readJSONData reads a single JSON object of replacement values, which may be nested and typed
+func readJSONData(path string) (internal.Data, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var data internal.Data
+	err = internal.DecodeJSON(content, &data)
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func readCSVRecords(path string) ([]internal.Data, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		return nil, err
@@ -40,7 +60,7 @@ func readCSVRecords(path string) ([]map[string]string, error) {
 	}
 
 	headers := rows[0]
-	var records []map[string]string
+	var records []internal.Data
 
 	for i := 1; i < len(rows); i++ {
 		if len(rows[i]) != len(headers) {
@@ -51,8 +71,73 @@ func readCSVRecords(path string) ([]map[string]string, error) {
 		for j, header := range headers {
 			record[header] = rows[i][j]
 		}
-		records = append(records, record)
+		records = append(records, internal.DataFromStrings(record))
+	}
+
+	return records, nil
+}
+
+// splitSheetPath splits "book.xlsx:Sheet2" into the workbook path and the sheet name; other
+// paths are returned as they are
+func splitSheetPath(path string) (string, string) {
+	if match := workbookSheetsPattern.FindStringSubmatch(path); match != nil {
+		return match[1], match[2]
+	}
+	return path, ""
+}
+
+// readXLSXRecords reads the rows of a sheet, the first one by default, as records keyed by
+// the header row
+func readXLSXRecords(path, sheet string) ([]internal.Data, error) {
+	rows, err := xlsx.ReadRecords(path, sheet)
+	if err != nil {
+		return nil, err
 	}
 
+	records := make([]internal.Data, len(rows))
+	for i, row := range rows {
+		records[i] = internal.DataFromStrings(row)
+	}
 	return records, nil
 }
+
+// parseDelims converts the --delims flag into a placeholder syntax, exiting on invalid input
+func parseDelims(delims string) internal.Syntax {
+	if delims == "" {
+		return internal.Syntax{}
+	}
+
+	syntax, err := internal.ParseSyntax(delims)
+	if err != nil {
+		fmt.Printf("Error: Invalid --delims value - %v\n", err)
+		os.Exit(1)
+	}
+	return syntax
+}
+
+// parseSetProp adds a --set-prop value such as title="Contract {{ID}}" to props, exiting on
+// invalid input
+func parseSetProp(arg string, props map[string]string) {
+	name, value, ok := strings.Cut(arg, "=")
+	if !ok || strings.TrimSpace(name) == "" {
+		fmt.Printf("Error: Invalid --set-prop value %q (use <name>=<value>)\n", arg)
+		os.Exit(1)
+	}
+	props[strings.TrimSpace(name)] = value
+}
+
+// parseRevisionDate converts the --revision-date flag, a date or a date and time such as
+// 2026-03-01T09:30:00Z, exiting on invalid input. Empty returns the zero time.
+func parseRevisionDate(value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date
+		}
+	}
+	fmt.Printf("Error: Invalid --revision-date value %q (use 2026-03-01 or 2026-03-01T09:30:00Z)\n", value)
+	os.Exit(1)
+	return time.Time{}
+}
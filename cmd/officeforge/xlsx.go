@@ -5,6 +5,7 @@ import (
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -17,11 +18,11 @@ func handleXlsxSingle(args []string) {
 	if len(args) < 8 {
 		fmt.Println("Error: Missing required arguments")
 		fmt.Println("\nUsage:")
-		fmt.Println("  officeforge xlsx-single --input <template> --output <file> --key <keyword> --value <replacement>")
+		fmt.Println("  officeforge xlsx-single --input <template> --output <file> --key <keyword> --value <replacement> [--delims \"<left> <right>\"] [--locale <tag>]")
 		os.Exit(1)
 	}
 
-	var inputPath, outputPath, keyword, replacement string
+	var inputPath, outputPath, keyword, replacement, delims, locale string
 
 	for i := 0; i < len(args); i++ {
 		switch args[i] {
@@ -45,6 +46,16 @@ func handleXlsxSingle(args []string) {
 				replacement = args[i+1]
 				i++
 			}
+		case "--delims":
+			if i+1 < len(args) {
+				delims = args[i+1]
+				i++
+			}
+		case "--locale":
+			if i+1 < len(args) {
+				locale = args[i+1]
+				i++
+			}
 		}
 	}
 
@@ -53,9 +64,10 @@ func handleXlsxSingle(args []string) {
 		os.Exit(1)
 	}
 
-	keyword = internal.NormalizeKey(keyword)
+	syntax := parseDelims(delims)
+	keyword = syntax.NormalizeKey(keyword)
 
-	err := xlsx.ProcessXlsxSingle(inputPath, outputPath, keyword, replacement)
+	err := xlsx.ProcessXlsxMultiWithOptions(inputPath, outputPath, map[string]string{keyword: replacement}, xlsx.Options{Syntax: syntax, Locale: locale})
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
@@ -68,11 +80,12 @@ func handleXlsxMulti(args []string) {
 	if len(args) < 6 {
 		fmt.Println("Error: Missing required arguments")
 		fmt.Println("\nUsage:")
-		fmt.Println("  officeforge xlsx-multi --input <template> --output <file> --data <json_file>")
+		fmt.Println("  officeforge xlsx-multi --input <template> --output <file> --data <json_file> [--delims \"<left> <right>\"] [--locale <tag>] [--set-prop <name>=<value>]")
 		os.Exit(1)
 	}
 
-	var inputPath, outputPath, dataPath string
+	var inputPath, outputPath, dataPath, delims, locale string
+	props := make(map[string]string)
 
 	for i := 0; i < len(args); i++ {
 		switch args[i] {
@@ -91,6 +104,21 @@ func handleXlsxMulti(args []string) {
 				dataPath = args[i+1]
 				i++
 			}
+		case "--set-prop":
+			if i+1 < len(args) {
+				parseSetProp(args[i+1], props)
+				i++
+			}
+		case "--delims":
+			if i+1 < len(args) {
+				delims = args[i+1]
+				i++
+			}
+		case "--locale":
+			if i+1 < len(args) {
+				locale = args[i+1]
+				i++
+			}
 		}
 	}
 
@@ -100,22 +128,16 @@ func handleXlsxMulti(args []string) {
 	}
 
 	// Read JSON file
-	data, err := os.ReadFile(dataPath)
+	data, err := readJSONData(dataPath)
 	if err != nil {
 		fmt.Printf("Error reading data file: %v\n", err)
 		os.Exit(1)
 	}
 
-	var replacements map[string]string
-	err = json.Unmarshal(data, &replacements)
-	if err != nil {
-		fmt.Printf("Error parsing JSON: %v\n", err)
-		os.Exit(1)
-	}
-
-	replacements = internal.NormalizeReplacements(replacements)
+	syntax := parseDelims(delims)
+	replacements := data.Flatten(syntax)
 
-	err = xlsx.ProcessXlsxMulti(inputPath, outputPath, replacements)
+	err = xlsx.ProcessXlsxMultiWithOptions(inputPath, outputPath, replacements, xlsx.Options{Syntax: syntax, Locale: locale, Properties: props})
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
@@ -129,7 +151,7 @@ func handleXlsxBatch(args []string) {
 	if len(args) < 6 {
 		fmt.Println("Error: Missing required arguments")
 		fmt.Println("\nUsage:")
-		fmt.Println("  officeforge xlsx-batch --input <template> --output <directory> --data <csv_or_json_file> [--pattern <pattern>]")
+		fmt.Println("  officeforge xlsx-batch --input <template> --output <directory> --data <csv_json_or_xlsx_file> [--pattern <pattern>] [--delims \"<left> <right>\"] [--locale <tag>] [--locale-field <field>] [--set-prop <name>=<value>]")
 		fmt.Println("\nPattern examples:")
 		fmt.Printf("  --pattern \"report_%%d.xlsx\"             Sequential: report_1.xlsx, report_2.xlsx\n")
 		fmt.Println("  --pattern \"{{NAME}}_report.xlsx\"        From data: Alice_report.xlsx, Bob_report.xlsx")
@@ -138,7 +160,8 @@ func handleXlsxBatch(args []string) {
 		os.Exit(1)
 	}
 
-	var inputPath, outputDir, dataPath, pattern string
+	var inputPath, outputDir, dataPath, pattern, delims, locale, localeField string
+	props := make(map[string]string)
 
 	for i := 0; i < len(args); i++ {
 		switch args[i] {
@@ -162,6 +185,26 @@ func handleXlsxBatch(args []string) {
 				pattern = args[i+1]
 				i++
 			}
+		case "--set-prop":
+			if i+1 < len(args) {
+				parseSetProp(args[i+1], props)
+				i++
+			}
+		case "--delims":
+			if i+1 < len(args) {
+				delims = args[i+1]
+				i++
+			}
+		case "--locale":
+			if i+1 < len(args) {
+				locale = args[i+1]
+				i++
+			}
+		case "--locale-field":
+			if i+1 < len(args) {
+				localeField = args[i+1]
+				i++
+			}
 		}
 	}
 
@@ -178,16 +221,19 @@ func handleXlsxBatch(args []string) {
 	}
 
 	// Determine file type and read data
+	dataPath, sheet := splitSheetPath(dataPath)
 	ext := strings.ToLower(filepath.Ext(dataPath))
-	var records []map[string]string
+	var records []internal.Data
 
 	switch ext {
 	case ".json":
 		records, err = readJSONRecords(dataPath)
 	case ".csv":
 		records, err = readCSVRecords(dataPath)
+	case ".xlsx":
+		records, err = readXLSXRecords(dataPath, sheet)
 	default:
-		fmt.Printf("Error: Unsupported data file format: %s (use .json or .csv)\n", ext)
+		fmt.Printf("Error: Unsupported data file format: %s (use .json, .csv or .xlsx)\n", ext)
 		os.Exit(1)
 	}
 
@@ -201,31 +247,38 @@ func handleXlsxBatch(args []string) {
 		os.Exit(1)
 	}
 
+	syntax := parseDelims(delims)
+	opts := xlsx.Options{Syntax: syntax, Locale: locale, LocaleField: localeField, Properties: props}
+	if err := opts.Validate(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	normalizedRecords := make([]map[string]string, len(records))
 	for i, record := range records {
-		normalizedRecords[i] = internal.NormalizeReplacements(record)
+		normalizedRecords[i] = record.Flatten(syntax)
 	}
 
 	// Validate pattern if provided
 	if pattern != "" {
-		if err := internal.ValidatePattern(pattern, normalizedRecords[0]); err != nil {
+		if err := internal.ValidatePattern(pattern, normalizedRecords[0], opts); err != nil {
 			fmt.Printf("Error: Invalid pattern - %v\n", err)
 			os.Exit(1)
 		}
 
 		// Show what pattern type is being used
-		patternType := internal.DetectPatternType(pattern)
+		patternType := internal.DetectPatternType(pattern, syntax)
 		switch patternType {
 		case internal.PatternTypeSequential:
 			fmt.Printf("Using sequential pattern: %s\n", pattern)
 		case internal.PatternTypeData:
-			placeholders := internal.ExtractPlaceholders(pattern)
+			placeholders := internal.ExtractPlaceholders(pattern, syntax)
 			fmt.Printf("Using data-based pattern with fields: %v\n", placeholders)
 		}
 	}
 
 	// Process spreadsheets using the pattern
-	err = xlsx.ProcessXlsxMultipleRecords(inputPath, outputDir, normalizedRecords, pattern)
+	err = xlsx.ProcessXlsxMultipleRecordsWithOptions(inputPath, outputDir, normalizedRecords, pattern, opts)
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
@@ -238,11 +291,11 @@ func handleXlsxCheck(args []string) {
 	if len(args) < 4 {
 		fmt.Println("Error: Missing required arguments")
 		fmt.Println("\nUsage:")
-		fmt.Println("  officeforge xlsx-check --input <file> [--keys \"K1,K2\" | --data <json_file>] [--json]")
+		fmt.Println("  officeforge xlsx-check --input <file> [--keys \"K1,K2\" | --data <json_file>] [--json] [--delims \"<left> <right>\"]")
 		os.Exit(1)
 	}
 
-	var inputPath, keysString, dataPath string
+	var inputPath, keysString, dataPath, delims string
 	var outputJson bool
 
 	for i := 0; i < len(args); i++ {
@@ -264,6 +317,11 @@ func handleXlsxCheck(args []string) {
 			}
 		case "--json":
 			outputJson = true
+		case "--delims":
+			if i+1 < len(args) {
+				delims = args[i+1]
+				i++
+			}
 		}
 	}
 
@@ -273,6 +331,7 @@ func handleXlsxCheck(args []string) {
 		os.Exit(1)
 	}
 
+	syntax := parseDelims(delims)
 	var keywords []string
 
 	// Load keywords from JSON or Comma Separated String
@@ -297,17 +356,13 @@ func handleXlsxCheck(args []string) {
 			var dataList []map[string]any
 			if err := json.Unmarshal(data, &dataList); err == nil {
 				if len(dataList) > 0 {
-					for k := range dataList[0] {
-						keywords = append(keywords, k)
-					}
+					keywords = internal.DataKeys(dataList[0], syntax)
 				}
 			} else {
 				// Try parsing as a single object (starts with {)
 				var keyMap map[string]any
 				if err := json.Unmarshal(data, &keyMap); err == nil {
-					for k := range keyMap {
-						keywords = append(keywords, k)
-					}
+					keywords = internal.DataKeys(keyMap, syntax)
 				} else {
 					// Try parsing as a simple array of strings (starts with ["str"])
 					var keyList []string
@@ -332,11 +387,11 @@ func handleXlsxCheck(args []string) {
 	}
 
 	for i := range keywords {
-		keywords[i] = internal.NormalizeKey(keywords[i])
+		keywords[i] = syntax.NormalizeKey(keywords[i])
 	}
 
 	// Call the validation function
-	results, err := internal.ValidateXlsxKeywords(inputPath, keywords)
+	results, err := internal.ValidateXlsxKeywords(inputPath, keywords, syntax)
 	if err != nil {
 		if outputJson {
 			fmt.Printf(`{"error": "%v"}`, err)
@@ -377,3 +432,78 @@ func handleXlsxCheck(args []string) {
 		os.Exit(1)
 	}
 }
+
+func handleXlsxExport(args []string) {
+	if len(args) < 2 {
+		fmt.Println("Error: Missing required arguments")
+		fmt.Println("\nUsage:")
+		fmt.Println("  officeforge xlsx-export --input <workbook>[:<sheet>] [--sheet <name>] [--format csv|json] [--output <file>]")
+		os.Exit(1)
+	}
+
+	var inputPath, sheet, outputPath string
+	format := "csv"
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--input", "-i":
+			if i+1 < len(args) {
+				inputPath = args[i+1]
+				i++
+			}
+		case "--sheet", "-s":
+			if i+1 < len(args) {
+				sheet = args[i+1]
+				i++
+			}
+		case "--format", "-f":
+			if i+1 < len(args) {
+				format = strings.ToLower(args[i+1])
+				i++
+			}
+		case "--output", "-o":
+			if i+1 < len(args) {
+				outputPath = args[i+1]
+				i++
+			}
+		}
+	}
+
+	if inputPath == "" {
+		fmt.Println("Error: --input is required")
+		os.Exit(1)
+	}
+	if path, name := splitSheetPath(inputPath); name != "" {
+		inputPath = path
+		if sheet == "" {
+			sheet = name
+		}
+	}
+
+	var export func(w io.Writer, inputPath, sheet string) error
+	switch format {
+	case "csv":
+		export = xlsx.ExportCSV
+	case "json":
+		export = xlsx.ExportJSON
+	default:
+		fmt.Printf("Error: Unknown format: %s (use csv or json)\n", format)
+		os.Exit(1)
+	}
+
+	var buf bytes.Buffer
+	if err := export(&buf, inputPath, sheet); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if outputPath == "" {
+		os.Stdout.Write(buf.Bytes())
+		return
+	}
+	if err := os.WriteFile(outputPath, buf.Bytes(), 0644); err != nil {
+		fmt.Printf("Error: failed to write output file: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Exported %s to %s\n", inputPath, outputPath)
+}
@@ -0,0 +1,65 @@
+package docx
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/siliconcatalyst/officeforge/internal"
+)
+
+// SplitBy selects where Split starts a new document.
+type SplitBy = internal.SplitBy
+
+const (
+	// SplitSections starts a new document after every section break.
+	SplitSections = internal.SplitSections
+	// SplitHeadings starts a new document at every top-level heading (Heading 1).
+	SplitHeadings = internal.SplitHeadings
+)
+
+// Concat writes the documents read from inputs, in order, as a single document to w.
+// Each document keeps its sections, so page setup, headers and footers are preserved.
+// Styles the first document lacks, lists, footnotes, comments, images and other related
+// parts are merged in and renumbered; styles of the same ID keep the first document's definition.
+func Concat(w io.Writer, inputs ...io.Reader) error {
+	if len(inputs) == 0 {
+		return fmt.Errorf("no documents to concatenate")
+	}
+
+	host, err := internal.ReadPackage(inputs[0])
+	if err != nil {
+		return fmt.Errorf("failed to open document 1: %v", err)
+	}
+	merger, err := internal.NewDocxMerger(host, false)
+	if err != nil {
+		return fmt.Errorf("failed to open document 1: %v", err)
+	}
+
+	for i, input := range inputs[1:] {
+		pkg, err := internal.ReadPackage(input)
+		if err != nil {
+			return fmt.Errorf("failed to open document %d: %v", i+2, err)
+		}
+		if err := merger.Append(pkg); err != nil {
+			return fmt.Errorf("failed to append document %d: %v", i+2, err)
+		}
+	}
+
+	merger.Finish()
+	return host.SaveTo(w)
+}
+
+// Split reads a document from r and splits it at its section breaks or top-level headings,
+// returning each part as a DOCX file. A part keeps the page setup, headers and footers of the
+// section it ends in; relationships, notes, comments and media only other parts use are dropped.
+func Split(r io.Reader, by SplitBy) ([][]byte, error) {
+	pkg, err := internal.ReadPackage(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open input: %v", err)
+	}
+	parts, err := internal.SplitDocx(pkg, by)
+	if err != nil {
+		return nil, fmt.Errorf("failed to split document: %v", err)
+	}
+	return parts, nil
+}
@@ -0,0 +1,58 @@
+package docx
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+
+	"github.com/siliconcatalyst/officeforge/internal"
+)
+
+// Normalize rewrites a DOCX template so that placeholders are contiguous text.
+// Adjacent runs with identical formatting are merged, and proofing marks, revision
+// session IDs (w:rsid*) and cached page breaks are removed. It returns the
+// placeholders that were split across several runs in the original template.
+func Normalize(inputPath, outputPath string) ([]string, error) {
+	return NormalizeWithOptions(inputPath, outputPath, Options{})
+}
+
+// NormalizeWithOptions normalizes a template like Normalize, reporting the split placeholders
+// written in opts.Syntax.
+func NormalizeWithOptions(inputPath, outputPath string, opts Options) ([]string, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	reader, err := zip.OpenReader(inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open input file: %v", err)
+	}
+	defer reader.Close()
+
+	outputFile, err := os.Create(outputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output file: %v", err)
+	}
+	defer outputFile.Close()
+
+	zipWriter := zip.NewWriter(outputFile)
+	defer zipWriter.Close()
+
+	var split []string
+	seen := make(map[string]bool)
+
+	for _, file := range reader.File {
+		found, err := internal.NormalizeDocxZipFile(file, zipWriter, opts.Syntax)
+		if err != nil {
+			return nil, fmt.Errorf("failed to process file %s: %v", file.Name, err)
+		}
+		for _, placeholder := range found {
+			if !seen[placeholder] {
+				seen[placeholder] = true
+				split = append(split, placeholder)
+			}
+		}
+	}
+
+	return split, nil
+}
@@ -0,0 +1,58 @@
+package docx
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/siliconcatalyst/officeforge/internal"
+)
+
+// ConvertToPDF writes the document at inputPath as a PDF file to outputPath. Paragraphs keep
+// their fonts, sizes, colors, spacing and alignment; lists, tables, pictures, page sizes,
+// margins, headers and footers are laid out in pages. Fonts embedded in the document are
+// embedded in the PDF and other fonts replaced by Helvetica, Times or Courier. The layout is
+// close to Word's but not identical, and text outside Western European scripts is not shown.
+func ConvertToPDF(inputPath, outputPath string) error {
+	pkg, err := internal.OpenPackage(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to open input file: %v", err)
+	}
+	defer pkg.Close()
+	return savePDF(pkg, outputPath)
+}
+
+// WritePDF writes the document at inputPath as PDF to w; see ConvertToPDF.
+func WritePDF(w io.Writer, inputPath string) error {
+	pkg, err := internal.OpenPackage(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to open input file: %v", err)
+	}
+	defer pkg.Close()
+	if err := internal.RenderDocxPDF(pkg, w); err != nil {
+		return fmt.Errorf("failed to render PDF: %v", err)
+	}
+	return nil
+}
+
+// save writes a rendered document to outputPath, as PDF when the path ends in ".pdf".
+func save(pkg *internal.Package, outputPath string) error {
+	if strings.EqualFold(filepath.Ext(outputPath), ".pdf") {
+		return savePDF(pkg, outputPath)
+	}
+	return pkg.Save(outputPath)
+}
+
+func savePDF(pkg *internal.Package, outputPath string) error {
+	outputFile, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %v", err)
+	}
+	if err := internal.RenderDocxPDF(pkg, outputFile); err != nil {
+		outputFile.Close()
+		return fmt.Errorf("failed to render PDF: %v", err)
+	}
+	return outputFile.Close()
+}
@@ -0,0 +1,36 @@
+package docx
+
+import (
+	"fmt"
+
+	"github.com/siliconcatalyst/officeforge/internal"
+)
+
+// AcceptAllRevisions writes a copy of the document at inputPath with its tracked changes
+// accepted: insertions and moved-to text are kept, deletions and moved-from text removed,
+// formatting, table row and cell changes applied. It returns the number of changes accepted.
+// Set Options.Revisions to "accept" to do the same before rendering a template.
+func AcceptAllRevisions(inputPath, outputPath string) (int, error) {
+	return resolveRevisions(inputPath, outputPath, true)
+}
+
+// RejectAllRevisions writes a copy of the document at inputPath with its tracked changes
+// rejected, restoring the text, formatting and tables as they were before the changes. It
+// returns the number of changes rejected.
+func RejectAllRevisions(inputPath, outputPath string) (int, error) {
+	return resolveRevisions(inputPath, outputPath, false)
+}
+
+func resolveRevisions(inputPath, outputPath string, accept bool) (int, error) {
+	pkg, err := internal.OpenPackage(inputPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open input file: %v", err)
+	}
+	defer pkg.Close()
+
+	count, err := internal.ResolveDocxRevisions(pkg, accept)
+	if err != nil {
+		return count, fmt.Errorf("failed to resolve tracked changes: %v", err)
+	}
+	return count, save(pkg, outputPath)
+}
@@ -0,0 +1,33 @@
+package docx
+
+import (
+	"fmt"
+
+	"github.com/siliconcatalyst/officeforge/internal"
+)
+
+// ScrubOptions selects what Scrub leaves in a file; the zero value removes everything it can.
+type ScrubOptions = internal.ScrubOptions
+
+// ScrubReport lists what Scrub removed.
+type ScrubReport = internal.ScrubReport
+
+// ScrubItem is a kind of content Scrub removed, with how much of it.
+type ScrubItem = internal.ScrubItem
+
+// Scrub writes a copy of the document at inputPath without authoring traces: personal properties,
+// comments, hidden text, the people part, custom XML data and the thumbnail.
+// Tracked changes are accepted unless opts.RejectRevisions is set.
+func Scrub(inputPath, outputPath string, opts ScrubOptions) (ScrubReport, error) {
+	pkg, err := internal.OpenPackage(inputPath)
+	if err != nil {
+		return ScrubReport{}, fmt.Errorf("failed to open input file: %v", err)
+	}
+	defer pkg.Close()
+
+	report, err := internal.ScrubDocx(pkg, opts)
+	if err != nil {
+		return report, fmt.Errorf("failed to scrub document: %v", err)
+	}
+	return report, save(pkg, outputPath)
+}
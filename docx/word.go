@@ -1,8 +1,8 @@
 package docx
 
 import (
-	"archive/zip"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
@@ -10,63 +10,145 @@ import (
 	"github.com/siliconcatalyst/officeforge/internal"
 )
 
+// Options controls how templates are rendered.
+type Options = internal.Options
+
+// Syntax describes how placeholders are written in templates.
+type Syntax = internal.Syntax
+
+// FilterFunc formats a placeholder value, e.g. the currency filter in {{AMOUNT | currency:"EUR"}}.
+type FilterFunc = internal.FilterFunc
+
+// FuncMap registers custom placeholder filters by name.
+type FuncMap = internal.FuncMap
+
+// Data holds typed record values: strings, numbers, bools, nested objects addressed as
+// {{customer.address.city}} and arrays addressed as {{items[0].name}}.
+type Data = internal.Data
+
+// Link is a hyperlink value shown with the link filter, e.g. {{PORTAL | link}}.
+type Link = internal.Link
+
+// Table is a table value shown with the table filter, e.g. {{ITEMS | table}}; the placeholder's
+// paragraph is replaced by a Word table.
+type Table = internal.Table
+
+// TableFromRows builds a table whose first row is the header.
+func TableFromRows(rows [][]string) Table {
+	return internal.TableFromRows(rows)
+}
+
+// TableFromCSV reads a table from CSV; the first record is the header.
+func TableFromCSV(r io.Reader) (Table, error) {
+	return internal.TableFromCSV(r)
+}
+
+// TableFromJSON reads a table from a JSON array of arrays (header first) or of objects.
+func TableFromJSON(content []byte) (Table, error) {
+	return internal.TableFromJSON(content)
+}
+
 func ProcessDocxSingle(inputPath, outputPath, keyword, replacement string) error {
-	reader, err := zip.OpenReader(inputPath)
-	if err != nil {
-		return fmt.Errorf("failed to open input file: %v", err)
+	// Create replacements map
+	replacements := map[string]string{keyword: replacement}
+	return ProcessDocxMultiWithOptions(inputPath, outputPath, replacements, Options{})
+}
+
+func ProcessDocxMulti(inputPath, outputPath string, replacements map[string]string) error {
+	return ProcessDocxMultiWithOptions(inputPath, outputPath, replacements, Options{})
+}
+
+// ProcessDocxMultiWithOptions performs multiple keyword replacements using the given options.
+// An outputPath ending in ".pdf" writes the result as PDF; see ConvertToPDF.
+func ProcessDocxMultiWithOptions(inputPath, outputPath string, replacements map[string]string, opts Options) error {
+	if err := opts.Validate(); err != nil {
+		return err
 	}
-	defer reader.Close()
 
-	outputFile, err := os.Create(outputPath)
+	pkg, err := renderDocx(inputPath, replacements, opts)
 	if err != nil {
-		return fmt.Errorf("failed to create output file: %v", err)
+		return err
 	}
-	defer outputFile.Close()
+	defer pkg.Close()
 
-	zipWriter := zip.NewWriter(outputFile)
-	defer zipWriter.Close()
+	// log.Printf("Successfully processed %d replacements in %s", len(replacements), outputPath)
+	return save(pkg, outputPath)
+}
 
-	// Create replacements map
-	replacements := map[string]string{keyword: replacement}
+// renderDocx opens the template and replaces its placeholders; the caller closes the package.
+func renderDocx(inputPath string, replacements map[string]string, opts Options) (*internal.Package, error) {
+	pkg, err := internal.OpenPackage(inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open input file: %v", err)
+	}
+	pkg.SetCompressionLevel(opts.CompressionLevel)
 
-	// Process each file in the DOCX
-	for _, file := range reader.File {
-		err := internal.ProcessDocxZipFile(file, zipWriter, replacements)
-		if err != nil {
-			return fmt.Errorf("failed to process file %s: %v", file.Name, err)
+	if opts.Revisions != "" {
+		if _, err := internal.ResolveDocxRevisions(pkg, opts.Revisions == "accept"); err != nil {
+			pkg.Close()
+			return nil, fmt.Errorf("failed to resolve tracked changes: %v", err)
 		}
 	}
 
-	return nil
-}
+	replacer := internal.NewReplacer(replacements, opts)
 
-func ProcessDocxMulti(inputPath, outputPath string, replacements map[string]string) error {
-	reader, err := zip.OpenReader(inputPath)
-	if err != nil {
-		return fmt.Errorf("failed to open input file: %v", err)
+	includeDir := opts.IncludeDir
+	if includeDir == "" {
+		includeDir = filepath.Dir(inputPath)
+	}
+	if err := internal.IncludeDocx(pkg, includeDir, replacer, opts.IncludeAltChunk); err != nil {
+		pkg.Close()
+		return nil, fmt.Errorf("failed to include documents: %v", err)
 	}
-	defer reader.Close()
 
-	outputFile, err := os.Create(outputPath)
-	if err != nil {
-		return fmt.Errorf("failed to create output file: %v", err)
+	if err := internal.ProcessDocxPackage(pkg, replacer); err != nil {
+		pkg.Close()
+		return nil, fmt.Errorf("failed to process document: %v", err)
+	}
+	return pkg, nil
+}
+
+// RenderMerged renders the template once per record and writes all copies into a single
+// document, each in its own section so it keeps its headers and footers. Bookmarks, notes,
+// comments and drawings are renumbered; styles, lists and shared parts such as headers with
+// the same content are added once. Set opts.MergePageBreaks to separate copies with page
+// breaks instead. An outputPath ending in ".pdf" writes the merged document as PDF.
+func RenderMerged(inputPath, outputPath string, records []Data, opts Options) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("no records to merge")
 	}
-	defer outputFile.Close()
 
-	// Create zip writer for output
-	zipWriter := zip.NewWriter(outputFile)
-	defer zipWriter.Close()
+	var merged *internal.Package
+	var merger *internal.DocxMerger
+	for i, record := range records {
+		flat := record.Flatten(opts.Syntax)
+		pkg, err := renderDocx(inputPath, flat, opts.ForRecord(flat))
+		if err != nil {
+			return fmt.Errorf("record %d: %v", i+1, err)
+		}
+
+		if merger == nil {
+			// The first copy holds the merged document
+			merged = pkg
+			defer merged.Close()
+			if merger, err = internal.NewDocxMerger(pkg, opts.MergePageBreaks); err != nil {
+				return fmt.Errorf("failed to merge documents: %v", err)
+			}
+			continue
+		}
 
-	// Process each file in the DOCX
-	for _, file := range reader.File {
-		err := internal.ProcessDocxZipFile(file, zipWriter, replacements)
+		err = merger.Append(pkg)
+		pkg.Close()
 		if err != nil {
-			return fmt.Errorf("failed to process file %s: %v", file.Name, err)
+			return fmt.Errorf("failed to merge record %d: %v", i+1, err)
 		}
 	}
 
-	// log.Printf("Successfully processed %d replacements in %s", len(replacements), outputPath)
-	return nil
+	merger.Finish()
+	return save(merged, outputPath)
 }
 
 // ProcessDocxMultipleRecords generates multiple documents using a naming pattern
@@ -75,27 +157,36 @@ func ProcessDocxMulti(inputPath, outputPath string, replacements map[string]stri
 //   - Data-based: "{NAME}_contract.docx" (uses record fields)
 //   - Empty: defaults to "document_%d.docx"
 func ProcessDocxMultipleRecords(inputPath, outputDir string, records []map[string]string, fileNamePattern string) error {
+	return ProcessDocxMultipleRecordsWithOptions(inputPath, outputDir, records, fileNamePattern, Options{})
+}
+
+// ProcessDocxMultipleRecordsWithOptions generates multiple documents using a naming pattern and the given options
+func ProcessDocxMultipleRecordsWithOptions(inputPath, outputDir string, records []map[string]string, fileNamePattern string, opts Options) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
 		return fmt.Errorf("failed to create output directory: %v", err)
 	}
 
 	// Validate pattern with first record
 	if len(records) > 0 && fileNamePattern != "" {
-		if err := internal.ValidatePattern(fileNamePattern, records[0]); err != nil {
+		if err := internal.ValidatePattern(fileNamePattern, records[0], opts); err != nil {
 			return fmt.Errorf("invalid pattern: %v", err)
 		}
 	}
 
 	// Create naming function based on pattern
-	nameFunc := internal.CreateDocxNamingFunction(fileNamePattern)
+	nameFunc := internal.CreateDocxNamingFunction(fileNamePattern, opts)
 
 	for i, record := range records {
 		// Generate filename using the naming function
 		fileName := nameFunc(record, i+1)
 		outputPath := filepath.Join(outputDir, fileName)
 
-		// Process the document with this record's replacements
-		err := ProcessDocxMulti(inputPath, outputPath, record)
+		// Process the document with this record's replacements and locale
+		err := ProcessDocxMultiWithOptions(inputPath, outputPath, record, opts.ForRecord(record))
 		if err != nil {
 			log.Printf("Failed to process record %d: %v", i+1, err)
 			continue
@@ -131,3 +222,17 @@ func ProcessDocxMultipleRecordsWithNames(inputPath, outputDir string, records []
 	// log.Printf("Successfully processed %d records", len(records))
 	return nil
 }
+
+// ProcessDocxData replaces placeholders with values from data, which may be nested and typed
+func ProcessDocxData(inputPath, outputPath string, data Data, opts Options) error {
+	return ProcessDocxMultiWithOptions(inputPath, outputPath, data.Flatten(opts.Syntax), opts)
+}
+
+// ProcessDocxDataRecords generates one document per record using a naming pattern; see ProcessDocxMultipleRecords
+func ProcessDocxDataRecords(inputPath, outputDir string, records []Data, fileNamePattern string, opts Options) error {
+	flat := make([]map[string]string, len(records))
+	for i, record := range records {
+		flat[i] = record.Flatten(opts.Syntax)
+	}
+	return ProcessDocxMultipleRecordsWithOptions(inputPath, outputDir, flat, fileNamePattern, opts)
+}
@@ -0,0 +1,153 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Data holds the values of a record as decoded from JSON or built in Go: strings, numbers,
+// bools, nil, nested maps and slices. Nested values are addressed with dot paths and
+// indexes, e.g. {{customer.address.city}} or {{items[0].name}}.
+type Data map[string]any
+
+// Flatten converts data into replacements keyed by placeholder, e.g.
+// {"customer": {"name": "Jane"}} -> {"{{customer.name}}": "Jane"}.
+// Top-level keys may be written with or without delimiters.
+func (d Data) Flatten(syntax Syntax) map[string]string {
+	flat := make(map[string]string)
+	for key, value := range d {
+		if name, ok := syntax.Key(key); ok {
+			key = name
+		}
+		flattenValue(flat, key, value, syntax)
+	}
+	return flat
+}
+
+// Link is a hyperlink value. Placeholders using the link filter, e.g. {{PORTAL | link}},
+// become clickable links showing Text; other placeholders show "[Text](URL)".
+type Link struct {
+	Text string
+	URL  string
+}
+
+// String returns the link in the "[text](url)" form read by the link filter.
+func (l Link) String() string {
+	if l.Text == "" {
+		return l.URL
+	}
+	return "[" + strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`).Replace(l.Text) + "](" + l.URL + ")"
+}
+
+// DataFromStrings wraps a flat string record as Data.
+func DataFromStrings(record map[string]string) Data {
+	data := make(Data, len(record))
+	for key, value := range record {
+		data[key] = value
+	}
+	return data
+}
+
+// DecodeJSON decodes JSON into Go values the way Data expects, keeping numbers as written
+// so "1234.50" is not turned into "1234.5".
+func DecodeJSON(content []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if decoder.More() {
+		return fmt.Errorf("unexpected data after the top-level JSON value")
+	}
+	return nil
+}
+
+func flattenValue(flat map[string]string, path string, value any, syntax Syntax) {
+	switch v := value.(type) {
+	case Data:
+		flattenMap(flat, path, v, syntax)
+	case map[string]any:
+		flattenMap(flat, path, v, syntax)
+	case []any:
+		for i, item := range v {
+			flattenValue(flat, indexPath(path, i), item, syntax)
+		}
+	case nil, string, []byte, json.Number, time.Time:
+		flat[syntax.Wrap(path)] = FormatValue(value)
+	default:
+		// Other collections built in Go, e.g. map[string]string, []map[string]any or []int
+		rv := reflect.ValueOf(value)
+		switch {
+		case rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array:
+			for i := 0; i < rv.Len(); i++ {
+				flattenValue(flat, indexPath(path, i), rv.Index(i).Interface(), syntax)
+			}
+		case rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String:
+			iter := rv.MapRange()
+			for iter.Next() {
+				flattenValue(flat, path+"."+iter.Key().String(), iter.Value().Interface(), syntax)
+			}
+		default:
+			flat[syntax.Wrap(path)] = FormatValue(value)
+		}
+	}
+}
+
+func flattenMap(flat map[string]string, path string, m map[string]any, syntax Syntax) {
+	for key, item := range m {
+		flattenValue(flat, path+"."+key, item, syntax)
+	}
+}
+
+func indexPath(path string, index int) string {
+	return path + "[" + strconv.Itoa(index) + "]"
+}
+
+// FormatValue converts a scalar data value to the text placed in a document.
+// Whole numbers have no decimals, other numbers are never written in exponent form,
+// bools become "true"/"false" and nil becomes empty.
+func FormatValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		if f, err := v.Float64(); err == nil && strings.ContainsAny(string(v), "eE") {
+			return strconv.FormatFloat(f, 'f', -1, 64)
+		}
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case []byte:
+		return string(v)
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprint(value)
+}
+
+// DataKeys returns the placeholders provided by data in sorted order.
+func DataKeys(data Data, syntax Syntax) []string {
+	flat := data.Flatten(syntax)
+	keys := make([]string, 0, len(flat))
+	for key := range flat {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,223 @@
+package internal
+
+import (
+	"log"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	// Containers that must be opened and closed within the same loop body
+	wordContainerPattern = regexp.MustCompile(`<(/?)w:(tbl|tr|tc|sdt|sdtContent|hyperlink)\b[^>]*?(/?)>`)
+	// Paragraph IDs and bookmarks must stay unique, so repeated paragraphs drop them
+	wordParaIDAttrPattern = regexp.MustCompile(` w14:(?:paraId|textId)="[^"]*"`)
+	wordBookmarkPattern   = regexp.MustCompile(`<w:bookmark(?:Start|End)\b[^>]*/>`)
+)
+
+// docBlock is a piece of the document body: a paragraph or the markup between
+// paragraphs, or a loop repeating its children once per element of an array.
+type docBlock struct {
+	content  string
+	loop     string
+	children []docBlock
+}
+
+// blockTag is a loop marker paragraph: {{#each items}} opens a loop, {{/each}} closes it
+type blockTag struct {
+	open bool
+	path string
+}
+
+// parseDocumentBlocks groups the parts produced by splitIntoParagraphs into blocks.
+// Loop markers must be alone in their paragraph; the marker paragraphs are dropped.
+// If the markers don't pair up, the document is processed without loops.
+func parseDocumentBlocks(parts []string, syntax Syntax) []docBlock {
+	type frame struct {
+		path   string
+		blocks []docBlock
+		start  int
+	}
+	stack := []frame{{}}
+
+	for i, part := range parts {
+		tag, ok := parseBlockTag(part, syntax)
+		if !ok {
+			top := &stack[len(stack)-1]
+			top.blocks = append(top.blocks, docBlock{content: part})
+			continue
+		}
+
+		if tag.open {
+			stack = append(stack, frame{path: tag.path, start: i})
+			continue
+		}
+
+		if len(stack) == 1 {
+			log.Printf("Warning: %s without a matching %s, loops are ignored", syntax.Wrap("/each"), syntax.Wrap("#each"))
+			return flatBlocks(parts)
+		}
+		loop := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if !balancedMarkup(strings.Join(parts[loop.start+1:i], "")) {
+			log.Printf("Warning: loop over %q starts and ends in different table cells or containers, loops are ignored", loop.path)
+			return flatBlocks(parts)
+		}
+		top := &stack[len(stack)-1]
+		top.blocks = append(top.blocks, docBlock{loop: loop.path, children: loop.blocks})
+	}
+
+	if len(stack) > 1 {
+		log.Printf("Warning: %s over %q is never closed, loops are ignored", syntax.Wrap("#each"), stack[len(stack)-1].path)
+		return flatBlocks(parts)
+	}
+	return stack[0].blocks
+}
+
+func flatBlocks(parts []string) []docBlock {
+	blocks := make([]docBlock, len(parts))
+	for i, part := range parts {
+		blocks[i] = docBlock{content: part}
+	}
+	return blocks
+}
+
+// paragraphTag returns the inside of the tag when a paragraph holds nothing but a single
+// tag, e.g. "#each items" for {{#each items}}.
+func paragraphTag(part string, syntax Syntax) (string, bool) {
+	if !strings.HasPrefix(part, "<w:p") {
+		return "", false
+	}
+
+	text := strings.TrimSpace(extractTextFromParagraph(part))
+	left, right := syntax.Delimiters()
+	if len(text) < len(left)+len(right) || !strings.HasPrefix(text, left) || !strings.HasSuffix(text, right) {
+		return "", false
+	}
+
+	inner := strings.TrimSpace(text[len(left) : len(text)-len(right)])
+	if strings.Contains(inner, left) || strings.Contains(inner, right) {
+		return "", false
+	}
+	return inner, true
+}
+
+// parseBlockTag recognizes a paragraph holding nothing but a loop marker.
+func parseBlockTag(part string, syntax Syntax) (blockTag, bool) {
+	inner, ok := paragraphTag(part, syntax)
+	if !ok {
+		return blockTag{}, false
+	}
+
+	if inner == "/each" {
+		return blockTag{}, true
+	}
+	if args, found := strings.CutPrefix(inner, "#each"); found {
+		fields := strings.Fields(args)
+		if len(fields) == 1 && unicode.IsSpace(rune(args[0])) {
+			return blockTag{open: true, path: fields[0]}, true
+		}
+	}
+	return blockTag{}, false
+}
+
+// balancedMarkup reports whether every table, row, cell, content control and hyperlink
+// opened in markup is also closed in it.
+func balancedMarkup(markup string) bool {
+	depth := make(map[string]int)
+	for _, match := range wordContainerPattern.FindAllStringSubmatch(markup, -1) {
+		if match[3] == "/" {
+			continue // self-closing
+		}
+		if match[1] == "/" {
+			depth[match[2]]--
+			if depth[match[2]] < 0 {
+				return false
+			}
+		} else {
+			depth[match[2]]++
+		}
+	}
+	for _, d := range depth {
+		if d != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// hasLoop reports whether any of the blocks is a loop.
+func hasLoop(blocks []docBlock) bool {
+	for _, block := range blocks {
+		if block.loop != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// fillEmptyContainers adds an empty paragraph to the table cells and text boxes a loop over
+// an empty array left without content, as each must hold at least one paragraph.
+func fillEmptyContainers(xmlContent string) string {
+	for _, name := range []string{"w:tc", "w:txbxContent"} {
+		closeTag := "</" + name + ">"
+		var b strings.Builder
+		rest := xmlContent
+		for {
+			end := strings.Index(rest, closeTag)
+			if end < 0 {
+				break
+			}
+			// The closest start tag belongs to this element unless it holds nested ones
+			start := strings.LastIndex(rest[:end], "<"+name)
+			for start >= 0 && strings.IndexByte(" \t\r\n>", rest[start+len(name)+1]) < 0 {
+				start = strings.LastIndex(rest[:start], "<"+name)
+			}
+			b.WriteString(rest[:end])
+			if start >= 0 && isEmptyContainer(rest[start:end]) {
+				b.WriteString("<w:p/>")
+			}
+			b.WriteString(closeTag)
+			rest = rest[end+len(closeTag):]
+		}
+		b.WriteString(rest)
+		xmlContent = b.String()
+	}
+	return xmlContent
+}
+
+// isEmptyContainer reports whether an element, given without its end tag, holds nothing but
+// properties.
+func isEmptyContainer(element string) bool {
+	tagEnd := strings.IndexByte(element, '>')
+	if tagEnd < 0 || strings.Contains(element, "</"+elementName(element)+">") {
+		return false
+	}
+	for _, child := range xmlChildren(element[tagEnd+1:]) {
+		if elementName(child) != "w:tcPr" {
+			return false
+		}
+	}
+	return true
+}
+
+// renderBlocks writes the blocks with placeholders replaced, expanding loops.
+func renderBlocks(out *strings.Builder, blocks []docBlock, replacer *Replacer, rich *docxRich, repeated bool) {
+	for _, block := range blocks {
+		if block.loop == "" {
+			content := renderParagraph(block.content, replacer, rich)
+			if repeated {
+				content = wordParaIDAttrPattern.ReplaceAllString(content, "")
+				content = wordBookmarkPattern.ReplaceAllString(content, "")
+			}
+			out.WriteString(content)
+			continue
+		}
+
+		count := replacer.arrayLen(block.loop)
+		for i := 0; i < count; i++ {
+			renderBlocks(out, block.children, replacer.scope(block.loop, i, count), rich, repeated || i > 0)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	wordNumberingLevelPattern  = regexp.MustCompile(`<w:ilvl\s+w:val="(\d+)"`)
+	wordNumberingFormatPattern = regexp.MustCompile(`<w:lvl\b[^>]*?\bw:ilvl="(\d+)"[^>]*>.*?<w:numFmt\s+w:val="([^"]*)"`)
+	wordToggleOffPattern       = regexp.MustCompile(`\sw:val="(?:0|false|off)"`)
+	wordUnderlineNonePattern   = regexp.MustCompile(`<w:u\s+w:val="none"`)
+)
+
+// ExportDocx returns the body of a document as text, Markdown or HTML. Headings come from
+// the outline level of paragraphs and their styles, lists from their numbering. Headers,
+// footers, notes and comments are left out.
+func ExportDocx(pkg *Package, format ExportFormat) (string, error) {
+	content, err := pkg.Read(documentPartName)
+	if err != nil {
+		return "", err
+	}
+	body, ok := documentBody(string(content))
+	if !ok {
+		return "", fmt.Errorf("%s has no body", documentPartName)
+	}
+
+	e := &docxExport{pkg: pkg}
+	if e.levels, err = outlineLevels(pkg); err != nil {
+		return "", err
+	}
+	if e.styleLists, err = styleNumbering(pkg); err != nil {
+		return "", err
+	}
+	if e.numbering, err = numberingFormats(pkg); err != nil {
+		return "", err
+	}
+	if e.rels, err = pkg.readRelationships(documentPartName); err != nil {
+		return "", err
+	}
+
+	return renderExport(e.blocks(body), format), nil
+}
+
+// docxExport reads the blocks of a document body.
+type docxExport struct {
+	pkg        *Package
+	levels     map[string]int      // outline levels of heading styles
+	styleLists map[string]string   // list IDs of paragraph styles with numbering
+	numbering  map[string][]string // number formats of the levels of each list
+	rels       map[string]relationship
+}
+
+func (e *docxExport) blocks(content string) []exportBlock {
+	var blocks []exportBlock
+	for _, child := range xmlChildren(content) {
+		switch elementName(child) {
+		case "w:p":
+			blocks = append(blocks, e.paragraph(child))
+		case "w:tbl":
+			blocks = append(blocks, exportBlock{table: e.table(child)})
+		case "w:sdt", "w:sdtContent", "w:customXml", "w:ins":
+			blocks = append(blocks, e.blocks(elementContent(child))...)
+		}
+	}
+	return blocks
+}
+
+func (e *docxExport) paragraph(paragraph string) exportBlock {
+	var block exportBlock
+	pPr := ""
+	if match := wordPPrPattern.FindStringSubmatch(paragraph); match != nil {
+		pPr = match[1]
+	}
+	if level := paragraphOutlineLevel(paragraph, e.levels); level >= 0 {
+		block.heading = level + 1
+	}
+
+	// Lists: numbering set on the paragraph or by its style
+	listID, level := "", 0
+	if match := wordNumRefPattern.FindStringSubmatch(pPr); match != nil {
+		listID = match[2]
+	} else if style := wordParaStylePattern.FindStringSubmatch(pPr); style != nil {
+		listID = e.styleLists[style[1]]
+	}
+	if match := wordNumberingLevelPattern.FindStringSubmatch(pPr); match != nil {
+		level, _ = strconv.Atoi(match[1])
+	}
+	if listID != "" && listID != "0" && block.heading == 0 {
+		block.paragraph.list = listNumber
+		if formats := e.numbering[listID]; level < len(formats) && (formats[level] == "bullet" || formats[level] == "none") {
+			block.paragraph.list = listBullet
+		}
+		block.paragraph.level = level
+		block.paragraph.listID, _ = strconv.Atoi(listID)
+	}
+
+	block.paragraph.runs = e.runs(elementContent(paragraph), "")
+	return block
+}
+
+// runs reads the runs of paragraph content, within hyperlinks, fields, content controls and
+// insertions. Deleted text is left out.
+func (e *docxExport) runs(content, link string) []richRun {
+	var runs []richRun
+	for _, child := range xmlChildren(content) {
+		switch elementName(child) {
+		case "w:r":
+			runs = append(runs, e.run(child, link)...)
+		case "w:hyperlink":
+			target := link
+			tag := child[:strings.IndexByte(child, '>')]
+			if rel, ok := e.rels[attrValue(tag, "r:id")]; ok {
+				target = rel.target
+			} else if anchor := attrValue(tag, "w:anchor"); anchor != "" {
+				target = "#" + UnescapeXMLText(anchor)
+			}
+			runs = append(runs, e.runs(elementContent(child), target)...)
+		case "w:ins", "w:smartTag", "w:customXml", "w:fldSimple", "w:sdt", "w:sdtContent", "w:bdo", "w:dir":
+			runs = append(runs, e.runs(elementContent(child), link)...)
+		}
+	}
+	return runs
+}
+
+func (e *docxExport) run(run, link string) []richRun {
+	style := richRun{link: link}
+	content := elementContent(run)
+	if start := indexElement(content, "w:rPr"); start >= 0 {
+		rPr := leadingElement(content[start:], "w:rPr")
+		style.bold = hasToggle(rPr, "w:b")
+		style.italic = hasToggle(rPr, "w:i")
+		if start := indexElement(rPr, "w:u"); start >= 0 {
+			style.underline = !wordUnderlineNonePattern.MatchString(rPr[start:])
+		}
+		content = strings.Replace(content, rPr, "", 1)
+	}
+
+	var runs []richRun
+	for _, child := range xmlChildren(content) {
+		part := style
+		switch elementName(child) {
+		case "w:t":
+			part.text = UnescapeXMLText(elementContent(child))
+		case "w:tab":
+			part.tab = true
+		case "w:br", "w:cr":
+			if attrValue(child, "w:type") == "page" {
+				continue
+			}
+			part.lineBreak = true
+		case "w:noBreakHyphen":
+			part.text = "-"
+		default:
+			continue
+		}
+		runs = append(runs, part)
+	}
+	return runs
+}
+
+func (e *docxExport) table(table string) *exportTable {
+	result := &exportTable{}
+	for _, row := range xmlChildren(elementContent(table)) {
+		if elementName(row) != "w:tr" {
+			continue
+		}
+		if len(result.rows) == 0 && strings.Contains(row, "<w:tblHeader") {
+			result.header = true
+		}
+		var cells [][]richParagraph
+		for _, cell := range xmlChildren(elementContent(row)) {
+			if elementName(cell) != "w:tc" {
+				continue
+			}
+			var paragraphs []richParagraph
+			for _, block := range e.blocks(elementContent(cell)) {
+				if block.table != nil {
+					// Nested tables are flattened into their cells' paragraphs
+					for _, nested := range block.table.rows {
+						for _, paragraph := range nested {
+							paragraphs = append(paragraphs, paragraph...)
+						}
+					}
+					continue
+				}
+				paragraphs = append(paragraphs, block.paragraph)
+			}
+			cells = append(cells, paragraphs)
+		}
+		result.rows = append(result.rows, cells)
+	}
+	return result
+}
+
+// elementContent returns what is between the start and end tags of an element.
+func elementContent(element string) string {
+	start := strings.IndexByte(element, '>')
+	if start < 0 || element[start-1] == '/' {
+		return ""
+	}
+	end := strings.LastIndex(element, "</")
+	if end <= start {
+		return ""
+	}
+	return element[start+1 : end]
+}
+
+// hasToggle reports whether run properties switch a toggle property such as w:b on.
+func hasToggle(rPr, name string) bool {
+	start := indexElement(rPr, name)
+	if start < 0 {
+		return false
+	}
+	tag := rPr[start : start+strings.IndexByte(rPr[start:], '>')+1]
+	return !wordToggleOffPattern.MatchString(tag)
+}
+
+// styleNumbering returns the list IDs of the paragraph styles that number their paragraphs.
+func styleNumbering(pkg *Package) (map[string]string, error) {
+	lists := make(map[string]string)
+	part, ok := pkg.relatedPart(documentPartName, RelTypeStyles)
+	if !ok {
+		return lists, nil
+	}
+	content, err := pkg.Read(part)
+	if err != nil {
+		return nil, err
+	}
+	for _, style := range wordStylePattern.FindAllString(string(content), -1) {
+		if match := wordNumRefPattern.FindStringSubmatch(style); match != nil {
+			lists[styleID(style)] = match[2]
+		}
+	}
+	return lists, nil
+}
+
+// numberingFormats returns the number format of each level of each list, such as "bullet"
+// or "decimal".
+func numberingFormats(pkg *Package) (map[string][]string, error) {
+	formats := make(map[string][]string)
+	part, ok := pkg.relatedPart(documentPartName, RelTypeNumbering)
+	if !ok {
+		return formats, nil
+	}
+	content, err := pkg.Read(part)
+	if err != nil {
+		return nil, err
+	}
+
+	abstracts := make(map[string][]string)
+	for _, abstract := range wordAbstractPattern.FindAllStringSubmatch(string(content), -1) {
+		var levels []string
+		for _, level := range wordNumberingFormatPattern.FindAllStringSubmatch(abstract[0], -1) {
+			i, _ := strconv.Atoi(level[1])
+			for len(levels) <= i {
+				levels = append(levels, "")
+			}
+			levels[i] = level[2]
+		}
+		abstracts[abstract[1]] = levels
+	}
+	for _, num := range wordNumPattern.FindAllStringSubmatch(string(content), -1) {
+		if ref := wordAbstractRefPattern.FindStringSubmatch(num[0]); ref != nil {
+			formats[num[1]] = abstracts[ref[1]]
+		}
+	}
+	return formats, nil
+}
@@ -0,0 +1,188 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	altChunkContentType = "application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"
+	includeTagPrefix    = "include:"
+)
+
+// A table cell must end with a paragraph
+var wordCellEndPattern = regexp.MustCompile(`(</w:tbl>|<w:altChunk\b[^>]*/>)</w:tc>`)
+
+// IncludeDocx replaces paragraphs holding nothing but {{include:path.docx}} with the body of
+// that document. Relative paths are resolved against dir; documents included by included
+// documents are resolved against their own directory. Paths leading outside dir are rejected
+// unless the replacer's options set IncludeOutsideDir.
+//
+// The included content brings the styles the host doesn't define (styles of the same ID keep
+// the host's definition), its list definitions, footnotes, endnotes, fonts and related parts
+// such as images and hyperlinks, renumbered so they don't clash with the host's. Section
+// properties and comments of included documents are dropped.
+//
+// With altChunk, included documents are rendered with replacer and embedded as altChunk parts
+// instead, which Word merges into the document when it is opened.
+func IncludeDocx(pkg *Package, dir string, replacer *Replacer, altChunk bool) error {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	return includeDocx(pkg, dir, root, replacer, altChunk, nil)
+}
+
+func includeDocx(pkg *Package, dir, root string, replacer *Replacer, altChunk bool, stack []string) error {
+	if !pkg.Has(documentPartName) {
+		return nil
+	}
+	content, err := pkg.Read(documentPartName)
+	if err != nil {
+		return err
+	}
+	documentXML := string(content)
+
+	inc := &docxIncluder{docxHost: newDocxHost(pkg, documentXML), replacer: replacer, root: root, altChunk: altChunk}
+	processed, err := inc.expand(documentXML, dir, stack)
+	if err != nil || !inc.included {
+		return err
+	}
+
+	processed = inc.namespaces(wordCellEndPattern.ReplaceAllString(processed, "$1<w:p/></w:tc>"))
+	if inc.altChunk {
+		processed = EnsureNamespace(processed, "r", officeRelNamespace)
+	}
+	pkg.Write(documentPartName, []byte(processed))
+	return nil
+}
+
+// docxIncluder expands the include tags of one host package.
+type docxIncluder struct {
+	*docxHost
+	replacer *Replacer
+	root     string // the directory included documents must be in
+	altChunk bool
+	included bool
+}
+
+// expand replaces the include tags in a document or in merged body content.
+func (inc *docxIncluder) expand(content, dir string, stack []string) (string, error) {
+	syntax := inc.replacer.Syntax()
+
+	var b strings.Builder
+	b.Grow(len(content))
+	for _, part := range splitIntoParagraphs(content) {
+		inner, ok := paragraphTag(part, syntax)
+		target, isInclude := strings.CutPrefix(inner, includeTagPrefix)
+		if !ok || !isInclude {
+			b.WriteString(part)
+			continue
+		}
+
+		target = strings.Trim(strings.TrimSpace(target), `"'“”‘’`)
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(dir, target)
+		}
+		target, err := filepath.Abs(target)
+		if err != nil {
+			return "", err
+		}
+		if !inc.replacer.opts.IncludeOutsideDir && !withinDir(inc.root, target) {
+			return "", fmt.Errorf("include %s is outside the include directory %s", target, inc.root)
+		}
+		for i, including := range stack {
+			if including == target {
+				return "", fmt.Errorf("include cycle: %s", strings.Join(append(stack[i:], target), " -> "))
+			}
+		}
+
+		var included string
+		if inc.altChunk {
+			included, err = inc.embed(target, stack)
+		} else {
+			included, err = inc.merge(target, stack)
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to include %s: %v", target, err)
+		}
+		inc.included = true
+		b.WriteString(included)
+
+		// Keep a section break held by the include paragraph
+		if match := wordPPrPattern.FindStringSubmatch(part); match != nil && strings.Contains(match[1], "<w:sectPr") {
+			b.WriteString("<w:p>" + match[1] + "</w:p>")
+		}
+	}
+	return b.String(), nil
+}
+
+// withinDir reports whether path is dir or lies below it; both must be absolute.
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// embed renders an included document and adds it as an altChunk part.
+func (inc *docxIncluder) embed(target string, stack []string) (string, error) {
+	src, err := OpenPackage(target)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	if err := includeDocx(src, filepath.Dir(target), inc.root, inc.replacer, true, append(stack, target)); err != nil {
+		return "", err
+	}
+	if err := ProcessDocxPackage(src, inc.replacer); err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := src.SaveTo(&buf); err != nil {
+		return "", err
+	}
+
+	name := inc.partName("word/include.docx")
+	inc.pkg.Write(name, buf.Bytes())
+	if err := inc.pkg.AddContentTypeOverride(name, altChunkContentType); err != nil {
+		return "", err
+	}
+	id, err := inc.addRelationship(documentPartName, RelTypeAltChunk, relativeTarget(documentPartName, name), false)
+	if err != nil {
+		return "", err
+	}
+	return `<w:altChunk r:id="` + id + `"/>`, nil
+}
+
+// merge returns the body of an included document with its resources added to the host.
+func (inc *docxIncluder) merge(target string, stack []string) (string, error) {
+	src, err := OpenPackage(target)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	content, err := src.Read(documentPartName)
+	if err != nil {
+		return "", err
+	}
+	documentXML := string(content)
+	body, ok := documentBody(documentXML)
+	if !ok {
+		return "", fmt.Errorf("%s has no body", documentPartName)
+	}
+	inc.addRoot(documentXML)
+
+	m := newDocxMerge(inc.docxHost, src)
+	body, err = m.body(body)
+	if err != nil {
+		return "", err
+	}
+
+	// Included documents may include others in turn; their content is merged into the host
+	// the same way once this document's references have been renumbered
+	return inc.expand(body, filepath.Dir(target), append(stack, target))
+}
@@ -1,38 +1,74 @@
 package internal
 
 import (
-	"archive/zip"
 	"regexp"
 	"strings"
 )
 
-// ProcessDocxZipFile processes a single file from a DOCX zip archive.
-func ProcessDocxZipFile(file *zip.File, zipWriter *zip.Writer, replacements map[string]string) error {
-	return ProcessZipFile(file, zipWriter, func(fileName string, content []byte) []byte {
-		// If this is the main document XML file, process it for replacements
-		if fileName == "word/document.xml" {
-			processedContent := processDocumentXML(string(content), replacements)
-			return []byte(processedContent)
-		}
-		// For other files, copy as-is
-		return content
-	})
+// wordTextPattern matches a <w:t> element and captures its content.
+var wordTextPattern = regexp.MustCompile(`<w:t(?:\s[^>]*)?>(.*?)</w:t>`)
+
+// ProcessDocxPackage replaces the keywords in the main document of a DOCX package and in
+// its hyperlink targets. Rich text values add their hyperlinks and list definitions to the package.
+func ProcessDocxPackage(pkg *Package, replacer *Replacer) error {
+	if err := ProcessRelationships(pkg, replacer); err != nil {
+		return err
+	}
+	if err := applyTemplateProperties(pkg, replacer); err != nil {
+		return err
+	}
+	if !pkg.Has("word/document.xml") {
+		return nil
+	}
+	content, err := pkg.Read("word/document.xml")
+	if err != nil {
+		return err
+	}
+
+	rich := newDocxRich(pkg)
+	rich.textWidth = wordTextWidth(string(content))
+	if replacer.opts.TrackChanges {
+		rich.tracker = newDocxTracker(string(content), replacer.opts)
+	}
+	processed, err := rich.finish(processDocumentXML(string(content), replacer, rich))
+	if err != nil {
+		return err
+	}
+	pkg.Write("word/document.xml", []byte(processed))
+	return nil
 }
 
-func processDocumentXML(xmlContent string, replacements map[string]string) string {
-	paragraphs := splitIntoParagraphs(xmlContent)
+func processDocumentXML(xmlContent string, replacer *Replacer, rich *docxRich) string {
+	blocks := parseDocumentBlocks(splitIntoParagraphs(xmlContent), replacer.Syntax())
+
+	var result strings.Builder
+	result.Grow(len(xmlContent))
+	renderBlocks(&result, blocks, replacer, rich, false)
+	if hasLoop(blocks) {
+		return fillEmptyContainers(result.String())
+	}
+	return result.String()
+}
 
-	for i, paragraph := range paragraphs {
-		if strings.Contains(paragraph, "<w:t>") || strings.Contains(paragraph, "<w:t ") {
-			plainText := extractTextFromParagraph(paragraph)
+// renderParagraph replaces the keywords in a single paragraph. Without rich, rich text
+// values are written as plain text.
+func renderParagraph(paragraph string, replacer *Replacer, rich *docxRich) string {
+	if strings.Contains(paragraph, "<w:t>") || strings.Contains(paragraph, "<w:t ") {
+		plainText := extractTextFromParagraph(paragraph)
 
-			if ContainsAnyKeyword(plainText, replacements) {
-				positionMap := buildPositionMap(paragraph)
-				paragraphs[i] = ApplyReplacements(paragraph, plainText, replacements, positionMap)
+		if replacer.ContainsAnyKeyword(plainText) {
+			nodes := FindTextNodes(paragraph, wordTextPattern)
+			if rich != nil && rich.tracker != nil {
+				return rich.tracker.replace(paragraph, nodes, replacer)
+			}
+			result, values := replaceInNodes(paragraph, nodes, replacer, rich != nil)
+			if rich != nil && len(values) > 0 {
+				result = rich.render(result, values)
 			}
+			return result
 		}
 	}
-	return strings.Join(paragraphs, "")
+	return paragraph
 }
 
 func splitIntoParagraphs(xmlContent string) []string {
@@ -57,37 +93,14 @@ func splitIntoParagraphs(xmlContent string) []string {
 }
 
 func extractTextFromParagraph(paragraph string) string {
-	re := regexp.MustCompile(`<w:t(?:\s[^>]*)?>(.*?)</w:t>`)
-	matches := re.FindAllStringSubmatch(paragraph, -1)
+	matches := wordTextPattern.FindAllStringSubmatch(paragraph, -1)
 
 	var text strings.Builder
 	for _, match := range matches {
 		if len(match) > 1 {
-			text.WriteString(match[1])
+			text.WriteString(UnescapeXMLText(match[1]))
 		}
 	}
 
 	return text.String()
 }
-
-func buildPositionMap(paragraph string) map[int]int {
-	positionMap := make(map[int]int)
-
-	re := regexp.MustCompile(`<w:t(?:\s[^>]*)?>(.*?)</w:t>`)
-	matches := re.FindAllStringSubmatchIndex(paragraph, -1)
-
-	plainPos := 0
-	for _, match := range matches {
-		if len(match) >= 4 {
-			contentStart := match[2] // Start of content group
-			contentEnd := match[3]   // End of content group
-
-			textContent := paragraph[contentStart:contentEnd]
-			for i := 0; i < len(textContent); i++ {
-				positionMap[plainPos+i] = contentStart + i
-			}
-			plainPos += len(textContent)
-		}
-	}
-	return positionMap
-}
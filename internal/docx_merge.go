@@ -0,0 +1,837 @@
+package internal
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const documentPartName = "word/document.xml"
+
+var (
+	// Attributes holding relationship IDs: r:id, r:embed, r:link, r:dm and the like
+	wordRelAttrPattern = regexp.MustCompile(`(\sr:[A-Za-z]+=")([^"]*)(")`)
+
+	wordBookmarkIDPattern = regexp.MustCompile(`(<w:bookmark(?:Start|End)\b[^>]*?\bw:id=")(-?\d+)(")`)
+	wordGoBackPattern     = regexp.MustCompile(`<w:bookmarkStart\b[^>]*\bw:name="_GoBack"[^>]*/>`)
+	wordDrawingIDPattern  = regexp.MustCompile(`(<wp:docPr\b[^>]*?\bid=")(\d+)(")`)
+	wordCommentPattern    = regexp.MustCompile(`<w:comment(?:RangeStart|RangeEnd|Reference)\b[^>]*/>`)
+	wordNamespacePattern  = regexp.MustCompile(`\sxmlns:(\w+)="([^"]*)"`)
+
+	wordStylePattern       = regexp.MustCompile(`<w:style\b[^>]*>.*?</w:style>`)
+	wordStyleRefPattern    = regexp.MustCompile(`<w:(?:pStyle|rStyle|tblStyle|basedOn|link|next)\s+w:val="([^"]*)"`)
+	wordNumRefPattern      = regexp.MustCompile(`(<w:numId\s+w:val=")(\d+)(")`)
+	wordNumPattern         = regexp.MustCompile(`<w:num\b[^>]*?\bw:numId="(\d+)"[^>]*>.*?</w:num>`)
+	wordAbstractPattern    = regexp.MustCompile(`<w:abstractNum\b[^>]*?\bw:abstractNumId="(\d+)"[^>]*>.*?</w:abstractNum>`)
+	wordAbstractRefPattern = regexp.MustCompile(`<w:abstractNumId\s+w:val="(\d+)"`)
+	wordNsidPattern        = regexp.MustCompile(`<w:nsid\b[^>]*/>`)
+	wordTemplatePattern    = regexp.MustCompile(`<w:tmpl\b[^>]*/>`)
+	wordLevelStartPattern  = regexp.MustCompile(`<w:start\s+w:val="(\d+)"`)
+	wordFontPattern        = regexp.MustCompile(`<w:font\b[^>]*>.*?</w:font>`)
+)
+
+// wordAnnotation describes footnotes, endnotes or comments: the body elements referring
+// to them by ID and the part holding them.
+type wordAnnotation struct {
+	name     string
+	relType  string
+	refs     *regexp.Regexp // references in the body, with the ID as second group
+	elements *regexp.Regexp // annotations in their part
+	first    int            // lowest ID of a regular note; lower IDs are separators
+}
+
+var wordAnnotations = []wordAnnotation{
+	newWordAnnotation("footnote", RelTypeFootnotes, "footnoteReference", 1),
+	newWordAnnotation("endnote", RelTypeEndnotes, "endnoteReference", 1),
+	newWordAnnotation("comment", RelTypeComments, "comment(?:RangeStart|RangeEnd|Reference)", 0),
+}
+
+func newWordAnnotation(name, relType, refs string, first int) wordAnnotation {
+	return wordAnnotation{
+		name:     name,
+		relType:  relType,
+		refs:     regexp.MustCompile(`(<w:` + refs + `\b[^>]*?\bw:id=")(-?\d+)(")`),
+		elements: regexp.MustCompile(`<w:` + name + `\b[^>]*>.*?</w:` + name + `>`),
+		first:    first,
+	}
+}
+
+// DocxMerger concatenates rendered documents into one, each copy in its own section so it
+// keeps its page setup, headers and footers, or separated by page breaks.
+type DocxMerger struct {
+	host       *docxHost
+	pageBreaks bool
+
+	head, tail string
+	body       strings.Builder
+	section    string // section properties of the last document appended
+}
+
+// NewDocxMerger starts a merged document from pkg, the first copy; the documents appended
+// are added to it. With pageBreaks, copies are separated by page breaks and share the last
+// section of the first copy.
+func NewDocxMerger(pkg *Package, pageBreaks bool) (*DocxMerger, error) {
+	content, err := pkg.Read(documentPartName)
+	if err != nil {
+		return nil, err
+	}
+	documentXML := string(content)
+	start, end, ok := documentBodyBounds(documentXML)
+	if !ok {
+		return nil, fmt.Errorf("%s has no body", documentPartName)
+	}
+
+	m := &DocxMerger{
+		host:       newDocxHost(pkg, documentXML),
+		pageBreaks: pageBreaks,
+		head:       documentXML[:start],
+		tail:       documentXML[end:],
+	}
+	body, section := splitFinalSection(documentXML[start:end])
+	m.section = section
+	m.body.WriteString(body)
+	return m, nil
+}
+
+// Append adds the body of another document after the ones merged so far, along with the
+// styles, lists, notes, comments, fonts and related parts it uses.
+func (d *DocxMerger) Append(src *Package) error {
+	content, err := src.Read(documentPartName)
+	if err != nil {
+		return err
+	}
+	documentXML := string(content)
+	body, ok := documentBody(documentXML)
+	if !ok {
+		return fmt.Errorf("%s has no body", documentPartName)
+	}
+	d.host.addRoot(documentXML)
+
+	m := newDocxMerge(d.host, src)
+	m.sections, m.comments = true, true
+	body, err = m.body(body)
+	if err != nil {
+		return err
+	}
+	body, section := splitFinalSection(body)
+
+	if d.pageBreaks {
+		d.body.WriteString(`<w:p><w:r><w:br w:type="page"/></w:r></w:p>`)
+	} else {
+		// The section of the previous copy ends with a paragraph holding its properties
+		d.body.WriteString(`<w:p><w:pPr>` + d.section + `</w:pPr></w:p>`)
+		d.section = section
+	}
+	d.body.WriteString(body)
+	return nil
+}
+
+// Finish writes the merged document to the package.
+func (d *DocxMerger) Finish() {
+	documentXML := d.head + d.body.String() + d.section + d.tail
+	d.host.pkg.Write(documentPartName, []byte(d.host.namespaces(documentXML)))
+}
+
+// documentBody returns the content of a document's body element.
+func documentBody(documentXML string) (string, bool) {
+	start, end, ok := documentBodyBounds(documentXML)
+	return documentXML[start:end], ok
+}
+
+// documentBodyBounds returns the offsets of the content of a document's body element.
+func documentBodyBounds(documentXML string) (int, int, bool) {
+	start := strings.Index(documentXML, "<w:body>")
+	end := strings.LastIndex(documentXML, "</w:body>")
+	if start < 0 || end < start {
+		return 0, 0, false
+	}
+	return start + len("<w:body>"), end, true
+}
+
+// splitFinalSection separates the body-level section properties, the last child of a body.
+func splitFinalSection(body string) (string, string) {
+	trimmed := strings.TrimRight(body, " \t\r\n")
+	for i := strings.LastIndex(trimmed, "<w:sectPr"); i >= 0; i = strings.LastIndex(trimmed[:i], "<w:sectPr") {
+		if section := leadingElement(trimmed[i:], "w:sectPr"); section != "" && len(section) == len(trimmed)-i {
+			return trimmed[:i], section
+		}
+	}
+	return body, ""
+}
+
+// docxHost is a package other documents are merged into, with the IDs already in use.
+type docxHost struct {
+	pkg      *Package
+	bookmark int // next free bookmark ID
+	drawing  int // next free drawing object ID
+
+	roots map[string]bool              // root start tags of merged documents, for their namespaces
+	rels  map[string]map[string]string // relationship IDs by part, then by type, target and mode
+}
+
+func newDocxHost(pkg *Package, documentXML string) *docxHost {
+	return &docxHost{
+		pkg:      pkg,
+		bookmark: maxID(documentXML, wordBookmarkIDPattern) + 1,
+		drawing:  max(maxID(documentXML, wordDrawingIDPattern), 0) + 1,
+		roots:    make(map[string]bool),
+		rels:     make(map[string]map[string]string),
+	}
+}
+
+// addRoot remembers the root start tag of a merged document.
+func (h *docxHost) addRoot(documentXML string) {
+	if root := rootElementStart(documentXML); root >= 0 {
+		h.roots[documentXML[root:root+strings.IndexByte(documentXML[root:], '>')+1]] = true
+	}
+}
+
+// namespaces declares the namespaces of the merged documents on the root of documentXML.
+func (h *docxHost) namespaces(documentXML string) string {
+	for root := range h.roots {
+		documentXML = mergeRootNamespaces(documentXML, root)
+	}
+	return documentXML
+}
+
+// addRelationship returns the ID of a relationship from part, adding it unless the part
+// already has the same one.
+func (h *docxHost) addRelationship(part, relType, target string, external bool) (string, error) {
+	known, ok := h.rels[part]
+	if !ok {
+		rels, err := h.pkg.readRelationships(part)
+		if err != nil {
+			return "", err
+		}
+		known = make(map[string]string, len(rels))
+		for id, rel := range rels {
+			known[relationshipKey(rel.relType, rel.target, rel.external)] = id
+		}
+		h.rels[part] = known
+	}
+
+	key := relationshipKey(relType, target, external)
+	if id, ok := known[key]; ok {
+		return id, nil
+	}
+	id, err := h.pkg.AddRelationship(part, relType, target, external)
+	if err != nil {
+		return "", err
+	}
+	known[key] = id
+	return id, nil
+}
+
+func relationshipKey(relType, target string, external bool) string {
+	return relType + " " + target + " " + strconv.FormatBool(external)
+}
+
+// partName returns name, or name with a number added when the host already has such a part.
+func (h *docxHost) partName(name string) string {
+	if !h.pkg.Has(name) {
+		return name
+	}
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for n := 2; ; n++ {
+		candidate := base + "_" + strconv.Itoa(n) + ext
+		if !h.pkg.Has(candidate) {
+			return candidate
+		}
+	}
+}
+
+// docxMerge merges the resources of one document into the host.
+type docxMerge struct {
+	host  *docxHost
+	src   *Package
+	parts map[string]string // host part names of copied parts
+	rels  map[string]string // host relationship IDs by source part and ID
+	same  *sharedParts      // parts the host has as well
+
+	sections bool // keep section properties
+	comments bool // keep comments
+}
+
+func newDocxMerge(host *docxHost, src *Package) *docxMerge {
+	return &docxMerge{
+		host:  host,
+		src:   src,
+		parts: make(map[string]string),
+		rels:  make(map[string]string),
+		same:  newSharedParts(src, host.pkg),
+	}
+}
+
+// body prepares the body content of the source document for the host.
+func (m *docxMerge) body(body string) (string, error) {
+	if !m.sections {
+		body = removeElements(body, "w:sectPr")
+	}
+	if !m.comments {
+		body = wordCommentPattern.ReplaceAllString(body, "")
+	}
+	body = wordParaIDAttrPattern.ReplaceAllString(body, "")
+	for _, goBack := range wordGoBackPattern.FindAllString(body, -1) {
+		body = strings.Replace(body, goBack, "", 1)
+		body = strings.Replace(body, `<w:bookmarkEnd w:id="`+attrValue(goBack, "w:id")+`"/>`, "", 1)
+	}
+	body = renumber(body, wordBookmarkIDPattern, &m.host.bookmark)
+	body = renumberEach(body, wordDrawingIDPattern, &m.host.drawing)
+
+	body, err := m.relationships(body, documentPartName, documentPartName)
+	if err != nil {
+		return "", err
+	}
+
+	var annotations []*wordNotes
+	for _, kind := range wordAnnotations {
+		if kind.name == "comment" && !m.comments {
+			continue
+		}
+		a, err := m.annotations(body, kind)
+		if err != nil {
+			return "", err
+		}
+		if a != nil {
+			body = a.body
+			annotations = append(annotations, a)
+		}
+	}
+
+	annotationText := ""
+	for _, a := range annotations {
+		annotationText += strings.Join(a.added, "")
+	}
+	stylesPart, styles, err := m.styles(body + annotationText)
+	if err != nil {
+		return "", err
+	}
+
+	numbers, err := m.numbering(body + annotationText + strings.Join(styles, ""))
+	if err != nil {
+		return "", err
+	}
+	renumberLists := func(content string) string {
+		return wordNumRefPattern.ReplaceAllStringFunc(content, func(ref string) string {
+			match := wordNumRefPattern.FindStringSubmatch(ref)
+			if id, ok := numbers[match[2]]; ok {
+				return match[1] + id + match[3]
+			}
+			return ref
+		})
+	}
+	body = renumberLists(body)
+
+	if len(styles) > 0 {
+		for i := range styles {
+			styles[i] = renumberLists(styles[i])
+		}
+		if err := m.insert(stylesPart, "</w:styles>", styles); err != nil {
+			return "", err
+		}
+	}
+	for _, a := range annotations {
+		for i := range a.added {
+			a.added[i] = renumberLists(a.added[i])
+		}
+		if err := m.insert(a.part, "</w:"+a.kind.name+"s>", a.added); err != nil {
+			return "", err
+		}
+	}
+	if err := m.fonts(); err != nil {
+		return "", err
+	}
+	return body, nil
+}
+
+// relationships gives the relationships referenced in content, which comes from srcPart of the
+// source document, matching relationships from dstPart in the host, copying the parts
+// they point to.
+func (m *docxMerge) relationships(content, srcPart, dstPart string) (string, error) {
+	if !wordRelAttrPattern.MatchString(content) {
+		return content, nil
+	}
+	rels, err := m.src.readRelationships(srcPart)
+	if err != nil {
+		return "", err
+	}
+
+	var failed error
+	content = wordRelAttrPattern.ReplaceAllStringFunc(content, func(attr string) string {
+		match := wordRelAttrPattern.FindStringSubmatch(attr)
+		rel, ok := rels[match[2]]
+		if !ok || failed != nil {
+			return attr
+		}
+
+		key := srcPart + "#" + dstPart + "#" + match[2]
+		if id, ok := m.rels[key]; ok {
+			return match[1] + id + match[3]
+		}
+
+		target := rel.target
+		if !rel.external {
+			name, err := m.copyPart(resolveTarget(srcPart, rel.target))
+			if err != nil {
+				failed = err
+				return attr
+			}
+			target = relativeTarget(dstPart, name)
+		}
+		id, err := m.host.addRelationship(dstPart, rel.relType, target, rel.external)
+		if err != nil {
+			failed = err
+			return attr
+		}
+		m.rels[key] = id
+		return match[1] + id + match[3]
+	})
+	return content, failed
+}
+
+// copyPart copies a part of the source document, along with the parts it relates to, and
+// returns its name in the host. A part the host has with the same content is shared.
+func (m *docxMerge) copyPart(name string) (string, error) {
+	if copied, ok := m.parts[name]; ok {
+		return copied, nil
+	}
+	if m.same.has(name) {
+		m.parts[name] = name
+		return name, nil
+	}
+	content, err := m.src.Read(name)
+	if err != nil {
+		return "", err
+	}
+
+	copied := m.host.partName(name)
+	m.parts[name] = copied
+	if strings.HasSuffix(name, ".xml") {
+		processed, err := m.relationships(string(content), name, copied)
+		if err != nil {
+			return "", err
+		}
+		content = []byte(processed)
+	}
+	m.host.pkg.Write(copied, content)
+
+	contentType, override := m.src.ContentType(name)
+	switch {
+	case contentType == "":
+	case override:
+		err = m.host.pkg.AddContentTypeOverride(copied, contentType)
+	default:
+		err = m.host.pkg.AddContentTypeDefault(strings.TrimPrefix(path.Ext(copied), "."), contentType)
+	}
+	return copied, err
+}
+
+// wordNotes are the footnotes, endnotes or comments a body refers to.
+type wordNotes struct {
+	kind  wordAnnotation
+	part  string   // host part
+	body  string   // body with the references renumbered
+	added []string // annotations to add to the host part
+}
+
+// annotations renumbers the references of body to footnotes, endnotes or comments and
+// collects them. A host without a part for them gets one, starting with the source's separators.
+func (m *docxMerge) annotations(body string, kind wordAnnotation) (*wordNotes, error) {
+	if !kind.refs.MatchString(body) {
+		return nil, nil
+	}
+	srcPart, ok := m.src.relatedPart(documentPartName, kind.relType)
+	if !ok {
+		return nil, nil
+	}
+	content, err := m.src.Read(srcPart)
+	if err != nil {
+		return nil, err
+	}
+	srcContent := string(content)
+	closingTag := "</w:" + kind.name + "s>"
+
+	part, ok := m.host.pkg.relatedPart(documentPartName, kind.relType)
+	if !ok {
+		part = m.host.partName("word/" + kind.name + "s.xml")
+		root := rootElementStart(srcContent)
+		end := strings.LastIndex(srcContent, closingTag)
+		if root < 0 || end < 0 {
+			return nil, fmt.Errorf("%s has no %ss element", srcPart, kind.name)
+		}
+		skeleton := srcContent[:root+strings.IndexByte(srcContent[root:], '>')+1]
+		for _, element := range kind.elements.FindAllString(srcContent, -1) {
+			if attrValue(element[:strings.IndexByte(element, '>')], "w:type") != "" {
+				skeleton += element
+			}
+		}
+		m.host.pkg.Write(part, []byte(skeleton+srcContent[end:]))
+		if contentType, override := m.src.ContentType(srcPart); override {
+			if err := m.host.pkg.AddContentTypeOverride(part, contentType); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := m.host.addRelationship(documentPartName, kind.relType, relativeTarget(documentPartName, part), false); err != nil {
+			return nil, err
+		}
+	}
+
+	hostContent, err := m.host.pkg.Read(part)
+	if err != nil {
+		return nil, err
+	}
+	idPattern := regexp.MustCompile(`(<w:` + kind.name + `\b[^>]*?\bw:id=")(-?\d+)(")`)
+	next := max(maxID(string(hostContent), idPattern)+1, kind.first)
+
+	byID := make(map[string]string)
+	for _, element := range kind.elements.FindAllString(srcContent, -1) {
+		byID[attrValue(element[:strings.IndexByte(element, '>')], "w:id")] = element
+	}
+
+	a := &wordNotes{kind: kind, part: part}
+	ids := make(map[string]string)
+	var failed error
+	a.body = kind.refs.ReplaceAllStringFunc(body, func(ref string) string {
+		match := kind.refs.FindStringSubmatch(ref)
+		id, ok := ids[match[2]]
+		if !ok {
+			element, exists := byID[match[2]]
+			if !exists {
+				return ref
+			}
+			id = strconv.Itoa(next)
+			next++
+			ids[match[2]] = id
+
+			element, err := m.relationships(setAttr(element, "w:id", id), srcPart, part)
+			if err != nil && failed == nil {
+				failed = err
+			}
+			a.added = append(a.added, wordParaIDAttrPattern.ReplaceAllString(element, ""))
+		}
+		return match[1] + id + match[3]
+	})
+	return a, failed
+}
+
+// styles returns the host's styles part and the style definitions content uses that the
+// host lacks, with the styles they are based on or linked to.
+func (m *docxMerge) styles(content string) (string, []string, error) {
+	srcPart, ok := m.src.relatedPart(documentPartName, RelTypeStyles)
+	if !ok {
+		return "", nil, nil
+	}
+	hostPart, ok := m.host.pkg.relatedPart(documentPartName, RelTypeStyles)
+	if !ok {
+		return "", nil, nil
+	}
+	if m.same.has(srcPart) && srcPart == hostPart {
+		return "", nil, nil
+	}
+
+	srcContent, err := m.src.Read(srcPart)
+	if err != nil {
+		return "", nil, err
+	}
+	hostContent, err := m.host.pkg.Read(hostPart)
+	if err != nil {
+		return "", nil, err
+	}
+
+	hostIDs := make(map[string]bool)
+	for _, style := range wordStylePattern.FindAllString(string(hostContent), -1) {
+		hostIDs[styleID(style)] = true
+	}
+	srcStyles := wordStylePattern.FindAllString(string(srcContent), -1)
+	byID := make(map[string]string, len(srcStyles))
+	for _, style := range srcStyles {
+		byID[styleID(style)] = style
+	}
+
+	needed := make(map[string]bool)
+	var visit func(content string)
+	visit = func(content string) {
+		for _, ref := range wordStyleRefPattern.FindAllStringSubmatch(content, -1) {
+			id := ref[1]
+			if needed[id] || hostIDs[id] {
+				continue
+			}
+			if style, ok := byID[id]; ok {
+				needed[id] = true
+				visit(style)
+			}
+		}
+	}
+	visit(content)
+
+	var styles []string
+	for _, style := range srcStyles {
+		if needed[styleID(style)] {
+			styles = append(styles, style)
+		}
+	}
+	return hostPart, styles, nil
+}
+
+func styleID(style string) string {
+	return attrValue(style[:strings.IndexByte(style, '>')], "w:styleId")
+}
+
+// numbering copies the list definitions content refers to and returns the host list IDs by
+// the source's IDs. Every list gets a list ID of its own, so its numbering starts over;
+// definitions the host already has are shared.
+func (m *docxMerge) numbering(content string) (map[string]string, error) {
+	var used []string
+	seen := make(map[string]bool)
+	for _, ref := range wordNumRefPattern.FindAllStringSubmatch(content, -1) {
+		if ref[2] != "0" && !seen[ref[2]] {
+			seen[ref[2]] = true
+			used = append(used, ref[2])
+		}
+	}
+	if len(used) == 0 {
+		return nil, nil
+	}
+	srcPart, ok := m.src.relatedPart(documentPartName, RelTypeNumbering)
+	if !ok {
+		return nil, nil
+	}
+	srcContent, err := m.src.Read(srcPart)
+	if err != nil {
+		return nil, err
+	}
+
+	nums := make(map[string]string)
+	for _, num := range wordNumPattern.FindAllStringSubmatch(string(srcContent), -1) {
+		nums[num[1]] = num[0]
+	}
+	abstracts := make(map[string]string)
+	for _, abstract := range wordAbstractPattern.FindAllStringSubmatch(string(srcContent), -1) {
+		abstracts[abstract[1]] = abstract[0]
+	}
+
+	host := &docxNumbering{pkg: m.host.pkg}
+	if err := host.load(); err != nil {
+		return nil, err
+	}
+	hostAbstracts := make(map[string]string)
+	for _, abstract := range wordAbstractPattern.FindAllStringSubmatch(host.content, -1) {
+		hostAbstracts[abstractKey(abstract[0])] = abstract[1]
+	}
+
+	ids := make(map[string]string)
+	for _, id := range used {
+		num, ok := nums[id]
+		if !ok {
+			continue
+		}
+		ref := wordAbstractRefPattern.FindStringSubmatch(num)
+		if ref == nil {
+			continue
+		}
+		abstract, ok := abstracts[ref[1]]
+		if !ok {
+			continue
+		}
+
+		abstractID, shared := hostAbstracts[abstractKey(abstract)]
+		if !shared {
+			abstractID = strconv.Itoa(host.nextAbstract)
+			host.nextAbstract++
+			hostAbstracts[abstractKey(abstract)] = abstractID
+			// Without the list's nsid, Word doesn't join it with a host list of the same origin
+			abstract = wordNsidPattern.ReplaceAllString(setAttr(abstract, "w:abstractNumId", abstractID), "")
+			host.added = append(host.added, abstract)
+		}
+
+		ids[id] = strconv.Itoa(host.nextNum)
+		host.nextNum++
+		num = setAttr(num, "w:numId", ids[id])
+		num = strings.Replace(num, ref[0], `<w:abstractNumId w:val="`+abstractID+`"`, 1)
+		if shared && !strings.Contains(num, `<w:lvlOverride w:ilvl="0"`) {
+			// A second list of a shared definition would continue the first one's numbering
+			start := "1"
+			if match := wordLevelStartPattern.FindStringSubmatch(abstract); match != nil {
+				start = match[1]
+			}
+			num = strings.Replace(num, "</w:num>", `<w:lvlOverride w:ilvl="0"><w:startOverride w:val="`+start+`"/></w:lvlOverride></w:num>`, 1)
+		}
+		host.nums = append(host.nums, num)
+	}
+	return ids, host.flush()
+}
+
+// abstractKey identifies list definitions that differ only in their IDs.
+func abstractKey(abstract string) string {
+	abstract = setAttr(abstract, "w:abstractNumId", "")
+	abstract = wordNsidPattern.ReplaceAllString(abstract, "")
+	return wordTemplatePattern.ReplaceAllString(abstract, "")
+}
+
+// fonts adds the font declarations of the source document the host lacks.
+func (m *docxMerge) fonts() error {
+	srcPart, ok := m.src.relatedPart(documentPartName, RelTypeFontTable)
+	if !ok {
+		return nil
+	}
+	hostPart, ok := m.host.pkg.relatedPart(documentPartName, RelTypeFontTable)
+	if !ok || (srcPart == hostPart && m.same.has(srcPart)) {
+		return nil
+	}
+	srcContent, err := m.src.Read(srcPart)
+	if err != nil {
+		return err
+	}
+	hostContent, err := m.host.pkg.Read(hostPart)
+	if err != nil {
+		return err
+	}
+
+	hostFonts := make(map[string]bool)
+	for _, font := range wordFontPattern.FindAllString(string(hostContent), -1) {
+		hostFonts[attrValue(font[:strings.IndexByte(font, '>')], "w:name")] = true
+	}
+	var fonts []string
+	for _, font := range wordFontPattern.FindAllString(string(srcContent), -1) {
+		name := attrValue(font[:strings.IndexByte(font, '>')], "w:name")
+		if hostFonts[name] {
+			continue
+		}
+		hostFonts[name] = true
+		font, err := m.relationships(font, srcPart, hostPart)
+		if err != nil {
+			return err
+		}
+		fonts = append(fonts, font)
+	}
+	return m.insert(hostPart, "</w:fonts>", fonts)
+}
+
+// insert adds elements to a host part before its closing tag.
+func (m *docxMerge) insert(part, closingTag string, elements []string) error {
+	if len(elements) == 0 {
+		return nil
+	}
+	content, err := m.host.pkg.Read(part)
+	if err != nil {
+		return err
+	}
+	end := strings.LastIndex(string(content), closingTag)
+	if end < 0 {
+		return fmt.Errorf("%s has no %s", part, closingTag)
+	}
+	m.host.pkg.Write(part, []byte(string(content[:end])+strings.Join(elements, "")+string(content[end:])))
+	return nil
+}
+
+// maxID returns the highest ID captured by the second group of pattern, or -1.
+func maxID(content string, pattern *regexp.Regexp) int {
+	highest := -1
+	for _, match := range pattern.FindAllStringSubmatch(content, -1) {
+		if id, err := strconv.Atoi(match[2]); err == nil && id > highest {
+			highest = id
+		}
+	}
+	return highest
+}
+
+// renumber gives the IDs captured by pattern new values from next; the same ID, such as
+// a bookmark's start and end, gets the same new value.
+func renumber(content string, pattern *regexp.Regexp, next *int) string {
+	ids := make(map[string]string)
+	return pattern.ReplaceAllStringFunc(content, func(ref string) string {
+		match := pattern.FindStringSubmatch(ref)
+		id, ok := ids[match[2]]
+		if !ok {
+			id = strconv.Itoa(*next)
+			*next++
+			ids[match[2]] = id
+		}
+		return match[1] + id + match[3]
+	})
+}
+
+// renumberEach gives every ID captured by pattern a new value from next.
+func renumberEach(content string, pattern *regexp.Regexp, next *int) string {
+	return pattern.ReplaceAllStringFunc(content, func(ref string) string {
+		match := pattern.FindStringSubmatch(ref)
+		id := strconv.Itoa(*next)
+		*next++
+		return match[1] + id + match[3]
+	})
+}
+
+// removeElements removes the elements named name, with everything they contain.
+func removeElements(content, name string) string {
+	var b strings.Builder
+	for {
+		start := indexElement(content, name)
+		if start < 0 {
+			b.WriteString(content)
+			return b.String()
+		}
+		b.WriteString(content[:start])
+		content = content[start:]
+
+		element := leadingElement(content, name)
+		if element == "" {
+			// Unterminated; keep the rest as is
+			b.WriteString(content)
+			return b.String()
+		}
+		content = content[len(element):]
+	}
+}
+
+// indexElement returns the offset of the first start tag of the element named name.
+func indexElement(content, name string) int {
+	for offset := 0; ; {
+		i := strings.Index(content[offset:], "<"+name)
+		if i < 0 {
+			return -1
+		}
+		i += offset
+		if end := i + len(name) + 1; end < len(content) && strings.IndexByte(" \t\r\n/>", content[end]) >= 0 {
+			return i
+		}
+		offset = i + 1
+	}
+}
+
+// mergeRootNamespaces declares the namespaces of another document's root start tag on the
+// root of documentXML and marks them ignorable where the other document does.
+func mergeRootNamespaces(documentXML, rootTag string) string {
+	for _, ns := range wordNamespacePattern.FindAllStringSubmatch(rootTag, -1) {
+		documentXML = EnsureNamespace(documentXML, ns[1], ns[2])
+	}
+
+	ignorable := strings.Fields(UnescapeXMLText(attrValue(rootTag, "mc:Ignorable")))
+	root := rootElementStart(documentXML)
+	if len(ignorable) == 0 || root < 0 {
+		return documentXML
+	}
+	end := root + strings.IndexByte(documentXML[root:], '>') + 1
+	tag := documentXML[root:end]
+
+	current := strings.Fields(attrValue(tag, "mc:Ignorable"))
+	known := make(map[string]bool, len(current))
+	for _, prefix := range current {
+		known[prefix] = true
+	}
+	for _, prefix := range ignorable {
+		if !known[prefix] {
+			known[prefix] = true
+			current = append(current, prefix)
+		}
+	}
+	if len(current) == len(strings.Fields(attrValue(tag, "mc:Ignorable"))) {
+		return documentXML
+	}
+	if !strings.Contains(tag, `xmlns:mc="`) {
+		return documentXML
+	}
+	return documentXML[:root] + setAttr(tag, "mc:Ignorable", strings.Join(current, " ")) + documentXML[end:]
+}
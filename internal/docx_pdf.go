@@ -0,0 +1,946 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	wordThemeFontPattern = regexp.MustCompile(`<a:(major|minor)Font>\s*<a:latin\s+typeface="([^"]*)"`)
+	wordBlipPattern      = regexp.MustCompile(`<a:blip\b[^>]*?\br:embed="([^"]*)"`)
+	wordExtentPattern    = regexp.MustCompile(`<wp:extent\s+cx="(\d+)"\s+cy="(\d+)"`)
+	coreTitlePattern     = regexp.MustCompile(`<dc:title>([^<]*)</dc:title>`)
+)
+
+// RenderDocxPDF writes a document as PDF: paragraphs with their fonts, sizes, colors, spacing
+// and alignment, lists, tables, pictures, and the page size, margins, headers and footers of
+// each section. Fonts embedded in the document are embedded in the PDF; other fonts are
+// replaced by the closest standard PDF font. Text is limited to the characters of
+// WinAnsiEncoding and JPEG, PNG and GIF pictures are drawn; other pictures are left out.
+func RenderDocxPDF(pkg *Package, w io.Writer) error {
+	content, err := pkg.Read(documentPartName)
+	if err != nil {
+		return err
+	}
+	body, ok := documentBody(string(content))
+	if !ok {
+		return fmt.Errorf("%s has no body", documentPartName)
+	}
+
+	r, err := newDocxPDF(pkg)
+	if err != nil {
+		return err
+	}
+	pager := &pdfPaginator{}
+	for i, section := range r.sections(body) {
+		r.measureSection(section)
+		r.part, r.rels = documentPartName, r.documentRels
+		blocks := r.blocks(section.elements, section.left, section.width-section.left-section.right)
+		if i == 0 || !section.continuous || section.width != pager.page.section.width || section.height != pager.page.section.height {
+			pager.newPage(section, true)
+		}
+		pager.section = section
+		pager.place(blocks)
+	}
+	return r.write(pager.pages, w)
+}
+
+// docxPDF lays out a document for PDF output.
+type docxPDF struct {
+	pkg          *Package
+	doc          *pdfDocument
+	documentRels map[string]relationship
+	part         string // the part being laid out, the body or a header or footer
+	rels         map[string]relationship
+
+	styles       map[string]*pdfStyle
+	defaultStyle string
+	defaultPara  pdfParaProps
+	defaultRun   pdfRunProps
+	resolved     map[string]*pdfStyleProps
+	levels       map[string]int // outline levels of heading styles
+	lists        map[string]*pdfList
+	themeFonts   map[string]string
+	embedded     map[string]*trueTypeFont // by lower-case font name and style
+	fonts        map[string]*pdfFont
+	images       map[string]*pdfImage
+	page, pages  int // the page whose header or footer is laid out, for page number fields
+}
+
+// pdfStyle is the formatting a style sets.
+type pdfStyle struct {
+	basedOn string
+	pPr     string
+	rPr     string
+	tblPr   string
+}
+
+// pdfStyleProps is the paragraph and run formatting of a paragraph style.
+type pdfStyleProps struct {
+	para pdfParaProps
+	run  pdfRunProps
+}
+
+// pdfRunProps is the formatting of a run; sizes are in points.
+type pdfRunProps struct {
+	font      string
+	bold      bool
+	italic    bool
+	underline bool
+	strike    bool
+	caps      bool
+	hidden    bool
+	size      float64
+	sized     bool // set by a style rather than the document defaults
+	color     [3]float64
+	vertAlign string
+}
+
+// pdfParaProps is the formatting of a paragraph; lengths are in points.
+type pdfParaProps struct {
+	align           string
+	before          float64
+	after           float64
+	line            float64 // in 240ths of a line for auto spacing, in points otherwise
+	lineRule        string
+	left            float64
+	right           float64
+	firstLine       float64 // negative for a hanging indent
+	keepNext        bool
+	pageBreakBefore bool
+	contextual      bool
+	numID           string
+	level           int
+	tabs            []pdfTabStop
+	borderTop       pdfBorder
+	borderBottom    pdfBorder
+}
+
+// pdfTabStop is a tab stop, its position measured from the left margin.
+type pdfTabStop struct {
+	pos    float64
+	align  string
+	leader string
+}
+
+// pdfBorder is a border line; a zero width draws none.
+type pdfBorder struct {
+	width float64
+	color [3]float64
+	set   bool
+}
+
+// pdfList is a numbering definition and its counters.
+type pdfList struct {
+	levels   []*pdfListLevel
+	counters [9]int
+	started  [9]bool
+}
+
+type pdfListLevel struct {
+	start  int
+	format string
+	text   string
+	suffix string
+	pPr    string
+	rPr    string
+}
+
+func newDocxPDF(pkg *Package) (*docxPDF, error) {
+	r := &docxPDF{
+		pkg:        pkg,
+		doc:        &pdfDocument{},
+		styles:     make(map[string]*pdfStyle),
+		resolved:   make(map[string]*pdfStyleProps),
+		lists:      make(map[string]*pdfList),
+		themeFonts: make(map[string]string),
+		embedded:   make(map[string]*trueTypeFont),
+		fonts:      make(map[string]*pdfFont),
+		images:     make(map[string]*pdfImage),
+	}
+	r.defaultPara = pdfParaProps{line: 240, lineRule: "auto"}
+	r.defaultRun = pdfRunProps{font: "Times New Roman", size: 10}
+
+	var err error
+	if r.documentRels, err = pkg.readRelationships(documentPartName); err != nil {
+		return nil, err
+	}
+	if r.levels, err = outlineLevels(pkg); err != nil {
+		return nil, err
+	}
+	if core, err := pkg.Read("docProps/core.xml"); err == nil {
+		if match := coreTitlePattern.FindSubmatch(core); match != nil {
+			r.doc.title = UnescapeXMLText(string(match[1]))
+		}
+	}
+	if err := r.loadTheme(); err != nil {
+		return nil, err
+	}
+	if err := r.loadStyles(); err != nil {
+		return nil, err
+	}
+	if err := r.loadNumbering(); err != nil {
+		return nil, err
+	}
+	if err := r.loadFonts(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *docxPDF) loadTheme() error {
+	part, ok := r.pkg.relatedPart(documentPartName, RelTypeTheme)
+	if !ok {
+		return nil
+	}
+	content, err := r.pkg.Read(part)
+	if err != nil {
+		return err
+	}
+	for _, match := range wordThemeFontPattern.FindAllStringSubmatch(string(content), -1) {
+		r.themeFonts[match[1]] = UnescapeXMLText(match[2])
+	}
+	return nil
+}
+
+// loadStyles reads the document defaults and the styles.
+func (r *docxPDF) loadStyles() error {
+	part, ok := r.pkg.relatedPart(documentPartName, RelTypeStyles)
+	if !ok {
+		return nil
+	}
+	data, err := r.pkg.Read(part)
+	if err != nil {
+		return err
+	}
+	content := string(data)
+
+	if start := indexElement(content, "w:rPrDefault"); start >= 0 {
+		defaults := leadingElement(content[start:], "w:rPrDefault")
+		if start := indexElement(defaults, "w:rPr"); start >= 0 {
+			r.applyRunProps(&r.defaultRun, leadingElement(defaults[start:], "w:rPr"))
+			r.defaultRun.sized = false
+		}
+	}
+	if start := indexElement(content, "w:pPrDefault"); start >= 0 {
+		defaults := leadingElement(content[start:], "w:pPrDefault")
+		if start := indexElement(defaults, "w:pPr"); start >= 0 {
+			r.applyParaProps(&r.defaultPara, leadingElement(defaults[start:], "w:pPr"))
+		}
+	}
+
+	for _, element := range wordStylePattern.FindAllString(content, -1) {
+		style := &pdfStyle{}
+		for _, child := range xmlChildren(elementContent(element)) {
+			switch elementName(child) {
+			case "w:basedOn":
+				style.basedOn = attrValue(child, "w:val")
+			case "w:pPr":
+				style.pPr = child
+			case "w:rPr":
+				style.rPr = child
+			case "w:tblPr":
+				style.tblPr = child
+			}
+		}
+		id := styleID(element)
+		r.styles[id] = style
+		tag := element[:strings.IndexByte(element, '>')]
+		if attrValue(tag, "w:type") == "paragraph" && (attrValue(tag, "w:default") == "1" || attrValue(tag, "w:default") == "true") {
+			r.defaultStyle = id
+		}
+	}
+	return nil
+}
+
+// styleChain returns a style and the styles it is based on, the base style first.
+func (r *docxPDF) styleChain(id string) []*pdfStyle {
+	var chain []*pdfStyle
+	for id != "" && len(chain) < 16 {
+		style, ok := r.styles[id]
+		if !ok {
+			break
+		}
+		chain = append([]*pdfStyle{style}, chain...)
+		id = style.basedOn
+	}
+	return chain
+}
+
+// paragraphStyle returns the formatting of a paragraph style over the document defaults.
+func (r *docxPDF) paragraphStyle(id string) pdfStyleProps {
+	if props, ok := r.resolved[id]; ok {
+		return *props
+	}
+	props := &pdfStyleProps{para: r.defaultPara, run: r.defaultRun}
+	for _, style := range r.styleChain(id) {
+		r.applyParaProps(&props.para, style.pPr)
+		r.applyRunProps(&props.run, style.rPr)
+	}
+	r.resolved[id] = props
+	return *props
+}
+
+// applyParaProps applies the properties a w:pPr sets; paragraph mark run properties, section
+// properties and property changes are not paragraph formatting and are skipped.
+func (r *docxPDF) applyParaProps(props *pdfParaProps, pPr string) {
+	if pPr == "" {
+		return
+	}
+	_, children := splitProps(pPr, "w:pPr")
+	for _, child := range children {
+		switch elementName(child) {
+		case "w:jc":
+			props.align = attrValue(child, "w:val")
+		case "w:spacing":
+			if value, ok := twips(child, "w:before"); ok {
+				props.before = value
+			}
+			if value, ok := twips(child, "w:after"); ok {
+				props.after = value
+			}
+			if value := attrValue(child, "w:line"); value != "" {
+				rule := attrValue(child, "w:lineRule")
+				if rule == "" {
+					rule = "auto"
+				}
+				line, _ := strconv.ParseFloat(value, 64)
+				if rule != "auto" {
+					line /= 20
+				}
+				props.line, props.lineRule = line, rule
+			}
+		case "w:ind":
+			for _, name := range []string{"w:left", "w:start"} {
+				if value, ok := twips(child, name); ok {
+					props.left = value
+				}
+			}
+			for _, name := range []string{"w:right", "w:end"} {
+				if value, ok := twips(child, name); ok {
+					props.right = value
+				}
+			}
+			if value, ok := twips(child, "w:firstLine"); ok {
+				props.firstLine = value
+			}
+			if value, ok := twips(child, "w:hanging"); ok {
+				props.firstLine = -value
+			}
+		case "w:keepNext":
+			props.keepNext = hasToggle(child, "w:keepNext")
+		case "w:pageBreakBefore":
+			props.pageBreakBefore = hasToggle(child, "w:pageBreakBefore")
+		case "w:contextualSpacing":
+			props.contextual = hasToggle(child, "w:contextualSpacing")
+		case "w:numPr":
+			if match := wordNumRefPattern.FindStringSubmatch(child); match != nil {
+				props.numID = match[2]
+			}
+			if match := wordNumberingLevelPattern.FindStringSubmatch(child); match != nil {
+				props.level, _ = strconv.Atoi(match[1])
+			}
+		case "w:tabs":
+			tabs := append([]pdfTabStop(nil), props.tabs...)
+			for _, tab := range xmlChildren(elementContent(child)) {
+				pos, _ := twips(tab, "w:pos")
+				kept := tabs[:0]
+				for _, stop := range tabs {
+					if math.Abs(stop.pos-pos) > 0.5 {
+						kept = append(kept, stop)
+					}
+				}
+				tabs = kept
+				if align := attrValue(tab, "w:val"); align != "clear" {
+					tabs = append(tabs, pdfTabStop{pos: pos, align: align, leader: attrValue(tab, "w:leader")})
+				}
+			}
+			sort.Slice(tabs, func(i, j int) bool { return tabs[i].pos < tabs[j].pos })
+			props.tabs = tabs
+		case "w:pBdr":
+			for _, border := range xmlChildren(elementContent(child)) {
+				switch elementName(border) {
+				case "w:top":
+					props.borderTop = parseBorder(border)
+				case "w:bottom":
+					props.borderBottom = parseBorder(border)
+				}
+			}
+		}
+	}
+}
+
+// applyRunProps applies the properties a w:rPr sets.
+func (r *docxPDF) applyRunProps(props *pdfRunProps, rPr string) {
+	if rPr == "" {
+		return
+	}
+	_, children := splitProps(rPr, "w:rPr")
+	for _, child := range children {
+		name := elementName(child)
+		switch name {
+		case "w:rFonts":
+			if theme := attrValue(child, "w:asciiTheme"); theme != "" {
+				font := r.themeFonts["minor"]
+				if strings.HasPrefix(theme, "major") {
+					font = r.themeFonts["major"]
+				}
+				if font != "" {
+					props.font = font
+					continue
+				}
+			}
+			if font := attrValue(child, "w:ascii"); font != "" {
+				props.font = UnescapeXMLText(font)
+			} else if font := attrValue(child, "w:hAnsi"); font != "" {
+				props.font = UnescapeXMLText(font)
+			}
+		case "w:b":
+			props.bold = hasToggle(child, name)
+		case "w:i":
+			props.italic = hasToggle(child, name)
+		case "w:strike", "w:dstrike":
+			props.strike = hasToggle(child, name)
+		case "w:caps", "w:smallCaps":
+			props.caps = hasToggle(child, name)
+		case "w:vanish":
+			props.hidden = hasToggle(child, name)
+		case "w:u":
+			props.underline = attrValue(child, "w:val") != "none"
+		case "w:sz":
+			if size, err := strconv.ParseFloat(attrValue(child, "w:val"), 64); err == nil && size > 0 {
+				props.size, props.sized = size/2, true
+			}
+		case "w:color":
+			if color, ok := hexColor(attrValue(child, "w:val")); ok {
+				props.color = color
+			} else {
+				props.color = [3]float64{}
+			}
+		case "w:vertAlign":
+			props.vertAlign = attrValue(child, "w:val")
+		}
+	}
+}
+
+// runProps returns the formatting of a run in a paragraph of base formatting.
+func (r *docxPDF) runProps(base pdfRunProps, rPr string) pdfRunProps {
+	props := base
+	if rPr == "" {
+		return props
+	}
+	if start := indexElement(rPr, "w:rStyle"); start >= 0 {
+		for _, style := range r.styleChain(attrValue(leadingElement(rPr[start:], "w:rStyle"), "w:val")) {
+			r.applyRunProps(&props, style.rPr)
+		}
+	}
+	r.applyRunProps(&props, rPr)
+	return props
+}
+
+func (r *docxPDF) loadNumbering() error {
+	part, ok := r.pkg.relatedPart(documentPartName, RelTypeNumbering)
+	if !ok {
+		return nil
+	}
+	data, err := r.pkg.Read(part)
+	if err != nil {
+		return err
+	}
+	content := string(data)
+
+	abstracts := make(map[string][]*pdfListLevel)
+	for _, abstract := range wordAbstractPattern.FindAllStringSubmatch(content, -1) {
+		abstracts[abstract[1]] = listLevels(elementContent(abstract[0]), nil)
+	}
+	for _, num := range wordNumPattern.FindAllStringSubmatch(content, -1) {
+		ref := wordAbstractRefPattern.FindStringSubmatch(num[0])
+		if ref == nil {
+			continue
+		}
+		levels := append([]*pdfListLevel(nil), abstracts[ref[1]]...)
+		for _, override := range xmlChildren(elementContent(num[0])) {
+			if elementName(override) != "w:lvlOverride" {
+				continue
+			}
+			level, _ := strconv.Atoi(attrValue(override, "w:ilvl"))
+			if level < 0 || level >= 9 {
+				continue
+			}
+			for len(levels) <= level {
+				levels = append(levels, nil)
+			}
+			levels = listLevels(elementContent(override), levels)
+			if start := indexElement(override, "w:startOverride"); start >= 0 && levels[level] != nil {
+				copied := *levels[level]
+				copied.start, _ = strconv.Atoi(attrValue(leadingElement(override[start:], "w:startOverride"), "w:val"))
+				levels[level] = &copied
+			}
+		}
+		r.lists[num[1]] = &pdfList{levels: levels}
+	}
+	return nil
+}
+
+// listLevels reads the w:lvl elements of content into levels.
+func listLevels(content string, levels []*pdfListLevel) []*pdfListLevel {
+	for _, element := range xmlChildren(content) {
+		if elementName(element) != "w:lvl" {
+			continue
+		}
+		index, _ := strconv.Atoi(attrValue(element, "w:ilvl"))
+		if index < 0 || index >= 9 {
+			continue
+		}
+		level := &pdfListLevel{start: 1, format: "decimal"}
+		for _, child := range xmlChildren(elementContent(element)) {
+			switch elementName(child) {
+			case "w:start":
+				level.start, _ = strconv.Atoi(attrValue(child, "w:val"))
+			case "w:numFmt":
+				level.format = attrValue(child, "w:val")
+			case "w:lvlText":
+				level.text = UnescapeXMLText(attrValue(child, "w:val"))
+			case "w:suff":
+				level.suffix = attrValue(child, "w:val")
+			case "w:pPr":
+				level.pPr = child
+			case "w:rPr":
+				level.rPr = child
+			}
+		}
+		for len(levels) <= index {
+			levels = append(levels, nil)
+		}
+		levels[index] = level
+	}
+	return levels
+}
+
+// listMarker counts a paragraph of a list level and returns its number or bullet.
+func (r *docxPDF) listMarker(list *pdfList, level int) string {
+	if level < 0 || level >= len(list.levels) || list.levels[level] == nil {
+		return ""
+	}
+	for deeper := level + 1; deeper < len(list.started); deeper++ {
+		list.started[deeper] = false
+	}
+	if list.started[level] {
+		list.counters[level]++
+	} else {
+		list.counters[level], list.started[level] = list.levels[level].start, true
+	}
+
+	lvl := list.levels[level]
+	switch lvl.format {
+	case "none":
+		return ""
+	case "bullet":
+		if lvl.text == "o" || lvl.text == "-" || lvl.text == "*" {
+			return lvl.text
+		}
+		return "•"
+	}
+	text := lvl.text
+	for n := len(list.levels); n >= 1; n-- {
+		placeholder := "%" + strconv.Itoa(n)
+		if !strings.Contains(text, placeholder) || list.levels[n-1] == nil {
+			continue
+		}
+		count := list.counters[n-1]
+		if !list.started[n-1] {
+			count = list.levels[n-1].start
+		}
+		format := list.levels[n-1].format
+		if n-1 != level && format == "bullet" {
+			format = "decimal"
+		}
+		text = strings.ReplaceAll(text, placeholder, formatListNumber(count, format))
+	}
+	return text
+}
+
+// formatListNumber writes a list counter in a Word number format.
+func formatListNumber(n int, format string) string {
+	switch format {
+	case "lowerLetter", "upperLetter":
+		if n < 1 {
+			return strconv.Itoa(n)
+		}
+		letter := string(rune('a' + (n-1)%26))
+		text := strings.Repeat(letter, (n-1)/26+1)
+		if format == "upperLetter" {
+			return strings.ToUpper(text)
+		}
+		return text
+	case "lowerRoman", "upperRoman":
+		if n < 1 || n >= 4000 {
+			return strconv.Itoa(n)
+		}
+		var b strings.Builder
+		for _, numeral := range []struct {
+			value int
+			text  string
+		}{{1000, "m"}, {900, "cm"}, {500, "d"}, {400, "cd"}, {100, "c"}, {90, "xc"}, {50, "l"}, {40, "xl"}, {10, "x"}, {9, "ix"}, {5, "v"}, {4, "iv"}, {1, "i"}} {
+			for ; n >= numeral.value; n -= numeral.value {
+				b.WriteString(numeral.text)
+			}
+		}
+		if format == "upperRoman" {
+			return strings.ToUpper(b.String())
+		}
+		return b.String()
+	case "decimalZero":
+		return fmt.Sprintf("%02d", n)
+	}
+	return strconv.Itoa(n)
+}
+
+// loadFonts reads the fonts embedded in the document, which Word obfuscates with a key kept
+// in the font table.
+func (r *docxPDF) loadFonts() error {
+	part, ok := r.pkg.relatedPart(documentPartName, RelTypeFontTable)
+	if !ok {
+		return nil
+	}
+	content, err := r.pkg.Read(part)
+	if err != nil {
+		return err
+	}
+	rels, err := r.pkg.readRelationships(part)
+	if err != nil {
+		return err
+	}
+	variants := map[string]string{"w:embedRegular": "", "w:embedBold": "b", "w:embedItalic": "i", "w:embedBoldItalic": "bi"}
+	for _, font := range wordFontPattern.FindAllString(string(content), -1) {
+		name := strings.ToLower(UnescapeXMLText(attrValue(font[:strings.IndexByte(font, '>')], "w:name")))
+		for _, embed := range xmlChildren(elementContent(font)) {
+			variant, ok := variants[elementName(embed)]
+			rel, found := rels[attrValue(embed, "r:id")]
+			if !ok || !found || rel.external {
+				continue
+			}
+			data, err := r.pkg.Read(resolveTarget(part, rel.target))
+			if err != nil {
+				continue
+			}
+			if key := attrValue(embed, "w:fontKey"); key != "" {
+				if data, err = deobfuscateFont(data, key); err != nil {
+					continue
+				}
+			}
+			if ttf, err := parseTrueType(data); err == nil {
+				r.embedded[name+"|"+variant] = ttf
+			}
+		}
+	}
+	return nil
+}
+
+// font returns the PDF font for a Word font: the font embedded in the document, or else the
+// closest standard font.
+func (r *docxPDF) font(name string, bold, italic bool) *pdfFont {
+	variant := ""
+	if bold {
+		variant += "b"
+	}
+	if italic {
+		variant += "i"
+	}
+	key := strings.ToLower(name) + "|" + variant
+	if font, ok := r.fonts[key]; ok {
+		return font
+	}
+
+	var font *pdfFont
+	if ttf, ok := r.embedded[key]; ok {
+		font = r.doc.addTrueTypeFont(ttf, name)
+	} else {
+		base, widths := standardFont(name, bold, italic)
+		if font = r.fonts[base]; font == nil {
+			font = r.doc.addStandardFont(base, widths)
+			r.fonts[base] = font
+		}
+	}
+	r.fonts[key] = font
+	return font
+}
+
+// image returns a picture of the part being laid out, or nil for a picture it can't draw.
+func (r *docxPDF) image(id string) *pdfImage {
+	rel, ok := r.rels[id]
+	if !ok || rel.external {
+		return nil
+	}
+	name := resolveTarget(r.part, rel.target)
+	if img, ok := r.images[name]; ok {
+		return img
+	}
+	var img *pdfImage
+	if data, err := r.pkg.Read(name); err == nil {
+		img, _ = r.doc.addImage(data)
+	}
+	r.images[name] = img
+	return img
+}
+
+// pdfFragmentKind is what a fragment of a paragraph is.
+type pdfFragmentKind int
+
+const (
+	fragmentText pdfFragmentKind = iota
+	fragmentSpace
+	fragmentTab
+	fragmentBreak
+	fragmentPageBreak
+	fragmentImage
+)
+
+// pdfFragment is a piece of a paragraph in one format: a word, spaces, a tab, a break or a
+// picture.
+type pdfFragment struct {
+	kind      pdfFragmentKind
+	text      []byte
+	font      *pdfFont
+	size      float64
+	rise      float64
+	color     [3]float64
+	underline bool
+	strike    bool
+	width     float64
+	height    float64 // of a picture
+	image     *pdfImage
+	leader    string // of a tab
+}
+
+// fragments returns the fragments of text with a run's formatting, split into words and spaces.
+func (r *docxPDF) fragments(text string, props pdfRunProps) []*pdfFragment {
+	if props.hidden || text == "" {
+		return nil
+	}
+	if props.caps {
+		text = strings.ToUpper(text)
+	}
+	var fragments []*pdfFragment
+	for text != "" {
+		kind, end := fragmentText, strings.IndexAny(text, " \t")
+		switch {
+		case text[0] == '\t':
+			kind, end = fragmentTab, 1
+		case text[0] == ' ':
+			kind, end = fragmentSpace, len(text)-len(strings.TrimLeft(text, " "))
+		case end < 0:
+			end = len(text)
+		}
+		fragments = append(fragments, r.fragment(kind, text[:end], props))
+		text = text[end:]
+	}
+	return fragments
+}
+
+func (r *docxPDF) fragment(kind pdfFragmentKind, text string, props pdfRunProps) *pdfFragment {
+	f := &pdfFragment{
+		kind:      kind,
+		font:      r.font(props.font, props.bold, props.italic),
+		size:      props.size,
+		color:     props.color,
+		underline: props.underline,
+		strike:    props.strike,
+	}
+	switch props.vertAlign {
+	case "superscript":
+		f.size, f.rise = props.size*0.65, props.size*0.33
+	case "subscript":
+		f.size, f.rise = props.size*0.65, -props.size*0.14
+	}
+	if kind == fragmentText || kind == fragmentSpace {
+		f.text = encodeWinAnsi(text)
+		f.width = f.font.width(f.text, f.size)
+	}
+	return f
+}
+
+// pdfFields tracks the complex fields a paragraph's runs are in.
+type pdfFields struct {
+	stack []*pdfField
+}
+
+type pdfField struct {
+	instr     strings.Builder
+	separated bool
+	replaced  bool
+}
+
+// visible reports whether run content is shown: outside fields or in their results, unless
+// a field's result is replaced by a computed value.
+func (f *pdfFields) visible() bool {
+	for _, field := range f.stack {
+		if !field.separated || field.replaced {
+			return false
+		}
+	}
+	return true
+}
+
+// fieldValue returns the value of a page number field while a header or footer is laid out.
+func (r *docxPDF) fieldValue(instr string) (string, bool) {
+	words := strings.Fields(instr)
+	if r.page == 0 || len(words) == 0 {
+		return "", false
+	}
+	switch strings.ToUpper(words[0]) {
+	case "PAGE":
+		return strconv.Itoa(r.page), true
+	case "NUMPAGES", "SECTIONPAGES":
+		return strconv.Itoa(r.pages), true
+	}
+	return "", false
+}
+
+// paragraphFragments returns the fragments of paragraph content: runs within hyperlinks,
+// fields, content controls and insertions. Deleted text is left out.
+func (r *docxPDF) paragraphFragments(content string, base pdfRunProps, fields *pdfFields) []*pdfFragment {
+	var fragments []*pdfFragment
+	for _, child := range xmlChildren(content) {
+		switch elementName(child) {
+		case "w:r":
+			fragments = append(fragments, r.runFragments(child, base, fields)...)
+		case "w:fldSimple":
+			if value, ok := r.fieldValue(UnescapeXMLText(attrValue(child[:strings.IndexByte(child, '>')], "w:instr"))); ok {
+				props := base
+				if start := indexElement(child, "w:rPr"); start >= 0 {
+					props = r.runProps(base, leadingElement(child[start:], "w:rPr"))
+				}
+				fragments = append(fragments, r.fragments(value, props)...)
+				continue
+			}
+			fragments = append(fragments, r.paragraphFragments(elementContent(child), base, fields)...)
+		case "w:hyperlink", "w:ins", "w:moveTo", "w:smartTag", "w:customXml", "w:sdt", "w:sdtContent", "w:bdo", "w:dir":
+			fragments = append(fragments, r.paragraphFragments(elementContent(child), base, fields)...)
+		}
+	}
+	return fragments
+}
+
+func (r *docxPDF) runFragments(run string, base pdfRunProps, fields *pdfFields) []*pdfFragment {
+	content := elementContent(run)
+	props := base
+	if start := indexElement(content, "w:rPr"); start >= 0 {
+		rPr := leadingElement(content[start:], "w:rPr")
+		props = r.runProps(base, rPr)
+		content = strings.Replace(content, rPr, "", 1)
+	}
+
+	var fragments []*pdfFragment
+	for _, child := range xmlChildren(content) {
+		name := elementName(child)
+		if name == "w:fldChar" {
+			switch attrValue(child, "w:fldCharType") {
+			case "begin":
+				fields.stack = append(fields.stack, &pdfField{})
+			case "separate", "end":
+				if len(fields.stack) == 0 {
+					continue
+				}
+				field := fields.stack[len(fields.stack)-1]
+				if !field.separated {
+					field.separated = true
+					if value, ok := r.fieldValue(field.instr.String()); ok {
+						field.replaced = true
+						fields.stack = fields.stack[:len(fields.stack)-1]
+						if fields.visible() {
+							fragments = append(fragments, r.fragments(value, props)...)
+						}
+						fields.stack = append(fields.stack, field)
+					}
+				}
+				if attrValue(child, "w:fldCharType") == "end" {
+					fields.stack = fields.stack[:len(fields.stack)-1]
+				}
+			}
+			continue
+		}
+		if name == "w:instrText" && len(fields.stack) > 0 && !fields.stack[len(fields.stack)-1].separated {
+			fields.stack[len(fields.stack)-1].instr.WriteString(UnescapeXMLText(elementContent(child)))
+			continue
+		}
+		if !fields.visible() {
+			continue
+		}
+
+		switch name {
+		case "w:t":
+			fragments = append(fragments, r.fragments(UnescapeXMLText(elementContent(child)), props)...)
+		case "w:tab":
+			if !props.hidden {
+				fragments = append(fragments, r.fragment(fragmentTab, "", props))
+			}
+		case "w:br", "w:cr":
+			kind := fragmentBreak
+			if attrValue(child, "w:type") == "page" {
+				kind = fragmentPageBreak
+			}
+			fragments = append(fragments, r.fragment(kind, "", props))
+		case "w:noBreakHyphen":
+			fragments = append(fragments, r.fragments("-", props)...)
+		case "w:sym":
+			if code, err := strconv.ParseUint(attrValue(child, "w:char"), 16, 32); err == nil {
+				if code < 0x100 {
+					code |= 0xF000 // symbol fonts map their characters to the private use area
+				}
+				fragments = append(fragments, r.fragments(string(rune(code)), props)...)
+			}
+		case "w:drawing":
+			blip := wordBlipPattern.FindStringSubmatch(child)
+			extent := wordExtentPattern.FindStringSubmatch(child)
+			if blip == nil || extent == nil || props.hidden {
+				continue
+			}
+			if img := r.image(blip[1]); img != nil {
+				cx, _ := strconv.ParseFloat(extent[1], 64)
+				cy, _ := strconv.ParseFloat(extent[2], 64)
+				fragments = append(fragments, &pdfFragment{kind: fragmentImage, image: img, width: cx / 12700, height: cy / 12700})
+			}
+		}
+	}
+	return fragments
+}
+
+func twips(attrs, name string) (float64, bool) {
+	value, err := strconv.ParseFloat(attrValue(attrs, name), 64)
+	if err != nil {
+		return 0, false
+	}
+	return value / 20, true
+}
+
+// hexColor parses a color such as "1F4E79"; "auto" and invalid values are not colors.
+func hexColor(value string) ([3]float64, bool) {
+	if len(value) != 6 {
+		return [3]float64{}, false
+	}
+	n, err := strconv.ParseUint(value, 16, 32)
+	if err != nil {
+		return [3]float64{}, false
+	}
+	return [3]float64{float64(n>>16) / 255, float64(n>>8&0xFF) / 255, float64(n&0xFF) / 255}, true
+}
+
+// parseBorder reads a border such as <w:top w:val="single" w:sz="4" w:color="auto"/>, whose
+// size is in eighths of a point.
+func parseBorder(element string) pdfBorder {
+	border := pdfBorder{set: true}
+	switch attrValue(element, "w:val") {
+	case "", "nil", "none":
+		return border
+	}
+	size, _ := strconv.ParseFloat(attrValue(element, "w:sz"), 64)
+	border.width = math.Max(size/8, 0.25)
+	border.color, _ = hexColor(attrValue(element, "w:color"))
+	return border
+}
@@ -0,0 +1,749 @@
+package internal
+
+import (
+	"bytes"
+	"io"
+	"math"
+)
+
+// pdfSection is a section of a document: its body elements and page setup, in points.
+type pdfSection struct {
+	elements       []string
+	width          float64
+	height         float64
+	top            float64
+	bottom         float64
+	left           float64
+	right          float64
+	headerDistance float64
+	footerDistance float64
+	bodyTop        float64 // the top margin, extended to fit the header
+	bodyBottom     float64 // the bottom margin, extended to fit the footer
+	titlePage      bool
+	continuous     bool
+	headers        map[string]string // header parts by type: default or first
+	footers        map[string]string
+}
+
+// sections splits a document body into its sections. A section's headers and footers are
+// those of the section before unless it sets its own.
+func (r *docxPDF) sections(body string) []*pdfSection {
+	var sections []*pdfSection
+	var elements []string
+	add := func(sectPr string) {
+		var previous *pdfSection
+		if len(sections) > 0 {
+			previous = sections[len(sections)-1]
+		}
+		sections = append(sections, r.section(sectPr, elements, previous))
+		elements = nil
+	}
+	for _, child := range xmlChildren(body) {
+		if elementName(child) == "w:sectPr" {
+			add(child)
+			continue
+		}
+		sectPr := paragraphSection(child)
+		if sectPr == "" {
+			elements = append(elements, child)
+			continue
+		}
+		if paragraph := removeParagraphSection(child, sectPr); paragraph != "" {
+			elements = append(elements, paragraph)
+		}
+		add(sectPr)
+	}
+	if len(elements) > 0 || len(sections) == 0 {
+		add("")
+	}
+	return sections
+}
+
+func (r *docxPDF) section(sectPr string, elements []string, previous *pdfSection) *pdfSection {
+	section := &pdfSection{
+		elements: elements, width: 612, height: 792, top: 72, bottom: 72, left: 72, right: 72,
+		headerDistance: 36, footerDistance: 36,
+		headers: make(map[string]string), footers: make(map[string]string),
+	}
+	if previous != nil {
+		for kind, part := range previous.headers {
+			section.headers[kind] = part
+		}
+		for kind, part := range previous.footers {
+			section.footers[kind] = part
+		}
+	}
+	if sectPr == "" {
+		return section
+	}
+
+	_, children := splitProps(sectPr, "w:sectPr")
+	for _, child := range children {
+		switch elementName(child) {
+		case "w:pgSz":
+			if value, ok := twips(child, "w:w"); ok && value > 0 {
+				section.width = value
+			}
+			if value, ok := twips(child, "w:h"); ok && value > 0 {
+				section.height = value
+			}
+		case "w:pgMar":
+			for name, length := range map[string]*float64{
+				"w:top": &section.top, "w:bottom": &section.bottom, "w:left": &section.left, "w:right": &section.right,
+				"w:header": &section.headerDistance, "w:footer": &section.footerDistance,
+			} {
+				if value, ok := twips(child, name); ok {
+					*length = math.Abs(value)
+				}
+			}
+		case "w:titlePg":
+			section.titlePage = hasToggle(child, "w:titlePg")
+		case "w:type":
+			section.continuous = attrValue(child, "w:val") == "continuous"
+		case "w:headerReference", "w:footerReference":
+			rel, ok := r.documentRels[attrValue(child, "r:id")]
+			kind := attrValue(child, "w:type")
+			if !ok || rel.external || (kind != "default" && kind != "first") {
+				continue
+			}
+			if elementName(child) == "w:headerReference" {
+				section.headers[kind] = resolveTarget(documentPartName, rel.target)
+			} else {
+				section.footers[kind] = resolveTarget(documentPartName, rel.target)
+			}
+		}
+	}
+	return section
+}
+
+// headerPart returns the header or footer part of a page of a section.
+func (s *pdfSection) headerPart(parts map[string]string, first bool) string {
+	if first && s.titlePage {
+		return parts["first"]
+	}
+	return parts["default"]
+}
+
+// measureSection extends the margins of a section where its headers or footers need more room.
+func (r *docxPDF) measureSection(section *pdfSection) {
+	section.bodyTop, section.bodyBottom = section.top, section.bottom
+	r.page, r.pages = 1, 1
+	defer func() { r.page, r.pages = 0, 0 }()
+	for _, first := range []bool{false, true} {
+		if height := blocksHeight(r.headerBlocks(section.headerPart(section.headers, first), section)); height > 0 {
+			section.bodyTop = math.Max(section.bodyTop, section.headerDistance+height)
+		}
+		if height := blocksHeight(r.headerBlocks(section.headerPart(section.footers, first), section)); height > 0 {
+			section.bodyBottom = math.Max(section.bodyBottom, section.footerDistance+height)
+		}
+	}
+}
+
+// headerBlocks lays out a header or footer part.
+func (r *docxPDF) headerBlocks(part string, section *pdfSection) []*pdfBlock {
+	if part == "" {
+		return nil
+	}
+	content, err := r.pkg.Read(part)
+	if err != nil {
+		return nil
+	}
+	rels, err := r.pkg.readRelationships(part)
+	if err != nil {
+		return nil
+	}
+	root := string(content)
+	if start := rootElementStart(root); start >= 0 {
+		root = root[start:]
+	}
+	r.part, r.rels = part, rels
+	defer func() { r.part, r.rels = documentPartName, r.documentRels }()
+	return r.blocks(xmlChildren(elementContent(root)), section.left, section.width-section.left-section.right)
+}
+
+func blocksHeight(blocks []*pdfBlock) float64 {
+	height := 0.0
+	for _, block := range blocks {
+		height += block.height
+	}
+	return height
+}
+
+// pdfBlock is a piece of the flow of a page: a line of a paragraph, a table row, or space
+// between paragraphs. Blocks are placed top to bottom and drawn once the pages are known.
+type pdfBlock struct {
+	height    float64
+	space     bool        // spacing left out at the top of a page
+	keep      bool        // kept on the page of the block after it
+	pageBreak bool        // starts a new page
+	header    []*pdfBlock // table header rows to repeat when the block starts a page
+	split     func(height float64) (*pdfBlock, *pdfBlock)
+	draw      func(p *pdfPage, top float64)
+}
+
+// blocks lays out body elements between x and x+width.
+func (r *docxPDF) blocks(elements []string, x, width float64) []*pdfBlock {
+	var blocks []*pdfBlock
+	var previous *pdfParagraph
+	for _, element := range elements {
+		switch elementName(element) {
+		case "w:p":
+			paragraph := r.paragraph(element, x, width)
+			if previous != nil && previous.style == paragraph.style {
+				// Contextual spacing leaves out the spacing between paragraphs of a style
+				if previous.contextual && previous.after != nil {
+					previous.after.height = 0
+				}
+				if paragraph.contextual && paragraph.before != nil {
+					paragraph.before.height = 0
+				}
+			}
+			blocks = append(blocks, paragraph.blocks...)
+			previous = paragraph
+		case "w:tbl":
+			blocks = append(blocks, r.table(element, x, width)...)
+			previous = nil
+		case "w:sdt", "w:sdtContent", "w:customXml", "w:ins", "w:moveTo":
+			blocks = append(blocks, r.blocks(xmlChildren(elementContent(element)), x, width)...)
+		}
+	}
+	return blocks
+}
+
+// pdfParagraph is a laid out paragraph.
+type pdfParagraph struct {
+	blocks     []*pdfBlock
+	before     *pdfBlock
+	after      *pdfBlock
+	style      string
+	contextual bool
+}
+
+func (r *docxPDF) paragraph(paragraph string, x, width float64) *pdfParagraph {
+	pPr := ""
+	if match := wordPPrPattern.FindStringSubmatch(paragraph); match != nil {
+		pPr = match[1]
+	}
+	style := r.defaultStyle
+	if pPr != "" {
+		if start := indexElement(pPr, "w:pStyle"); start >= 0 {
+			style = attrValue(leadingElement(pPr[start:], "w:pStyle"), "w:val")
+		}
+	}
+	styled := r.paragraphStyle(style)
+	props, base := styled.para, styled.run
+
+	// Numbering indents apply over the style and under direct formatting
+	direct := props
+	r.applyParaProps(&direct, pPr)
+	var list *pdfList
+	var level *pdfListLevel
+	if direct.numID != "" && direct.numID != "0" {
+		if list = r.lists[direct.numID]; list != nil && direct.level < len(list.levels) {
+			level = list.levels[direct.level]
+		}
+	}
+	if level != nil {
+		r.applyParaProps(&props, level.pPr)
+	}
+	r.applyParaProps(&props, pPr)
+
+	if outline := paragraphOutlineLevel(paragraph, r.levels); outline >= 0 && !base.sized {
+		base.size, base.bold = []float64{20, 16, 14, 13, 12, 11}[min(outline, 5)], true
+	}
+	mark := base
+	if pPr != "" {
+		if start := indexElement(pPr, "w:rPr"); start >= 0 {
+			mark = r.runProps(base, leadingElement(pPr[start:], "w:rPr"))
+		}
+	}
+
+	var fragments []*pdfFragment
+	if level != nil {
+		if marker := r.listMarker(list, direct.level); marker != "" {
+			markerProps := mark
+			markerProps.underline, markerProps.strike = false, false
+			r.applyRunProps(&markerProps, level.rPr)
+			fragments = append(fragments, r.fragments(marker, markerProps)...)
+			switch level.suffix {
+			case "space":
+				fragments = append(fragments, r.fragment(fragmentSpace, " ", markerProps))
+			case "nothing":
+			default:
+				fragments = append(fragments, r.fragment(fragmentTab, "", markerProps))
+			}
+		}
+	}
+	fragments = append(fragments, r.paragraphFragments(elementContent(paragraph), base, &pdfFields{})...)
+
+	result := &pdfParagraph{style: style, contextual: props.contextual}
+	if props.pageBreakBefore {
+		result.blocks = append(result.blocks, &pdfBlock{pageBreak: true})
+	}
+	if props.before > 0 {
+		result.before = &pdfBlock{height: props.before, space: true, keep: true}
+		result.blocks = append(result.blocks, result.before)
+	}
+
+	markFont := r.font(mark.font, mark.bold, mark.italic)
+	lines := breakLines(fragments, &props, width, markFont, mark.size)
+	for i, line := range lines {
+		block := lineBlock(line, x, props.keepNext || (len(lines) > 1 && (i == 0 || i == len(lines)-2)))
+		if i == 0 && props.borderTop.width > 0 {
+			block.draw = withBorder(block.draw, props.borderTop, x+props.left, x+width-props.right, 0)
+		}
+		if i == len(lines)-1 && props.borderBottom.width > 0 {
+			block.draw = withBorder(block.draw, props.borderBottom, x+props.left, x+width-props.right, line.height)
+		}
+		result.blocks = append(result.blocks, block)
+		if line.pageBreak {
+			result.blocks = append(result.blocks, &pdfBlock{pageBreak: true})
+		}
+	}
+
+	if props.after > 0 {
+		result.after = &pdfBlock{height: props.after, space: true, keep: props.keepNext}
+		result.blocks = append(result.blocks, result.after)
+	}
+	return result
+}
+
+// withBorder draws a paragraph border at offset below the top of a line.
+func withBorder(draw func(p *pdfPage, top float64), border pdfBorder, left, right, offset float64) func(p *pdfPage, top float64) {
+	return func(p *pdfPage, top float64) {
+		draw(p, top)
+		p.line(left, top-offset, right, top-offset, border.width, border.color)
+	}
+}
+
+// pdfLine is a line of a paragraph; fragment positions are from the left of the text area.
+type pdfLine struct {
+	placed    []pdfPlaced
+	end       float64 // where the last word or tab ends
+	ascent    float64
+	descent   float64
+	height    float64
+	pageBreak bool
+}
+
+type pdfPlaced struct {
+	fragment *pdfFragment
+	x        float64
+	width    float64
+}
+
+// breakLines fills lines with the fragments of a paragraph word by word, then aligns them.
+func breakLines(fragments []*pdfFragment, props *pdfParaProps, width float64, markFont *pdfFont, markSize float64) []*pdfLine {
+	limit := width - props.right
+	var lines []*pdfLine
+	line := &pdfLine{}
+	x := props.left + props.firstLine
+	content, wrapped := false, false
+
+	// finish ends a line; lines that wrap are justified, those ending the paragraph or broken
+	// by a break are not
+	finish := func(wrap bool) {
+		// Trailing spaces take no room
+		for len(line.placed) > 0 && line.placed[len(line.placed)-1].fragment.kind == fragmentSpace {
+			line.placed = line.placed[:len(line.placed)-1]
+		}
+		justify := props.align == "both" || props.align == "distribute"
+		alignLine(line, props.align, limit, justify && wrap)
+		measureLine(line, props, markFont, markSize)
+		lines = append(lines, line)
+		line, x, content = &pdfLine{}, props.left, false
+	}
+	place := func(f *pdfFragment, width float64) {
+		line.placed = append(line.placed, pdfPlaced{fragment: f, x: x, width: width})
+		x += width
+		if f.kind != fragmentSpace {
+			line.end = x
+		}
+	}
+
+	for i := 0; i < len(fragments); {
+		f := fragments[i]
+		switch f.kind {
+		case fragmentSpace:
+			if !content && wrapped {
+				i++
+				continue
+			}
+			place(f, f.width)
+			i++
+		case fragmentTab:
+			stop := nextTabStop(x, props)
+			if stop.pos > limit && content {
+				wrapped = true
+				finish(true)
+				continue
+			}
+			target := stop.pos
+			if stop.align == "right" || stop.align == "end" || stop.align == "center" || stop.align == "decimal" {
+				segment := 0.0
+				for _, next := range fragments[i+1:] {
+					if next.kind == fragmentTab || next.kind == fragmentBreak || next.kind == fragmentPageBreak {
+						break
+					}
+					segment += next.width
+				}
+				if stop.align == "center" {
+					segment /= 2
+				}
+				target = math.Max(x, target-segment)
+			}
+			tab := *f
+			tab.leader = stop.leader
+			place(&tab, math.Max(0, target-x))
+			content = true
+			i++
+		case fragmentBreak, fragmentPageBreak:
+			line.pageBreak = f.kind == fragmentPageBreak
+			wrapped = false
+			finish(false)
+			i++
+		default:
+			// A word is the text and pictures up to the next space, tab or break
+			end := i + 1
+			for end < len(fragments) && fragments[end].kind == fragmentText && fragments[end-1].kind == fragmentText {
+				end++
+			}
+			word := 0.0
+			for _, part := range fragments[i:end] {
+				word += part.width
+			}
+			if x+word > limit && content {
+				wrapped = true
+				finish(true)
+				continue
+			}
+			for _, part := range fragments[i:end] {
+				for x+part.width > limit && part.kind == fragmentText && len(part.text) > 1 {
+					// A word longer than the line is broken between characters
+					n, used := 0, 0.0
+					for n < len(part.text) && x+used+part.font.widths[part.text[n]]*part.size/1000 <= limit {
+						used += part.font.widths[part.text[n]] * part.size / 1000
+						n++
+					}
+					if n == 0 && content {
+						wrapped = true
+						finish(true)
+						continue
+					}
+					n = max(n, 1)
+					head, tail := *part, *part
+					head.text, tail.text = part.text[:n], part.text[n:]
+					head.width, tail.width = head.font.width(head.text, head.size), tail.font.width(tail.text, tail.size)
+					place(&head, head.width)
+					wrapped = true
+					finish(true)
+					part = &tail
+				}
+				place(part, part.width)
+				content = true
+			}
+			i = end
+		}
+	}
+	finish(false)
+	return lines
+}
+
+// nextTabStop returns the tab stop after x: a custom stop, the hanging indent, or a default
+// stop every half inch.
+func nextTabStop(x float64, props *pdfParaProps) pdfTabStop {
+	stop := pdfTabStop{pos: (math.Floor(x/36+0.001) + 1) * 36}
+	for _, tab := range props.tabs {
+		if tab.pos > x+0.01 {
+			stop = tab
+			break
+		}
+	}
+	if props.firstLine < 0 && x < props.left-0.01 && props.left < stop.pos {
+		stop = pdfTabStop{pos: props.left}
+	}
+	return stop
+}
+
+// alignLine moves a line's fragments for its alignment; justified lines widen the spaces
+// after their last tab.
+func alignLine(line *pdfLine, align string, limit float64, justify bool) {
+	if len(line.placed) == 0 {
+		return
+	}
+	free := limit - line.end
+	if free <= 0 {
+		return
+	}
+	shift := 0.0
+	switch align {
+	case "center":
+		shift = free / 2
+	case "right", "end":
+		shift = free
+	}
+	if justify {
+		first, spaces := 0, 0
+		for i, placed := range line.placed {
+			if placed.fragment.kind == fragmentTab {
+				first, spaces = i+1, 0
+			} else if placed.fragment.kind == fragmentSpace {
+				spaces++
+			}
+		}
+		if spaces > 0 {
+			extra := free / float64(spaces)
+			for i := first; i < len(line.placed); i++ {
+				line.placed[i].x += shift
+				if line.placed[i].fragment.kind == fragmentSpace {
+					line.placed[i].width += extra
+					shift += extra
+				}
+			}
+			line.end = limit
+			return
+		}
+	}
+	for i := range line.placed {
+		line.placed[i].x += shift
+	}
+	line.end += shift
+}
+
+// measureLine sets the height of a line from its fonts and pictures and the line spacing.
+func measureLine(line *pdfLine, props *pdfParaProps, markFont *pdfFont, markSize float64) {
+	for _, placed := range line.placed {
+		f := placed.fragment
+		if f.kind == fragmentImage {
+			line.ascent = math.Max(line.ascent, f.height)
+			continue
+		}
+		line.ascent = math.Max(line.ascent, f.size*f.font.ascent/1000+f.rise)
+		line.descent = math.Max(line.descent, f.size*f.font.descent/1000-f.rise)
+	}
+	if line.ascent == 0 && line.descent == 0 {
+		line.ascent, line.descent = markSize*markFont.ascent/1000, markSize*markFont.descent/1000
+	}
+	natural := line.ascent + line.descent
+	switch props.lineRule {
+	case "exact":
+		line.height = props.line
+	case "atLeast":
+		line.height = math.Max(natural, props.line)
+	default:
+		line.height = natural * props.line / 240
+		if props.line <= 0 {
+			line.height = natural
+		}
+	}
+}
+
+// lineBlock returns the block drawing a line at x. Words and the spaces between them are
+// drawn together while their format is the same and the spaces keep their width.
+func lineBlock(line *pdfLine, x float64, keep bool) *pdfBlock {
+	block := &pdfBlock{height: line.height, keep: keep}
+	block.draw = func(p *pdfPage, top float64) {
+		baseline := top - line.height + line.descent
+		var text []byte
+		var start *pdfPlaced
+		flush := func() {
+			if start != nil {
+				f := start.fragment
+				p.text(f.font, f.size, x+start.x, baseline+f.rise, f.color, text)
+			}
+			text, start = nil, nil
+		}
+		for i := range line.placed {
+			placed := &line.placed[i]
+			f := placed.fragment
+			left := x + placed.x
+			if start != nil && (f.kind != fragmentText && f.kind != fragmentSpace || !sameTextFormat(start.fragment, f) || math.Abs(placed.width-f.width) > 0.01) {
+				flush()
+			}
+			switch f.kind {
+			case fragmentImage:
+				p.image(f.image, left, baseline, placed.width, f.height)
+				continue
+			case fragmentText, fragmentSpace:
+				if start == nil && f.kind == fragmentText {
+					start = placed
+				}
+				if start != nil {
+					text = append(text, f.text...)
+				}
+			case fragmentTab:
+				drawLeader(p, f, left, baseline, placed.width)
+			}
+			if f.underline {
+				p.line(left, baseline-f.size*0.12, left+placed.width, baseline-f.size*0.12, f.size*0.06, f.color)
+			}
+			if f.strike {
+				p.line(left, baseline+f.size*0.28, left+placed.width, baseline+f.size*0.28, f.size*0.06, f.color)
+			}
+		}
+		flush()
+	}
+	return block
+}
+
+func sameTextFormat(a, b *pdfFragment) bool {
+	return a.font == b.font && a.size == b.size && a.rise == b.rise && a.color == b.color
+}
+
+// drawLeader fills a tab with its leader: dots, hyphens or a line.
+func drawLeader(p *pdfPage, f *pdfFragment, left, baseline, width float64) {
+	var char byte
+	switch f.leader {
+	case "dot":
+		char = '.'
+	case "hyphen":
+		char = '-'
+	case "middleDot":
+		char = 0xB7
+	case "underscore", "heavy":
+		p.line(left, baseline-f.size*0.12, left+width, baseline-f.size*0.12, f.size*0.06, f.color)
+		return
+	default:
+		return
+	}
+	charWidth := f.font.widths[char] * f.size / 1000
+	if charWidth <= 0 || width < 2*charWidth {
+		return
+	}
+	count := int((width - charWidth) / charWidth)
+	p.text(f.font, f.size, left+width-float64(count)*charWidth-charWidth/2, baseline, f.color, bytes.Repeat([]byte{char}, count))
+}
+
+// pdfPaginator places blocks on pages.
+type pdfPaginator struct {
+	pages   []*pdfLayoutPage
+	page    *pdfLayoutPage
+	section *pdfSection // the section of the pages to come
+	y       float64     // the top of the room left on the page
+	fresh   bool        // nothing but repeated table headers is on the page yet
+}
+
+// pdfLayoutPage is a page and the tops of the blocks placed on it.
+type pdfLayoutPage struct {
+	section *pdfSection
+	first   bool // the first page of its section
+	blocks  []*pdfBlock
+	tops    []float64
+}
+
+func (p *pdfPaginator) newPage(section *pdfSection, first bool) {
+	p.page = &pdfLayoutPage{section: section, first: first}
+	p.pages = append(p.pages, p.page)
+	p.section = section
+	p.y, p.fresh = section.height-section.bodyTop, true
+}
+
+func (p *pdfPaginator) available() float64 {
+	return p.y - p.page.section.bodyBottom
+}
+
+func (p *pdfPaginator) pageHeight() float64 {
+	return p.section.height - p.section.bodyTop - p.section.bodyBottom
+}
+
+// place places blocks, starting a page where one is full. Blocks that keep with the next are
+// moved to a new page together with it when they don't fit.
+func (p *pdfPaginator) place(blocks []*pdfBlock) {
+	for i, block := range blocks {
+		if block.pageBreak {
+			p.newPage(p.section, false)
+			continue
+		}
+		if block.space && p.fresh {
+			continue
+		}
+		if block.keep && !p.fresh && (i == 0 || !blocks[i-1].keep) {
+			kept := 0.0
+			for _, next := range blocks[i:] {
+				if next.pageBreak {
+					break
+				}
+				kept += next.height
+				if !next.keep {
+					break
+				}
+			}
+			if kept > p.available() && kept <= p.pageHeight() {
+				p.newPage(p.section, false)
+			}
+		}
+		p.put(block)
+	}
+}
+
+// put places a block, on a new page if it doesn't fit. Blocks taller than a page are split
+// where they can be.
+func (p *pdfPaginator) put(block *pdfBlock) {
+	for block.height > p.available()+0.01 {
+		if !p.fresh && (block.split == nil || block.height <= p.pageHeight()) {
+			p.breakPage(block)
+			continue
+		}
+		if block.split == nil {
+			break
+		}
+		first, rest := block.split(p.available())
+		if first == nil {
+			if p.fresh {
+				break
+			}
+			p.breakPage(block)
+			continue
+		}
+		p.add(first)
+		p.breakPage(block)
+		block = rest
+	}
+	p.add(block)
+}
+
+// breakPage starts a new page for a block, repeating the header rows of its table.
+func (p *pdfPaginator) breakPage(block *pdfBlock) {
+	p.newPage(p.section, false)
+	for _, header := range block.header {
+		p.add(header)
+	}
+	p.fresh = true
+}
+
+func (p *pdfPaginator) add(block *pdfBlock) {
+	p.page.blocks = append(p.page.blocks, block)
+	p.page.tops = append(p.page.tops, p.y)
+	p.y -= block.height
+	p.fresh = false
+}
+
+// write draws the pages with their headers and footers, whose page number fields are known
+// now, and writes the PDF.
+func (r *docxPDF) write(pages []*pdfLayoutPage, w io.Writer) error {
+	defer func() { r.page, r.pages = 0, 0 }()
+	for i, layout := range pages {
+		section := layout.section
+		page := r.doc.addPage(section.width, section.height)
+		r.page, r.pages = i+1, len(pages)
+
+		drawBlocks(page, r.headerBlocks(section.headerPart(section.headers, layout.first), section), section.height-section.headerDistance)
+		footer := r.headerBlocks(section.headerPart(section.footers, layout.first), section)
+		drawBlocks(page, footer, section.footerDistance+blocksHeight(footer))
+		for j, block := range layout.blocks {
+			if block.draw != nil {
+				block.draw(page, layout.tops[j])
+			}
+		}
+	}
+	return r.doc.write(w)
+}
+
+func drawBlocks(page *pdfPage, blocks []*pdfBlock, top float64) {
+	for _, block := range blocks {
+		if block.draw != nil {
+			block.draw(page, top)
+		}
+		top -= block.height
+	}
+}
@@ -0,0 +1,336 @@
+package internal
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// pdfTableRow is a row of a table laid out for PDF output.
+type pdfTableRow struct {
+	cells     []*pdfCell
+	header    bool
+	cantSplit bool
+	height    float64
+	rule      string // how the height applies: exact or at least
+}
+
+// pdfCell is a table cell; margins and borders are top, left, bottom, right.
+type pdfCell struct {
+	col     int
+	span    int
+	x       float64
+	width   float64
+	margins [4]float64
+	borders [4]pdfBorder
+	fill    *[3]float64
+	vAlign  string
+	merged  bool // continues the vertically merged cell above
+	content []string
+}
+
+var tableBorderSides = map[string]int{"top": 0, "left": 1, "start": 1, "bottom": 2, "right": 3, "end": 3}
+
+// table lays out a table as a block per row. Header rows are repeated at the top of each page
+// the table continues on, and rows taller than a page are split between their lines.
+func (r *docxPDF) table(table string, x, width float64) []*pdfBlock {
+	tblPr := ""
+	var grid []float64
+	var rows []string
+	for _, child := range xmlChildren(elementContent(table)) {
+		switch elementName(child) {
+		case "w:tblPr":
+			tblPr = child
+		case "w:tblGrid":
+			for _, col := range xmlChildren(elementContent(child)) {
+				value, _ := twips(col, "w:w")
+				grid = append(grid, value)
+			}
+		case "w:tr":
+			rows = append(rows, child)
+		}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	// Table properties: from the table style, then set directly
+	borders := make(map[string]pdfBorder)
+	margins := [4]float64{0, 5.4, 0, 5.4}
+	align, indent := "", 0.0
+	apply := func(tblPr string) {
+		if tblPr == "" {
+			return
+		}
+		_, children := splitProps(tblPr, "w:tblPr")
+		for _, child := range children {
+			switch elementName(child) {
+			case "w:tblBorders":
+				for _, border := range xmlChildren(elementContent(child)) {
+					side := strings.TrimPrefix(elementName(border), "w:")
+					switch side {
+					case "start":
+						side = "left"
+					case "end":
+						side = "right"
+					}
+					borders[side] = parseBorder(border)
+				}
+			case "w:tblCellMar":
+				cellMargins(child, &margins)
+			case "w:jc":
+				align = attrValue(child, "w:val")
+			case "w:tblInd":
+				indent, _ = twips(child, "w:w")
+			}
+		}
+	}
+	if start := indexElement(tblPr, "w:tblStyle"); start >= 0 {
+		for _, style := range r.styleChain(attrValue(leadingElement(tblPr[start:], "w:tblStyle"), "w:val")) {
+			apply(style.tblPr)
+		}
+	}
+	apply(tblPr)
+
+	// Columns are scaled down to fit the width
+	total := 0.0
+	for _, col := range grid {
+		total += col
+	}
+	if total <= 0 {
+		columns := 1
+		for _, row := range rows {
+			columns = max(columns, strings.Count(row, "<w:tc>")+strings.Count(row, "<w:tc "))
+		}
+		grid = make([]float64, columns)
+		for i := range grid {
+			grid[i] = width / float64(columns)
+		}
+		total = width
+	}
+	if total > width {
+		for i := range grid {
+			grid[i] *= width / total
+		}
+		total = width
+	}
+	switch align {
+	case "center":
+		x += (width - total) / 2
+	case "right", "end":
+		x += width - total
+	default:
+		x += indent
+	}
+	offsets := make([]float64, len(grid)+1)
+	for i, col := range grid {
+		offsets[i+1] = offsets[i] + col
+	}
+
+	parsed := make([]*pdfTableRow, len(rows))
+	for i, row := range rows {
+		parsed[i] = r.tableRow(row, x, offsets, margins)
+	}
+
+	// Cell borders: set on the cell, or else the table's outer or inside borders. Vertically
+	// merged cells have no borders between them.
+	for i, row := range parsed {
+		for _, cell := range row.cells {
+			outer := [4]bool{i == 0, cell.col == 0, i == len(parsed)-1, cell.col+cell.span >= len(grid)}
+			inside := [4]string{"insideH", "insideV", "insideH", "insideV"}
+			names := [4]string{"top", "left", "bottom", "right"}
+			for side := range cell.borders {
+				if cell.borders[side].set {
+					continue
+				}
+				if outer[side] {
+					cell.borders[side] = borders[names[side]]
+				} else {
+					cell.borders[side] = borders[inside[side]]
+				}
+			}
+			if cell.merged {
+				cell.borders[0] = pdfBorder{}
+			}
+			if i+1 < len(parsed) {
+				for _, below := range parsed[i+1].cells {
+					if below.col == cell.col && below.merged {
+						cell.borders[2] = pdfBorder{}
+					}
+				}
+			}
+		}
+	}
+
+	var blocks, header []*pdfBlock
+	for i, row := range parsed {
+		contents := make([][]*pdfBlock, len(row.cells))
+		for j, cell := range row.cells {
+			if cell.merged {
+				continue
+			}
+			for _, block := range r.blocks(cell.content, cell.x+cell.margins[1], cell.width-cell.margins[1]-cell.margins[3]) {
+				if !block.pageBreak {
+					contents[j] = append(contents[j], block)
+				}
+			}
+		}
+		block := tableRowBlock(row, contents)
+		if row.header && len(header) == i {
+			block.keep = true
+			header = append(header, block)
+		} else if len(header) < len(parsed) {
+			block.header = header
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+// tableRow reads a row's cells and places them on the grid.
+func (r *docxPDF) tableRow(row string, x float64, offsets []float64, margins [4]float64) *pdfTableRow {
+	result := &pdfTableRow{}
+	col := 0
+	for _, child := range xmlChildren(elementContent(row)) {
+		switch elementName(child) {
+		case "w:trPr":
+			for _, prop := range xmlChildren(elementContent(child)) {
+				switch elementName(prop) {
+				case "w:tblHeader":
+					result.header = hasToggle(prop, "w:tblHeader")
+				case "w:cantSplit":
+					result.cantSplit = hasToggle(prop, "w:cantSplit")
+				case "w:trHeight":
+					result.height, _ = twips(prop, "w:val")
+					result.rule = attrValue(prop, "w:hRule")
+				case "w:gridBefore":
+					n, _ := strconv.Atoi(attrValue(prop, "w:val"))
+					col += max(n, 0)
+				}
+			}
+		case "w:tc":
+			cell := &pdfCell{col: col, span: 1, margins: margins}
+			for _, element := range xmlChildren(elementContent(child)) {
+				if elementName(element) != "w:tcPr" {
+					cell.content = append(cell.content, element)
+					continue
+				}
+				for _, prop := range xmlChildren(elementContent(element)) {
+					switch elementName(prop) {
+					case "w:gridSpan":
+						if n, err := strconv.Atoi(attrValue(prop, "w:val")); err == nil && n > 1 {
+							cell.span = n
+						}
+					case "w:vMerge":
+						cell.merged = attrValue(prop, "w:val") != "restart"
+					case "w:tcBorders":
+						for _, border := range xmlChildren(elementContent(prop)) {
+							if side, ok := tableBorderSides[strings.TrimPrefix(elementName(border), "w:")]; ok {
+								cell.borders[side] = parseBorder(border)
+							}
+						}
+					case "w:shd":
+						if fill, ok := hexColor(attrValue(prop, "w:fill")); ok {
+							cell.fill = &fill
+						}
+					case "w:vAlign":
+						cell.vAlign = attrValue(prop, "w:val")
+					case "w:tcMar":
+						cellMargins(prop, &cell.margins)
+					}
+				}
+			}
+			first, last := min(col, len(offsets)-1), min(col+cell.span, len(offsets)-1)
+			cell.x, cell.width = x+offsets[first], offsets[last]-offsets[first]
+			col += cell.span
+			result.cells = append(result.cells, cell)
+		}
+	}
+	return result
+}
+
+// cellMargins reads cell margins such as <w:tblCellMar> into margins.
+func cellMargins(element string, margins *[4]float64) {
+	for _, side := range xmlChildren(elementContent(element)) {
+		if index, ok := tableBorderSides[strings.TrimPrefix(elementName(side), "w:")]; ok {
+			if value, ok := twips(side, "w:w"); ok {
+				margins[index] = value
+			}
+		}
+	}
+}
+
+// tableRowBlock returns the block drawing a row with the laid out contents of its cells.
+func tableRowBlock(row *pdfTableRow, contents [][]*pdfBlock) *pdfBlock {
+	height := 0.0
+	for i, cell := range row.cells {
+		height = math.Max(height, cell.margins[0]+blocksHeight(contents[i])+cell.margins[2])
+	}
+	switch row.rule {
+	case "exact":
+		height = row.height
+	default:
+		height = math.Max(height, row.height)
+	}
+
+	block := &pdfBlock{height: height}
+	block.draw = func(p *pdfPage, top float64) {
+		for _, cell := range row.cells {
+			if cell.fill != nil {
+				p.fill(cell.x, top-height, cell.width, height, *cell.fill)
+			}
+		}
+		for i, cell := range row.cells {
+			y := top - cell.margins[0]
+			free := height - cell.margins[0] - cell.margins[2] - blocksHeight(contents[i])
+			switch cell.vAlign {
+			case "center":
+				y -= free / 2
+			case "bottom":
+				y -= free
+			}
+			for _, content := range contents[i] {
+				if content.draw != nil {
+					content.draw(p, y)
+				}
+				y -= content.height
+			}
+		}
+		for _, cell := range row.cells {
+			right, bottom := cell.x+cell.width, top-height
+			edges := [4][4]float64{
+				{cell.x, top, right, top}, {cell.x, top, cell.x, bottom},
+				{cell.x, bottom, right, bottom}, {right, top, right, bottom},
+			}
+			for side, border := range cell.borders {
+				if border.width > 0 {
+					edge := edges[side]
+					p.line(edge[0], edge[1], edge[2], edge[3], border.width, border.color)
+				}
+			}
+		}
+	}
+
+	if !row.cantSplit && row.rule != "exact" {
+		block.split = func(available float64) (*pdfBlock, *pdfBlock) {
+			first, rest := make([][]*pdfBlock, len(contents)), make([][]*pdfBlock, len(contents))
+			fits := false
+			for i, cell := range row.cells {
+				used, n := cell.margins[0]+cell.margins[2], 0
+				for n < len(contents[i]) && used+contents[i][n].height <= available {
+					used += contents[i][n].height
+					n++
+				}
+				first[i], rest[i] = contents[i][:n], contents[i][n:]
+				fits = fits || n > 0
+			}
+			if !fits {
+				return nil, nil
+			}
+			part := *row
+			part.height, part.rule = 0, ""
+			return tableRowBlock(&part, first), tableRowBlock(&part, rest)
+		}
+	}
+	return block
+}
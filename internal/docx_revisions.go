@@ -0,0 +1,348 @@
+package internal
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// Tracked change markup: inserted, deleted and moved content, property changes and the
+	// markers of changed paragraph marks, table rows and cells
+	wordRevisionPattern = regexp.MustCompile(`<w:(?:ins|del|moveFrom|moveTo|move(?:From|To)Range(?:Start|End)|` +
+		`customXml(?:Ins|Del|MoveFrom|MoveTo)Range(?:Start|End)|cellIns|cellDel|cellMerge|numberingChange|` +
+		`(?:rPr|pPr|sectPr|tblPr|tblPrEx|trPr|tcPr|tblGrid)Change)\b`)
+
+	// Restores deleted text when a deletion is rejected
+	wordDeletedTextReplacer = strings.NewReplacer("<w:delText", "<w:t", "</w:delText>", "</w:t>",
+		"<w:delInstrText", "<w:instrText", "</w:delInstrText>", "</w:instrText>")
+
+	// Property elements whose changes are tracked in a child named after them, e.g. w:rPrChange
+	wordRevisionProps = map[string]bool{
+		"w:rPr": true, "w:pPr": true, "w:sectPr": true, "w:tblPr": true, "w:tblPrEx": true,
+		"w:trPr": true, "w:tcPr": true, "w:tblGrid": true,
+	}
+
+	wordTcPrOrder = []string{"w:cnfStyle", "w:tcW", "w:gridSpan", "w:hMerge", "w:vMerge", "w:tcBorders", "w:shd",
+		"w:noWrap", "w:tcMar", "w:textDirection", "w:tcFitText", "w:vAlign", "w:hideMark", "w:headers",
+		"w:cellIns", "w:cellDel", "w:cellMerge", "w:tcPrChange"}
+)
+
+// ResolveDocxRevisions accepts or rejects the tracked changes in the text and style parts of a
+// DOCX package, leaving the document as it reads with the changes applied or undone. It
+// returns the number of changes resolved.
+func ResolveDocxRevisions(pkg *Package, accept bool) (int, error) {
+	total := 0
+	for _, name := range pkg.Names() {
+		switch {
+		case IsWordStoryPart(name), name == "word/comments.xml", name == "word/styles.xml", name == "word/numbering.xml":
+		default:
+			continue
+		}
+
+		content, err := pkg.Read(name)
+		if err != nil {
+			return total, err
+		}
+		resolved, count := resolveRevisions(string(content), accept)
+		if count > 0 {
+			pkg.Write(name, []byte(resolved))
+			total += count
+		}
+	}
+	return total, nil
+}
+
+// resolveRevisions accepts or rejects the tracked changes in a part and returns the number resolved.
+func resolveRevisions(xmlContent string, accept bool) (string, int) {
+	if !wordRevisionPattern.MatchString(xmlContent) {
+		return xmlContent, 0
+	}
+	root := rootElementStart(xmlContent)
+	if root < 0 {
+		return xmlContent, 0
+	}
+	r := &revisionResolver{accept: accept}
+	resolved, _ := r.element(strings.TrimRight(xmlContent[root:], " \t\r\n"))
+	return xmlContent[:root] + resolved, r.count
+}
+
+// revisionResolver accepts or rejects tracked changes while rewriting an element tree.
+type revisionResolver struct {
+	accept bool
+	count  int
+}
+
+// content resolves the changes in a sequence of sibling elements. Paragraphs whose mark is
+// removed are joined with the paragraph that follows.
+func (r *revisionResolver) content(content string) string {
+	type resolved struct {
+		xml   string
+		merge bool
+	}
+	var elements []resolved
+	for _, child := range xmlChildren(content) {
+		if xml, merge := r.element(child); xml != "" {
+			elements = append(elements, resolved{xml, merge})
+		}
+	}
+
+	var b strings.Builder
+	carry := ""
+	for i, element := range elements {
+		xml := element.xml
+		if carry != "" {
+			xml = prependParagraphContent(xml, carry)
+			carry = ""
+		}
+		if element.merge && i+1 < len(elements) && elementName(elements[i+1].xml) == "w:p" {
+			carry = paragraphContent(xml)
+			continue
+		}
+		b.WriteString(xml)
+	}
+	return b.String()
+}
+
+// element resolves the changes in an element. It returns "" when the element goes away, and
+// whether it is a paragraph whose mark does.
+func (r *revisionResolver) element(element string) (string, bool) {
+	name := elementName(element)
+	switch name {
+	case "w:ins", "w:moveTo":
+		r.count++
+		if r.accept {
+			return r.content(elementContent(element)), false
+		}
+		return "", false
+	case "w:del", "w:moveFrom":
+		r.count++
+		if r.accept {
+			return "", false
+		}
+		return r.content(wordDeletedTextReplacer.Replace(elementContent(element))), false
+	case "w:moveFromRangeStart", "w:moveFromRangeEnd", "w:moveToRangeStart", "w:moveToRangeEnd",
+		"w:customXmlInsRangeStart", "w:customXmlInsRangeEnd", "w:customXmlDelRangeStart", "w:customXmlDelRangeEnd",
+		"w:customXmlMoveFromRangeStart", "w:customXmlMoveFromRangeEnd", "w:customXmlMoveToRangeStart", "w:customXmlMoveToRangeEnd":
+		return "", false
+	}
+
+	if !wordRevisionPattern.MatchString(element) {
+		return element, false
+	}
+	switch {
+	case wordRevisionProps[name]:
+		return r.props(element), false
+	case name == "w:p":
+		return r.paragraph(element)
+	case name == "w:tr":
+		return r.row(element), false
+	case name == "w:tc":
+		return r.cell(element), false
+	}
+
+	start := element[:strings.IndexByte(element, '>')+1]
+	resolved := start + r.content(elementContent(element)) + "</" + name + ">"
+	if name == "w:tbl" && indexElement(resolved, "w:tr") < 0 {
+		// Every row was removed
+		return "", false
+	}
+	return resolved, false
+}
+
+// paragraph resolves the changes in a paragraph. A paragraph mark that is inserted and
+// rejected, or deleted and accepted, joins the paragraph with the next one.
+func (r *revisionResolver) paragraph(paragraph string) (string, bool) {
+	start := paragraph[:strings.IndexByte(paragraph, '>')+1]
+	pPr := leadingElement(paragraph[len(start):], "w:pPr")
+	if elementName(pPr) != "w:pPr" {
+		pPr = ""
+	}
+	content := paragraph[len(start)+len(pPr) : len(paragraph)-len("</w:p>")]
+
+	removed := false
+	if pPr != "" {
+		pPrStart, children := splitProps(pPr, "w:pPr")
+		section := false
+		for i, child := range children {
+			switch elementName(child) {
+			case "w:rPr":
+				rPrStart, props := splitProps(child, "w:rPr")
+				var kept []string
+				for _, prop := range props {
+					switch elementName(prop) {
+					case "w:ins", "w:moveTo":
+						r.count++
+						removed = removed || !r.accept
+					case "w:del", "w:moveFrom":
+						r.count++
+						removed = removed || r.accept
+					default:
+						kept = append(kept, prop)
+					}
+				}
+				children[i] = rPrStart + strings.Join(kept, "") + "</w:rPr>"
+			case "w:sectPr":
+				section = true
+			}
+		}
+		// A paragraph ending a section keeps its mark
+		removed = removed && !section
+		pPr = r.props(pPrStart + strings.Join(children, "") + "</w:pPr>")
+	}
+	return start + pPr + r.content(content) + "</w:p>", removed
+}
+
+// row resolves the changes in a table row, removing rows that are inserted and rejected, or
+// deleted and accepted.
+func (r *revisionResolver) row(row string) string {
+	start := row[:strings.IndexByte(row, '>')+1]
+	var b strings.Builder
+	b.WriteString(start)
+	for _, child := range xmlChildren(elementContent(row)) {
+		if elementName(child) == "w:trPr" {
+			_, props := splitProps(child, "w:trPr")
+			for _, prop := range props {
+				switch elementName(prop) {
+				case "w:ins":
+					r.count++
+					if !r.accept {
+						return ""
+					}
+				case "w:del":
+					r.count++
+					if r.accept {
+						return ""
+					}
+				}
+			}
+			child = removeChild(removeChild(child, "w:trPr", "w:ins"), "w:trPr", "w:del")
+		}
+		resolved, _ := r.element(child)
+		b.WriteString(resolved)
+	}
+	b.WriteString("</w:tr>")
+	return b.String()
+}
+
+// cell resolves the changes in a table cell, removing cells that are inserted and rejected, or
+// deleted and accepted. Accepted merges become vertical merges.
+func (r *revisionResolver) cell(cell string) string {
+	start := cell[:strings.IndexByte(cell, '>')+1]
+	content := elementContent(cell)
+	tcPr := leadingElement(content, "w:tcPr")
+	if elementName(tcPr) != "w:tcPr" {
+		tcPr = ""
+	}
+	content = content[len(tcPr):]
+
+	if tcPr != "" {
+		_, props := splitProps(tcPr, "w:tcPr")
+		for _, prop := range props {
+			switch elementName(prop) {
+			case "w:cellIns":
+				r.count++
+				if !r.accept {
+					return ""
+				}
+			case "w:cellDel":
+				r.count++
+				if r.accept {
+					return ""
+				}
+			case "w:cellMerge":
+				r.count++
+				if r.accept {
+					vMerge := `<w:vMerge/>`
+					if attrValue(prop, "w:vMerge") == "rest" {
+						vMerge = `<w:vMerge w:val="restart"/>`
+					}
+					tcPr = setChild(tcPr, "w:tcPr", vMerge, wordTcPrOrder)
+				}
+			}
+		}
+		for _, marker := range []string{"w:cellIns", "w:cellDel", "w:cellMerge"} {
+			tcPr = removeChild(tcPr, "w:tcPr", marker)
+		}
+		tcPr = r.props(tcPr)
+	}
+	return start + tcPr + r.content(content) + "</w:tc>"
+}
+
+// props resolves the changes recorded in property elements such as w:rPr: accepting keeps the
+// current properties, rejecting restores the ones recorded in the change.
+func (r *revisionResolver) props(props string) string {
+	if !wordRevisionPattern.MatchString(props) {
+		return props
+	}
+	name := elementName(props)
+	start, children := splitProps(props, name)
+
+	change := ""
+	kept := make([]string, 0, len(children))
+	for _, child := range children {
+		switch childName := elementName(child); {
+		case childName == name+"Change":
+			change = child
+		case childName == "w:numberingChange":
+			r.count++
+		case wordRevisionProps[childName]:
+			kept = append(kept, r.props(child))
+		default:
+			kept = append(kept, child)
+		}
+	}
+	if change != "" {
+		r.count++
+		if !r.accept {
+			kept = rejectedProps(name, change, kept)
+		}
+	}
+	return start + strings.Join(kept, "") + "</" + name + ">"
+}
+
+// rejectedProps returns the properties recorded in a property change, along with the children
+// of the current properties the change doesn't record: header and footer references of a
+// section, and the mark and section properties of a paragraph.
+func rejectedProps(name, change string, current []string) []string {
+	var old []string
+	for _, child := range xmlChildren(elementContent(change)) {
+		if elementName(child) == name {
+			_, old = splitProps(child, name)
+		}
+	}
+
+	var leading, trailing []string
+	for _, child := range current {
+		switch elementName(child) {
+		case "w:headerReference", "w:footerReference":
+			leading = append(leading, child)
+		case "w:rPr", "w:sectPr":
+			if name == "w:pPr" {
+				trailing = append(trailing, child)
+			}
+		}
+	}
+	return append(append(leading, old...), trailing...)
+}
+
+// paragraphContent returns the content of a paragraph after its properties.
+func paragraphContent(paragraph string) string {
+	if tagEnd := strings.IndexByte(paragraph, '>'); paragraph[tagEnd-1] == '/' {
+		return ""
+	}
+	prefix := wordPPrPattern.FindString(paragraph)
+	return paragraph[len(prefix) : len(paragraph)-len("</w:p>")]
+}
+
+// prependParagraphContent inserts content at the start of a paragraph, after its properties.
+func prependParagraphContent(paragraph, content string) string {
+	if elementName(paragraph) != "w:p" {
+		return paragraph
+	}
+	tagEnd := strings.IndexByte(paragraph, '>')
+	if paragraph[tagEnd-1] == '/' {
+		return strings.TrimSuffix(paragraph[:tagEnd-1], " ") + ">" + content + "</w:p>"
+	}
+	prefix := wordPPrPattern.FindString(paragraph)
+	return prefix + content + paragraph[len(prefix):]
+}
@@ -0,0 +1,538 @@
+package internal
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	wordNamespace        = "http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+	numberingPartName    = "word/numbering.xml"
+	numberingContentType = "application/vnd.openxmlformats-officedocument.wordprocessingml.numbering+xml"
+
+	// Hyperlinks get the look of Word's Hyperlink style without depending on it
+	wordLinkColor = "0563C1"
+)
+
+var (
+	wordRun        = runMarkup{run: "w:r", props: "w:rPr", text: "w:t"}
+	wordPPrPattern = regexp.MustCompile(`^<w:p\b[^>]*>(<w:pPr\b.*?</w:pPr>|<w:pPr\b[^>]*/>)?`)
+
+	// Schema order of the run and paragraph property children
+	wordRPrOrder = []string{"w:rStyle", "w:rFonts", "w:b", "w:bCs", "w:i", "w:iCs", "w:caps", "w:smallCaps",
+		"w:strike", "w:dstrike", "w:outline", "w:shadow", "w:emboss", "w:imprint", "w:noProof", "w:snapToGrid",
+		"w:vanish", "w:webHidden", "w:color", "w:spacing", "w:w", "w:kern", "w:position", "w:sz", "w:szCs",
+		"w:highlight", "w:u", "w:effect", "w:bdr", "w:shd", "w:fitText", "w:vertAlign", "w:rtl", "w:cs", "w:em",
+		"w:lang", "w:eastAsianLayout", "w:specVanish", "w:oMath", "w:rPrChange"}
+	wordPPrOrder = []string{"w:pStyle", "w:keepNext", "w:keepLines", "w:pageBreakBefore", "w:framePr",
+		"w:widowControl", "w:numPr", "w:suppressLineNumbers", "w:pBdr", "w:shd", "w:tabs", "w:suppressAutoHyphens",
+		"w:kinsoku", "w:wordWrap", "w:overflowPunct", "w:topLinePunct", "w:autoSpaceDE", "w:autoSpaceDN", "w:bidi",
+		"w:adjustRightInd", "w:snapToGrid", "w:spacing", "w:ind", "w:contextualSpacing", "w:mirrorIndents",
+		"w:suppressOverlap", "w:jc", "w:textDirection", "w:textAlignment", "w:textboxTightWrap", "w:outlineLvl",
+		"w:divId", "w:cnfStyle", "w:rPr", "w:sectPr", "w:pPrChange"}
+
+	numberingIDPattern  = regexp.MustCompile(`<w:(abstractNum|num)\b[^>]*?\bw:(?:abstractNumId|numId)="(\d+)"`)
+	numberingNumPattern = regexp.MustCompile(`<w:num[ >]`)
+)
+
+// docxRich renders rich text values into a document, adding the hyperlink relationships and
+// list definitions they need to the package.
+type docxRich struct {
+	pkg       *Package
+	links     map[string]string
+	numbering *docxNumbering
+	values    int
+	err       error
+
+	textWidth   int               // width between the page margins, in twips
+	tableStyles map[string]string // table style IDs by lowercase name and ID
+	tables      bool
+
+	tracker *docxTracker // set when replacements are written as tracked changes
+}
+
+func newDocxRich(pkg *Package) *docxRich {
+	return &docxRich{pkg: pkg, links: make(map[string]string), numbering: &docxNumbering{pkg: pkg}}
+}
+
+// render swaps the rich value markers in a paragraph for formatted runs. A value that is
+// alone in its paragraph replaces the paragraph, so its paragraphs and lists keep their
+// structure; a value within other text is written inline with line breaks.
+func (d *docxRich) render(paragraph string, values []richValue) string {
+	for i, value := range values {
+		d.values++
+		marker := richMarker(i)
+		pos := strings.Index(paragraph, marker)
+		if pos < 0 {
+			continue
+		}
+		run, ok := findMarkerRun(paragraph, pos, pos+len(marker), wordRun)
+		if !ok {
+			paragraph = paragraph[:pos] + EscapeXMLText(value.plainText()) + paragraph[pos+len(marker):]
+			continue
+		}
+
+		if len(values) == 1 && strings.TrimSpace(extractTextFromParagraph(paragraph)) == marker {
+			if value.table != nil {
+				return d.table(paragraph, run, *value.table)
+			}
+			return d.paragraphs(paragraph, run, value.paragraphs)
+		}
+		runs := d.runs(flattenRichText(value), run.props)
+		paragraph = paragraph[:run.start] + splitMarkerRun(run, runs, wordRun) + paragraph[run.end:]
+	}
+	return paragraph
+}
+
+// paragraphs writes value as paragraphs based on the paragraph holding its marker.
+func (d *docxRich) paragraphs(paragraph string, run markerRun, value []richParagraph) string {
+	match := wordPPrPattern.FindStringSubmatch(paragraph)
+	startTag := paragraph[:strings.IndexByte(paragraph, '>')+1]
+	pPr := ""
+	if match != nil {
+		pPr = match[1]
+	}
+	bookmarks := strings.Join(wordBookmarkPattern.FindAllString(paragraph, -1), "")
+
+	if len(value) == 0 {
+		value = []richParagraph{{}}
+	}
+
+	var b strings.Builder
+	for i, item := range value {
+		props := pPr
+		if i < len(value)-1 {
+			props = removeChild(props, "w:pPr", "w:sectPr")
+		}
+		if item.list != listNone {
+			numID, err := d.numbering.numID(item.list, fmt.Sprintf("%d/%d", d.values, item.listID))
+			if err != nil {
+				d.fail(err)
+			} else {
+				numPr := `<w:numPr><w:ilvl w:val="` + strconv.Itoa(item.level) + `"/><w:numId w:val="` + strconv.Itoa(numID) + `"/></w:numPr>`
+				props = setChild(removeChild(props, "w:pPr", "w:ind"), "w:pPr", numPr, wordPPrOrder)
+			}
+		}
+		if props == "<w:pPr></w:pPr>" {
+			props = ""
+		}
+
+		if i == 0 {
+			b.WriteString(startTag + props + bookmarks)
+		} else {
+			b.WriteString(wordParaIDAttrPattern.ReplaceAllString(startTag, "") + props)
+		}
+		b.WriteString(d.runs(item.runs, run.props))
+		b.WriteString("</w:p>")
+	}
+	return b.String()
+}
+
+// runs writes formatted runs based on the run properties of the placeholder.
+func (d *docxRich) runs(runs []richRun, rPr string) string {
+	var b strings.Builder
+	for _, run := range runs {
+		if run.lineBreak {
+			b.WriteString("<w:r>" + rPr + "<w:br/></w:r>")
+			continue
+		}
+		if run.tab {
+			b.WriteString("<w:r>" + rPr + "<w:tab/></w:r>")
+			continue
+		}
+		if run.text == "" {
+			continue
+		}
+
+		props := rPr
+		if run.bold {
+			props = setChild(props, "w:rPr", "<w:b/>", wordRPrOrder)
+			props = setChild(props, "w:rPr", "<w:bCs/>", wordRPrOrder)
+		}
+		if run.italic {
+			props = setChild(props, "w:rPr", "<w:i/>", wordRPrOrder)
+			props = setChild(props, "w:rPr", "<w:iCs/>", wordRPrOrder)
+		}
+
+		id := ""
+		if run.link != "" {
+			id = d.linkID(run.link)
+		}
+		if run.underline || id != "" {
+			props = setChild(props, "w:rPr", `<w:u w:val="single"/>`, wordRPrOrder)
+		}
+		if id != "" {
+			props = setChild(props, "w:rPr", `<w:color w:val="`+wordLinkColor+`"/>`, wordRPrOrder)
+		}
+
+		content := EscapeXMLText(run.text)
+		markup := "<w:r>" + props + textStartTag("w:t", content) + content + "</w:t></w:r>"
+		if id != "" {
+			markup = `<w:hyperlink r:id="` + id + `" w:history="1">` + markup + "</w:hyperlink>"
+		}
+		b.WriteString(markup)
+	}
+	return b.String()
+}
+
+// linkID returns the relationship ID of a hyperlink target, adding the relationship once.
+// If it can't be added the text is written without the link.
+func (d *docxRich) linkID(target string) string {
+	if id, ok := d.links[target]; ok {
+		return id
+	}
+	id, err := d.pkg.AddRelationship("word/document.xml", RelTypeHyperlink, target, true)
+	if err != nil {
+		d.fail(err)
+		return ""
+	}
+	d.links[target] = id
+	return id
+}
+
+func (d *docxRich) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+// finish declares the relationship namespace when links were added and writes the list
+// definitions. It returns the first error met while rendering.
+func (d *docxRich) finish(documentXML string) (string, error) {
+	if len(d.links) > 0 {
+		documentXML = EnsureNamespace(documentXML, "r", officeRelNamespace)
+	}
+	if d.tables {
+		// A table cell must end with a paragraph
+		documentXML = strings.ReplaceAll(documentXML, "</w:tbl></w:tc>", "</w:tbl><w:p/></w:tc>")
+	}
+	if err := d.numbering.flush(); err != nil {
+		d.fail(err)
+	}
+	return documentXML, d.err
+}
+
+// flattenRichText turns paragraphs and list items, or table rows, into a single line-broken
+// run sequence for values written inline.
+func flattenRichText(value richValue) []richRun {
+	var runs []richRun
+	if value.table != nil {
+		for i, row := range value.table.allRows(value.table.columnCount()) {
+			if i > 0 {
+				runs = append(runs, richRun{lineBreak: true})
+			}
+			for j, cell := range row {
+				if j > 0 {
+					runs = append(runs, richRun{tab: true})
+				}
+				runs = append(runs, cellRuns(cell, false)...)
+			}
+		}
+		return runs
+	}
+
+	numbers := make(map[[2]int]int)
+	for i, paragraph := range value.paragraphs {
+		if i > 0 {
+			runs = append(runs, richRun{lineBreak: true})
+		}
+		switch paragraph.list {
+		case listBullet:
+			runs = append(runs, richRun{text: strings.Repeat("  ", paragraph.level) + "• "})
+		case listNumber:
+			key := [2]int{paragraph.listID, paragraph.level}
+			numbers[key]++
+			runs = append(runs, richRun{text: strings.Repeat("  ", paragraph.level) + strconv.Itoa(numbers[key]) + ". "})
+		}
+		runs = append(runs, paragraph.runs...)
+	}
+	return runs
+}
+
+// docxNumbering adds the list definitions used by rich values to word/numbering.xml,
+// creating the part when the template has no lists.
+type docxNumbering struct {
+	pkg                   *Package
+	loaded                bool
+	content               string
+	abstracts             map[int]int
+	lists                 map[string]int
+	added                 []string // new abstractNum elements
+	nums                  []string // new num elements
+	nextAbstract, nextNum int
+}
+
+// numID returns the numbering instance of a list. All bulleted lists share one instance;
+// every numbered list gets its own so it starts at 1.
+func (n *docxNumbering) numID(kind int, list string) (int, error) {
+	if err := n.load(); err != nil {
+		return 0, err
+	}
+	if kind == listBullet {
+		list = "bullet"
+	}
+	if id, ok := n.lists[list]; ok {
+		return id, nil
+	}
+
+	abstractID, ok := n.abstracts[kind]
+	if !ok {
+		abstractID = n.nextAbstract
+		n.nextAbstract++
+		n.abstracts[kind] = abstractID
+		n.added = append(n.added, abstractNumbering(abstractID, kind))
+	}
+
+	id := n.nextNum
+	n.nextNum++
+	num := `<w:num w:numId="` + strconv.Itoa(id) + `"><w:abstractNumId w:val="` + strconv.Itoa(abstractID) + `"/>`
+	if kind == listNumber {
+		num += `<w:lvlOverride w:ilvl="0"><w:startOverride w:val="1"/></w:lvlOverride>`
+	}
+	n.nums = append(n.nums, num+`</w:num>`)
+	n.lists[list] = id
+	return id, nil
+}
+
+func (n *docxNumbering) load() error {
+	if n.loaded {
+		return nil
+	}
+	n.loaded = true
+	n.abstracts = make(map[int]int)
+	n.lists = make(map[string]int)
+	n.nextNum = 1
+
+	if !n.pkg.Has(numberingPartName) {
+		n.content = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+			`<w:numbering xmlns:w="` + wordNamespace + `"></w:numbering>`
+		return nil
+	}
+
+	content, err := n.pkg.Read(numberingPartName)
+	if err != nil {
+		return err
+	}
+	n.content = string(content)
+	for _, match := range numberingIDPattern.FindAllStringSubmatch(n.content, -1) {
+		id, _ := strconv.Atoi(match[2])
+		if match[1] == "abstractNum" {
+			n.nextAbstract = max(n.nextAbstract, id+1)
+		} else {
+			n.nextNum = max(n.nextNum, id+1)
+		}
+	}
+	return nil
+}
+
+// flush writes the new definitions. Abstract definitions must precede all num elements.
+func (n *docxNumbering) flush() error {
+	if len(n.nums) == 0 {
+		return nil
+	}
+
+	content := n.content
+	end := strings.Index(content, "<w:numIdMacAtCleanup")
+	if end < 0 {
+		end = strings.LastIndex(content, "</w:numbering>")
+	}
+	if end < 0 {
+		return fmt.Errorf("%s has no numbering element", numberingPartName)
+	}
+	content = content[:end] + strings.Join(n.nums, "") + content[end:]
+
+	abstractsAt := end
+	if loc := numberingNumPattern.FindStringIndex(content); loc != nil {
+		abstractsAt = loc[0]
+	}
+	content = content[:abstractsAt] + strings.Join(n.added, "") + content[abstractsAt:]
+
+	if !n.pkg.Has(numberingPartName) {
+		if _, err := n.pkg.AddRelationship("word/document.xml", RelTypeNumbering, "numbering.xml", false); err != nil {
+			return err
+		}
+		if err := n.pkg.AddContentTypeOverride(numberingPartName, numberingContentType); err != nil {
+			return err
+		}
+	}
+	n.pkg.Write(numberingPartName, []byte(content))
+	n.nums, n.added = nil, nil
+	return nil
+}
+
+// abstractNumbering defines nine list levels: bullets cycle through •, ◦ and ▪, numbers
+// through 1., a. and i.
+func abstractNumbering(id, kind int) string {
+	var b strings.Builder
+	b.WriteString(`<w:abstractNum w:abstractNumId="` + strconv.Itoa(id) + `"><w:multiLevelType w:val="hybridMultilevel"/>`)
+	for level := 0; level < 9; level++ {
+		format, text := "bullet", []string{"•", "◦", "▪"}[level%3]
+		if kind == listNumber {
+			format = []string{"decimal", "lowerLetter", "lowerRoman"}[level%3]
+			text = "%" + strconv.Itoa(level+1) + "."
+		}
+		b.WriteString(`<w:lvl w:ilvl="` + strconv.Itoa(level) + `"><w:start w:val="1"/><w:numFmt w:val="` + format + `"/>`)
+		b.WriteString(`<w:lvlText w:val="` + text + `"/><w:lvlJc w:val="left"/>`)
+		b.WriteString(`<w:pPr><w:ind w:left="` + strconv.Itoa(720*(level+1)) + `" w:hanging="360"/></w:pPr></w:lvl>`)
+	}
+	b.WriteString(`</w:abstractNum>`)
+	return b.String()
+}
+
+// cellRuns splits the text of a table cell into runs at its line breaks.
+func cellRuns(text string, bold bool) []richRun {
+	var runs []richRun
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			runs = append(runs, richRun{lineBreak: true})
+		}
+		runs = append(runs, richRun{text: line, bold: bold})
+	}
+	return runs
+}
+
+// table replaces the paragraph holding a table value with the table. Section properties
+// of the paragraph are kept in an empty paragraph after it.
+func (d *docxRich) table(paragraph string, run markerRun, table Table) string {
+	count := table.columnCount()
+	if count == 0 {
+		return d.paragraphs(paragraph, run, nil)
+	}
+
+	style, found := d.tableStyle(table.Style)
+	if !found {
+		log.Printf("Warning: table style %q not found in styles.xml", table.Style)
+	}
+
+	d.tables = true
+	var b strings.Builder
+	b.WriteString("<w:tbl><w:tblPr>")
+	if style != "" {
+		b.WriteString(`<w:tblStyle w:val="` + EscapeXMLAttr(style) + `"/>`)
+	}
+	b.WriteString(`<w:tblW w:w="5000" w:type="pct"/>`)
+	if style == "" {
+		b.WriteString(`<w:tblBorders>`)
+		for _, side := range []string{"top", "left", "bottom", "right", "insideH", "insideV"} {
+			b.WriteString(`<w:` + side + ` w:val="single" w:sz="4" w:space="0" w:color="auto"/>`)
+		}
+		b.WriteString(`</w:tblBorders>`)
+	}
+	b.WriteString(wordTableLook(len(table.Header) > 0, table.Banded))
+	b.WriteString("</w:tblPr><w:tblGrid>")
+
+	widths := table.columnWidths(count, d.textWidth)
+	shares := table.columnWidths(count, 5000)
+	for _, width := range widths {
+		b.WriteString(`<w:gridCol w:w="` + strconv.Itoa(width) + `"/>`)
+	}
+	b.WriteString("</w:tblGrid>")
+
+	header := len(table.Header) > 0
+	for i, row := range table.allRows(count) {
+		isHeader := header && i == 0
+		b.WriteString("<w:tr>")
+		if isHeader {
+			b.WriteString("<w:trPr><w:tblHeader/></w:trPr>")
+		}
+
+		// Without a table style, format the header and bands directly
+		dataRow := i
+		if header {
+			dataRow--
+		}
+		shade := style == "" && table.Banded && !isHeader && dataRow%2 == 1
+
+		for j, cell := range row {
+			b.WriteString(`<w:tc><w:tcPr><w:tcW w:w="` + strconv.Itoa(shares[j]) + `" w:type="pct"/>`)
+			if shade {
+				b.WriteString(`<w:shd w:val="clear" w:color="auto" w:fill="F2F2F2"/>`)
+			}
+			b.WriteString("</w:tcPr><w:p>")
+			if style == "" {
+				b.WriteString(`<w:pPr><w:spacing w:after="0"/></w:pPr>`)
+			}
+			b.WriteString(d.runs(cellRuns(cell, isHeader && style == ""), run.props))
+			b.WriteString("</w:p></w:tc>")
+		}
+		b.WriteString("</w:tr>")
+	}
+	b.WriteString("</w:tbl>")
+
+	if match := wordPPrPattern.FindStringSubmatch(paragraph); match != nil && strings.Contains(match[1], "<w:sectPr") {
+		b.WriteString("<w:p>" + match[1] + "</w:p>")
+	}
+	return b.String()
+}
+
+// wordTableLook sets which conditional formats of the table style apply.
+func wordTableLook(header, banded bool) string {
+	firstRow, noHBand := "0", "1"
+	look := 0x0400 // noVBand
+	if header {
+		firstRow = "1"
+		look |= 0x0020
+	}
+	if banded {
+		noHBand = "0"
+	} else {
+		look |= 0x0200
+	}
+	return fmt.Sprintf(`<w:tblLook w:val="%04X" w:firstRow="%s" w:lastRow="0" w:firstColumn="0" w:lastColumn="0" w:noHBand="%s" w:noVBand="1"/>`, look, firstRow, noHBand)
+}
+
+// tableStyle returns the ID of a table style given by name or ID. Without a name, Word's
+// "Table Grid" is used when the template defines it.
+func (d *docxRich) tableStyle(name string) (string, bool) {
+	if d.tableStyles == nil {
+		d.tableStyles = make(map[string]string)
+		if content, err := d.pkg.Read("word/styles.xml"); err == nil {
+			for _, style := range wordTableStylePattern.FindAllStringSubmatch(string(content), -1) {
+				id := wordStyleIDPattern.FindStringSubmatch(style[1])
+				if id == nil {
+					continue
+				}
+				d.tableStyles[strings.ToLower(id[1])] = id[1]
+				if styleName := wordStyleNamePattern.FindStringSubmatch(style[2]); styleName != nil {
+					d.tableStyles[strings.ToLower(styleName[1])] = id[1]
+				}
+			}
+		}
+	}
+
+	if name == "" {
+		return d.tableStyles["tablegrid"], true
+	}
+	id, ok := d.tableStyles[strings.ToLower(name)]
+	return id, ok
+}
+
+var (
+	wordTableStylePattern = regexp.MustCompile(`<w:style\b([^>]*\bw:type="table"[^>]*)>(.*?)</w:style>`)
+	wordStyleIDPattern    = regexp.MustCompile(`\bw:styleId="([^"]*)"`)
+	wordStyleNamePattern  = regexp.MustCompile(`<w:name w:val="([^"]*)"`)
+	wordPageSizePattern   = regexp.MustCompile(`<w:pgSz\b[^>]*?\bw:w="(\d+)"`)
+	wordMarginPattern     = regexp.MustCompile(`<w:pgMar\b[^>]*>`)
+)
+
+// wordTextWidth returns the width between the margins of the document's last section, in twips.
+func wordTextWidth(documentXML string) int {
+	width := 9360 // Letter with 1" margins
+	sizes := wordPageSizePattern.FindAllStringSubmatch(documentXML, -1)
+	margins := wordMarginPattern.FindAllString(documentXML, -1)
+	if len(sizes) == 0 || len(margins) == 0 {
+		return width
+	}
+
+	page, _ := strconv.Atoi(sizes[len(sizes)-1][1])
+	margin := margins[len(margins)-1]
+	left, _ := strconv.Atoi(attrValue(margin, "w:left"))
+	right, _ := strconv.Atoi(attrValue(margin, "w:right"))
+	if text := page - left - right; text > 0 {
+		return text
+	}
+	return width
+}
@@ -0,0 +1,346 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SplitBy selects where SplitDocx starts a new document.
+type SplitBy int
+
+const (
+	// SplitSections starts a new document after every section break.
+	SplitSections SplitBy = iota
+	// SplitHeadings starts a new document at every top-level heading.
+	SplitHeadings
+)
+
+var (
+	wordOutlineLevelPattern = regexp.MustCompile(`<w:outlineLvl\s+w:val="(\d+)"`)
+	wordParaStylePattern    = regexp.MustCompile(`<w:pStyle\s+w:val="([^"]*)"`)
+	wordBasedOnPattern      = regexp.MustCompile(`<w:basedOn\s+w:val="([^"]*)"`)
+)
+
+// SplitDocx splits a document at its section breaks or top-level headings and returns each
+// part as a DOCX file. A part keeps the page setup, headers and footers of the section it ends
+// in; relationships, notes, comments and parts only the other parts use are left out.
+func SplitDocx(pkg *Package, by SplitBy) ([][]byte, error) {
+	content, err := pkg.Read(documentPartName)
+	if err != nil {
+		return nil, err
+	}
+	documentXML := string(content)
+	start, end, ok := documentBodyBounds(documentXML)
+	if !ok {
+		return nil, fmt.Errorf("%s has no body", documentPartName)
+	}
+	body, final := splitFinalSection(documentXML[start:end])
+
+	var headings map[string]int
+	if by == SplitHeadings {
+		if headings, err = outlineLevels(pkg); err != nil {
+			return nil, err
+		}
+	}
+
+	var pieces [][]string
+	var current []string
+	for _, child := range xmlChildren(body) {
+		if by == SplitHeadings && len(current) > 0 && isHeading(child, headings) {
+			pieces = append(pieces, current)
+			current = nil
+		}
+		current = append(current, child)
+		if by == SplitSections && paragraphSection(child) != "" {
+			pieces = append(pieces, current)
+			current = nil
+		}
+	}
+	if len(current) > 0 || len(pieces) == 0 {
+		pieces = append(pieces, current)
+	}
+
+	// A part uses the section it ends in: the section of its last paragraph, or else the
+	// first section ending after it
+	sections := make([]string, len(pieces))
+	following := final
+	for i := len(pieces) - 1; i >= 0; i-- {
+		piece := pieces[i]
+		first := ""
+		for _, child := range piece {
+			if first = paragraphSection(child); first != "" {
+				break
+			}
+		}
+
+		sections[i] = following
+		if n := len(piece); n > 0 {
+			if section := paragraphSection(piece[n-1]); section != "" {
+				sections[i] = section
+				piece[n-1] = removeParagraphSection(piece[n-1], section)
+			}
+		}
+		if first != "" {
+			following = first
+		}
+	}
+
+	files := make([][]byte, 0, len(pieces))
+	for i, piece := range pieces {
+		part := pkg.clone()
+		pieceXML := documentXML[:start] + strings.Join(piece, "") + sections[i] + documentXML[end:]
+		part.Write(documentPartName, []byte(pieceXML))
+		if err := pruneDocx(part, documentXML, pieceXML); err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+		if err := part.SaveTo(&buf); err != nil {
+			return nil, err
+		}
+		files = append(files, buf.Bytes())
+	}
+	return files, nil
+}
+
+// paragraphSection returns the section properties ending a section at a paragraph.
+func paragraphSection(element string) string {
+	if elementName(element) != "w:p" {
+		return ""
+	}
+	match := wordPPrPattern.FindStringSubmatch(element)
+	if match == nil {
+		return ""
+	}
+	start := indexElement(match[1], "w:sectPr")
+	if start < 0 {
+		return ""
+	}
+	return leadingElement(match[1][start:], "w:sectPr")
+}
+
+// removeParagraphSection removes the section properties from a paragraph, and the paragraph
+// itself when it held nothing else.
+func removeParagraphSection(paragraph, section string) string {
+	paragraph = strings.Replace(paragraph, section, "", 1)
+	match := wordPPrPattern.FindStringSubmatchIndex(paragraph)
+	if match == nil {
+		return paragraph
+	}
+	rest := paragraph[match[1]:]
+	if rest == "</w:p>" && !strings.Contains(paragraph[match[2]:match[3]], "<w:rPr") {
+		return ""
+	}
+	return paragraph
+}
+
+// isHeading reports whether a body element is a top-level heading: a paragraph with
+// outline level 0, set directly or by its style.
+func isHeading(element string, levels map[string]int) bool {
+	return elementName(element) == "w:p" && paragraphOutlineLevel(element, levels) == 0
+}
+
+// paragraphOutlineLevel returns the outline level of a paragraph, set directly or by its
+// style, or -1 for body text.
+func paragraphOutlineLevel(paragraph string, levels map[string]int) int {
+	match := wordPPrPattern.FindStringSubmatch(paragraph)
+	if match == nil || match[1] == "" {
+		return -1
+	}
+	if level := wordOutlineLevelPattern.FindStringSubmatch(match[1]); level != nil {
+		if n, _ := strconv.Atoi(level[1]); n < 9 {
+			return n
+		}
+		return -1 // level 9 is body text
+	}
+	if style := wordParaStylePattern.FindStringSubmatch(match[1]); style != nil {
+		if level, ok := levels[style[1]]; ok {
+			return level
+		}
+	}
+	return -1
+}
+
+// outlineLevels returns the outline levels of the heading styles: styles with an outline
+// level, set directly or by the styles they are based on, and "heading 1" to "heading 9".
+func outlineLevels(pkg *Package) (map[string]int, error) {
+	headings := make(map[string]int)
+	for n := 1; n <= 9; n++ {
+		headings["Heading"+strconv.Itoa(n)] = n - 1
+	}
+	part, ok := pkg.relatedPart(documentPartName, RelTypeStyles)
+	if !ok {
+		return headings, nil
+	}
+	content, err := pkg.Read(part)
+	if err != nil {
+		return nil, err
+	}
+
+	type styleInfo struct{ basedOn, level, name string }
+	styles := make(map[string]styleInfo)
+	for _, style := range wordStylePattern.FindAllString(string(content), -1) {
+		var info styleInfo
+		if match := wordBasedOnPattern.FindStringSubmatch(style); match != nil {
+			info.basedOn = match[1]
+		}
+		if match := wordOutlineLevelPattern.FindStringSubmatch(style); match != nil {
+			info.level = match[1]
+		}
+		if match := wordStyleNamePattern.FindStringSubmatch(style); match != nil {
+			info.name = match[1]
+		}
+		styles[styleID(style)] = info
+	}
+
+	for id, info := range styles {
+		level := info.level
+		for seen := 0; level == "" && info.basedOn != "" && seen < len(styles); seen++ {
+			info = styles[info.basedOn]
+			level = info.level
+		}
+
+		delete(headings, id)
+		if n, err := strconv.Atoi(level); err == nil && n < 9 {
+			headings[id] = n
+		} else if name, ok := strings.CutPrefix(strings.ToLower(styles[id].name), "heading "); ok {
+			if n, err := strconv.Atoi(name); err == nil && n >= 1 && n <= 9 {
+				headings[id] = n - 1
+			}
+		}
+	}
+	return headings, nil
+}
+
+// pruneDocx removes what the document no longer uses after its body changed from before to
+// after: relationships, footnotes, endnotes, comments and the parts only they refer to.
+func pruneDocx(pkg *Package, before, after string) error {
+	removed, err := pruneRelationships(pkg, documentPartName, before, after)
+	if err != nil {
+		return err
+	}
+
+	for _, kind := range wordAnnotations {
+		part, ok := pkg.relatedPart(documentPartName, kind.relType)
+		if !ok {
+			continue
+		}
+		content, err := pkg.Read(part)
+		if err != nil {
+			return err
+		}
+
+		used := make(map[string]bool)
+		for _, ref := range kind.refs.FindAllStringSubmatch(after, -1) {
+			used[ref[2]] = true
+		}
+		pruned := kind.elements.ReplaceAllStringFunc(string(content), func(element string) string {
+			tag := element[:strings.IndexByte(element, '>')]
+			if attrValue(tag, "w:type") != "" || used[attrValue(tag, "w:id")] {
+				return element
+			}
+			return ""
+		})
+		if pruned == string(content) {
+			continue
+		}
+		pkg.Write(part, []byte(pruned))
+
+		targets, err := pruneRelationships(pkg, part, string(content), pruned)
+		if err != nil {
+			return err
+		}
+		removed = append(removed, targets...)
+	}
+	return pkg.deleteUnreferenced(removed)
+}
+
+// pruneRelationships removes the relationships of part that its content referred to before
+// but no longer does, and returns the parts they pointed to.
+func pruneRelationships(pkg *Package, part, before, after string) ([]string, error) {
+	used := make(map[string]bool)
+	for _, attr := range wordRelAttrPattern.FindAllStringSubmatch(after, -1) {
+		used[attr[2]] = true
+	}
+	rels, err := pkg.readRelationships(part)
+	if err != nil {
+		return nil, err
+	}
+
+	unused := make(map[string]bool)
+	var targets []string
+	for _, attr := range wordRelAttrPattern.FindAllStringSubmatch(before, -1) {
+		id := attr[2]
+		rel, ok := rels[id]
+		if !ok || used[id] || unused[id] {
+			continue
+		}
+		unused[id] = true
+		if !rel.external {
+			targets = append(targets, resolveTarget(part, rel.target))
+		}
+	}
+	return targets, pkg.removeRelationships(part, unused)
+}
+
+// deleteUnreferenced deletes the parts no relationship points to any more, along with the
+// parts only they refer to.
+func (p *Package) deleteUnreferenced(parts []string) error {
+	for len(parts) > 0 {
+		targets, err := p.relationshipTargets()
+		if err != nil {
+			return err
+		}
+
+		var next []string
+		for _, name := range parts {
+			if targets[name] || !p.Has(name) {
+				continue
+			}
+			rels, err := p.readRelationships(name)
+			if err != nil {
+				return err
+			}
+			for _, rel := range rels {
+				if !rel.external {
+					next = append(next, resolveTarget(name, rel.target))
+				}
+			}
+
+			p.Delete(name)
+			p.Delete(RelsPartName(name))
+			if err := p.removeContentTypeOverride(name); err != nil {
+				return err
+			}
+		}
+		parts = next
+	}
+	return nil
+}
+
+// relationshipTargets returns the parts internal relationships of the package point to.
+func (p *Package) relationshipTargets() (map[string]bool, error) {
+	targets := make(map[string]bool)
+	for _, relsName := range p.Names() {
+		dir, base := path.Split(relsName)
+		if !strings.HasSuffix(dir, "_rels/") || !strings.HasSuffix(base, ".rels") {
+			continue
+		}
+		source := strings.TrimSuffix(dir, "_rels/") + strings.TrimSuffix(base, ".rels")
+
+		rels, err := p.readRelationships(source)
+		if err != nil {
+			return nil, err
+		}
+		for _, rel := range rels {
+			if !rel.external {
+				targets[resolveTarget(source, rel.target)] = true
+			}
+		}
+	}
+	return targets, nil
+}
@@ -0,0 +1,178 @@
+package internal
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRevisionAuthor = "OfficeForge"
+
+	// Stands for the ID of a change until the paragraph is complete
+	revisionIDMarker = "\uE004"
+)
+
+var wordIDPattern = regexp.MustCompile(`\bw:id="(-?\d+)"`)
+
+// docxTracker writes replacements as tracked changes: each placeholder is deleted and its value
+// inserted, so reviewers can accept or reject what was generated.
+type docxTracker struct {
+	attrs  string // author and date of the changes
+	nextID int
+}
+
+// newDocxTracker prepares tracked changes for documentXML, numbering them after the IDs the
+// document already uses.
+func newDocxTracker(documentXML string, opts Options) *docxTracker {
+	author := opts.RevisionAuthor
+	if author == "" {
+		author = defaultRevisionAuthor
+	}
+	date := opts.RevisionDate
+	if date.IsZero() {
+		date = time.Now()
+	}
+
+	t := &docxTracker{
+		attrs: ` w:author="` + EscapeXMLAttr(author) + `" w:date="` + date.UTC().Format("2006-01-02T15:04:05Z") + `"`,
+	}
+	for _, match := range wordIDPattern.FindAllStringSubmatch(documentXML, -1) {
+		if id, err := strconv.Atoi(match[1]); err == nil && id >= t.nextID {
+			t.nextID = id + 1
+		}
+	}
+	return t
+}
+
+// revision returns the start tag of a w:ins or w:del element. Its ID is set by numberRevisions.
+func (t *docxTracker) revision(name string) string {
+	return "<" + name + ` w:id="` + revisionIDMarker + `"` + t.attrs + ">"
+}
+
+// numberRevisions gives the changes written in element their IDs, in document order.
+func (t *docxTracker) numberRevisions(element string) string {
+	var b strings.Builder
+	for {
+		marker := strings.Index(element, revisionIDMarker)
+		if marker < 0 {
+			b.WriteString(element)
+			return b.String()
+		}
+		b.WriteString(element[:marker] + strconv.Itoa(t.nextID))
+		t.nextID++
+		element = element[marker+len(revisionIDMarker):]
+	}
+}
+
+// replace replaces the keywords in a paragraph whose text is spread over nodes, like
+// ApplyReplacements, but keeps each keyword as deleted text followed by its value as inserted
+// text. The parts of a keyword spanning several runs are deleted in their own runs; the value
+// is inserted after the last one with the formatting of the first. Rich values are written as
+// plain text.
+func (t *docxTracker) replace(element string, nodes []TextNode, replacer *Replacer) string {
+	plainText, offsets := nodesPlainText(element, nodes)
+	points := replacer.FindReplacementPoints(plainText)
+	if len(points) == 0 {
+		return element
+	}
+
+	// Formatting of each node's run, read before any run is split
+	props := make([]string, len(nodes))
+	for i, node := range nodes {
+		if run, ok := findMarkerRun(element, node.ContentStart, node.ContentEnd, wordRun); ok {
+			props[i] = run.props
+		}
+	}
+	nodeAt := func(pos int) int {
+		for i := range nodes {
+			if pos < offsets[i+1] {
+				return i
+			}
+		}
+		return len(nodes) - 1
+	}
+
+	// Rebuild nodes from the last to the first so earlier offsets stay valid
+	for i := len(nodes) - 1; i >= 0; i-- {
+		node := nodes[i]
+		nodeStart, nodeEnd := offsets[i], offsets[i+1]
+
+		run, ok := findMarkerRun(element, node.ContentStart, node.ContentEnd, wordRun)
+		if !ok {
+			// Text outside a run can't hold revisions
+			if text, changed := rewriteNodeText(plainText, nodeStart, nodeEnd, points); changed {
+				element = element[:node.ContentStart] + EscapeXMLText(text) + element[node.ContentEnd:]
+			}
+			continue
+		}
+
+		// The node's text split into kept, deleted and inserted pieces
+		type piece struct{ revision, text, props string }
+		var pieces []piece
+		cursor := nodeStart
+		for _, rp := range points {
+			if rp.endPos <= nodeStart || rp.startPos >= nodeEnd {
+				continue
+			}
+			start, end := max(rp.startPos, nodeStart), min(rp.endPos, nodeEnd)
+			if start > cursor {
+				pieces = append(pieces, piece{"", plainText[cursor:start], run.props})
+			}
+			if end > start {
+				pieces = append(pieces, piece{"w:del", plainText[start:end], run.props})
+			}
+			if rp.endPos <= nodeEnd && rp.replacement != "" {
+				pieces = append(pieces, piece{"w:ins", rp.replacement, props[nodeAt(rp.startPos)]})
+			}
+			cursor = end
+		}
+		if len(pieces) == 0 {
+			continue
+		}
+		if cursor < nodeEnd {
+			pieces = append(pieces, piece{"", plainText[cursor:nodeEnd], run.props})
+		}
+
+		var b strings.Builder
+		for j, p := range pieces {
+			content := EscapeXMLText(p.text)
+			last := j == len(pieces)-1
+			switch {
+			case p.revision == "" && j == 0:
+				// The first kept text stays in the original run
+				openTag := element[node.Start:node.ContentStart]
+				if needsSpacePreserve(openTag, content) {
+					openTag = addSpacePreserve(openTag)
+				}
+				b.WriteString(run.head + openTag + content + "</w:t>")
+			case p.revision == "":
+				b.WriteString("<w:r>" + p.props + textStartTag("w:t", content) + content + "</w:t>")
+			default:
+				if j == 0 && !run.plainHead {
+					// Keep what precedes the text in the run
+					b.WriteString(run.head + "</w:r>")
+				}
+				textTag := "w:t"
+				if p.revision == "w:del" {
+					textTag = "w:delText"
+				}
+				b.WriteString(t.revision(p.revision) + "<w:r>" + p.props + textStartTag(textTag, content) + content +
+					"</" + textTag + "></w:r></" + p.revision + ">")
+				if last && run.tail != "</w:r>" {
+					// Keep what follows the text in the run, such as a tab or a break
+					b.WriteString("<w:r>" + run.props + run.tail)
+				}
+				continue
+			}
+			if last {
+				b.WriteString(run.tail)
+			} else {
+				b.WriteString("</w:r>")
+			}
+		}
+		element = element[:run.start] + b.String() + element[run.end:]
+	}
+	return t.numberRevisions(element)
+}
@@ -0,0 +1,322 @@
+package internal
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// ExportFormat selects what a document is exported as.
+type ExportFormat int
+
+const (
+	// ExportText is plain text: a line per paragraph, table cells separated by tabs.
+	ExportText ExportFormat = iota
+	// ExportMarkdown is Markdown with headings, lists, tables, emphasis and links.
+	ExportMarkdown
+	// ExportHTML is an HTML fragment with the same structure as ExportMarkdown.
+	ExportHTML
+)
+
+// ParseExportFormat reads a format name: txt, md or html.
+func ParseExportFormat(name string) (ExportFormat, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "txt", "text":
+		return ExportText, nil
+	case "md", "markdown":
+		return ExportMarkdown, nil
+	case "html", "htm":
+		return ExportHTML, nil
+	}
+	return 0, fmt.Errorf("unknown export format %q (use txt, md or html)", name)
+}
+
+// exportBlock is a paragraph, heading or table of exported content, or the break between
+// two slides.
+type exportBlock struct {
+	paragraph richParagraph
+	heading   int // 1 to 6 for headings
+	table     *exportTable
+	separator bool
+}
+
+// exportTable is a table of exported content; each cell holds paragraphs.
+type exportTable struct {
+	rows   [][][]richParagraph
+	header bool // the first row holds column headings
+}
+
+// renderExport writes blocks in the given format.
+func renderExport(blocks []exportBlock, format ExportFormat) string {
+	r := &exportRenderer{format: format, numbers: make(map[[2]int]int)}
+	for _, block := range blocks {
+		r.block(block)
+	}
+	r.closeLists(0)
+	return strings.TrimSpace(r.b.String()) + "\n"
+}
+
+type exportRenderer struct {
+	format  ExportFormat
+	b       strings.Builder
+	numbers map[[2]int]int // item numbers by list and level
+	lists   []string       // open HTML lists, outermost first
+	inList  bool
+}
+
+func (r *exportRenderer) block(block exportBlock) {
+	if block.paragraph.list == listNone || block.heading > 0 || block.table != nil || block.separator {
+		r.closeLists(0)
+		if r.inList && r.format == ExportMarkdown {
+			r.b.WriteString("\n")
+		}
+		r.inList = false
+	}
+
+	switch {
+	case block.separator:
+		switch r.format {
+		case ExportText:
+			r.b.WriteString("\n")
+		case ExportMarkdown:
+			r.b.WriteString("---\n\n")
+		case ExportHTML:
+			r.b.WriteString("<hr>\n")
+		}
+	case block.table != nil:
+		r.table(block.table)
+	case block.heading > 0:
+		r.heading(block.paragraph, block.heading)
+	case block.paragraph.list != listNone:
+		r.listItem(block.paragraph)
+		r.inList = true
+	default:
+		r.paragraph(block.paragraph)
+	}
+}
+
+func (r *exportRenderer) heading(paragraph richParagraph, level int) {
+	level = min(level, 6)
+	switch r.format {
+	case ExportText:
+		r.b.WriteString(r.inline(paragraph.runs) + "\n")
+	case ExportMarkdown:
+		r.b.WriteString(strings.Repeat("#", level) + " " + r.inline(paragraph.runs) + "\n\n")
+	case ExportHTML:
+		tag := "h" + strconv.Itoa(level)
+		r.b.WriteString("<" + tag + ">" + r.inline(paragraph.runs) + "</" + tag + ">\n")
+	}
+}
+
+func (r *exportRenderer) paragraph(paragraph richParagraph) {
+	text := r.inline(paragraph.runs)
+	switch r.format {
+	case ExportText:
+		r.b.WriteString(text + "\n")
+	case ExportMarkdown:
+		if strings.TrimSpace(text) != "" {
+			r.b.WriteString(text + "\n\n")
+		}
+	case ExportHTML:
+		if strings.TrimSpace(text) != "" {
+			r.b.WriteString("<p>" + text + "</p>\n")
+		}
+	}
+}
+
+func (r *exportRenderer) listItem(paragraph richParagraph) {
+	number := 0
+	if paragraph.list == listNumber {
+		key := [2]int{paragraph.listID, paragraph.level}
+		r.numbers[key]++
+		number = r.numbers[key]
+	}
+	text := r.inline(paragraph.runs)
+
+	switch r.format {
+	case ExportText:
+		marker := "• "
+		if number > 0 {
+			marker = strconv.Itoa(number) + ". "
+		}
+		r.b.WriteString(strings.Repeat("  ", paragraph.level) + marker + text + "\n")
+	case ExportMarkdown:
+		marker := "- "
+		if number > 0 {
+			marker = strconv.Itoa(number) + ". "
+		}
+		r.b.WriteString(strings.Repeat("    ", paragraph.level) + marker + text + "\n")
+	case ExportHTML:
+		tag := "ul"
+		if paragraph.list == listNumber {
+			tag = "ol"
+		}
+		depth := paragraph.level + 1
+		r.closeLists(depth)
+		if len(r.lists) == depth {
+			if r.lists[depth-1] != tag {
+				r.closeLists(depth - 1)
+			} else {
+				r.b.WriteString("</li>\n")
+			}
+		}
+		for len(r.lists) < depth {
+			r.lists = append(r.lists, tag)
+			r.b.WriteString("<" + tag + ">\n")
+		}
+		r.b.WriteString("<li>" + text)
+	}
+}
+
+// closeLists closes the open HTML lists nested deeper than depth.
+func (r *exportRenderer) closeLists(depth int) {
+	for len(r.lists) > depth {
+		r.b.WriteString("</li>\n</" + r.lists[len(r.lists)-1] + ">\n")
+		r.lists = r.lists[:len(r.lists)-1]
+	}
+}
+
+func (r *exportRenderer) table(table *exportTable) {
+	cell := func(paragraphs []richParagraph, separator string) string {
+		texts := make([]string, 0, len(paragraphs))
+		for _, paragraph := range paragraphs {
+			if text := r.inline(paragraph.runs); text != "" {
+				texts = append(texts, text)
+			}
+		}
+		return strings.Join(texts, separator)
+	}
+
+	switch r.format {
+	case ExportText:
+		for _, row := range table.rows {
+			cells := make([]string, len(row))
+			for i, paragraphs := range row {
+				cells[i] = cell(paragraphs, " ")
+			}
+			r.b.WriteString(strings.Join(cells, "\t") + "\n")
+		}
+	case ExportMarkdown:
+		columns := 0
+		for _, row := range table.rows {
+			columns = max(columns, len(row))
+		}
+		for i, row := range table.rows {
+			cells := make([]string, columns)
+			for j, paragraphs := range row {
+				cells[j] = strings.ReplaceAll(cell(paragraphs, "<br>"), "|", `\|`)
+			}
+			r.b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+			if i == 0 {
+				r.b.WriteString(strings.Repeat("| --- ", columns) + "|\n")
+			}
+		}
+		r.b.WriteString("\n")
+	case ExportHTML:
+		r.b.WriteString("<table>\n")
+		for i, row := range table.rows {
+			tag := "td"
+			if i == 0 && table.header {
+				tag = "th"
+			}
+			r.b.WriteString("<tr>")
+			for _, paragraphs := range row {
+				r.b.WriteString("<" + tag + ">" + cell(paragraphs, "<br>") + "</" + tag + ">")
+			}
+			r.b.WriteString("</tr>\n")
+		}
+		r.b.WriteString("</table>\n")
+	}
+}
+
+// inline writes runs with their emphasis and links. Neighbouring runs of the same format are
+// written together.
+func (r *exportRenderer) inline(runs []richRun) string {
+	var b strings.Builder
+	for i := 0; i < len(runs); {
+		run := runs[i]
+		var text strings.Builder
+		for ; i < len(runs) && sameRunFormat(runs[i], run); i++ {
+			switch {
+			case runs[i].lineBreak:
+				text.WriteString("\n")
+			case runs[i].tab:
+				text.WriteString("\t")
+			default:
+				text.WriteString(runs[i].text)
+			}
+		}
+		b.WriteString(r.span(text.String(), run))
+	}
+	return b.String()
+}
+
+func sameRunFormat(a, b richRun) bool {
+	return a.bold == b.bold && a.italic == b.italic && a.underline == b.underline && a.link == b.link
+}
+
+// span writes text of one format. Emphasis markers go inside the surrounding spaces, where
+// Markdown expects them.
+func (r *exportRenderer) span(text string, run richRun) string {
+	switch r.format {
+	case ExportMarkdown:
+		content := strings.TrimSpace(text)
+		if content == "" {
+			return markdownEscape(text)
+		}
+		lead := text[:strings.Index(text, content)]
+		trail := text[len(lead)+len(content):]
+		content = markdownEscape(content)
+		if run.bold && run.italic {
+			content = "***" + content + "***"
+		} else if run.bold {
+			content = "**" + content + "**"
+		} else if run.italic {
+			content = "*" + content + "*"
+		}
+		if target, ok := exportLink(run.link); ok {
+			content = "[" + content + "](" + strings.ReplaceAll(strings.ReplaceAll(target, "(", "%28"), ")", "%29") + ")"
+		}
+		return markdownEscape(lead) + content + markdownEscape(trail)
+	case ExportHTML:
+		content := strings.ReplaceAll(html.EscapeString(text), "\n", "<br>")
+		if run.underline {
+			content = "<u>" + content + "</u>"
+		}
+		if run.italic {
+			content = "<em>" + content + "</em>"
+		}
+		if run.bold {
+			content = "<strong>" + content + "</strong>"
+		}
+		if target, ok := exportLink(run.link); ok {
+			content = `<a href="` + html.EscapeString(target) + `">` + content + "</a>"
+		}
+		return content
+	}
+	return text
+}
+
+// exportLink returns the target of a link when it is safe to export: a web, mail or phone
+// link, or a bookmark within the document.
+func exportLink(link string) (string, bool) {
+	if link == "" {
+		return "", false
+	}
+	if strings.HasPrefix(link, "#") {
+		return link, true
+	}
+	return safeLink(link)
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`, "<", `\<`, "\t", " ", "\n", "  \n")
+
+// markdownEscape escapes the characters of text that Markdown would read as formatting.
+func markdownEscape(text string) string {
+	text = markdownEscaper.Replace(text)
+	if strings.HasPrefix(text, "#") {
+		text = `\` + text
+	}
+	return text
+}
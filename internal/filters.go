@@ -0,0 +1,335 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// FilterFunc formats a placeholder value. args holds the filter arguments written in the
+// template, e.g. {{AMOUNT | currency:"EUR"}} calls the currency filter with args ["EUR"].
+type FilterFunc func(value string, args ...string) (string, error)
+
+// FuncMap maps filter names to their implementation.
+type FuncMap map[string]FilterFunc
+
+// filterCall is a single filter invocation parsed from a placeholder
+type filterCall struct {
+	name string
+	args []string
+}
+
+// builtinFilters returns the filters available in every template, formatting numbers,
+// amounts and dates for loc. Options.Filters may override them.
+func builtinFilters(loc locale) FuncMap {
+	return FuncMap{
+		"upper":    filterUpper,
+		"lower":    filterLower,
+		"title":    filterTitle,
+		"trim":     filterTrim,
+		"default":  filterDefault,
+		"number":   loc.filterNumber,
+		"currency": loc.filterCurrency,
+		"date":     loc.filterDate,
+		"truncate": filterTruncate,
+		"replace":  filterReplace,
+		"markdown": filterMarkdown,
+		"html":     filterHTML,
+		"link":     filterLink,
+		"table":    filterTable,
+	}
+}
+
+// currencyInfo describes how amounts in a currency are written
+type currencyInfo struct {
+	symbol   string
+	decimals int
+}
+
+var currencies = map[string]currencyInfo{
+	"USD": {"$", 2},
+	"EUR": {"€", 2},
+	"GBP": {"£", 2},
+	"JPY": {"¥", 0},
+	"CNY": {"¥", 2},
+	"INR": {"₹", 2},
+	"KRW": {"₩", 0},
+	"BRL": {"R$", 2},
+	"CAD": {"CA$", 2},
+	"AUD": {"A$", 2},
+	"MXN": {"MX$", 2},
+	"CHF": {"CHF", 2},
+	"SEK": {"kr", 2},
+	"NOK": {"kr", 2},
+	"DKK": {"kr", 2},
+	"PLN": {"zł", 2},
+}
+
+// dateLayouts are the input formats the date filter understands
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02",
+	"20060102",
+}
+
+func filterUpper(value string, args ...string) (string, error) {
+	return strings.ToUpper(value), nil
+}
+
+func filterLower(value string, args ...string) (string, error) {
+	return strings.ToLower(value), nil
+}
+
+func filterTitle(value string, args ...string) (string, error) {
+	var b strings.Builder
+	startOfWord := true
+	for _, r := range value {
+		if startOfWord {
+			b.WriteRune(unicode.ToTitle(r))
+		} else {
+			b.WriteRune(unicode.ToLower(r))
+		}
+		startOfWord = unicode.IsSpace(r) || r == '-'
+	}
+	return b.String(), nil
+}
+
+func filterTrim(value string, args ...string) (string, error) {
+	return strings.TrimSpace(value), nil
+}
+
+func filterDefault(value string, args ...string) (string, error) {
+	if strings.TrimSpace(value) != "" {
+		return value, nil
+	}
+	if len(args) == 0 {
+		return "", nil
+	}
+	return args[0], nil
+}
+
+func (l locale) filterNumber(value string, args ...string) (string, error) {
+	number, err := parseNumber(value)
+	if err != nil {
+		return "", err
+	}
+
+	decimals, err := intArg(args, 0, 0)
+	if err != nil {
+		return "", err
+	}
+	return formatNumber(number, decimals, l.groupSep, l.decimalSep), nil
+}
+
+func (l locale) filterCurrency(value string, args ...string) (string, error) {
+	number, err := parseNumber(value)
+	if err != nil {
+		return "", err
+	}
+
+	code := l.currency
+	if len(args) > 0 && args[0] != "" {
+		code = strings.ToUpper(args[0])
+	}
+	return l.formatAmount(number, code), nil
+}
+
+func (l locale) filterDate(value string, args ...string) (string, error) {
+	t, err := parseDate(value)
+	if err != nil {
+		return "", err
+	}
+
+	layout := l.dateLayout
+	if len(args) > 0 && args[0] != "" {
+		layout = args[0]
+	}
+	return l.formatDate(t, layout), nil
+}
+
+func filterTruncate(value string, args ...string) (string, error) {
+	limit, err := intArg(args, 0, -1)
+	if err != nil {
+		return "", err
+	}
+	if limit < 0 {
+		return "", fmt.Errorf("truncate needs a length")
+	}
+
+	if utf8.RuneCountInString(value) <= limit {
+		return value, nil
+	}
+	if limit == 0 {
+		return "", nil
+	}
+
+	// Keep the result within the limit, including the ellipsis
+	runes := []rune(value)
+	return strings.TrimRightFunc(string(runes[:limit-1]), unicode.IsSpace) + "…", nil
+}
+
+func filterReplace(value string, args ...string) (string, error) {
+	if len(args) != 2 {
+		return "", fmt.Errorf("replace needs two arguments, got %d", len(args))
+	}
+	return strings.ReplaceAll(value, args[0], args[1]), nil
+}
+
+// parseNumber reads a plain number such as "1234.5" or "-12", ignoring surrounding spaces.
+func parseNumber(value string) (float64, error) {
+	number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", value)
+	}
+	return number, nil
+}
+
+// parseDate reads a date in one of dateLayouts.
+func parseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a recognized date", value)
+}
+
+// intArg returns args[index] as an integer, or fallback when the argument is absent.
+func intArg(args []string, index, fallback int) (int, error) {
+	if index >= len(args) || args[index] == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(args[index])
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a whole number", args[index])
+	}
+	return n, nil
+}
+
+// formatNumber writes number with the given decimals, grouping thousands with groupSep.
+func formatNumber(number float64, decimals int, groupSep, decimalSep string) string {
+	formatted := strconv.FormatFloat(math.Abs(number), 'f', decimals, 64)
+
+	intPart, fracPart, _ := strings.Cut(formatted, ".")
+
+	var b strings.Builder
+	if number < 0 && strings.Trim(formatted, "0.") != "" {
+		b.WriteString("-")
+	}
+	for i, digit := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteString(groupSep)
+		}
+		b.WriteRune(digit)
+	}
+	if fracPart != "" {
+		b.WriteString(decimalSep)
+		b.WriteString(fracPart)
+	}
+	return b.String()
+}
+
+// parseFilters parses the filter chain of a placeholder, e.g. ` upper | default:"N/A"`.
+func parseFilters(chain []string) ([]filterCall, bool) {
+	calls := make([]filterCall, 0, len(chain))
+	for _, segment := range chain {
+		segment = strings.TrimSpace(segment)
+		name, rawArgs, hasArgs := strings.Cut(segment, ":")
+		name = strings.TrimSpace(name)
+		if !isFilterName(name) {
+			return nil, false
+		}
+
+		call := filterCall{name: name}
+		if hasArgs {
+			args, ok := splitFilterArgs(rawArgs)
+			if !ok {
+				return nil, false
+			}
+			call.args = args
+		}
+		calls = append(calls, call)
+	}
+	return calls, true
+}
+
+func isFilterName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		if !(r == '_' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r))) {
+			return false
+		}
+	}
+	return true
+}
+
+// splitFilterArgs splits filter arguments on commas outside quotes and unquotes them.
+// Straight and typographic quotes are accepted, since Word replaces "EUR" with “EUR” as you type.
+func splitFilterArgs(rawArgs string) ([]string, bool) {
+	var args []string
+	var current strings.Builder
+	var opening rune
+	quoted := false
+
+	for _, r := range rawArgs {
+		switch {
+		case opening != 0:
+			if isClosingQuote(opening, r) {
+				opening = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case !quoted && (r == '"' || r == '\'' || r == '“' || r == '‘' || r == '„'):
+			if strings.TrimSpace(current.String()) != "" {
+				return nil, false
+			}
+			current.Reset()
+			opening = r
+			quoted = true
+		case r == ',':
+			args = append(args, finishArg(current.String(), quoted))
+			current.Reset()
+			quoted = false
+		case quoted:
+			// Only whitespace may follow a closing quote
+			if !unicode.IsSpace(r) {
+				return nil, false
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if opening != 0 {
+		return nil, false
+	}
+	return append(args, finishArg(current.String(), quoted)), true
+}
+
+func isClosingQuote(opening, r rune) bool {
+	switch opening {
+	case '“', '„':
+		return r == '”' || r == '“'
+	case '‘':
+		return r == '’'
+	}
+	return r == opening
+}
+
+// finishArg trims the whitespace around an argument; quoted arguments keep their inner spaces.
+func finishArg(arg string, quoted bool) string {
+	if quoted {
+		return arg
+	}
+	return strings.TrimSpace(arg)
+}
@@ -0,0 +1,267 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// locale describes how numbers, amounts and dates are written in a region
+type locale struct {
+	tag        string
+	decimalSep string
+	// groupSep separates thousands; several locales use a (narrow) no-break space
+	groupSep string
+
+	// currencyPattern places the symbol (¤) relative to the amount (#), e.g. "¤#" or "# ¤".
+	// Empty keeps the neutral style: symbol first, separated by a space when alphabetic.
+	currencyPattern string
+	// currency is the code used by the currency filter when none is given
+	currency string
+	// currencySymbol overrides the symbol of the locale's own currency, e.g. "$" for CAD in Canada
+	currencySymbol string
+
+	dateLayout string
+	names      *dateNames
+}
+
+// dateNames holds the month and weekday names of a language, January and Sunday first
+type dateNames struct {
+	months      [12]string
+	shortMonths [12]string
+	days        [7]string
+	shortDays   [7]string
+}
+
+// neutralLocale is used when no locale is selected and keeps the formatting of earlier releases
+var neutralLocale = locale{
+	decimalSep: ".",
+	groupSep:   ",",
+	currency:   "USD",
+	dateLayout: "2006-01-02",
+}
+
+var (
+	englishNames = &dateNames{
+		months:      [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+		shortMonths: [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+		days:        [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+		shortDays:   [7]string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"},
+	}
+	germanNames = &dateNames{
+		months:      [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		shortMonths: [12]string{"Jan.", "Feb.", "März", "Apr.", "Mai", "Juni", "Juli", "Aug.", "Sept.", "Okt.", "Nov.", "Dez."},
+		days:        [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+		shortDays:   [7]string{"So.", "Mo.", "Di.", "Mi.", "Do.", "Fr.", "Sa."},
+	}
+	frenchNames = &dateNames{
+		months:      [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		shortMonths: [12]string{"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."},
+		days:        [7]string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+		shortDays:   [7]string{"dim.", "lun.", "mar.", "mer.", "jeu.", "ven.", "sam."},
+	}
+	spanishNames = &dateNames{
+		months:      [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		shortMonths: [12]string{"ene", "feb", "mar", "abr", "may", "jun", "jul", "ago", "sept", "oct", "nov", "dic"},
+		days:        [7]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+		shortDays:   [7]string{"dom", "lun", "mar", "mié", "jue", "vie", "sáb"},
+	}
+	italianNames = &dateNames{
+		months:      [12]string{"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno", "luglio", "agosto", "settembre", "ottobre", "novembre", "dicembre"},
+		shortMonths: [12]string{"gen", "feb", "mar", "apr", "mag", "giu", "lug", "ago", "set", "ott", "nov", "dic"},
+		days:        [7]string{"domenica", "lunedì", "martedì", "mercoledì", "giovedì", "venerdì", "sabato"},
+		shortDays:   [7]string{"dom", "lun", "mar", "mer", "gio", "ven", "sab"},
+	}
+	dutchNames = &dateNames{
+		months:      [12]string{"januari", "februari", "maart", "april", "mei", "juni", "juli", "augustus", "september", "oktober", "november", "december"},
+		shortMonths: [12]string{"jan", "feb", "mrt", "apr", "mei", "jun", "jul", "aug", "sep", "okt", "nov", "dec"},
+		days:        [7]string{"zondag", "maandag", "dinsdag", "woensdag", "donderdag", "vrijdag", "zaterdag"},
+		shortDays:   [7]string{"zo", "ma", "di", "wo", "do", "vr", "za"},
+	}
+	portugueseNames = &dateNames{
+		months:      [12]string{"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro", "outubro", "novembro", "dezembro"},
+		shortMonths: [12]string{"jan", "fev", "mar", "abr", "mai", "jun", "jul", "ago", "set", "out", "nov", "dez"},
+		days:        [7]string{"domingo", "segunda-feira", "terça-feira", "quarta-feira", "quinta-feira", "sexta-feira", "sábado"},
+		shortDays:   [7]string{"dom", "seg", "ter", "qua", "qui", "sex", "sáb"},
+	}
+	polishNames = &dateNames{
+		months:      [12]string{"stycznia", "lutego", "marca", "kwietnia", "maja", "czerwca", "lipca", "sierpnia", "września", "października", "listopada", "grudnia"},
+		shortMonths: [12]string{"sty", "lut", "mar", "kwi", "maj", "cze", "lip", "sie", "wrz", "paź", "lis", "gru"},
+		days:        [7]string{"niedziela", "poniedziałek", "wtorek", "środa", "czwartek", "piątek", "sobota"},
+		shortDays:   [7]string{"niedz.", "pon.", "wt.", "śr.", "czw.", "pt.", "sob."},
+	}
+	swedishNames = &dateNames{
+		months:      [12]string{"januari", "februari", "mars", "april", "maj", "juni", "juli", "augusti", "september", "oktober", "november", "december"},
+		shortMonths: [12]string{"jan.", "feb.", "mars", "apr.", "maj", "juni", "juli", "aug.", "sep.", "okt.", "nov.", "dec."},
+		days:        [7]string{"söndag", "måndag", "tisdag", "onsdag", "torsdag", "fredag", "lördag"},
+		shortDays:   [7]string{"sön", "mån", "tis", "ons", "tors", "fre", "lör"},
+	}
+	norwegianNames = &dateNames{
+		months:      [12]string{"januar", "februar", "mars", "april", "mai", "juni", "juli", "august", "september", "oktober", "november", "desember"},
+		shortMonths: [12]string{"jan.", "feb.", "mar.", "apr.", "mai", "jun.", "jul.", "aug.", "sep.", "okt.", "nov.", "des."},
+		days:        [7]string{"søndag", "mandag", "tirsdag", "onsdag", "torsdag", "fredag", "lørdag"},
+		shortDays:   [7]string{"søn.", "man.", "tir.", "ons.", "tor.", "fre.", "lør."},
+	}
+	danishNames = &dateNames{
+		months:      [12]string{"januar", "februar", "marts", "april", "maj", "juni", "juli", "august", "september", "oktober", "november", "december"},
+		shortMonths: [12]string{"jan.", "feb.", "mar.", "apr.", "maj", "jun.", "jul.", "aug.", "sep.", "okt.", "nov.", "dec."},
+		days:        [7]string{"søndag", "mandag", "tirsdag", "onsdag", "torsdag", "fredag", "lørdag"},
+		shortDays:   [7]string{"søn.", "man.", "tirs.", "ons.", "tors.", "fre.", "lør."},
+	}
+	finnishNames = &dateNames{
+		months:      [12]string{"tammikuuta", "helmikuuta", "maaliskuuta", "huhtikuuta", "toukokuuta", "kesäkuuta", "heinäkuuta", "elokuuta", "syyskuuta", "lokakuuta", "marraskuuta", "joulukuuta"},
+		shortMonths: [12]string{"tammik.", "helmik.", "maalisk.", "huhtik.", "toukok.", "kesäk.", "heinäk.", "elok.", "syysk.", "lokak.", "marrask.", "jouluk."},
+		days:        [7]string{"sunnuntai", "maanantai", "tiistai", "keskiviikko", "torstai", "perjantai", "lauantai"},
+		shortDays:   [7]string{"su", "ma", "ti", "ke", "to", "pe", "la"},
+	}
+	japaneseNames = &dateNames{
+		months:      [12]string{"1月", "2月", "3月", "4月", "5月", "6月", "7月", "8月", "9月", "10月", "11月", "12月"},
+		shortMonths: [12]string{"1月", "2月", "3月", "4月", "5月", "6月", "7月", "8月", "9月", "10月", "11月", "12月"},
+		days:        [7]string{"日曜日", "月曜日", "火曜日", "水曜日", "木曜日", "金曜日", "土曜日"},
+		shortDays:   [7]string{"日", "月", "火", "水", "木", "金", "土"},
+	}
+	chineseNames = &dateNames{
+		months:      [12]string{"一月", "二月", "三月", "四月", "五月", "六月", "七月", "八月", "九月", "十月", "十一月", "十二月"},
+		shortMonths: [12]string{"1月", "2月", "3月", "4月", "5月", "6月", "7月", "8月", "9月", "10月", "11月", "12月"},
+		days:        [7]string{"星期日", "星期一", "星期二", "星期三", "星期四", "星期五", "星期六"},
+		shortDays:   [7]string{"周日", "周一", "周二", "周三", "周四", "周五", "周六"},
+	}
+)
+
+// locales lists the supported locales. The first locale of each language is used when
+// only a language is requested, e.g. "de" selects de-DE.
+var locales = []locale{
+	{tag: "en-US", decimalSep: ".", groupSep: ",", currencyPattern: "¤#", currency: "USD", currencySymbol: "$", dateLayout: "01/02/2006", names: englishNames},
+	{tag: "en-GB", decimalSep: ".", groupSep: ",", currencyPattern: "¤#", currency: "GBP", dateLayout: "02/01/2006", names: englishNames},
+	{tag: "en-AU", decimalSep: ".", groupSep: ",", currencyPattern: "¤#", currency: "AUD", currencySymbol: "$", dateLayout: "02/01/2006", names: englishNames},
+	{tag: "en-CA", decimalSep: ".", groupSep: ",", currencyPattern: "¤#", currency: "CAD", currencySymbol: "$", dateLayout: "2006-01-02", names: englishNames},
+	{tag: "en-IE", decimalSep: ".", groupSep: ",", currencyPattern: "¤#", currency: "EUR", dateLayout: "02/01/2006", names: englishNames},
+	{tag: "de-DE", decimalSep: ",", groupSep: ".", currencyPattern: "# ¤", currency: "EUR", dateLayout: "02.01.2006", names: germanNames},
+	{tag: "de-AT", decimalSep: ",", groupSep: "\u00a0", currencyPattern: "¤ #", currency: "EUR", dateLayout: "02.01.2006", names: germanNames},
+	{tag: "de-CH", decimalSep: ".", groupSep: "’", currencyPattern: "¤ #", currency: "CHF", dateLayout: "02.01.2006", names: germanNames},
+	{tag: "fr-FR", decimalSep: ",", groupSep: "\u202f", currencyPattern: "# ¤", currency: "EUR", dateLayout: "02/01/2006", names: frenchNames},
+	{tag: "fr-BE", decimalSep: ",", groupSep: "\u202f", currencyPattern: "# ¤", currency: "EUR", dateLayout: "02/01/2006", names: frenchNames},
+	{tag: "fr-CH", decimalSep: ",", groupSep: "\u202f", currencyPattern: "# ¤", currency: "CHF", dateLayout: "02.01.2006", names: frenchNames},
+	{tag: "fr-CA", decimalSep: ",", groupSep: "\u202f", currencyPattern: "# ¤", currency: "CAD", currencySymbol: "$", dateLayout: "2006-01-02", names: frenchNames},
+	{tag: "es-ES", decimalSep: ",", groupSep: ".", currencyPattern: "# ¤", currency: "EUR", dateLayout: "02/01/2006", names: spanishNames},
+	{tag: "es-MX", decimalSep: ".", groupSep: ",", currencyPattern: "¤#", currency: "MXN", currencySymbol: "$", dateLayout: "02/01/2006", names: spanishNames},
+	{tag: "it-IT", decimalSep: ",", groupSep: ".", currencyPattern: "# ¤", currency: "EUR", dateLayout: "02/01/2006", names: italianNames},
+	{tag: "nl-NL", decimalSep: ",", groupSep: ".", currencyPattern: "¤ #", currency: "EUR", dateLayout: "02-01-2006", names: dutchNames},
+	{tag: "nl-BE", decimalSep: ",", groupSep: ".", currencyPattern: "¤ #", currency: "EUR", dateLayout: "02/01/2006", names: dutchNames},
+	{tag: "pt-BR", decimalSep: ",", groupSep: ".", currencyPattern: "¤ #", currency: "BRL", dateLayout: "02/01/2006", names: portugueseNames},
+	{tag: "pt-PT", decimalSep: ",", groupSep: "\u00a0", currencyPattern: "# ¤", currency: "EUR", dateLayout: "02/01/2006", names: portugueseNames},
+	{tag: "pl-PL", decimalSep: ",", groupSep: "\u00a0", currencyPattern: "# ¤", currency: "PLN", dateLayout: "02.01.2006", names: polishNames},
+	{tag: "sv-SE", decimalSep: ",", groupSep: "\u00a0", currencyPattern: "# ¤", currency: "SEK", dateLayout: "2006-01-02", names: swedishNames},
+	{tag: "nb-NO", decimalSep: ",", groupSep: "\u00a0", currencyPattern: "# ¤", currency: "NOK", dateLayout: "02.01.2006", names: norwegianNames},
+	{tag: "da-DK", decimalSep: ",", groupSep: ".", currencyPattern: "# ¤", currency: "DKK", dateLayout: "02.01.2006", names: danishNames},
+	{tag: "fi-FI", decimalSep: ",", groupSep: "\u00a0", currencyPattern: "# ¤", currency: "EUR", dateLayout: "2.1.2006", names: finnishNames},
+	{tag: "ja-JP", decimalSep: ".", groupSep: ",", currencyPattern: "¤#", currency: "JPY", currencySymbol: "￥", dateLayout: "2006/01/02", names: japaneseNames},
+	{tag: "zh-CN", decimalSep: ".", groupSep: ",", currencyPattern: "¤#", currency: "CNY", dateLayout: "2006/01/02", names: chineseNames},
+}
+
+// lookupLocale finds a locale by tag, ignoring case and accepting "_" for "-".
+// A bare language such as "fr" selects the first locale of that language.
+// The empty tag selects the neutral locale.
+func lookupLocale(tag string) (locale, error) {
+	tag = strings.ReplaceAll(strings.TrimSpace(tag), "_", "-")
+	if tag == "" {
+		return neutralLocale, nil
+	}
+
+	for _, l := range locales {
+		if strings.EqualFold(l.tag, tag) {
+			return l, nil
+		}
+	}
+	for _, l := range locales {
+		language, _, _ := strings.Cut(l.tag, "-")
+		if strings.EqualFold(language, tag) {
+			return l, nil
+		}
+	}
+	return locale{}, fmt.Errorf("unknown locale %q (supported: %s)", tag, strings.Join(SupportedLocales(), ", "))
+}
+
+// SupportedLocales returns the tags of all supported locales, sorted.
+func SupportedLocales() []string {
+	tags := make([]string, 0, len(locales))
+	for _, l := range locales {
+		tags = append(tags, l.tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// formatAmount writes number in the given currency following the locale's conventions.
+func (l locale) formatAmount(number float64, code string) string {
+	info, known := currencies[code]
+	if !known {
+		info = currencyInfo{symbol: code, decimals: 2}
+	}
+	if code == l.currency && l.currencySymbol != "" {
+		info.symbol = l.currencySymbol
+	}
+
+	amount := formatNumber(number, info.decimals, l.groupSep, l.decimalSep)
+	sign := ""
+	if strings.HasPrefix(amount, "-") {
+		sign, amount = "-", amount[1:]
+	}
+
+	if l.currencyPattern != "" {
+		return sign + strings.NewReplacer("¤", info.symbol, "#", amount).Replace(l.currencyPattern)
+	}
+
+	// Alphabetic symbols (CHF, kr, unknown codes) are separated from the amount
+	if r := []rune(info.symbol); len(r) > 0 && unicode.IsLetter(r[0]) && !strings.HasSuffix(info.symbol, "$") {
+		return sign + info.symbol + " " + amount
+	}
+	return sign + info.symbol + amount
+}
+
+// formatDate formats t with a Go layout, writing month and weekday names in the locale's language.
+func (l locale) formatDate(t time.Time, layout string) string {
+	if l.names == nil || l.names == englishNames {
+		return t.Format(layout)
+	}
+
+	var b strings.Builder
+	start := 0
+	for i := 0; i < len(layout); {
+		name, length := l.nameToken(layout[i:], t)
+		if length == 0 {
+			i++
+			continue
+		}
+		b.WriteString(t.Format(layout[start:i]))
+		b.WriteString(name)
+		i += length
+		start = i
+	}
+	b.WriteString(t.Format(layout[start:]))
+	return b.String()
+}
+
+// nameToken recognizes the month and weekday elements of a Go layout at the start of s,
+// returning the localized name and the length of the element, or 0 if there is none.
+func (l locale) nameToken(s string, t time.Time) (string, int) {
+	switch {
+	case strings.HasPrefix(s, "January"):
+		return l.names.months[t.Month()-1], len("January")
+	case strings.HasPrefix(s, "Monday"):
+		return l.names.days[t.Weekday()], len("Monday")
+	case strings.HasPrefix(s, "Jan") && !startsWithLower(s[3:]):
+		return l.names.shortMonths[t.Month()-1], len("Jan")
+	case strings.HasPrefix(s, "Mon") && !startsWithLower(s[3:]):
+		return l.names.shortDays[t.Weekday()], len("Mon")
+	}
+	return "", 0
+}
+
+// startsWithLower mirrors the time package: "Jan" and "Mon" are only layout
+// elements when not followed by a lowercase letter, so "Month" stays literal.
+func startsWithLower(s string) bool {
+	return s != "" && 'a' <= s[0] && s[0] <= 'z'
+}
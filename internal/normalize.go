@@ -0,0 +1,189 @@
+package internal
+
+import (
+	"archive/zip"
+	"regexp"
+	"strings"
+)
+
+var (
+	// Revision session IDs Word stamps on paragraphs, runs, rows and sections
+	wordRsidAttrPattern = regexp.MustCompile(` w:rsid\w*="[^"]*"`)
+	// Spell/grammar check markers and cached page break positions
+	wordNoisePattern = regexp.MustCompile(`<w:proofErr\b[^>]*/>|<w:lastRenderedPageBreak/>`)
+	// A run holding nothing but optional properties and a single text element. The properties
+	// may only hold empty elements, so a match never reaches into the next run.
+	wordTextRunPattern = regexp.MustCompile(`<w:r>(<w:rPr>(?:<[^>]*/>)*</w:rPr>)?<w:t(?:\s[^>]*)?>([^<]*)</w:t></w:r>`)
+
+	// Proofing and editing state PowerPoint keeps on run properties
+	drawingNoiseAttrPattern = regexp.MustCompile(` (?:dirty|err|smtClean|smtId)="[^"]*"`)
+	drawingRunPropsPattern  = regexp.MustCompile(`<a:(?:rPr|endParaRPr)\b[^>]*>`)
+	// A DrawingML run holding nothing but optional properties and a single text element
+	drawingTextRunPattern = regexp.MustCompile(`<a:r>(<a:rPr\b[^>]*/>|<a:rPr\b[^>]*>(?:.*?)</a:rPr>)?<a:t>([^<]*)</a:t></a:r>`)
+)
+
+// NormalizeDocxZipFile normalizes a single file from a DOCX zip archive and writes it to the output zip.
+// It returns the placeholders of syntax that were split across several runs before normalization.
+func NormalizeDocxZipFile(file *zip.File, zipWriter *zip.Writer, syntax Syntax) ([]string, error) {
+	var split []string
+	err := ProcessZipFile(file, zipWriter, IsWordStoryPart, func(fileName string, content []byte) []byte {
+		normalized, found := normalizeWordXML(string(content), syntax)
+		split = found
+		return []byte(normalized)
+	})
+	return split, err
+}
+
+// NormalizePptxZipFile normalizes a single file from a PPTX zip archive and writes it to the output zip.
+// It returns the placeholders of syntax that were split across several runs before normalization.
+func NormalizePptxZipFile(file *zip.File, zipWriter *zip.Writer, syntax Syntax) ([]string, error) {
+	var split []string
+	err := ProcessZipFile(file, zipWriter, isSlidePart, func(fileName string, content []byte) []byte {
+		normalized, found := normalizeSlideXML(string(content), syntax)
+		split = found
+		return []byte(normalized)
+	})
+	return split, err
+}
+
+// isSlidePart reports whether a PPTX part is a slide.
+func isSlidePart(fileName string) bool {
+	return strings.HasPrefix(fileName, "ppt/slides/slide") && strings.HasSuffix(fileName, ".xml")
+}
+
+// IsWordStoryPart reports whether a DOCX part holds document text (body, headers, footers, notes).
+func IsWordStoryPart(fileName string) bool {
+	if !strings.HasPrefix(fileName, "word/") || !strings.HasSuffix(fileName, ".xml") || strings.Count(fileName, "/") != 1 {
+		return false
+	}
+	name := strings.TrimSuffix(strings.TrimPrefix(fileName, "word/"), ".xml")
+	switch {
+	case name == "document", name == "footnotes", name == "endnotes":
+		return true
+	case strings.HasPrefix(name, "header"), strings.HasPrefix(name, "footer"):
+		return true
+	}
+	return false
+}
+
+func normalizeWordXML(xmlContent string, syntax Syntax) (string, []string) {
+	split := findSplitPlaceholders(splitIntoParagraphs(xmlContent), wordTextPattern, syntax)
+
+	xmlContent = wordRsidAttrPattern.ReplaceAllString(xmlContent, "")
+	xmlContent = wordNoisePattern.ReplaceAllString(xmlContent, "")
+
+	paragraphs := splitIntoParagraphs(xmlContent)
+	for i, paragraph := range paragraphs {
+		paragraphs[i] = mergeTextRuns(paragraph, wordTextRunPattern, func(props, text string) string {
+			openTag := "<w:t>"
+			if needsSpacePreserve(openTag, text) {
+				openTag = addSpacePreserve(openTag)
+			}
+			return "<w:r>" + props + openTag + text + "</w:t></w:r>"
+		})
+	}
+	return strings.Join(paragraphs, ""), split
+}
+
+func normalizeSlideXML(xmlContent string, syntax Syntax) (string, []string) {
+	split := findSplitPlaceholders(splitIntoTextFrames(xmlContent), drawingTextPattern, syntax)
+
+	frames := splitIntoTextFrames(xmlContent)
+	for i, frame := range frames {
+		if !strings.HasPrefix(frame, "<a:p") {
+			continue
+		}
+		frames[i] = mergeTextRuns(stripDrawingRunNoise(frame), drawingTextRunPattern, func(props, text string) string {
+			return "<a:r>" + props + "<a:t>" + text + "</a:t></a:r>"
+		})
+	}
+	return strings.Join(frames, ""), split
+}
+
+// stripDrawingRunNoise removes proofing attributes from <a:rPr> and <a:endParaRPr> elements only.
+func stripDrawingRunNoise(frame string) string {
+	return drawingRunPropsPattern.ReplaceAllStringFunc(frame, func(tag string) string {
+		return drawingNoiseAttrPattern.ReplaceAllString(tag, "")
+	})
+}
+
+// mergeTextRuns joins directly adjacent text-only runs that share identical run properties.
+// runPattern must capture the run properties in its first group and the text in its second.
+func mergeTextRuns(paragraph string, runPattern *regexp.Regexp, buildRun func(props, text string) string) string {
+	matches := runPattern.FindAllStringSubmatchIndex(paragraph, -1)
+	if len(matches) < 2 {
+		return paragraph
+	}
+
+	var result strings.Builder
+	lastEnd := 0
+
+	for i := 0; i < len(matches); {
+		props := submatch(paragraph, matches[i], 1)
+		text := submatch(paragraph, matches[i], 2)
+
+		j := i + 1
+		for j < len(matches) && matches[j][0] == matches[j-1][1] && submatch(paragraph, matches[j], 1) == props {
+			text += submatch(paragraph, matches[j], 2)
+			j++
+		}
+
+		result.WriteString(paragraph[lastEnd:matches[i][0]])
+		if j == i+1 {
+			result.WriteString(paragraph[matches[i][0]:matches[i][1]])
+		} else {
+			result.WriteString(buildRun(props, text))
+		}
+		lastEnd = matches[j-1][1]
+		i = j
+	}
+
+	result.WriteString(paragraph[lastEnd:])
+	return result.String()
+}
+
+func submatch(s string, match []int, group int) string {
+	if match[2*group] < 0 {
+		return ""
+	}
+	return s[match[2*group]:match[2*group+1]]
+}
+
+// findSplitPlaceholders returns the placeholders whose text is spread over more than one text element.
+func findSplitPlaceholders(paragraphs []string, textPattern *regexp.Regexp, syntax Syntax) []string {
+	var split []string
+	for _, paragraph := range paragraphs {
+		nodes := FindTextNodes(paragraph, textPattern)
+		if len(nodes) < 2 {
+			continue
+		}
+
+		var plain strings.Builder
+		nodeEnds := make([]int, len(nodes))
+		for i, node := range nodes {
+			plain.WriteString(UnescapeXMLText(paragraph[node.ContentStart:node.ContentEnd]))
+			nodeEnds[i] = plain.Len()
+		}
+
+		plainText := plain.String()
+		pos := 0
+		for _, placeholder := range syntax.FindPlaceholders(plainText) {
+			start := pos + strings.Index(plainText[pos:], placeholder)
+			pos = start + len(placeholder)
+			if nodeIndexAt(nodeEnds, start) != nodeIndexAt(nodeEnds, pos-1) {
+				split = append(split, placeholder)
+			}
+		}
+	}
+	return split
+}
+
+// nodeIndexAt returns the index of the text node holding the plain text offset pos.
+func nodeIndexAt(nodeEnds []int, pos int) int {
+	for i, end := range nodeEnds {
+		if pos < end {
+			return i
+		}
+	}
+	return len(nodeEnds) - 1
+}
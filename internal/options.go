@@ -0,0 +1,130 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Options controls how templates are rendered.
+type Options struct {
+	// Syntax sets the placeholder delimiters and key rules. The zero value uses {{KEY}}.
+	Syntax Syntax
+
+	// Filters registers custom placeholder filters, e.g. {{NAME | initials}}.
+	// They are used alongside the built-in filters and replace built-ins of the same name.
+	Filters FuncMap
+
+	// Locale selects how the number, currency and date filters write values, e.g. "de-DE"
+	// renders {{TOTAL | currency}} as "1.234,50 €". Empty keeps the neutral formatting.
+	Locale string
+
+	// LocaleField names a record field holding the locale of each record in batch processing,
+	// e.g. "LANG". Records without a value for it use Locale.
+	LocaleField string
+
+	// IncludeDir is the directory the paths of {{include:clauses/liability.docx}} are resolved
+	// against. Empty uses the directory of the template.
+	IncludeDir string
+
+	// IncludeOutsideDir allows include paths leading outside IncludeDir, such as absolute paths
+	// or paths starting with "../". Leave it unset when templates come from untrusted sources.
+	IncludeOutsideDir bool
+
+	// IncludeAltChunk embeds included documents as altChunk parts, which Word merges into the
+	// document when it is opened, instead of merging their content, styles and lists.
+	IncludeAltChunk bool
+
+	// MergePageBreaks separates the copies of a merged document with page breaks instead of
+	// section breaks; all copies then use the page setup, headers and footers of the last section.
+	MergePageBreaks bool
+
+	// Properties sets document properties on each output, e.g. {"title": "Contract {{ID}}",
+	// "ContractId": "{{ID}}"}. Values are templates rendered with the record's data; see
+	// SetProperties for the names.
+	Properties map[string]string
+
+	// Revisions resolves the tracked changes of DOCX templates before rendering: "accept" keeps
+	// the document as it reads with the changes applied, "reject" as it read before them. Empty
+	// leaves them as they are.
+	Revisions string
+
+	// TrackChanges writes each replacement in DOCX documents as a tracked change: the
+	// placeholder deleted and the value inserted, so reviewers can accept or reject what was
+	// generated in Word. Rich values are written as plain text.
+	TrackChanges bool
+
+	// RevisionAuthor and RevisionDate are recorded on the tracked changes. Empty uses
+	// "OfficeForge" and the time of rendering.
+	RevisionAuthor string
+	RevisionDate   time.Time
+
+	// CompressionLevel sets the deflate level of the parts rewritten by rendering, from 1
+	// (fastest) to 9 (smallest). Zero uses the default level. Parts left as they are, such as
+	// media, are always copied without being recompressed.
+	CompressionLevel int
+}
+
+// Validate checks that the options can be used for rendering.
+func (o Options) Validate() error {
+	if err := o.Syntax.Validate(); err != nil {
+		return fmt.Errorf("invalid placeholder syntax: %v", err)
+	}
+	if _, err := lookupLocale(o.Locale); err != nil {
+		return err
+	}
+	for name, filter := range o.Filters {
+		if !isFilterName(name) {
+			return fmt.Errorf("invalid filter name %q", name)
+		}
+		if filter == nil {
+			return fmt.Errorf("filter %q has no implementation", name)
+		}
+	}
+	if o.CompressionLevel < 0 || o.CompressionLevel > 9 {
+		return fmt.Errorf("invalid compression level %d (use 1 to 9, or 0 for the default)", o.CompressionLevel)
+	}
+	switch o.Revisions {
+	case "", "accept", "reject":
+	default:
+		return fmt.Errorf("invalid revisions mode %q (use \"accept\" or \"reject\")", o.Revisions)
+	}
+	for name := range o.Properties {
+		if err := ValidatePropertyName(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ForRecord returns the options for rendering a single record, taking the locale from the
+// record's LocaleField when it has a value.
+func (o Options) ForRecord(record map[string]string) Options {
+	if o.LocaleField == "" {
+		return o
+	}
+
+	value, ok := record[o.LocaleField]
+	if !ok {
+		value = record[o.Syntax.NormalizeKey(o.LocaleField)]
+	}
+	if value = strings.TrimSpace(value); value != "" {
+		o.Locale = value
+	}
+	return o
+}
+
+// filterFuncs returns the built-in filters for the selected locale merged with the custom ones.
+// An unknown locale falls back to the neutral formatting; Validate reports it.
+func (o Options) filterFuncs() FuncMap {
+	loc, err := lookupLocale(o.Locale)
+	if err != nil {
+		loc = neutralLocale
+	}
+
+	funcs := builtinFilters(loc)
+	for name, filter := range o.Filters {
+		funcs[name] = filter
+	}
+	return funcs
+}
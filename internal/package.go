@@ -0,0 +1,302 @@
+package internal
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// Package is an Office Open XML package (DOCX, PPTX or XLSX) opened for editing.
+// Parts are read on demand and kept in memory once changed; parts that are never
+// written are copied straight from the source archive when the package is saved,
+// without being decompressed.
+type Package struct {
+	closer io.Closer
+	order  []string
+	files  map[string]*zip.File
+	parts  map[string][]byte
+	level  int // deflate level of written parts, 0 for the default
+}
+
+// OpenPackage opens the package at path. Close must be called when done.
+func OpenPackage(path string) (*Package, error) {
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	pkg := newPackage(reader.File)
+	pkg.closer = reader
+	return pkg, nil
+}
+
+// ReadPackage reads a package from r into memory.
+func ReadPackage(r io.Reader) (*Package, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, err
+	}
+	return newPackage(reader.File), nil
+}
+
+func newPackage(files []*zip.File) *Package {
+	pkg := &Package{
+		files: make(map[string]*zip.File, len(files)),
+		parts: make(map[string][]byte),
+	}
+	for _, file := range files {
+		if _, exists := pkg.files[file.Name]; exists {
+			continue
+		}
+		pkg.order = append(pkg.order, file.Name)
+		pkg.files[file.Name] = file
+	}
+	return pkg
+}
+
+// Close releases the source archive.
+func (p *Package) Close() error {
+	if p.closer == nil {
+		return nil
+	}
+	return p.closer.Close()
+}
+
+// clone returns a copy of the package that can be changed independently. It reads from the
+// same source archive, so it is only valid until the package is closed.
+func (p *Package) clone() *Package {
+	c := &Package{
+		order: make([]string, len(p.order)),
+		files: make(map[string]*zip.File, len(p.files)),
+		parts: make(map[string][]byte, len(p.parts)),
+		level: p.level,
+	}
+	copy(c.order, p.order)
+	for name, file := range p.files {
+		c.files[name] = file
+	}
+	for name, content := range p.parts {
+		c.parts[name] = content
+	}
+	return c
+}
+
+// SetCompressionLevel sets the deflate level of the parts written to the package, from 1
+// (fastest) to 9 (smallest); 0 uses the default level.
+func (p *Package) SetCompressionLevel(level int) {
+	p.level = level
+}
+
+// Names returns the part names in archive order, followed by parts added since opening.
+func (p *Package) Names() []string {
+	names := make([]string, len(p.order))
+	copy(names, p.order)
+	return names
+}
+
+// Has reports whether the package contains a part.
+func (p *Package) Has(name string) bool {
+	if _, ok := p.parts[name]; ok {
+		return true
+	}
+	_, ok := p.files[name]
+	return ok
+}
+
+// Read returns the content of a part.
+func (p *Package) Read(name string) ([]byte, error) {
+	if content, ok := p.parts[name]; ok {
+		return content, nil
+	}
+
+	file, ok := p.files[name]
+	if !ok {
+		return nil, fmt.Errorf("part %s not found", name)
+	}
+
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return io.ReadAll(rc)
+}
+
+// Write replaces the content of a part, adding it if it doesn't exist yet.
+func (p *Package) Write(name string, content []byte) {
+	if !p.Has(name) {
+		p.order = append(p.order, name)
+	}
+	p.parts[name] = content
+}
+
+// Delete removes a part from the package.
+func (p *Package) Delete(name string) {
+	if !p.Has(name) {
+		return
+	}
+	for i, existing := range p.order {
+		if existing == name {
+			p.order = append(p.order[:i:i], p.order[i+1:]...)
+			break
+		}
+	}
+	delete(p.parts, name)
+	delete(p.files, name)
+}
+
+// Save writes the package to outputPath.
+func (p *Package) Save(outputPath string) error {
+	outputFile, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %v", err)
+	}
+
+	if err := p.SaveTo(outputFile); err != nil {
+		outputFile.Close()
+		return err
+	}
+	return outputFile.Close()
+}
+
+// SaveTo writes the package as a zip archive to w. Parts that are unchanged are copied in their
+// compressed form with their original headers; rewritten parts keep the compression method,
+// modification time, comment and extra fields of the part they replace.
+func (p *Package) SaveTo(w io.Writer) error {
+	zipWriter := zip.NewWriter(w)
+	if p.level != 0 {
+		level := p.level
+		zipWriter.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+			return flate.NewWriter(out, level)
+		})
+	}
+
+	for _, name := range p.order {
+		file := p.files[name]
+		content, changed := p.parts[name]
+		if !changed || (file != nil && sameZipContent(file, content)) {
+			if err := zipWriter.Copy(file); err != nil {
+				return fmt.Errorf("failed to copy %s: %v", name, err)
+			}
+			continue
+		}
+
+		writer, err := zipWriter.CreateHeader(partHeader(name, file))
+		if err != nil {
+			return err
+		}
+		if _, err := writer.Write(content); err != nil {
+			return err
+		}
+	}
+	return zipWriter.Close()
+}
+
+// partHeader returns the zip header for writing a part. New parts are deflated; a part that
+// replaces file keeps its header fields, except for the sizes and checksum.
+func partHeader(name string, file *zip.File) *zip.FileHeader {
+	if file == nil {
+		return &zip.FileHeader{Name: name, Method: zip.Deflate}
+	}
+
+	header := &zip.FileHeader{
+		Name:           name,
+		Comment:        file.Comment,
+		NonUTF8:        file.NonUTF8,
+		CreatorVersion: file.CreatorVersion,
+		Method:         file.Method,
+		ExternalAttrs:  file.ExternalAttrs,
+		Extra:          withoutZip64Extra(file.Extra),
+	}
+	// The MS-DOS time is copied as it is; setting Modified would add a second timestamp field
+	// to the extra fields
+	header.ModifiedTime = file.ModifiedTime
+	header.ModifiedDate = file.ModifiedDate
+	if header.Method != zip.Store {
+		header.Method = zip.Deflate
+	}
+	return header
+}
+
+// withoutZip64Extra returns extra fields without the zip64 field, which the writer adds itself
+// when a part needs it.
+func withoutZip64Extra(extra []byte) []byte {
+	var kept []byte
+	for len(extra) >= 4 {
+		tag := binary.LittleEndian.Uint16(extra)
+		size := 4 + int(binary.LittleEndian.Uint16(extra[2:]))
+		if size > len(extra) {
+			break
+		}
+		if tag != 0x0001 {
+			kept = append(kept, extra[:size]...)
+		}
+		extra = extra[size:]
+	}
+	return kept
+}
+
+// sameZipContent reports whether content is what file holds, by size and checksum.
+func sameZipContent(file *zip.File, content []byte) bool {
+	return file.UncompressedSize64 == uint64(len(content)) && file.CRC32 == crc32.ChecksumIEEE(content)
+}
+
+// sharedParts tells which parts of src another package dst has as well: a part of the same
+// name and content, relating to the same parts.
+type sharedParts struct {
+	src, dst *Package
+	same     map[string]bool
+}
+
+func newSharedParts(src, dst *Package) *sharedParts {
+	return &sharedParts{src: src, dst: dst, same: make(map[string]bool)}
+}
+
+func (s *sharedParts) has(name string) bool {
+	if same, ok := s.same[name]; ok {
+		return same
+	}
+	s.same[name] = false // until proven, also for parts relating to each other
+	if !s.dst.Has(name) || !s.sameContent(name) {
+		return false
+	}
+
+	relsName := RelsPartName(name)
+	if s.src.Has(relsName) != s.dst.Has(relsName) {
+		return false
+	}
+	if s.src.Has(relsName) {
+		if !s.sameContent(relsName) {
+			return false
+		}
+		rels, err := s.src.readRelationships(name)
+		if err != nil {
+			return false
+		}
+		for _, rel := range rels {
+			if !rel.external && !s.has(resolveTarget(name, rel.target)) {
+				return false
+			}
+		}
+	}
+	s.same[name] = true
+	return true
+}
+
+func (s *sharedParts) sameContent(name string) bool {
+	srcContent, err := s.src.Read(name)
+	if err != nil {
+		return false
+	}
+	dstContent, err := s.dst.Read(name)
+	return err == nil && bytes.Equal(srcContent, dstContent)
+}
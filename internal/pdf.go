@@ -0,0 +1,376 @@
+package internal
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // decoders for pictures in documents
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// pdfDocument builds a PDF file: pages with their content streams, and the fonts and images
+// they use. Text is encoded in WinAnsiEncoding, so simple fonts cover Western European text.
+type pdfDocument struct {
+	pages  []*pdfPage
+	fonts  []*pdfFont
+	images []*pdfImage
+	title  string
+}
+
+// pdfFont is a standard font, or a TrueType font embedded in the PDF.
+type pdfFont struct {
+	resource string
+	base     string
+	widths   [256]float64 // in thousandths of the font size, by WinAnsiEncoding code
+	ascent   float64      // in thousandths of the font size
+	descent  float64      // in thousandths of the font size, positive
+	ttf      *trueTypeFont
+}
+
+// width returns the width of encoded text at a font size.
+func (f *pdfFont) width(text []byte, size float64) float64 {
+	total := 0.0
+	for _, code := range text {
+		total += f.widths[code]
+	}
+	return total * size / 1000
+}
+
+// pdfImage is a picture XObject; mask holds the alpha channel of pictures with transparency.
+type pdfImage struct {
+	resource   string
+	width      int
+	height     int
+	colorSpace string
+	filter     string
+	decode     string
+	data       []byte
+	mask       *pdfImage
+}
+
+// pdfPage is a page and the drawing operators of its content.
+type pdfPage struct {
+	width   float64
+	height  float64
+	content bytes.Buffer
+	fonts   map[*pdfFont]bool
+	images  map[*pdfImage]bool
+}
+
+func (d *pdfDocument) addPage(width, height float64) *pdfPage {
+	page := &pdfPage{width: width, height: height, fonts: make(map[*pdfFont]bool), images: make(map[*pdfImage]bool)}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// addStandardFont adds one of the 14 standard fonts, which viewers provide.
+func (d *pdfDocument) addStandardFont(base string, widths [256]float64) *pdfFont {
+	font := &pdfFont{resource: "F" + strconv.Itoa(len(d.fonts)+1), base: base, widths: widths, ascent: 905, descent: 245}
+	if strings.HasPrefix(base, "Times") {
+		font.ascent, font.descent = 891, 259
+	}
+	d.fonts = append(d.fonts, font)
+	return font
+}
+
+// addTrueTypeFont adds a TrueType font to embed, named after name if the font has no
+// PostScript name.
+func (d *pdfDocument) addTrueTypeFont(ttf *trueTypeFont, name string) *pdfFont {
+	base := ttf.name
+	if base == "" {
+		base = name
+	}
+	base = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || strings.ContainsRune("()<>[]{}/%#", r) {
+			return -1
+		}
+		return r
+	}, base)
+	font := &pdfFont{
+		resource: "F" + strconv.Itoa(len(d.fonts)+1),
+		base:     base,
+		widths:   ttf.widths,
+		ascent:   ttf.ascent,
+		descent:  -ttf.descent,
+		ttf:      ttf,
+	}
+	d.fonts = append(d.fonts, font)
+	return font
+}
+
+// addImage adds a JPEG, PNG or GIF picture. JPEG data is embedded as it is; other pictures
+// are decoded and compressed.
+func (d *pdfDocument) addImage(data []byte) (*pdfImage, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	img := &pdfImage{resource: "Im" + strconv.Itoa(len(d.images)+1), width: config.Width, height: config.Height}
+
+	if format == "jpeg" {
+		img.filter, img.data, img.colorSpace = "DCTDecode", data, "DeviceRGB"
+		switch config.ColorModel {
+		case color.GrayModel:
+			img.colorSpace = "DeviceGray"
+		case color.CMYKModel:
+			// Adobe applications write CMYK JPEGs inverted
+			img.colorSpace, img.decode = "DeviceCMYK", "[1 0 1 0 1 0 1 0]"
+		}
+		d.images = append(d.images, img)
+		return img, nil
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	bounds := decoded.Bounds()
+	pixels := make([]byte, 0, 3*bounds.Dx()*bounds.Dy())
+	alpha := make([]byte, 0, bounds.Dx()*bounds.Dy())
+	opaque := true
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(decoded.At(x, y)).(color.NRGBA)
+			pixels = append(pixels, c.R, c.G, c.B)
+			alpha = append(alpha, c.A)
+			opaque = opaque && c.A == 0xFF
+		}
+	}
+	img.filter, img.colorSpace, img.data = "FlateDecode", "DeviceRGB", deflate(pixels)
+	if !opaque {
+		img.mask = &pdfImage{width: img.width, height: img.height, colorSpace: "DeviceGray", filter: "FlateDecode", data: deflate(alpha)}
+	}
+	d.images = append(d.images, img)
+	return img, nil
+}
+
+// text draws encoded text with its baseline starting at (x, y).
+func (p *pdfPage) text(font *pdfFont, size, x, y float64, rgb [3]float64, text []byte) {
+	p.fonts[font] = true
+	fmt.Fprintf(&p.content, "BT %s rg /%s %s Tf 1 0 0 1 %s %s Tm %s Tj ET\n",
+		pdfColor(rgb), font.resource, pdfNumber(size), pdfNumber(x), pdfNumber(y), pdfString(text))
+}
+
+// line draws a line from (x1, y1) to (x2, y2).
+func (p *pdfPage) line(x1, y1, x2, y2, width float64, rgb [3]float64) {
+	fmt.Fprintf(&p.content, "%s RG %s w %s %s m %s %s l S\n",
+		pdfColor(rgb), pdfNumber(width), pdfNumber(x1), pdfNumber(y1), pdfNumber(x2), pdfNumber(y2))
+}
+
+// fill paints a rectangle whose lower left corner is (x, y).
+func (p *pdfPage) fill(x, y, width, height float64, rgb [3]float64) {
+	fmt.Fprintf(&p.content, "%s rg %s %s %s %s re f\n",
+		pdfColor(rgb), pdfNumber(x), pdfNumber(y), pdfNumber(width), pdfNumber(height))
+}
+
+// image draws a picture with its lower left corner at (x, y).
+func (p *pdfPage) image(img *pdfImage, x, y, width, height float64) {
+	p.images[img] = true
+	fmt.Fprintf(&p.content, "q %s 0 0 %s %s %s cm /%s Do Q\n",
+		pdfNumber(width), pdfNumber(height), pdfNumber(x), pdfNumber(y), img.resource)
+}
+
+// write writes the document as a PDF file.
+func (d *pdfDocument) write(w io.Writer) error {
+	var out bytes.Buffer
+	var offsets []int
+	// Objects are numbered in the order they're reserved and written in that order too
+	reserve := func() int {
+		offsets = append(offsets, 0)
+		return len(offsets)
+	}
+	begin := func(id int) {
+		offsets[id-1] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n", id)
+	}
+	stream := func(id int, dict string, data []byte) {
+		begin(id)
+		fmt.Fprintf(&out, "<< %s /Length %d >>\nstream\n", dict, len(data))
+		out.Write(data)
+		out.WriteString("\nendstream\nendobj\n")
+	}
+
+	out.WriteString("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n")
+	catalog, pagesID, info := reserve(), reserve(), reserve()
+
+	fontIDs := make(map[*pdfFont]int)
+	for _, font := range d.fonts {
+		fontIDs[font] = reserve()
+	}
+	imageIDs := make(map[*pdfImage]int)
+	for _, img := range d.images {
+		imageIDs[img] = reserve()
+		if img.mask != nil {
+			imageIDs[img.mask] = reserve()
+		}
+	}
+	pageIDs := make([]int, len(d.pages))
+	for i := range d.pages {
+		pageIDs[i] = reserve()
+		reserve() // the page's content
+	}
+
+	begin(catalog)
+	fmt.Fprintf(&out, "<< /Type /Catalog /Pages %d 0 R >>\nendobj\n", pagesID)
+	begin(pagesID)
+	kids := make([]string, len(pageIDs))
+	for i, id := range pageIDs {
+		kids[i] = fmt.Sprintf("%d 0 R", id)
+	}
+	fmt.Fprintf(&out, "<< /Type /Pages /Kids [%s] /Count %d >>\nendobj\n", strings.Join(kids, " "), len(pageIDs))
+	begin(info)
+	out.WriteString("<< /Producer (OfficeForge)")
+	if d.title != "" {
+		out.WriteString(" /Title " + pdfTextString(d.title))
+	}
+	out.WriteString(" >>\nendobj\n")
+
+	for _, font := range d.fonts {
+		id := fontIDs[font]
+		if font.ttf == nil {
+			begin(id)
+			fmt.Fprintf(&out, "<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>\nendobj\n", font.base)
+			continue
+		}
+		descriptor, file := reserve(), reserve()
+		widths := make([]string, 0, 224)
+		for code := 32; code < 256; code++ {
+			widths = append(widths, pdfNumber(font.widths[code]))
+		}
+		begin(id)
+		fmt.Fprintf(&out, "<< /Type /Font /Subtype /TrueType /BaseFont /%s /FirstChar 32 /LastChar 255 /Widths [%s] /FontDescriptor %d 0 R /Encoding /WinAnsiEncoding >>\nendobj\n",
+			font.base, strings.Join(widths, " "), descriptor)
+		ttf := font.ttf
+		flags := 32 // nonsymbolic
+		if ttf.italicAngle != 0 {
+			flags |= 64
+		}
+		stemV := 80
+		if ttf.bold {
+			stemV = 140
+		}
+		capHeight := ttf.capHeight
+		if capHeight == 0 {
+			capHeight = ttf.ascent
+		}
+		begin(descriptor)
+		fmt.Fprintf(&out, "<< /Type /FontDescriptor /FontName /%s /Flags %d /FontBBox [%s %s %s %s] /ItalicAngle %s /Ascent %s /Descent %s /CapHeight %s /StemV %d /FontFile2 %d 0 R >>\nendobj\n",
+			font.base, flags, pdfNumber(ttf.bbox[0]), pdfNumber(ttf.bbox[1]), pdfNumber(ttf.bbox[2]), pdfNumber(ttf.bbox[3]),
+			pdfNumber(ttf.italicAngle), pdfNumber(ttf.ascent), pdfNumber(ttf.descent), pdfNumber(capHeight), stemV, file)
+		stream(file, fmt.Sprintf("/Filter /FlateDecode /Length1 %d", len(ttf.data)), deflate(ttf.data))
+	}
+
+	for _, img := range d.images {
+		for _, picture := range []*pdfImage{img, img.mask} {
+			if picture == nil {
+				continue
+			}
+			dict := fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /%s /BitsPerComponent 8 /Filter /%s",
+				picture.width, picture.height, picture.colorSpace, picture.filter)
+			if picture.decode != "" {
+				dict += " /Decode " + picture.decode
+			}
+			if picture.mask != nil {
+				dict += fmt.Sprintf(" /SMask %d 0 R", imageIDs[picture.mask])
+			}
+			stream(imageIDs[picture], dict, picture.data)
+		}
+	}
+
+	for i, page := range d.pages {
+		var resources strings.Builder
+		resources.WriteString("<< /Font <<")
+		for _, font := range d.fonts {
+			if page.fonts[font] {
+				fmt.Fprintf(&resources, " /%s %d 0 R", font.resource, fontIDs[font])
+			}
+		}
+		resources.WriteString(" >> /XObject <<")
+		for _, img := range d.images {
+			if page.images[img] {
+				fmt.Fprintf(&resources, " /%s %d 0 R", img.resource, imageIDs[img])
+			}
+		}
+		resources.WriteString(" >> >>")
+
+		begin(pageIDs[i])
+		fmt.Fprintf(&out, "<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources %s /Contents %d 0 R >>\nendobj\n",
+			pagesID, pdfNumber(page.width), pdfNumber(page.height), resources.String(), pageIDs[i]+1)
+		stream(pageIDs[i]+1, "/Filter /FlateDecode", deflate(page.content.Bytes()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, catalog, info, xref)
+
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+func deflate(data []byte) []byte {
+	var b bytes.Buffer
+	writer := zlib.NewWriter(&b)
+	writer.Write(data)
+	writer.Close()
+	return b.Bytes()
+}
+
+// pdfNumber writes a number with at most two decimals.
+func pdfNumber(n float64) string {
+	s := strconv.FormatFloat(n, 'f', 2, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+func pdfColor(rgb [3]float64) string {
+	return pdfNumber(rgb[0]) + " " + pdfNumber(rgb[1]) + " " + pdfNumber(rgb[2])
+}
+
+// pdfString writes bytes as a literal string.
+func pdfString(text []byte) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for _, c := range text {
+		switch c {
+		case '(', ')', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\n':
+			b.WriteString(`\n`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// pdfTextString writes text for the document information as a UTF-16 string.
+func pdfTextString(text string) string {
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, r := range text {
+		if r > 0xFFFF {
+			r -= 0x10000
+			fmt.Fprintf(&b, "%04X%04X", 0xD800+(r>>10), 0xDC00+(r&0x3FF))
+			continue
+		}
+		fmt.Fprintf(&b, "%04X", r)
+	}
+	b.WriteString(">")
+	return b.String()
+}
@@ -0,0 +1,381 @@
+package internal
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Widths of the printable ASCII characters (32 to 126) in the standard fonts, in thousandths
+// of the font size, from their Adobe font metrics. Oblique variants share the upright widths.
+var (
+	helveticaWidths = []int{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	}
+	helveticaBoldWidths = []int{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	}
+	timesWidths = []int{
+		250, 333, 408, 500, 500, 833, 778, 180, 333, 333, 500, 564, 250, 333, 250, 278,
+		500, 500, 500, 500, 500, 500, 500, 500, 500, 500, 278, 278, 564, 564, 564, 444,
+		921, 722, 667, 667, 722, 611, 556, 722, 722, 333, 389, 722, 611, 889, 722, 722,
+		556, 722, 667, 556, 611, 722, 722, 944, 722, 722, 611, 333, 278, 333, 469, 500,
+		333, 444, 500, 444, 500, 444, 333, 500, 500, 278, 278, 500, 278, 778, 500, 500,
+		500, 500, 333, 389, 278, 500, 500, 722, 500, 500, 444, 480, 200, 480, 541,
+	}
+	timesBoldWidths = []int{
+		250, 333, 555, 500, 500, 1000, 833, 278, 333, 333, 500, 570, 250, 333, 250, 278,
+		500, 500, 500, 500, 500, 500, 500, 500, 500, 500, 333, 333, 570, 570, 570, 500,
+		930, 722, 667, 722, 722, 667, 611, 778, 778, 389, 500, 778, 667, 944, 722, 778,
+		611, 778, 722, 556, 667, 722, 722, 1000, 722, 722, 667, 333, 278, 333, 581, 500,
+		333, 500, 556, 444, 556, 444, 333, 500, 556, 278, 333, 556, 278, 833, 556, 500,
+		556, 556, 444, 389, 333, 556, 500, 722, 500, 500, 444, 394, 220, 394, 520,
+	}
+	timesItalicWidths = []int{
+		250, 333, 420, 500, 500, 833, 778, 214, 333, 333, 500, 675, 250, 333, 250, 278,
+		500, 500, 500, 500, 500, 500, 500, 500, 500, 500, 333, 333, 675, 675, 675, 500,
+		920, 611, 611, 667, 722, 611, 611, 722, 722, 333, 444, 667, 556, 833, 667, 722,
+		611, 722, 611, 500, 556, 722, 611, 833, 611, 556, 556, 389, 278, 389, 422, 500,
+		333, 500, 500, 444, 500, 444, 278, 500, 500, 278, 278, 444, 278, 722, 500, 500,
+		500, 500, 389, 389, 278, 500, 444, 667, 444, 444, 389, 400, 275, 400, 541,
+	}
+	timesBoldItalicWidths = []int{
+		250, 389, 555, 500, 500, 833, 778, 278, 333, 333, 500, 570, 250, 333, 250, 278,
+		500, 500, 500, 500, 500, 500, 500, 500, 500, 500, 333, 333, 570, 570, 570, 500,
+		832, 667, 667, 667, 722, 667, 667, 722, 778, 389, 500, 667, 611, 889, 722, 722,
+		611, 722, 667, 556, 611, 722, 667, 889, 667, 611, 611, 333, 278, 333, 570, 500,
+		333, 500, 500, 444, 500, 444, 333, 500, 556, 278, 278, 500, 278, 778, 556, 500,
+		500, 500, 389, 389, 278, 556, 444, 667, 500, 444, 389, 348, 220, 348, 570,
+	}
+)
+
+// winAnsiHigh holds the characters of WinAnsiEncoding from 0x80 to 0x9F; the codes from
+// 0xA0 up are those of Latin-1. Zero marks unused codes.
+var winAnsiHigh = [32]rune{
+	'€', 0, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0, 'Ž', 0,
+	0, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0, 'ž', 'Ÿ',
+}
+
+// The ASCII characters whose widths stand in for those of the characters from 0x80 to 0xFF
+// in the standard fonts: a base letter for accented letters, a similar shape for the rest.
+const (
+	winAnsiHighWidthOf  = "$ ,f\"m$$`%S(WZZZ ''\"\"*$W~Ws)wzzY"
+	winAnsiLatinWidthOf = " !c$$$|$`@a$+-@`\"+\"\"`u$.`\"o$%%%?AAAAAAMCEEEEIIIIDNOOOOO+OUUUUYPbaaaaaamceeeeiiiionooooo+ouuuuypy"
+)
+
+// winAnsiCode returns the WinAnsiEncoding code of r, or false if the encoding lacks it.
+func winAnsiCode(r rune) (byte, bool) {
+	switch {
+	case r >= 32 && r < 127, r >= 0xA0 && r <= 0xFF:
+		return byte(r), true
+	case r == '\t':
+		return ' ', true
+	}
+	for i, high := range winAnsiHigh {
+		if high != 0 && high == r {
+			return byte(0x80 + i), true
+		}
+	}
+	switch r {
+	case '‐', '‑', '−': // hyphens and minus
+		return '-', true
+	case '\u2002', '\u2003', '\u2009', '\u202f': // en, em, thin and narrow spaces
+		return ' ', true
+	case '•', '●', '▪', '\uf0b7':
+		return 0x95, true // bullets, including Symbol's, print as •
+	}
+	return 0, false
+}
+
+// winAnsiRune returns the character of a WinAnsiEncoding code.
+func winAnsiRune(code byte) rune {
+	if code >= 0x80 && code < 0xA0 {
+		return winAnsiHigh[code-0x80]
+	}
+	return rune(code)
+}
+
+// encodeWinAnsi encodes text for a simple font; characters the encoding lacks become "?".
+func encodeWinAnsi(text string) []byte {
+	encoded := make([]byte, 0, len(text))
+	for _, r := range text {
+		code, ok := winAnsiCode(r)
+		if !ok {
+			code = '?'
+		}
+		encoded = append(encoded, code)
+	}
+	return encoded
+}
+
+// standardWidths returns the widths of all codes of a standard font from its ASCII widths.
+func standardWidths(ascii []int) [256]float64 {
+	var widths [256]float64
+	for code := 32; code < 127; code++ {
+		widths[code] = float64(ascii[code-32])
+	}
+	for code := 0x80; code <= 0xFF; code++ {
+		var like byte
+		if code < 0xA0 {
+			like = winAnsiHighWidthOf[code-0x80]
+		} else {
+			like = winAnsiLatinWidthOf[code-0xA0]
+		}
+		widths[code] = widths[like]
+	}
+	return widths
+}
+
+// standardFont returns the PostScript name and widths of the standard font closest to a
+// Word font: Times for serif faces, Courier for monospaced ones and Helvetica for the rest.
+func standardFont(name string, bold, italic bool) (string, [256]float64) {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.Contains(lower, "courier"), strings.Contains(lower, "mono"), strings.Contains(lower, "consolas"),
+		strings.Contains(lower, "console"):
+		var widths [256]float64
+		for code := 32; code < 256; code++ {
+			widths[code] = 600
+		}
+		return "Courier" + standardStyle(bold, italic, "Oblique"), widths
+	case strings.Contains(lower, "times"), strings.Contains(lower, "cambria"), strings.Contains(lower, "georgia"),
+		strings.Contains(lower, "garamond"), strings.Contains(lower, "book"), strings.Contains(lower, "palatino"),
+		strings.Contains(lower, "calisto"), strings.Contains(lower, "constantia"),
+		strings.Contains(lower, "serif") && !strings.Contains(lower, "sans"):
+		switch {
+		case bold && italic:
+			return "Times-BoldItalic", standardWidths(timesBoldItalicWidths)
+		case bold:
+			return "Times-Bold", standardWidths(timesBoldWidths)
+		case italic:
+			return "Times-Italic", standardWidths(timesItalicWidths)
+		}
+		return "Times-Roman", standardWidths(timesWidths)
+	}
+	if bold {
+		return "Helvetica" + standardStyle(bold, italic, "Oblique"), standardWidths(helveticaBoldWidths)
+	}
+	return "Helvetica" + standardStyle(bold, italic, "Oblique"), standardWidths(helveticaWidths)
+}
+
+func standardStyle(bold, italic bool, slant string) string {
+	switch {
+	case bold && italic:
+		return "-Bold" + slant
+	case bold:
+		return "-Bold"
+	case italic:
+		return "-" + slant
+	}
+	return ""
+}
+
+// trueTypeFont is a TrueType font file with the metrics a PDF font descriptor needs.
+type trueTypeFont struct {
+	data        []byte
+	name        string
+	unitsPerEm  float64
+	bbox        [4]float64 // in thousandths of the font size, like the metrics below
+	ascent      float64
+	descent     float64 // negative
+	capHeight   float64
+	italicAngle float64
+	bold        bool
+	widths      [256]float64 // by WinAnsiEncoding code
+}
+
+// parseTrueType reads the metrics of a TrueType font and the widths of the characters of
+// WinAnsiEncoding, mapped through its Unicode cmap.
+func parseTrueType(data []byte) (*trueTypeFont, error) {
+	if len(data) < 12 {
+		return nil, fmt.Errorf("font file is too short")
+	}
+	if version := binary.BigEndian.Uint32(data); version != 0x00010000 && version != 0x74727565 {
+		return nil, fmt.Errorf("not a TrueType font")
+	}
+	tables := make(map[string][]byte)
+	count := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < count; i++ {
+		entry := 12 + 16*i
+		if entry+16 > len(data) {
+			return nil, fmt.Errorf("font table directory is truncated")
+		}
+		offset := int(binary.BigEndian.Uint32(data[entry+8:]))
+		length := int(binary.BigEndian.Uint32(data[entry+12:]))
+		if offset < 0 || length < 0 || offset+length > len(data) {
+			return nil, fmt.Errorf("font table %q is out of range", data[entry:entry+4])
+		}
+		tables[string(data[entry:entry+4])] = data[offset : offset+length]
+	}
+	for _, name := range []string{"head", "hhea", "hmtx", "cmap"} {
+		if tables[name] == nil {
+			return nil, fmt.Errorf("font has no %s table", name)
+		}
+	}
+
+	head, hhea := tables["head"], tables["hhea"]
+	if len(head) < 54 || len(hhea) < 36 {
+		return nil, fmt.Errorf("font header is truncated")
+	}
+	f := &trueTypeFont{data: data, unitsPerEm: float64(binary.BigEndian.Uint16(head[18:]))}
+	if f.unitsPerEm == 0 {
+		return nil, fmt.Errorf("font has no units per em")
+	}
+	scale := 1000 / f.unitsPerEm
+	for i := range f.bbox {
+		f.bbox[i] = float64(int16(binary.BigEndian.Uint16(head[36+2*i:]))) * scale
+	}
+	f.bold = binary.BigEndian.Uint16(head[44:])&1 != 0
+	f.ascent = float64(int16(binary.BigEndian.Uint16(hhea[4:]))) * scale
+	f.descent = float64(int16(binary.BigEndian.Uint16(hhea[6:]))) * scale
+	f.capHeight = f.ascent * 0.7
+	if os2 := tables["OS/2"]; len(os2) >= 90 && binary.BigEndian.Uint16(os2) >= 2 {
+		f.capHeight = float64(int16(binary.BigEndian.Uint16(os2[88:]))) * scale
+	}
+	if post := tables["post"]; len(post) >= 8 {
+		f.italicAngle = float64(int32(binary.BigEndian.Uint32(post[4:]))) / 65536
+	}
+	f.name = trueTypeName(tables["name"])
+
+	metrics := int(binary.BigEndian.Uint16(hhea[34:]))
+	hmtx := tables["hmtx"]
+	advance := func(glyph int) float64 {
+		if metrics == 0 {
+			return 0
+		}
+		if glyph >= metrics {
+			glyph = metrics - 1
+		}
+		if 4*glyph+2 > len(hmtx) {
+			return 0
+		}
+		return float64(binary.BigEndian.Uint16(hmtx[4*glyph:])) * scale
+	}
+	glyphs := trueTypeCmap(tables["cmap"])
+	for code := 32; code < 256; code++ {
+		if r := winAnsiRune(byte(code)); r != 0 {
+			f.widths[code] = advance(glyphs(r))
+		}
+	}
+	return f, nil
+}
+
+// trueTypeCmap returns a lookup of the glyphs of characters from the font's Unicode BMP
+// cmap (format 4); characters it lacks map to glyph 0.
+func trueTypeCmap(cmap []byte) func(rune) int {
+	missing := func(rune) int { return 0 }
+	if len(cmap) < 4 {
+		return missing
+	}
+	count := int(binary.BigEndian.Uint16(cmap[2:]))
+	for i := 0; i < count; i++ {
+		entry := 4 + 8*i
+		if entry+8 > len(cmap) {
+			break
+		}
+		platform, encoding := binary.BigEndian.Uint16(cmap[entry:]), binary.BigEndian.Uint16(cmap[entry+2:])
+		offset := int(binary.BigEndian.Uint32(cmap[entry+4:]))
+		if !(platform == 3 && encoding == 1 || platform == 0) || offset+14 > len(cmap) {
+			continue
+		}
+		table := cmap[offset:]
+		if binary.BigEndian.Uint16(table) != 4 {
+			continue
+		}
+		segments := int(binary.BigEndian.Uint16(table[6:])) / 2
+		if 16+8*segments > len(table) {
+			continue
+		}
+		ends, starts := table[14:], table[16+2*segments:]
+		deltas, ranges := table[16+4*segments:], table[16+6*segments:]
+		return func(r rune) int {
+			for s := 0; s < segments; s++ {
+				end := rune(binary.BigEndian.Uint16(ends[2*s:]))
+				if r > end {
+					continue
+				}
+				start := rune(binary.BigEndian.Uint16(starts[2*s:]))
+				if r < start {
+					return 0
+				}
+				delta := int(binary.BigEndian.Uint16(deltas[2*s:]))
+				rangeOffset := int(binary.BigEndian.Uint16(ranges[2*s:]))
+				if rangeOffset == 0 {
+					return (int(r) + delta) & 0xFFFF
+				}
+				at := 16 + 6*segments + 2*s + rangeOffset + 2*int(r-start)
+				if at+2 > len(table) {
+					return 0
+				}
+				if glyph := int(binary.BigEndian.Uint16(table[at:])); glyph != 0 {
+					return (glyph + delta) & 0xFFFF
+				}
+				return 0
+			}
+			return 0
+		}
+	}
+	return missing
+}
+
+// trueTypeName returns the PostScript name of a font from its name table, or "" if it has
+// none in a form read here.
+func trueTypeName(table []byte) string {
+	if len(table) < 6 {
+		return ""
+	}
+	count := int(binary.BigEndian.Uint16(table[2:]))
+	strings := int(binary.BigEndian.Uint16(table[4:]))
+	for i := 0; i < count; i++ {
+		record := 6 + 12*i
+		if record+12 > len(table) {
+			break
+		}
+		platform := binary.BigEndian.Uint16(table[record:])
+		nameID := binary.BigEndian.Uint16(table[record+6:])
+		length := int(binary.BigEndian.Uint16(table[record+8:]))
+		offset := strings + int(binary.BigEndian.Uint16(table[record+10:]))
+		if nameID != 6 || offset+length > len(table) {
+			continue
+		}
+		value := table[offset : offset+length]
+		if platform == 3 || platform == 0 {
+			// UTF-16BE; PostScript names are ASCII
+			name := make([]byte, 0, length/2)
+			for j := 1; j < len(value); j += 2 {
+				name = append(name, value[j])
+			}
+			return string(name)
+		}
+		return string(value)
+	}
+	return ""
+}
+
+// deobfuscateFont restores a font embedded in a document (an .odttf part), whose first 32
+// bytes are XORed with the bytes of its font key GUID in reverse order.
+func deobfuscateFont(data []byte, fontKey string) ([]byte, error) {
+	key, err := hex.DecodeString(strings.NewReplacer("{", "", "}", "", "-", "").Replace(fontKey))
+	if err != nil || len(key) != 16 {
+		return nil, fmt.Errorf("invalid font key %q", fontKey)
+	}
+	if len(data) < 32 {
+		return nil, fmt.Errorf("embedded font is too short")
+	}
+	font := append([]byte(nil), data...)
+	for i := 0; i < 32; i++ {
+		font[i] ^= key[15-i%16]
+	}
+	return font, nil
+}
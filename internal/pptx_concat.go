@@ -0,0 +1,602 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Relationship types of presentations
+const (
+	RelTypeSlide       = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide"
+	RelTypeSlideLayout = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/slideLayout"
+	RelTypeSlideMaster = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/slideMaster"
+	RelTypeNotesSlide  = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/notesSlide"
+	RelTypeNotesMaster = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/notesMaster"
+	RelTypeTheme       = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/theme"
+)
+
+const (
+	presentationPartName = "ppt/presentation.xml"
+
+	// Slide master and layout IDs share a range starting here
+	firstMasterID = 2147483648
+	firstSlideID  = 256
+
+	// The PowerPoint 2010 extension listing the slides of each section
+	sectionListExtURI = "{521415D9-36F7-43E2-AB2F-B90AF26B5E84}"
+)
+
+var (
+	presentationSlideIDPattern  = regexp.MustCompile(`<p:sldId\b[^>]*/>`)
+	presentationMasterIDPattern = regexp.MustCompile(`<p:sldMasterId\b[^>]*/>`)
+	slideLayoutIDPattern        = regexp.MustCompile(`<p:sldLayoutId\b[^>]*/>`)
+	slideLayoutIDListPattern    = regexp.MustCompile(`<p:sldLayoutIdLst\s*/>|<p:sldLayoutIdLst>.*?</p:sldLayoutIdLst>`)
+	slideColorMapPattern        = regexp.MustCompile(`<p:clrMap\b[^>]*/>`)
+	slideSectionListPattern     = regexp.MustCompile(`<p:ext uri="` + regexp.QuoteMeta(sectionListExtURI) + `">.*?</p:ext>`)
+	appSlidesPattern            = regexp.MustCompile(`<Slides>\d+</Slides>`)
+	slideRangePattern           = regexp.MustCompile(`^\s*(\d*)\s*(?:(-)\s*(\d*))?\s*$`)
+)
+
+// ParseSlideRange reads a list of 1-based slides and ranges such as "3-7", "1,4-6" or "9-"
+// (to the last slide) of a presentation with count slides. Empty selects all slides.
+func ParseSlideRange(spec string, count int) ([]int, error) {
+	if strings.TrimSpace(spec) == "" {
+		slides := make([]int, count)
+		for i := range slides {
+			slides[i] = i + 1
+		}
+		return slides, nil
+	}
+
+	var slides []int
+	for _, item := range strings.Split(spec, ",") {
+		match := slideRangePattern.FindStringSubmatch(item)
+		if match == nil || (match[1] == "" && match[3] == "") {
+			return nil, fmt.Errorf("invalid slide range %q", item)
+		}
+		first, last := 1, count
+		if match[1] != "" {
+			first, _ = strconv.Atoi(match[1])
+		}
+		switch {
+		case match[2] == "":
+			last = first
+		case match[3] != "":
+			last, _ = strconv.Atoi(match[3])
+		}
+		if first < 1 || last > count || first > last {
+			return nil, fmt.Errorf("slide range %q is outside slides 1-%d", strings.TrimSpace(item), count)
+		}
+		for slide := first; slide <= last; slide++ {
+			slides = append(slides, slide)
+		}
+	}
+	return slides, nil
+}
+
+// PresentationSlides returns the slide parts of a presentation in order.
+func PresentationSlides(pkg *Package) ([]string, error) {
+	content, err := pkg.Read(presentationPartName)
+	if err != nil {
+		return nil, err
+	}
+	rels, err := pkg.readRelationships(presentationPartName)
+	if err != nil {
+		return nil, err
+	}
+
+	var slides []string
+	for _, entry := range presentationSlideIDPattern.FindAllString(string(content), -1) {
+		if rel, ok := rels[attrValue(entry, "r:id")]; ok {
+			slides = append(slides, resolveTarget(presentationPartName, rel.target))
+		}
+	}
+	return slides, nil
+}
+
+// PptxMerger concatenates presentations: the slides appended to it are added with the
+// layouts, masters, notes, media and charts they use. Masters identical to one the
+// presentation has are shared.
+type PptxMerger struct {
+	pkg          *Package
+	presentation string
+	slides       []string // slide entries of the slide ID list
+	nextSlideID  int
+	nextMasterID int
+}
+
+// NewPptxMerger starts a merged presentation from pkg, keeping the given 1-based slides in
+// that order; nil keeps all of them.
+func NewPptxMerger(pkg *Package, slides []int) (*PptxMerger, error) {
+	content, err := pkg.Read(presentationPartName)
+	if err != nil {
+		return nil, err
+	}
+	m := &PptxMerger{pkg: pkg, presentation: string(content), nextSlideID: firstSlideID, nextMasterID: firstMasterID}
+
+	entries := presentationSlideIDPattern.FindAllString(m.presentation, -1)
+	for _, entry := range entries {
+		id, _ := strconv.Atoi(attrValue(entry, "id"))
+		m.nextSlideID = max(m.nextSlideID, id+1)
+	}
+	for _, entry := range presentationMasterIDPattern.FindAllString(m.presentation, -1) {
+		id, _ := strconv.Atoi(attrValue(entry, "id"))
+		m.nextMasterID = max(m.nextMasterID, id+1)
+	}
+	masters, err := m.masters()
+	if err != nil {
+		return nil, err
+	}
+	for _, master := range masters {
+		content, err := pkg.Read(master)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range slideLayoutIDPattern.FindAllString(string(content), -1) {
+			id, _ := strconv.Atoi(attrValue(entry, "id"))
+			m.nextMasterID = max(m.nextMasterID, id+1)
+		}
+	}
+
+	if slides == nil {
+		m.slides = entries
+		return m, nil
+	}
+
+	// Keep the selected slides and remove the others
+	kept := make(map[int]bool)
+	for _, slide := range slides {
+		if slide < 1 || slide > len(entries) {
+			return nil, fmt.Errorf("presentation has no slide %d", slide)
+		}
+		if !kept[slide] {
+			kept[slide] = true
+			m.slides = append(m.slides, entries[slide-1])
+		}
+	}
+	rels, err := pkg.readRelationships(presentationPartName)
+	if err != nil {
+		return nil, err
+	}
+	removed := make(map[string]bool)
+	var targets []string
+	for i, entry := range entries {
+		if kept[i+1] {
+			continue
+		}
+		id := attrValue(entry, "r:id")
+		removed[id] = true
+		if rel, ok := rels[id]; ok {
+			slide := resolveTarget(presentationPartName, rel.target)
+			slideRels, err := pkg.readRelationships(slide)
+			if err != nil {
+				return nil, err
+			}
+			for _, slideRel := range slideRels {
+				if !slideRel.external {
+					targets = append(targets, resolveTarget(slide, slideRel.target))
+				}
+			}
+			pkg.Delete(slide)
+			pkg.Delete(RelsPartName(slide))
+			if err := pkg.removeContentTypeOverride(slide); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := pkg.removeRelationships(presentationPartName, removed); err != nil {
+		return nil, err
+	}
+	return m, pkg.deleteUnreferenced(targets)
+}
+
+// Append adds the given 1-based slides of src, in that order; nil adds all of them.
+func (m *PptxMerger) Append(src *Package, slides []int) error {
+	srcSlides, err := PresentationSlides(src)
+	if err != nil {
+		return err
+	}
+	if slides == nil {
+		slides, _ = ParseSlideRange("", len(srcSlides))
+	}
+
+	merge := &pptxMerge{
+		merger:  m,
+		src:     src,
+		parts:   make(map[string]string),
+		slides:  make(map[string]string),
+		masters: make(map[string]string),
+		same:    newSharedParts(src, m.pkg),
+	}
+
+	// Name the new slides first, so links between them can be kept
+	var selected []string
+	for _, slide := range slides {
+		if slide < 1 || slide > len(srcSlides) {
+			return fmt.Errorf("presentation has no slide %d", slide)
+		}
+		name := srcSlides[slide-1]
+		if _, ok := merge.slides[name]; ok {
+			continue
+		}
+		copied := nextPartName(m.pkg, "ppt/slides/slide1.xml")
+		m.pkg.Write(copied, nil)
+		merge.slides[name] = copied
+		selected = append(selected, name)
+	}
+
+	for _, name := range selected {
+		copied := merge.slides[name]
+		if err := merge.copyContent(name, copied, ""); err != nil {
+			return fmt.Errorf("failed to copy %s: %v", name, err)
+		}
+		id, err := m.pkg.AddRelationship(presentationPartName, RelTypeSlide, relativeTarget(presentationPartName, copied), false)
+		if err != nil {
+			return err
+		}
+		m.slides = append(m.slides, `<p:sldId id="`+strconv.Itoa(m.nextSlideID)+`" r:id="`+id+`"/>`)
+		m.nextSlideID++
+	}
+	return nil
+}
+
+// Finish writes the merged presentation to the package.
+func (m *PptxMerger) Finish() error {
+	slideList := "<p:sldIdLst>" + strings.Join(m.slides, "") + "</p:sldIdLst>"
+	presentation := m.presentation
+	if start := indexElement(presentation, "p:sldIdLst"); start >= 0 {
+		list := leadingElement(presentation[start:], "p:sldIdLst")
+		presentation = presentation[:start] + slideList + presentation[start+len(list):]
+	} else if at := indexElement(presentation, "p:sldSz"); at >= 0 {
+		presentation = presentation[:at] + slideList + presentation[at:]
+	} else {
+		return fmt.Errorf("%s has no slide size", presentationPartName)
+	}
+
+	// Sections list slide IDs; the slides of merged decks belong to none
+	presentation = slideSectionListPattern.ReplaceAllString(presentation, "")
+	presentation = strings.ReplaceAll(presentation, "<p:extLst></p:extLst>", "")
+	m.pkg.Write(presentationPartName, []byte(presentation))
+
+	if m.pkg.Has("docProps/app.xml") {
+		content, err := m.pkg.Read("docProps/app.xml")
+		if err != nil {
+			return err
+		}
+		app := appSlidesPattern.ReplaceAllLiteralString(string(content), "<Slides>"+strconv.Itoa(len(m.slides))+"</Slides>")
+		m.pkg.Write("docProps/app.xml", []byte(app))
+	}
+	return nil
+}
+
+// masters returns the slide master parts of the merged presentation.
+func (m *PptxMerger) masters() ([]string, error) {
+	rels, err := m.pkg.readRelationships(presentationPartName)
+	if err != nil {
+		return nil, err
+	}
+	var masters []string
+	for _, entry := range presentationMasterIDPattern.FindAllString(m.presentation, -1) {
+		if rel, ok := rels[attrValue(entry, "r:id")]; ok {
+			masters = append(masters, resolveTarget(presentationPartName, rel.target))
+		}
+	}
+	return masters, nil
+}
+
+// insertPresentationList adds an entry to a list of presentation.xml, creating the list
+// before the first of the elements following it when missing.
+func (m *PptxMerger) insertPresentationList(list, entry string, following ...string) error {
+	if end := strings.Index(m.presentation, "</"+list+">"); end >= 0 {
+		m.presentation = m.presentation[:end] + entry + m.presentation[end:]
+		return nil
+	}
+	for _, name := range following {
+		if at := indexElement(m.presentation, name); at >= 0 {
+			m.presentation = m.presentation[:at] + "<" + list + ">" + entry + "</" + list + ">" + m.presentation[at:]
+			return nil
+		}
+	}
+	return fmt.Errorf("%s has no %s", presentationPartName, list)
+}
+
+// pptxMerge copies the slides of one presentation into the merged one.
+type pptxMerge struct {
+	merger  *PptxMerger
+	src     *Package
+	parts   map[string]string // merged part names of copied parts
+	slides  map[string]string // merged part names of the slides appended
+	masters map[string]string // merged part names of masters, copied or shared
+	same    *sharedParts
+}
+
+// copyContent copies a part's content and relationships to the merged part copied. The
+// relationships keep their IDs, so the content refers to them unchanged. Relationships of
+// type skip are left out.
+func (m *pptxMerge) copyContent(name, copied, skip string) error {
+	content, err := m.src.Read(name)
+	if err != nil {
+		return err
+	}
+	rels, err := m.src.readRelationships(name)
+	if err != nil {
+		return err
+	}
+
+	kept := make(map[string]relationship, len(rels))
+	for id, rel := range rels {
+		if rel.external {
+			kept[id] = rel
+			continue
+		}
+		target := resolveTarget(name, rel.target)
+
+		var merged string
+		switch rel.relType {
+		case skip:
+			continue
+		case RelTypeSlide:
+			var ok bool
+			if merged, ok = m.slides[target]; !ok {
+				// A link to a slide that isn't copied
+				content = removeRelationshipElements(content, id)
+				continue
+			}
+		case RelTypeSlideLayout:
+			merged, err = m.layout(target)
+		case RelTypeSlideMaster:
+			merged, err = m.master(target)
+		case RelTypeNotesMaster:
+			merged, err = m.notesMaster(target)
+		default:
+			merged, err = m.copyPart(target)
+		}
+		if err != nil {
+			return err
+		}
+		rel.target = relativeTarget(copied, merged)
+		kept[id] = rel
+	}
+
+	m.merger.pkg.Write(copied, content)
+	if len(kept) > 0 {
+		m.merger.pkg.writeRelationships(copied, kept)
+	}
+	return m.copyContentType(name, copied)
+}
+
+// copyPart copies a part, renamed if the name is taken, and returns its name. Media the merged
+// presentation has as well are shared.
+func (m *pptxMerge) copyPart(name string) (string, error) {
+	if copied, ok := m.parts[name]; ok {
+		return copied, nil
+	}
+	if strings.HasPrefix(name, "ppt/media/") && m.same.has(name) {
+		m.parts[name] = name
+		return name, nil
+	}
+
+	copied := name
+	if m.merger.pkg.Has(name) {
+		copied = nextPartName(m.merger.pkg, name)
+	}
+	m.parts[name] = copied
+	m.merger.pkg.Write(copied, nil)
+	return copied, m.copyContent(name, copied, "")
+}
+
+func (m *pptxMerge) copyContentType(name, copied string) error {
+	contentType, override := m.src.ContentType(name)
+	switch {
+	case contentType == "":
+		return nil
+	case override:
+		return m.merger.pkg.AddContentTypeOverride(copied, contentType)
+	default:
+		return m.merger.pkg.AddContentTypeDefault(strings.TrimPrefix(path.Ext(copied), "."), contentType)
+	}
+}
+
+// layout returns the merged slide layout for a layout of the source: one of the same content
+// if its master is shared, or else a copy added to the master.
+func (m *pptxMerge) layout(name string) (string, error) {
+	if copied, ok := m.parts[name]; ok {
+		return copied, nil
+	}
+	srcMaster, ok := m.src.relatedPart(name, RelTypeSlideMaster)
+	if !ok {
+		return "", fmt.Errorf("%s has no slide master", name)
+	}
+	master, err := m.master(srcMaster)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := m.src.Read(name)
+	if err != nil {
+		return "", err
+	}
+	masterRels, err := m.merger.pkg.readRelationships(master)
+	if err != nil {
+		return "", err
+	}
+	for _, rel := range masterRels {
+		if rel.relType != RelTypeSlideLayout || rel.external {
+			continue
+		}
+		layout := resolveTarget(master, rel.target)
+		existing, err := m.merger.pkg.Read(layout)
+		if err == nil && bytes.Equal(existing, content) {
+			m.parts[name] = layout
+			return layout, nil
+		}
+	}
+
+	copied := nextPartName(m.merger.pkg, name)
+	m.parts[name] = copied
+	m.merger.pkg.Write(copied, nil)
+	if err := m.copyContent(name, copied, ""); err != nil {
+		return "", err
+	}
+
+	id, err := m.merger.pkg.AddRelationship(master, RelTypeSlideLayout, relativeTarget(master, copied), false)
+	if err != nil {
+		return "", err
+	}
+	masterXML, err := m.merger.pkg.Read(master)
+	if err != nil {
+		return "", err
+	}
+	entry := `<p:sldLayoutId id="` + strconv.Itoa(m.merger.nextMasterID) + `" r:id="` + id + `"/>`
+	m.merger.nextMasterID++
+	m.merger.pkg.Write(master, []byte(addSlideLayoutID(string(masterXML), entry)))
+	return copied, nil
+}
+
+// master returns the merged slide master for a master of the source: one with the same
+// content and theme, or else a copy without layouts, which are added as slides use them.
+func (m *pptxMerge) master(name string) (string, error) {
+	if merged, ok := m.masters[name]; ok {
+		return merged, nil
+	}
+	content, err := m.src.Read(name)
+	if err != nil {
+		return "", err
+	}
+
+	masters, err := m.merger.masters()
+	if err != nil {
+		return "", err
+	}
+	key := slideLayoutIDListPattern.ReplaceAllString(string(content), "")
+	for _, master := range masters {
+		existing, err := m.merger.pkg.Read(master)
+		if err != nil || slideLayoutIDListPattern.ReplaceAllString(string(existing), "") != key {
+			continue
+		}
+		if m.sameTheme(name, master) {
+			m.masters[name] = master
+			return master, nil
+		}
+	}
+
+	copied := nextPartName(m.merger.pkg, name)
+	m.masters[name] = copied
+	m.merger.pkg.Write(copied, nil)
+	if err := m.copyContent(name, copied, RelTypeSlideLayout); err != nil {
+		return "", err
+	}
+	masterXML := slideLayoutIDListPattern.ReplaceAllString(string(content), "<p:sldLayoutIdLst></p:sldLayoutIdLst>")
+	m.merger.pkg.Write(copied, []byte(masterXML))
+
+	id, err := m.merger.pkg.AddRelationship(presentationPartName, RelTypeSlideMaster, relativeTarget(presentationPartName, copied), false)
+	if err != nil {
+		return "", err
+	}
+	entry := `<p:sldMasterId id="` + strconv.Itoa(m.merger.nextMasterID) + `" r:id="` + id + `"/>`
+	m.merger.nextMasterID++
+	return copied, m.merger.insertPresentationList("p:sldMasterIdLst", entry, "p:notesMasterIdLst", "p:handoutMasterIdLst", "p:sldIdLst", "p:sldSz")
+}
+
+// sameTheme reports whether a master of the source uses a theme of the same content as a
+// master of the merged presentation.
+func (m *pptxMerge) sameTheme(srcMaster, master string) bool {
+	srcTheme, srcOK := m.src.relatedPart(srcMaster, RelTypeTheme)
+	theme, ok := m.merger.pkg.relatedPart(master, RelTypeTheme)
+	if !srcOK || !ok {
+		return srcOK == ok
+	}
+	srcContent, err := m.src.Read(srcTheme)
+	if err != nil {
+		return false
+	}
+	content, err := m.merger.pkg.Read(theme)
+	return err == nil && bytes.Equal(srcContent, content)
+}
+
+// notesMaster returns the notes master of the merged presentation, copying the source's
+// if it has none.
+func (m *pptxMerge) notesMaster(name string) (string, error) {
+	if master, ok := m.merger.pkg.relatedPart(presentationPartName, RelTypeNotesMaster); ok {
+		return master, nil
+	}
+	copied, err := m.copyPart(name)
+	if err != nil {
+		return "", err
+	}
+	id, err := m.merger.pkg.AddRelationship(presentationPartName, RelTypeNotesMaster, relativeTarget(presentationPartName, copied), false)
+	if err != nil {
+		return "", err
+	}
+	entry := `<p:notesMasterId r:id="` + id + `"/>`
+	return copied, m.merger.insertPresentationList("p:notesMasterIdLst", entry, "p:handoutMasterIdLst", "p:sldIdLst", "p:sldSz")
+}
+
+// addSlideLayoutID adds an entry to the layout list of a slide master.
+func addSlideLayoutID(masterXML, entry string) string {
+	if end := strings.Index(masterXML, "</p:sldLayoutIdLst>"); end >= 0 {
+		return masterXML[:end] + entry + masterXML[end:]
+	}
+	if list := slideLayoutIDListPattern.FindStringIndex(masterXML); list != nil {
+		return masterXML[:list[0]] + "<p:sldLayoutIdLst>" + entry + "</p:sldLayoutIdLst>" + masterXML[list[1]:]
+	}
+	if colorMap := slideColorMapPattern.FindStringIndex(masterXML); colorMap != nil {
+		return masterXML[:colorMap[1]] + "<p:sldLayoutIdLst>" + entry + "</p:sldLayoutIdLst>" + masterXML[colorMap[1]:]
+	}
+	return masterXML
+}
+
+// removeRelationshipElements removes the empty elements referring to a relationship, such as
+// the hyperlinks of a slide to another slide.
+func removeRelationshipElements(content []byte, id string) []byte {
+	pattern := regexp.MustCompile(`<[\w:]+\b[^>]*\sr:id="` + regexp.QuoteMeta(id) + `"[^>]*/>`)
+	return pattern.ReplaceAll(content, nil)
+}
+
+// nextPartName returns name with the lowest number the package doesn't use yet, e.g.
+// "ppt/slides/slide4.xml" for "ppt/slides/slide1.xml" when slides 1 to 3 exist.
+func nextPartName(pkg *Package, name string) string {
+	ext := path.Ext(name)
+	base := strings.TrimRight(strings.TrimSuffix(name, ext), "0123456789")
+	for n := 1; ; n++ {
+		candidate := base + strconv.Itoa(n) + ext
+		if !pkg.Has(candidate) {
+			return candidate
+		}
+	}
+}
+
+// writeRelationships writes the relationships part of a part.
+func (p *Package) writeRelationships(partName string, rels map[string]relationship) {
+	ids := make([]string, 0, len(rels))
+	for id := range rels {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, errA := strconv.Atoi(strings.TrimPrefix(ids[i], "rId"))
+		b, errB := strconv.Atoi(strings.TrimPrefix(ids[j], "rId"))
+		if errA != nil || errB != nil {
+			return ids[i] < ids[j]
+		}
+		return a < b
+	})
+
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	b.WriteString(`<Relationships xmlns="` + relationshipsNamespace + `">`)
+	for _, id := range ids {
+		rel := rels[id]
+		b.WriteString(`<Relationship Id="` + EscapeXMLAttr(id) + `" Type="` + rel.relType + `" Target="` + EscapeXMLAttr(rel.target) + `"`)
+		if rel.external {
+			b.WriteString(` TargetMode="External"`)
+		}
+		b.WriteString(`/>`)
+	}
+	b.WriteString(`</Relationships>`)
+	p.Write(RelsPartName(partName), []byte(b.String()))
+}
@@ -0,0 +1,172 @@
+package internal
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	slideShapePattern       = regexp.MustCompile(`(?s)<p:sp\b.*?</p:sp>|<p:graphicFrame\b.*?</p:graphicFrame>`)
+	slidePlaceholderPattern = regexp.MustCompile(`<p:ph\b[^>]*>`)
+)
+
+// ExportPptx returns the text of the slides of a presentation, in order, as text, Markdown
+// or HTML. Title placeholders become headings and the paragraphs of body placeholders list
+// items, unless bullets are turned off; tables are kept. Notes are left out.
+func ExportPptx(pkg *Package, format ExportFormat) (string, error) {
+	slides, err := PresentationSlides(pkg)
+	if err != nil {
+		return "", err
+	}
+
+	var blocks []exportBlock
+	for i, slide := range slides {
+		content, err := pkg.Read(slide)
+		if err != nil {
+			return "", err
+		}
+		rels, err := pkg.readRelationships(slide)
+		if err != nil {
+			return "", err
+		}
+		if i > 0 {
+			blocks = append(blocks, exportBlock{separator: true})
+		}
+		blocks = append(blocks, slideBlocks(string(content), rels, i)...)
+	}
+	return renderExport(blocks, format), nil
+}
+
+// slideBlocks reads the text of the shapes and tables of a slide. Lists of different slides
+// get different list IDs, so their numbering starts over.
+func slideBlocks(slide string, rels map[string]relationship, index int) []exportBlock {
+	var blocks []exportBlock
+	for shapeIndex, shape := range slideShapePattern.FindAllString(slide, -1) {
+		if strings.HasPrefix(shape, "<p:graphicFrame") {
+			if start := indexElement(shape, "a:tbl"); start >= 0 {
+				blocks = append(blocks, exportBlock{table: drawingTable(leadingElement(shape[start:], "a:tbl"), rels)})
+			}
+			continue
+		}
+
+		start := indexElement(shape, "p:txBody")
+		if start < 0 {
+			continue
+		}
+		title, body := false, false
+		if placeholder := slidePlaceholderPattern.FindString(shape); placeholder != "" {
+			switch attrValue(placeholder, "type") {
+			case "title", "ctrTitle":
+				title = true
+			case "", "body", "obj":
+				body = true
+			}
+		}
+
+		listID := index*1000 + shapeIndex
+		for _, paragraph := range drawingParagraphs(leadingElement(shape[start:], "p:txBody"), rels, body, listID) {
+			if title {
+				if len(paragraph.runs) > 0 {
+					blocks = append(blocks, exportBlock{paragraph: paragraph, heading: 2})
+				}
+				continue
+			}
+			blocks = append(blocks, exportBlock{paragraph: paragraph})
+		}
+	}
+	return blocks
+}
+
+// drawingParagraphs reads the paragraphs of a text body. With bulleted set, paragraphs are
+// list items unless they turn bullets off, as in body placeholders.
+func drawingParagraphs(txBody string, rels map[string]relationship, bulleted bool, listID int) []richParagraph {
+	var paragraphs []richParagraph
+	for _, element := range xmlChildren(elementContent(txBody)) {
+		if elementName(element) != "a:p" {
+			continue
+		}
+		var paragraph richParagraph
+		content := elementContent(element)
+		pPr := ""
+		if start := indexElement(content, "a:pPr"); start == 0 {
+			pPr = leadingElement(content, "a:pPr")
+		}
+
+		switch {
+		case strings.Contains(pPr, "<a:buAutoNum"):
+			paragraph.list = listNumber
+		case strings.Contains(pPr, "<a:buChar"), bulleted && !strings.Contains(pPr, "<a:buNone"):
+			paragraph.list = listBullet
+		}
+		if paragraph.list != listNone {
+			paragraph.level, _ = strconv.Atoi(attrValue(pPr, "lvl"))
+			paragraph.listID = listID
+		}
+
+		for _, child := range xmlChildren(content) {
+			switch elementName(child) {
+			case "a:r", "a:fld":
+				paragraph.runs = append(paragraph.runs, slideRun(child, rels))
+			case "a:br":
+				paragraph.runs = append(paragraph.runs, richRun{lineBreak: true})
+			}
+		}
+		if len(paragraph.runs) == 0 && paragraph.list != listNone {
+			continue // an empty bullet shows nothing
+		}
+		paragraphs = append(paragraphs, paragraph)
+	}
+	return paragraphs
+}
+
+func slideRun(run string, rels map[string]relationship) richRun {
+	var result richRun
+	content := elementContent(run)
+	if start := indexElement(content, "a:rPr"); start >= 0 {
+		rPr := leadingElement(content[start:], "a:rPr")
+		tag := rPr[:strings.IndexByte(rPr, '>')+1]
+		result.bold = attrValue(tag, "b") == "1"
+		result.italic = attrValue(tag, "i") == "1"
+		result.underline = attrValue(tag, "u") != "" && attrValue(tag, "u") != "none"
+		if link := indexElement(rPr, "a:hlinkClick"); link >= 0 {
+			if rel, ok := rels[attrValue(rPr[link:], "r:id")]; ok && rel.external {
+				result.link = rel.target
+			}
+		}
+	}
+	if start := indexElement(content, "a:t"); start >= 0 {
+		result.text = UnescapeXMLText(elementContent(leadingElement(content[start:], "a:t")))
+	}
+	return result
+}
+
+func drawingTable(table string, rels map[string]relationship) *exportTable {
+	result := &exportTable{}
+	if start := indexElement(table, "a:tblPr"); start >= 0 {
+		result.header = attrValue(table[start:start+strings.IndexByte(table[start:], '>')], "firstRow") == "1"
+	}
+	for _, row := range xmlChildren(elementContent(table)) {
+		if elementName(row) != "a:tr" {
+			continue
+		}
+		var cells [][]richParagraph
+		for _, cell := range xmlChildren(elementContent(row)) {
+			if elementName(cell) != "a:tc" {
+				continue
+			}
+			// Cells covered by a merged cell repeat nothing
+			if tag := cell[:strings.IndexByte(cell, '>')]; attrValue(tag, "hMerge") == "1" || attrValue(tag, "vMerge") == "1" {
+				cells = append(cells, nil)
+				continue
+			}
+			var paragraphs []richParagraph
+			if start := indexElement(cell, "a:txBody"); start >= 0 {
+				paragraphs = drawingParagraphs(leadingElement(cell[start:], "a:txBody"), rels, false, 0)
+			}
+			cells = append(cells, paragraphs)
+		}
+		result.rows = append(result.rows, cells)
+	}
+	return result
+}
@@ -1,34 +1,59 @@
 package internal
 
 import (
-	"archive/zip"
 	"regexp"
 	"strings"
 )
 
-// ProcessPptxZipFile processes a single file from a PPTX zip archive.
-func ProcessPptxZipFile(file *zip.File, zipWriter *zip.Writer, replacements map[string]string) error {
-	return ProcessZipFile(file, zipWriter, func(fileName string, content []byte) []byte {
-		// Process slide files - each slide is a separate XML file
-		if strings.HasPrefix(fileName, "ppt/slides/slide") && strings.HasSuffix(fileName, ".xml") {
-			processedContent := processSlideXML(string(content), replacements)
-			return []byte(processedContent)
+// drawingTextPattern matches a DrawingML <a:t> element and captures its content.
+var drawingTextPattern = regexp.MustCompile(`<a:t(?:\s[^>]*)?>(.*?)</a:t>`)
+
+// ProcessPptxPackage replaces the keywords in every slide of a PPTX package and in its
+// hyperlink targets. Rich text values add their hyperlinks to the slide relationships.
+func ProcessPptxPackage(pkg *Package, replacer *Replacer) error {
+	if err := ProcessRelationships(pkg, replacer); err != nil {
+		return err
+	}
+	if err := applyTemplateProperties(pkg, replacer); err != nil {
+		return err
+	}
+
+	for _, name := range pkg.Names() {
+		// Each slide is a separate XML file
+		if !strings.HasPrefix(name, "ppt/slides/slide") || !strings.HasSuffix(name, ".xml") {
+			continue
 		}
-		// For other files, copy as-is
-		return content
-	})
+
+		content, err := pkg.Read(name)
+		if err != nil {
+			return err
+		}
+		rich := newPptxRich(pkg, name)
+		processed, err := rich.finish(processSlideXML(string(content), replacer, rich))
+		if err != nil {
+			return err
+		}
+		pkg.Write(name, []byte(processed))
+	}
+	return nil
 }
 
-func processSlideXML(xmlContent string, replacements map[string]string) string {
+// processSlideXML replaces the keywords in a slide. Without rich, rich text values are
+// written as plain text.
+func processSlideXML(xmlContent string, replacer *Replacer, rich *pptxRich) string {
 	textFrames := splitIntoTextFrames(xmlContent)
 
 	for i, frame := range textFrames {
 		if strings.Contains(frame, "<a:t>") || strings.Contains(frame, "<a:t ") {
 			plainText := extractTextFromFrame(frame)
 
-			if ContainsAnyKeyword(plainText, replacements) {
-				positionMap := buildFramePositionMap(frame)
-				textFrames[i] = ApplyReplacements(frame, plainText, replacements, positionMap)
+			if replacer.ContainsAnyKeyword(plainText) {
+				nodes := FindTextNodes(frame, drawingTextPattern)
+				result, values := replaceInNodes(frame, nodes, replacer, rich != nil)
+				if rich != nil && len(values) > 0 {
+					result = rich.render(result, values)
+				}
+				textFrames[i] = result
 			}
 		}
 	}
@@ -61,38 +86,14 @@ func splitIntoTextFrames(xmlContent string) []string {
 func extractTextFromFrame(frame string) string {
 	// Extract text from <a:t> tags (DrawingML text runs)
 	// Note: PPTX uses DrawingML namespace with 'a:' prefix
-	re := regexp.MustCompile(`<a:t(?:\s[^>]*)?>(.*?)</a:t>`)
-	matches := re.FindAllStringSubmatch(frame, -1)
+	matches := drawingTextPattern.FindAllStringSubmatch(frame, -1)
 
 	var text strings.Builder
 	for _, match := range matches {
 		if len(match) > 1 {
-			text.WriteString(match[1])
+			text.WriteString(UnescapeXMLText(match[1]))
 		}
 	}
 
 	return text.String()
 }
-
-func buildFramePositionMap(frame string) map[int]int {
-	positionMap := make(map[int]int)
-
-	// Build position map for <a:t> tags in DrawingML paragraphs
-	re := regexp.MustCompile(`<a:t(?:\s[^>]*)?>(.*?)</a:t>`)
-	matches := re.FindAllStringSubmatchIndex(frame, -1)
-
-	plainPos := 0
-	for _, match := range matches {
-		if len(match) >= 4 {
-			contentStart := match[2] // Start of content group
-			contentEnd := match[3]   // End of content group
-
-			textContent := frame[contentStart:contentEnd]
-			for i := 0; i < len(textContent); i++ {
-				positionMap[plainPos+i] = contentStart + i
-			}
-			plainPos += len(textContent)
-		}
-	}
-	return positionMap
-}
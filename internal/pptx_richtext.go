@@ -0,0 +1,327 @@
+package internal
+
+import (
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Bullet indentation in EMU (0.375")
+const drawingListIndent = 342900
+
+var (
+	drawingRun               = runMarkup{run: "a:r", props: "a:rPr", text: "a:t"}
+	drawingPPrPattern        = regexp.MustCompile(`^<a:p\b[^>]*>(<a:pPr\b[^>]*/>|<a:pPr\b.*?</a:pPr>)?`)
+	drawingEndParaRPrPattern = regexp.MustCompile(`<a:endParaRPr\b[^>]*/>|<a:endParaRPr\b.*?</a:endParaRPr>`)
+
+	// Schema order of the text run and paragraph property children
+	drawingRPrOrder = []string{"a:ln", "a:noFill", "a:solidFill", "a:gradFill", "a:blipFill", "a:pattFill",
+		"a:grpFill", "a:effectLst", "a:effectDag", "a:highlight", "a:uLnTx", "a:uLn", "a:uFillTx", "a:uFill",
+		"a:latin", "a:ea", "a:cs", "a:sym", "a:hlinkClick", "a:hlinkMouseOver", "a:rtl", "a:extLst"}
+	drawingPPrOrder = []string{"a:lnSpc", "a:spcBef", "a:spcAft", "a:buClrTx", "a:buClr", "a:buSzTx",
+		"a:buSzPct", "a:buSzPts", "a:buFontTx", "a:buFont", "a:buNone", "a:buAutoNum", "a:buChar", "a:buBlip",
+		"a:tabLst", "a:defRPr", "a:extLst"}
+	drawingBullets = []string{"a:buFontTx", "a:buFont", "a:buNone", "a:buAutoNum", "a:buChar", "a:buBlip"}
+)
+
+// pptxRich renders rich text values into a slide, adding hyperlink relationships to the slide part.
+type pptxRich struct {
+	pkg    *Package
+	part   string
+	links  map[string]string
+	tables []pendingTable
+	err    error
+}
+
+// pendingTable is a table value waiting to replace the shape holding its marker
+type pendingTable struct {
+	table Table
+	rPr   string
+}
+
+func newPptxRich(pkg *Package, part string) *pptxRich {
+	return &pptxRich{pkg: pkg, part: part, links: make(map[string]string)}
+}
+
+// render swaps the rich value markers in a paragraph for formatted runs; see docxRich.render.
+func (p *pptxRich) render(paragraph string, values []richValue) string {
+	for i, value := range values {
+		marker := richMarker(i)
+		pos := strings.Index(paragraph, marker)
+		if pos < 0 {
+			continue
+		}
+		run, ok := findMarkerRun(paragraph, pos, pos+len(marker), drawingRun)
+		if !ok {
+			paragraph = paragraph[:pos] + EscapeXMLText(value.plainText()) + paragraph[pos+len(marker):]
+			continue
+		}
+
+		if len(values) == 1 && strings.TrimSpace(extractTextFromFrame(paragraph)) == marker {
+			if value.table != nil {
+				// The table replaces the whole shape once the slide is complete
+				p.tables = append(p.tables, pendingTable{table: *value.table, rPr: run.props})
+				return paragraph[:pos] + tableMarker(len(p.tables)-1) + paragraph[pos+len(marker):]
+			}
+			return p.paragraphs(paragraph, run, value.paragraphs)
+		}
+		runs := p.runs(flattenRichText(value), run.props)
+		paragraph = paragraph[:run.start] + splitMarkerRun(run, runs, drawingRun) + paragraph[run.end:]
+	}
+	return paragraph
+}
+
+// paragraphs writes value as paragraphs based on the paragraph holding its marker.
+func (p *pptxRich) paragraphs(paragraph string, run markerRun, value []richParagraph) string {
+	startTag := paragraph[:strings.IndexByte(paragraph, '>')+1]
+	pPr := ""
+	if match := drawingPPrPattern.FindStringSubmatch(paragraph); match != nil {
+		pPr = match[1]
+	}
+	endParaRPr := drawingEndParaRPrPattern.FindString(paragraph)
+
+	if len(value) == 0 {
+		value = []richParagraph{{}}
+	}
+
+	var b strings.Builder
+	for _, item := range value {
+		props := pPr
+		if item.list != listNone {
+			props = drawingListProps(props, item)
+		}
+		b.WriteString(startTag + props + p.runs(item.runs, run.props) + endParaRPr + "</a:p>")
+	}
+	return b.String()
+}
+
+// drawingListProps turns paragraph properties into those of a list item.
+func drawingListProps(pPr string, item richParagraph) string {
+	if pPr == "" {
+		pPr = "<a:pPr/>"
+	}
+	pPr = setAttr(pPr, "marL", strconv.Itoa(drawingListIndent*(item.level+1)))
+	pPr = setAttr(pPr, "indent", strconv.Itoa(-drawingListIndent))
+	if item.level > 0 {
+		pPr = setAttr(pPr, "lvl", strconv.Itoa(item.level))
+	}
+	for _, bullet := range drawingBullets {
+		pPr = removeChild(pPr, "a:pPr", bullet)
+	}
+
+	if item.list == listNumber {
+		scheme := []string{"arabicPeriod", "alphaLcPeriod", "romanLcPeriod"}[item.level%3]
+		return setChild(pPr, "a:pPr", `<a:buAutoNum type="`+scheme+`"/>`, drawingPPrOrder)
+	}
+	pPr = setChild(pPr, "a:pPr", `<a:buFont typeface="Arial"/>`, drawingPPrOrder)
+	return setChild(pPr, "a:pPr", `<a:buChar char="`+[]string{"•", "◦", "▪"}[item.level%3]+`"/>`, drawingPPrOrder)
+}
+
+// runs writes formatted runs based on the run properties of the placeholder.
+func (p *pptxRich) runs(runs []richRun, rPr string) string {
+	var b strings.Builder
+	for _, run := range runs {
+		if run.lineBreak {
+			if rPr == "" {
+				b.WriteString("<a:br/>")
+			} else {
+				b.WriteString("<a:br>" + rPr + "</a:br>")
+			}
+			continue
+		}
+		if run.tab {
+			run.text = "\t"
+		}
+		if run.text == "" {
+			continue
+		}
+
+		props := rPr
+		if props == "" && (run.bold || run.italic || run.underline || run.link != "") {
+			props = "<a:rPr/>"
+		}
+		if run.bold {
+			props = setAttr(props, "b", "1")
+		}
+		if run.italic {
+			props = setAttr(props, "i", "1")
+		}
+		if run.underline {
+			props = setAttr(props, "u", "sng")
+		}
+		if run.link != "" {
+			if id := p.linkID(run.link); id != "" {
+				props = setChild(props, "a:rPr", `<a:hlinkClick r:id="`+id+`"/>`, drawingRPrOrder)
+			}
+		}
+
+		b.WriteString("<a:r>" + props + "<a:t>" + EscapeXMLText(run.text) + "</a:t></a:r>")
+	}
+	return b.String()
+}
+
+// linkID returns the relationship ID of a hyperlink target, adding the relationship once.
+func (p *pptxRich) linkID(target string) string {
+	if id, ok := p.links[target]; ok {
+		return id
+	}
+	id, err := p.pkg.AddRelationship(p.part, RelTypeHyperlink, target, true)
+	if err != nil {
+		if p.err == nil {
+			p.err = err
+		}
+		return ""
+	}
+	p.links[target] = id
+	return id
+}
+
+// finish declares the relationship namespace when links were added and returns the
+// first error met while rendering.
+func (p *pptxRich) finish(slideXML string) (string, error) {
+	for i, pending := range p.tables {
+		slideXML = p.replaceShape(slideXML, tableMarker(i), pending)
+	}
+	if len(p.links) > 0 {
+		slideXML = EnsureNamespace(slideXML, "r", officeRelNamespace)
+	}
+	return slideXML, p.err
+}
+
+// tableMarker is the text standing in for a pending table of the slide
+func tableMarker(i int) string {
+	return "\uE002" + strconv.Itoa(i) + "\uE003"
+}
+
+const (
+	drawingTableURI = "http://schemas.openxmlformats.org/drawingml/2006/table"
+	// "Medium Style 2 - Accent 1", PowerPoint's default table style
+	drawingDefaultTableStyle = "{5C22544A-7EE6-4342-B048-85BDC9FD1C3A}"
+	drawingTableRowHeight    = 370840
+)
+
+var (
+	drawingShapeIDPattern  = regexp.MustCompile(`<p:cNvPr\b[^>]*?\bid="(\d+)"`)
+	drawingOffsetPattern   = regexp.MustCompile(`<a:off\b[^>]*/>`)
+	drawingExtentPattern   = regexp.MustCompile(`<a:ext\b[^>]*/>`)
+	drawingTableStyleEntry = regexp.MustCompile(`<a:tblStyle\b[^>]*>`)
+)
+
+// replaceShape replaces the shape holding a table marker with a graphic frame holding the
+// table, at the shape's position and width. A marker outside a shape is written as text.
+func (p *pptxRich) replaceShape(slideXML, marker string, pending pendingTable) string {
+	pos := strings.Index(slideXML, marker)
+	if pos < 0 {
+		return slideXML
+	}
+	start := max(strings.LastIndex(slideXML[:pos], "<p:sp>"), strings.LastIndex(slideXML[:pos], "<p:sp "))
+	end := strings.Index(slideXML[pos:], "</p:sp>")
+	if start < 0 || end < 0 {
+		return slideXML[:pos] + EscapeXMLText(pending.table.plainText()) + slideXML[pos+len(marker):]
+	}
+	end += pos + len("</p:sp>")
+	shape := slideXML[start:end]
+
+	id := "1"
+	if match := drawingShapeIDPattern.FindStringSubmatch(shape); match != nil {
+		id = match[1]
+	}
+	offset := `<a:off x="457200" y="1600200"/>`
+	extent := `<a:ext cx="8229600" cy="0"/>`
+	if xfrm := leadingXfrm(shape); xfrm != "" {
+		if match := drawingOffsetPattern.FindString(xfrm); match != "" {
+			offset = match
+		}
+		if match := drawingExtentPattern.FindString(xfrm); match != "" {
+			extent = match
+		}
+	}
+	width, _ := strconv.Atoi(attrValue(extent, "cx"))
+
+	table := pending.table
+	count := table.columnCount()
+	if count == 0 {
+		return slideXML[:start] + slideXML[end:]
+	}
+	rows := table.allRows(count)
+
+	var b strings.Builder
+	b.WriteString(`<p:graphicFrame><p:nvGraphicFramePr><p:cNvPr id="` + id + `" name="Table ` + id + `"/>`)
+	b.WriteString(`<p:cNvGraphicFramePr><a:graphicFrameLocks noGrp="1"/></p:cNvGraphicFramePr><p:nvPr/></p:nvGraphicFramePr>`)
+	b.WriteString(`<p:xfrm>` + offset + `<a:ext cx="` + strconv.Itoa(width) + `" cy="` + strconv.Itoa(drawingTableRowHeight*len(rows)) + `"/></p:xfrm>`)
+	b.WriteString(`<a:graphic><a:graphicData uri="` + drawingTableURI + `"><a:tbl><a:tblPr`)
+	if len(table.Header) > 0 {
+		b.WriteString(` firstRow="1"`)
+	}
+	if table.Banded {
+		b.WriteString(` bandRow="1"`)
+	}
+	b.WriteString(`><a:tableStyleId>` + p.tableStyle(table.Style) + `</a:tableStyleId></a:tblPr><a:tblGrid>`)
+	for _, w := range table.columnWidths(count, width) {
+		b.WriteString(`<a:gridCol w="` + strconv.Itoa(w) + `"/>`)
+	}
+	b.WriteString(`</a:tblGrid>`)
+
+	for _, row := range rows {
+		b.WriteString(`<a:tr h="` + strconv.Itoa(drawingTableRowHeight) + `">`)
+		for _, cell := range row {
+			b.WriteString(`<a:tc><a:txBody><a:bodyPr/><a:lstStyle/>`)
+			for _, line := range strings.Split(cell, "\n") {
+				if line == "" {
+					b.WriteString(`<a:p/>`)
+					continue
+				}
+				b.WriteString(`<a:p>` + p.runs([]richRun{{text: line}}, pending.rPr) + `</a:p>`)
+			}
+			b.WriteString(`</a:txBody><a:tcPr/></a:tc>`)
+		}
+		b.WriteString(`</a:tr>`)
+	}
+	b.WriteString(`</a:tbl></a:graphicData></a:graphic></p:graphicFrame>`)
+
+	return slideXML[:start] + b.String() + slideXML[end:]
+}
+
+// leadingXfrm returns the transform of a shape's own properties, if it has one.
+func leadingXfrm(shape string) string {
+	spPr := strings.Index(shape, "<p:spPr")
+	if spPr < 0 {
+		return ""
+	}
+	spPrEnd := strings.Index(shape[spPr:], "</p:spPr>")
+	if spPrEnd < 0 {
+		return ""
+	}
+	properties := shape[spPr : spPr+spPrEnd]
+	start := strings.Index(properties, "<a:xfrm")
+	if start < 0 {
+		return ""
+	}
+	end := strings.Index(properties[start:], "</a:xfrm>")
+	if end < 0 {
+		return ""
+	}
+	return properties[start : start+end]
+}
+
+// tableStyle returns the ID of a table style given by GUID or by a name defined in
+// ppt/tableStyles.xml. Other names fall back to PowerPoint's default style.
+func (p *pptxRich) tableStyle(name string) string {
+	if name == "" {
+		return drawingDefaultTableStyle
+	}
+	if strings.HasPrefix(name, "{") && strings.HasSuffix(name, "}") {
+		return EscapeXMLAttr(name)
+	}
+
+	if content, err := p.pkg.Read("ppt/tableStyles.xml"); err == nil {
+		for _, style := range drawingTableStyleEntry.FindAllString(string(content), -1) {
+			if strings.EqualFold(UnescapeXMLText(attrValue(style, "styleName")), name) {
+				return attrValue(style, "styleId")
+			}
+		}
+	}
+	log.Printf("Warning: table style %q not found in tableStyles.xml, using the default style", name)
+	return drawingDefaultTableStyle
+}
@@ -0,0 +1,468 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Relationship and content types of the document property parts
+const (
+	RelTypeCoreProperties   = "http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties"
+	RelTypeAppProperties    = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/extended-properties"
+	RelTypeCustomProperties = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/custom-properties"
+
+	coreContentType   = "application/vnd.openxmlformats-package.core-properties+xml"
+	appContentType    = "application/vnd.openxmlformats-officedocument.extended-properties+xml"
+	customContentType = "application/vnd.openxmlformats-officedocument.custom-properties+xml"
+
+	coreNamespace    = "http://schemas.openxmlformats.org/package/2006/metadata/core-properties"
+	appNamespace     = "http://schemas.openxmlformats.org/officeDocument/2006/extended-properties"
+	customNamespace  = "http://schemas.openxmlformats.org/officeDocument/2006/custom-properties"
+	vtNamespace      = "http://schemas.openxmlformats.org/officeDocument/2006/docPropsVTypes"
+	customPropFmtID  = "{D5CDD505-2E9C-101B-9397-08002B2CF9AE}"
+	xmlDeclaration   = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+	packageRelsOwner = "" // the package itself owns the relationships to the property parts
+)
+
+// coreProperties maps the names of the core properties to their elements.
+var coreProperties = map[string]string{
+	"title":          "dc:title",
+	"subject":        "dc:subject",
+	"creator":        "dc:creator",
+	"keywords":       "cp:keywords",
+	"description":    "dc:description",
+	"lastmodifiedby": "cp:lastModifiedBy",
+	"revision":       "cp:revision",
+	"created":        "dcterms:created",
+	"modified":       "dcterms:modified",
+	"lastprinted":    "cp:lastPrinted",
+	"category":       "cp:category",
+	"contentstatus":  "cp:contentStatus",
+	"language":       "dc:language",
+	"identifier":     "dc:identifier",
+	"version":        "cp:version",
+}
+
+// coreNamespaces are the namespaces of the prefixes used for core property elements.
+var coreNamespaces = map[string]string{
+	"cp":      coreNamespace,
+	"dc":      "http://purl.org/dc/elements/1.1/",
+	"dcterms": "http://purl.org/dc/terms/",
+	"xsi":     "http://www.w3.org/2001/XMLSchema-instance",
+}
+
+// appProperties are the extended properties set by name without the "app:" prefix.
+var appProperties = []string{"Application", "AppVersion", "Company", "HyperlinkBase", "Manager", "Template"}
+
+var (
+	propertyNamePattern = regexp.MustCompile(`^[A-Za-z_][\w.-]*$`)
+	customPropPattern   = regexp.MustCompile(`(?s)<property\b[^>]*?(?:/>|>.*?</property>)`)
+)
+
+// Properties holds the document properties of a package: the core properties such as title,
+// creator and keywords, keyed by element name without prefix; the extended properties Office
+// writes, such as Company and Manager; and the custom properties, keyed by name.
+type Properties struct {
+	Core   map[string]string
+	App    map[string]string
+	Custom map[string]string
+}
+
+// ReadProperties returns the document properties of a package. Missing parts have no properties.
+func ReadProperties(pkg *Package) (Properties, error) {
+	props := Properties{Core: map[string]string{}, App: map[string]string{}, Custom: map[string]string{}}
+
+	for _, part := range []struct {
+		relType string
+		values  map[string]string
+	}{{RelTypeCoreProperties, props.Core}, {RelTypeAppProperties, props.App}} {
+		content, ok, err := readPropertyPart(pkg, part.relType)
+		if err != nil {
+			return props, err
+		}
+		if !ok {
+			continue
+		}
+		_, children := propertyRoot(content)
+		for _, child := range children {
+			// Vectors such as TitlesOfParts are not simple values
+			if value := elementContent(child); !strings.Contains(value, "<") {
+				name := elementName(child)
+				part.values[name[strings.IndexByte(name, ':')+1:]] = UnescapeXMLText(value)
+			}
+		}
+	}
+
+	content, ok, err := readPropertyPart(pkg, RelTypeCustomProperties)
+	if err != nil {
+		return props, err
+	}
+	if ok {
+		for _, property := range customPropPattern.FindAllString(content, -1) {
+			name := UnescapeXMLText(attrValue(property, "name"))
+			if values := xmlChildren(elementContent(property)); name != "" && len(values) > 0 {
+				props.Custom[name] = UnescapeXMLText(elementContent(values[0]))
+			}
+		}
+	}
+	return props, nil
+}
+
+// SetProperties sets document properties by name:
+//   - core properties by element name, e.g. "title", "creator" (or "author"), "keywords" or "modified"
+//   - the extended properties Application, AppVersion, Company, HyperlinkBase, Manager and Template
+//   - any other name sets a custom property, e.g. "ContractId"
+//
+// Prefix a name with "core:", "app:" or "custom:" to choose the kind explicitly. Dates are written
+// as "2006-01-02" or "2006-01-02T15:04:05Z". An empty value removes the property. Property parts
+// missing from the package are added along with their relationship and content type.
+func SetProperties(pkg *Package, props map[string]string) error {
+	core, app, custom := make(map[string]string), make(map[string]string), make(map[string]string)
+
+	// Sorted so that the same properties always produce the same parts
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var customNames []string
+	for _, name := range names {
+		kind, key, err := resolvePropertyName(name)
+		if err != nil {
+			return err
+		}
+		switch kind {
+		case "core":
+			core[key] = props[name]
+		case "app":
+			app[key] = props[name]
+		default:
+			if _, exists := custom[key]; !exists {
+				customNames = append(customNames, key)
+			}
+			custom[key] = props[name]
+		}
+	}
+
+	if err := setCoreProperties(pkg, core); err != nil {
+		return err
+	}
+	if err := setAppProperties(pkg, app); err != nil {
+		return err
+	}
+	return setCustomProperties(pkg, customNames, custom)
+}
+
+// ValidatePropertyName checks that a name can be passed to SetProperties.
+func ValidatePropertyName(name string) error {
+	_, _, err := resolvePropertyName(name)
+	return err
+}
+
+// resolvePropertyName returns the kind of a property (core, app or custom) and its key: the
+// qualified element name of core properties, the element name of extended properties and the
+// name of custom properties.
+func resolvePropertyName(name string) (string, string, error) {
+	kind, key, explicit := strings.Cut(name, ":")
+	if !explicit {
+		key = name
+		switch lower := strings.ToLower(name); {
+		case lower == "author":
+			return "core", coreProperties["creator"], nil
+		case coreProperties[lower] != "":
+			return "core", coreProperties[lower], nil
+		}
+		for _, app := range appProperties {
+			if strings.EqualFold(name, app) {
+				return "app", app, nil
+			}
+		}
+		kind = "custom"
+	}
+
+	switch kind {
+	case "core":
+		if element, ok := coreProperties[strings.ToLower(key)]; ok {
+			return kind, element, nil
+		}
+		return "", "", fmt.Errorf("unknown core property %q", key)
+	case "app":
+		if !propertyNamePattern.MatchString(key) {
+			return "", "", fmt.Errorf("invalid extended property name %q", key)
+		}
+		for _, app := range appProperties {
+			if strings.EqualFold(key, app) {
+				key = app
+			}
+		}
+		return kind, key, nil
+	case "custom":
+		if strings.TrimSpace(key) == "" {
+			return "", "", fmt.Errorf("custom property name is empty")
+		}
+		return kind, key, nil
+	}
+	return "", "", fmt.Errorf("invalid property name %q (use core:, app: or custom:)", name)
+}
+
+// applyTemplateProperties sets the properties of the replacer's options, with their values
+// rendered as templates, e.g. "Contract {{ID}}".
+func applyTemplateProperties(pkg *Package, replacer *Replacer) error {
+	if len(replacer.opts.Properties) == 0 {
+		return nil
+	}
+	props := make(map[string]string, len(replacer.opts.Properties))
+	for name, value := range replacer.opts.Properties {
+		props[name] = replacer.Replace(value)
+	}
+	if err := SetProperties(pkg, props); err != nil {
+		return fmt.Errorf("failed to set document properties: %v", err)
+	}
+	return nil
+}
+
+func setCoreProperties(pkg *Package, values map[string]string) error {
+	if len(values) == 0 {
+		return nil
+	}
+	partName, content, err := propertyPart(pkg, RelTypeCoreProperties, "docProps/core.xml", coreContentType,
+		`<cp:coreProperties xmlns:cp="`+coreNamespace+`" xmlns:dc="`+coreNamespaces["dc"]+`" xmlns:dcterms="`+
+			coreNamespaces["dcterms"]+`" xmlns:dcmitype="http://purl.org/dc/dcmitype/" xmlns:xsi="`+coreNamespaces["xsi"]+`"></cp:coreProperties>`)
+	if err != nil {
+		return err
+	}
+
+	elements := make([]string, 0, len(values))
+	for element := range values {
+		elements = append(elements, element)
+	}
+	sort.Strings(elements)
+
+	start, children := propertyRoot(content)
+	for _, element := range elements {
+		value := values[element]
+		prefix, local, _ := strings.Cut(element, ":")
+		attrs := ""
+		switch local {
+		case "created", "modified", "lastPrinted":
+			if value != "" {
+				if value, err = propertyDate(value); err != nil {
+					return fmt.Errorf("property %s: %v", local, err)
+				}
+			}
+			if prefix == "dcterms" {
+				attrs = ` xsi:type="dcterms:W3CDTF"`
+				start = EnsureNamespace(start, "xsi", coreNamespaces["xsi"])
+			}
+		}
+		start = EnsureNamespace(start, prefix, coreNamespaces[prefix])
+
+		replacement := ""
+		if value != "" {
+			replacement = "<" + element + attrs + ">" + EscapeXMLText(value) + "</" + element + ">"
+		}
+		children = setPropertyElement(children, local, replacement)
+	}
+	pkg.Write(partName, []byte(joinPropertyRoot(content, start, children)))
+	return nil
+}
+
+func setAppProperties(pkg *Package, values map[string]string) error {
+	if len(values) == 0 {
+		return nil
+	}
+	partName, content, err := propertyPart(pkg, RelTypeAppProperties, "docProps/app.xml", appContentType,
+		`<Properties xmlns="`+appNamespace+`" xmlns:vt="`+vtNamespace+`"></Properties>`)
+	if err != nil {
+		return err
+	}
+
+	elements := make([]string, 0, len(values))
+	for element := range values {
+		elements = append(elements, element)
+	}
+	sort.Strings(elements)
+
+	start, children := propertyRoot(content)
+	for _, element := range elements {
+		replacement := ""
+		if value := values[element]; value != "" {
+			replacement = "<" + element + ">" + EscapeXMLText(value) + "</" + element + ">"
+		}
+		children = setPropertyElement(children, element, replacement)
+	}
+	pkg.Write(partName, []byte(joinPropertyRoot(content, start, children)))
+	return nil
+}
+
+// setCustomProperties sets custom properties in the order of names. Existing properties keep
+// their type; values that don't fit it are an error. New properties are strings.
+func setCustomProperties(pkg *Package, names []string, values map[string]string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	partName, content, err := propertyPart(pkg, RelTypeCustomProperties, "docProps/custom.xml", customContentType,
+		`<Properties xmlns="`+customNamespace+`" xmlns:vt="`+vtNamespace+`"></Properties>`)
+	if err != nil {
+		return err
+	}
+
+	start, children := propertyRoot(content)
+	start = EnsureNamespace(start, "vt", vtNamespace)
+	nextPID := 2
+	for _, child := range children {
+		if pid, err := strconv.Atoi(attrValue(child, "pid")); err == nil && pid >= nextPID {
+			nextPID = pid + 1
+		}
+	}
+
+	for _, name := range names {
+		value := values[name]
+		index := -1
+		for i, child := range children {
+			if UnescapeXMLText(attrValue(child, "name")) == name {
+				index = i
+				break
+			}
+		}
+
+		if value == "" {
+			if index >= 0 {
+				children = append(children[:index:index], children[index+1:]...)
+			}
+			continue
+		}
+
+		valueType, pid := "vt:lpwstr", strconv.Itoa(nextPID)
+		if index >= 0 {
+			if existing := xmlChildren(elementContent(children[index])); len(existing) > 0 {
+				valueType = elementName(existing[0])
+			}
+			pid = attrValue(children[index], "pid")
+		}
+		if value, err = customPropertyValue(valueType, value); err != nil {
+			return fmt.Errorf("custom property %s: %v", name, err)
+		}
+
+		property := `<property fmtid="` + customPropFmtID + `" pid="` + pid + `" name="` + EscapeXMLAttr(name) + `">` +
+			"<" + valueType + ">" + EscapeXMLText(value) + "</" + valueType + "></property>"
+		if index >= 0 {
+			children[index] = property
+		} else {
+			children = append(children, property)
+			nextPID++
+		}
+	}
+	pkg.Write(partName, []byte(joinPropertyRoot(content, start, children)))
+	return nil
+}
+
+// customPropertyValue checks that value can be stored as valueType, returning it as written.
+func customPropertyValue(valueType, value string) (string, error) {
+	var err error
+	switch valueType {
+	case "vt:bool":
+		var b bool
+		if b, err = strconv.ParseBool(value); err == nil {
+			return strconv.FormatBool(b), nil
+		}
+	case "vt:i1", "vt:i2", "vt:i4", "vt:i8", "vt:int":
+		_, err = strconv.ParseInt(value, 10, 64)
+	case "vt:ui1", "vt:ui2", "vt:ui4", "vt:ui8", "vt:uint":
+		_, err = strconv.ParseUint(value, 10, 64)
+	case "vt:r4", "vt:r8", "vt:decimal":
+		_, err = strconv.ParseFloat(value, 64)
+	case "vt:filetime", "vt:date":
+		return propertyDate(value)
+	default:
+		return value, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("%q is not a valid %s value", value, strings.TrimPrefix(valueType, "vt:"))
+	}
+	return value, nil
+}
+
+// propertyDate returns a date or time value as a W3CDTF timestamp in UTC.
+func propertyDate(value string) (string, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC().Format("2006-01-02T15:04:05Z"), nil
+		}
+	}
+	return "", fmt.Errorf("%q is not a date (use 2006-01-02 or 2006-01-02T15:04:05Z)", value)
+}
+
+// readPropertyPart returns the content of the property part related to the package by relType.
+func readPropertyPart(pkg *Package, relType string) (string, bool, error) {
+	partName, ok := pkg.relatedPart(packageRelsOwner, relType)
+	if !ok || !pkg.Has(partName) {
+		return "", false, nil
+	}
+	content, err := pkg.Read(partName)
+	if err != nil {
+		return "", false, err
+	}
+	return string(content), true, nil
+}
+
+// propertyPart returns the name and content of a property part, adding the part with root as
+// its content when the package has none.
+func propertyPart(pkg *Package, relType, defaultName, contentType, root string) (string, string, error) {
+	if partName, ok := pkg.relatedPart(packageRelsOwner, relType); ok && pkg.Has(partName) {
+		content, err := pkg.Read(partName)
+		return partName, string(content), err
+	}
+
+	partName := defaultName
+	for i := 2; pkg.Has(partName); i++ {
+		partName = strings.TrimSuffix(defaultName, ".xml") + strconv.Itoa(i) + ".xml"
+	}
+	if _, err := pkg.AddRelationship(packageRelsOwner, relType, partName, false); err != nil {
+		return "", "", err
+	}
+	if err := pkg.AddContentTypeOverride(partName, contentType); err != nil {
+		return "", "", err
+	}
+	return partName, xmlDeclaration + root, nil
+}
+
+// propertyRoot splits a property part into the start tag of its root element and the elements in it.
+func propertyRoot(content string) (string, []string) {
+	root := rootElementStart(content)
+	if root < 0 {
+		return "", nil
+	}
+	return splitProps(content[root:strings.LastIndexByte(content, '>')+1], elementName(content[root:]))
+}
+
+// joinPropertyRoot writes a property part back from the root's start tag and the elements in it.
+func joinPropertyRoot(content, start string, children []string) string {
+	root := rootElementStart(content)
+	name := elementName(start)
+	return content[:root] + start + strings.Join(children, "") + "</" + name + ">"
+}
+
+// setPropertyElement replaces the element with the local name of a property, or adds it at the
+// end. An empty replacement removes the element.
+func setPropertyElement(children []string, local, replacement string) []string {
+	for i, child := range children {
+		name := elementName(child)
+		if name[strings.IndexByte(name, ':')+1:] != local {
+			continue
+		}
+		if replacement == "" {
+			return append(children[:i:i], children[i+1:]...)
+		}
+		children[i] = replacement
+		return children
+	}
+	if replacement != "" {
+		children = append(children, replacement)
+	}
+	return children
+}
@@ -0,0 +1,401 @@
+package internal
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Relationship types used when adding parts and links
+const (
+	RelTypeHyperlink = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink"
+	RelTypeNumbering = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/numbering"
+	RelTypeStyles    = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles"
+	RelTypeFontTable = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/fontTable"
+	RelTypeFootnotes = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/footnotes"
+	RelTypeEndnotes  = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/endnotes"
+	RelTypeComments  = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/comments"
+	RelTypeAltChunk  = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/aFChunk"
+
+	relationshipsNamespace = "http://schemas.openxmlformats.org/package/2006/relationships"
+	officeRelNamespace     = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+)
+
+var (
+	relationshipIDPattern = regexp.MustCompile(`\bId="([^"]*)"`)
+	relationshipPattern   = regexp.MustCompile(`<Relationship\b[^>]*>`)
+	relationshipTarget    = regexp.MustCompile(`\sTarget="([^"]*)"`)
+	contentTypeOverride   = regexp.MustCompile(`<Override\b[^>]*>`)
+	contentTypeDefault    = regexp.MustCompile(`<Default\b[^>]*>`)
+)
+
+// relationship is an entry of a relationships part.
+type relationship struct {
+	relType  string
+	target   string
+	external bool
+}
+
+// readRelationships returns the relationships of a part by ID; a part without a
+// relationships part has none.
+func (p *Package) readRelationships(partName string) (map[string]relationship, error) {
+	rels := make(map[string]relationship)
+	relsName := RelsPartName(partName)
+	if !p.Has(relsName) {
+		return rels, nil
+	}
+	content, err := p.Read(relsName)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range relationshipPattern.FindAllString(string(content), -1) {
+		rels[UnescapeXMLText(attrValue(entry, "Id"))] = relationship{
+			relType:  attrValue(entry, "Type"),
+			target:   UnescapeXMLText(attrValue(entry, "Target")),
+			external: attrValue(entry, "TargetMode") == "External",
+		}
+	}
+	return rels, nil
+}
+
+// relatedPart returns the name of the first part related to partName with relType,
+// e.g. "word/styles.xml" for the styles of "word/document.xml".
+func (p *Package) relatedPart(partName, relType string) (string, bool) {
+	rels, err := p.readRelationships(partName)
+	if err != nil {
+		return "", false
+	}
+	for _, rel := range rels {
+		if rel.relType == relType && !rel.external {
+			return resolveTarget(partName, rel.target), true
+		}
+	}
+	return "", false
+}
+
+// resolveTarget returns the part name a relationship target of partName points to.
+func resolveTarget(partName, target string) string {
+	if strings.HasPrefix(target, "/") {
+		return strings.TrimPrefix(path.Clean(target), "/")
+	}
+	return strings.TrimPrefix(path.Join(path.Dir(partName), target), "/")
+}
+
+// relativeTarget returns the relationship target from partName to targetPart.
+func relativeTarget(partName, targetPart string) string {
+	dir := path.Dir(partName)
+	if dir == "." {
+		return targetPart
+	}
+
+	from := strings.Split(dir, "/")
+	to := strings.Split(targetPart, "/")
+	common := 0
+	for common < len(from) && common < len(to)-1 && from[common] == to[common] {
+		common++
+	}
+	return strings.Repeat("../", len(from)-common) + strings.Join(to[common:], "/")
+}
+
+// RelsPartName returns the relationships part of a part: "word/document.xml" -> "word/_rels/document.xml.rels".
+func RelsPartName(partName string) string {
+	dir, base := path.Split(partName)
+	return dir + "_rels/" + base + ".rels"
+}
+
+// AddRelationship adds a relationship from partName to target and returns its ID.
+// External targets (hyperlinks) are written with TargetMode="External".
+func (p *Package) AddRelationship(partName, relType, target string, external bool) (string, error) {
+	relsName := RelsPartName(partName)
+
+	rels := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+		`<Relationships xmlns="` + relationshipsNamespace + `"></Relationships>`
+	if p.Has(relsName) {
+		content, err := p.Read(relsName)
+		if err != nil {
+			return "", err
+		}
+		rels = string(content)
+	}
+
+	closing := strings.LastIndex(rels, "</Relationships>")
+	if closing < 0 {
+		return "", fmt.Errorf("%s is not a relationships part", relsName)
+	}
+
+	id := nextRelationshipID(rels)
+	relationship := `<Relationship Id="` + id + `" Type="` + relType + `" Target="` + EscapeXMLAttr(target) + `"`
+	if external {
+		relationship += ` TargetMode="External"`
+	}
+	relationship += `/>`
+
+	p.Write(relsName, []byte(rels[:closing]+relationship+rels[closing:]))
+	return id, nil
+}
+
+// removeRelationships removes the relationships of partName with the given IDs.
+func (p *Package) removeRelationships(partName string, ids map[string]bool) error {
+	relsName := RelsPartName(partName)
+	if len(ids) == 0 || !p.Has(relsName) {
+		return nil
+	}
+	content, err := p.Read(relsName)
+	if err != nil {
+		return err
+	}
+	rels := relationshipPattern.ReplaceAllStringFunc(string(content), func(entry string) string {
+		if ids[UnescapeXMLText(attrValue(entry, "Id"))] {
+			return ""
+		}
+		return entry
+	})
+	p.Write(relsName, []byte(rels))
+	return nil
+}
+
+// ProcessRelationships replaces the keywords in the targets of external relationships, such
+// as a template hyperlink to "https://portal.example.com/{{CUSTOMER_ID}}". Delimiters that
+// were percent-encoded when the link was inserted ("%7B%7BCUSTOMER_ID%7D%7D") are recognized too.
+// Values are percent-encoded for the part of the URL they land in; see replaceURLKeywords.
+func ProcessRelationships(pkg *Package, replacer *Replacer) error {
+	for _, name := range pkg.Names() {
+		if !strings.HasSuffix(name, ".rels") {
+			continue
+		}
+		content, err := pkg.Read(name)
+		if err != nil {
+			return err
+		}
+
+		rels := string(content)
+		processed := relationshipPattern.ReplaceAllStringFunc(rels, func(relationship string) string {
+			if !strings.Contains(relationship, `TargetMode="External"`) {
+				return relationship
+			}
+			match := relationshipTarget.FindStringSubmatchIndex(relationship)
+			if match == nil {
+				return relationship
+			}
+			target := decodeDelimiters(UnescapeXMLText(relationship[match[2]:match[3]]), replacer.Syntax())
+			if !replacer.ContainsAnyKeyword(target) {
+				return relationship
+			}
+			return relationship[:match[2]] + EscapeXMLAttr(replaceURLKeywords(target, replacer)) + relationship[match[3]:]
+		})
+		if processed != rels {
+			pkg.Write(name, []byte(processed))
+		}
+	}
+	return nil
+}
+
+// replaceURLKeywords replaces the keywords in a URL, escaping each value for where it lands: a
+// path segment, the query or the fragment, or a mailto address or header. A value starting the
+// URL, or in its scheme or host, is taken as is so a whole URL can come from the data.
+func replaceURLKeywords(target string, replacer *Replacer) string {
+	var b strings.Builder
+	last := 0
+	for _, rp := range replacer.FindReplacementPoints(target) {
+		b.WriteString(target[last:rp.startPos])
+		b.WriteString(escapeURLValue(target[:rp.startPos], rp.replacement))
+		last = rp.endPos
+	}
+	b.WriteString(target[last:])
+	return b.String()
+}
+
+// escapeURLValue escapes value for the URL position following prefix.
+func escapeURLValue(prefix, value string) string {
+	lower := strings.ToLower(prefix)
+	if strings.HasPrefix(lower, "mailto:") {
+		if !strings.Contains(prefix, "?") {
+			return url.PathEscape(value)
+		}
+		// Header values are not form-encoded, so a space must be %20 rather than "+"
+		return strings.ReplaceAll(url.QueryEscape(value), "+", "%20")
+	}
+
+	if prefix == "" {
+		return value
+	}
+	if _, authority, ok := strings.Cut(prefix, "://"); ok && !strings.ContainsAny(authority, "/?#") {
+		return value
+	}
+	switch {
+	case strings.Contains(prefix, "#"):
+		return url.PathEscape(value)
+	case strings.Contains(prefix, "?"):
+		return url.QueryEscape(value)
+	}
+	return url.PathEscape(value)
+}
+
+// decodeDelimiters undoes the percent-encoding of placeholder delimiters in a URL.
+func decodeDelimiters(target string, syntax Syntax) string {
+	if !strings.Contains(target, "%") {
+		return target
+	}
+	left, right := syntax.Delimiters()
+	for _, delimiter := range []string{left, right} {
+		encoded := regexp.MustCompile("(?i)" + regexp.QuoteMeta(url.PathEscape(delimiter)))
+		if encoded.String() != "(?i)"+regexp.QuoteMeta(delimiter) {
+			target = encoded.ReplaceAllLiteralString(target, delimiter)
+		}
+	}
+	return target
+}
+
+// nextRelationshipID returns the first "rIdN" above every rId already used in rels.
+func nextRelationshipID(rels string) string {
+	used := make(map[string]bool)
+	highest := 0
+	for _, match := range relationshipIDPattern.FindAllStringSubmatch(rels, -1) {
+		used[match[1]] = true
+		if n, err := strconv.Atoi(strings.TrimPrefix(match[1], "rId")); err == nil && n > highest {
+			highest = n
+		}
+	}
+
+	for n := highest + 1; ; n++ {
+		if id := "rId" + strconv.Itoa(n); !used[id] {
+			return id
+		}
+	}
+}
+
+// AddContentTypeOverride registers the content type of a part in [Content_Types].xml.
+func (p *Package) AddContentTypeOverride(partName, contentType string) error {
+	content, err := p.Read("[Content_Types].xml")
+	if err != nil {
+		return err
+	}
+
+	types := string(content)
+	if strings.Contains(types, `PartName="/`+partName+`"`) {
+		return nil
+	}
+
+	closing := strings.LastIndex(types, "</Types>")
+	if closing < 0 {
+		return fmt.Errorf("[Content_Types].xml has no Types element")
+	}
+
+	override := `<Override PartName="/` + partName + `" ContentType="` + contentType + `"/>`
+	p.Write("[Content_Types].xml", []byte(types[:closing]+override+types[closing:]))
+	return nil
+}
+
+// removeContentTypeOverride removes the content type registered for a part.
+func (p *Package) removeContentTypeOverride(partName string) error {
+	content, err := p.Read("[Content_Types].xml")
+	if err != nil {
+		return err
+	}
+	types := contentTypeOverride.ReplaceAllStringFunc(string(content), func(override string) string {
+		if strings.EqualFold(attrValue(override, "PartName"), "/"+partName) {
+			return ""
+		}
+		return override
+	})
+	p.Write("[Content_Types].xml", []byte(types))
+	return nil
+}
+
+// ContentType returns the content type of a part and whether it comes from an Override
+// rather than the Default for the part's extension.
+func (p *Package) ContentType(partName string) (string, bool) {
+	content, err := p.Read("[Content_Types].xml")
+	if err != nil {
+		return "", false
+	}
+	types := string(content)
+
+	for _, override := range contentTypeOverride.FindAllString(types, -1) {
+		if strings.EqualFold(attrValue(override, "PartName"), "/"+partName) {
+			return attrValue(override, "ContentType"), true
+		}
+	}
+	ext := strings.TrimPrefix(path.Ext(partName), ".")
+	for _, entry := range contentTypeDefault.FindAllString(types, -1) {
+		if strings.EqualFold(attrValue(entry, "Extension"), ext) {
+			return attrValue(entry, "ContentType"), false
+		}
+	}
+	return "", false
+}
+
+// AddContentTypeDefault registers the content type of an extension in [Content_Types].xml,
+// unless the extension already has one.
+func (p *Package) AddContentTypeDefault(ext, contentType string) error {
+	content, err := p.Read("[Content_Types].xml")
+	if err != nil {
+		return err
+	}
+
+	types := string(content)
+	for _, entry := range contentTypeDefault.FindAllString(types, -1) {
+		if strings.EqualFold(attrValue(entry, "Extension"), ext) {
+			return nil
+		}
+	}
+
+	// Defaults come before the overrides
+	at := strings.Index(types, "<Override")
+	if at < 0 {
+		at = strings.LastIndex(types, "</Types>")
+	}
+	if at < 0 {
+		return fmt.Errorf("[Content_Types].xml has no Types element")
+	}
+
+	entry := `<Default Extension="` + EscapeXMLAttr(ext) + `" ContentType="` + contentType + `"/>`
+	p.Write("[Content_Types].xml", []byte(types[:at]+entry+types[at:]))
+	return nil
+}
+
+// EnsureNamespace declares prefix on the root element of xmlContent if it isn't declared yet.
+func EnsureNamespace(xmlContent, prefix, uri string) string {
+	declaration := ` xmlns:` + prefix + `="`
+	root := rootElementStart(xmlContent)
+	if root < 0 {
+		return xmlContent
+	}
+
+	end := strings.IndexByte(xmlContent[root:], '>')
+	if end < 0 || strings.Contains(xmlContent[root:root+end], declaration) {
+		return xmlContent
+	}
+
+	// Insert right after the element name
+	nameEnd := root + 1
+	for nameEnd < root+end && !strings.ContainsRune(" \t\r\n/", rune(xmlContent[nameEnd])) {
+		nameEnd++
+	}
+	return xmlContent[:nameEnd] + declaration + uri + `"` + xmlContent[nameEnd:]
+}
+
+// rootElementStart returns the offset of the root element's start tag.
+func rootElementStart(xmlContent string) int {
+	for i := 0; i < len(xmlContent); {
+		open := strings.IndexByte(xmlContent[i:], '<')
+		if open < 0 {
+			return -1
+		}
+		open += i
+		if open+1 < len(xmlContent) && xmlContent[open+1] != '?' && xmlContent[open+1] != '!' {
+			return open
+		}
+		i = open + 1
+	}
+	return -1
+}
+
+// EscapeXMLAttr escapes a string for use in a double-quoted XML attribute.
+func EscapeXMLAttr(s string) string {
+	return strings.ReplaceAll(EscapeXMLText(s), `"`, "&quot;")
+}
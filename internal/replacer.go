@@ -0,0 +1,330 @@
+package internal
+
+import (
+	"log"
+	"strconv"
+	"strings"
+)
+
+type replacementPoint struct {
+	startPos    int
+	endPos      int
+	keyword     string
+	replacement string
+	filters     []filterCall
+	rich        *richValue // set when the value is rendered as rich text
+}
+
+// placeholderValue is the replacement registered for a placeholder key
+type placeholderValue struct {
+	keyword string
+	value   string
+}
+
+// trieNode is a node of the byte trie holding the verbatim keywords
+type trieNode struct {
+	children map[byte]*trieNode
+	keyword  string
+	value    string
+	terminal bool
+}
+
+// Replacer finds keywords in template text and resolves their replacement values.
+// Matching is a single left-to-right pass: at each position the longest keyword or
+// placeholder wins, and matches never overlap, so the result is independent of map order.
+type Replacer struct {
+	syntax       Syntax
+	filters      FuncMap
+	placeholders map[string]placeholderValue
+	literals     *trieNode
+	startBytes   [256]bool
+
+	// values and opts are kept to derive scoped replacers for loop iterations
+	values map[string]string
+	opts   Options
+}
+
+// NewReplacer prepares replacements for matching. Keys written as placeholders in the given
+// syntax (e.g. "{{NAME}}") match that placeholder in any form the syntax accepts, such as
+// "{{ NAME }}" when spaces are allowed, or "{{NAME | upper}}" with filters. Any other key is
+// matched verbatim.
+func NewReplacer(replacements map[string]string, opts Options) *Replacer {
+	syntax := opts.Syntax
+	r := &Replacer{
+		syntax:       syntax,
+		filters:      opts.filterFuncs(),
+		placeholders: make(map[string]placeholderValue),
+		literals:     &trieNode{},
+		values:       replacements,
+		opts:         opts,
+	}
+
+	for keyword, value := range replacements {
+		if keyword == "" {
+			continue
+		}
+
+		key, ok := syntax.Key(keyword)
+		if !ok {
+			r.addLiteral(keyword, value)
+			continue
+		}
+
+		// Several keywords can name the same key ("{{NAME}}", "{{ NAME }}"); prefer the
+		// canonical spelling, then the smallest keyword, so the choice never depends on map order
+		if existing, found := r.placeholders[key]; found {
+			canonical := syntax.Wrap(key)
+			if existing.keyword == canonical || (keyword != canonical && existing.keyword < keyword) {
+				continue
+			}
+		}
+		r.placeholders[key] = placeholderValue{keyword: keyword, value: value}
+	}
+
+	// Placeholders with a default filter match even without a value, so always look for them
+	left, _ := syntax.Delimiters()
+	r.startBytes[left[0]] = true
+	return r
+}
+
+func (r *Replacer) addLiteral(keyword, value string) {
+	node := r.literals
+	for i := 0; i < len(keyword); i++ {
+		if node.children == nil {
+			node.children = make(map[byte]*trieNode)
+		}
+		child, ok := node.children[keyword[i]]
+		if !ok {
+			child = &trieNode{}
+			node.children[keyword[i]] = child
+		}
+		node = child
+	}
+	node.keyword, node.value, node.terminal = keyword, value, true
+	r.startBytes[keyword[0]] = true
+}
+
+// Syntax returns the placeholder syntax used by the replacer.
+func (r *Replacer) Syntax() Syntax {
+	return r.syntax
+}
+
+// Lookup returns the value registered for a placeholder key such as "NAME".
+func (r *Replacer) Lookup(key string) (string, bool) {
+	entry, ok := r.placeholders[key]
+	return entry.value, ok
+}
+
+// arrayLen returns the number of elements of the array at path, e.g. 2 for "items" when
+// "{{items[0].name}}" and "{{items[1].name}}" are known.
+func (r *Replacer) arrayLen(path string) int {
+	count := 0
+	for key := range r.placeholders {
+		if index, _, ok := arrayElement(key, path); ok && index+1 > count {
+			count = index + 1
+		}
+	}
+	return count
+}
+
+// scope returns a replacer for one iteration over the array at path. Fields of the element
+// are available without the array prefix ({{name}} for {{items[1].name}}, {{this}} for the
+// element itself) along with the {{@index}}, {{@first}} and {{@last}} helpers.
+func (r *Replacer) scope(path string, index, count int) *Replacer {
+	scoped := make(map[string]string, len(r.values)+4)
+	for keyword, value := range r.values {
+		scoped[keyword] = value
+	}
+
+	for key, entry := range r.placeholders {
+		i, rest, ok := arrayElement(key, path)
+		if !ok || i != index {
+			continue
+		}
+		switch {
+		case rest == "":
+			scoped[r.syntax.Wrap("this")] = entry.value
+		case rest[0] == '.':
+			scoped[r.syntax.Wrap(rest[1:])] = entry.value
+		default:
+			scoped[r.syntax.Wrap("this"+rest)] = entry.value
+		}
+	}
+
+	scoped[r.syntax.Wrap("@index")] = strconv.Itoa(index)
+	scoped[r.syntax.Wrap("@first")] = strconv.FormatBool(index == 0)
+	scoped[r.syntax.Wrap("@last")] = strconv.FormatBool(index == count-1)
+	return NewReplacer(scoped, r.opts)
+}
+
+// arrayElement splits a key such as "items[3].name" into the element index (3) and the
+// remainder (".name") when it addresses an element of the array at path.
+func arrayElement(key, path string) (int, string, bool) {
+	if !strings.HasPrefix(key, path+"[") {
+		return 0, "", false
+	}
+	rest := key[len(path)+1:]
+	closing := strings.IndexByte(rest, ']')
+	if closing <= 0 {
+		return 0, "", false
+	}
+	index, err := strconv.Atoi(rest[:closing])
+	if err != nil || index < 0 {
+		return 0, "", false
+	}
+	return index, rest[closing+1:], true
+}
+
+// FindReplacementPoints identifies all positions in the text where keywords should be replaced.
+// Points are returned in order of position and never overlap; placeholder filters are applied.
+func (r *Replacer) FindReplacementPoints(text string) []replacementPoint {
+	var points []replacementPoint
+	for pos := 0; pos < len(text); {
+		if !r.startBytes[text[pos]] {
+			pos++
+			continue
+		}
+
+		rp, ok := r.longestMatchAt(text, pos)
+		if !ok {
+			pos++
+			continue
+		}
+		if call, ok := r.richFilter(rp.filters); ok {
+			// Keep the parsed value so formats with runs can render it with formatting
+			value := r.applyFilters(rp.replacement, rp.filters[:len(rp.filters)-1])
+			rich, err := parseRichText(call, value)
+			switch {
+			case err != nil:
+				log.Printf("Warning: filter %q failed: %v", call.name, err)
+				rp.replacement = value
+			case rich.paragraphs == nil && rich.table == nil:
+				rp.replacement = ""
+			default:
+				rp.rich = &rich
+				rp.replacement = rich.plainText()
+			}
+		} else {
+			rp.replacement = r.applyFilters(rp.replacement, rp.filters)
+		}
+		points = append(points, rp)
+		pos = rp.endPos
+	}
+	return points
+}
+
+// longestMatchAt returns the longest placeholder or verbatim keyword starting at text[pos:].
+func (r *Replacer) longestMatchAt(text string, pos int) (replacementPoint, bool) {
+	var best replacementPoint
+	found := false
+
+	// Walk the keyword trie as far as the text allows, remembering the last complete keyword
+	node := r.literals
+	for i := pos; i < len(text) && node.children != nil; i++ {
+		next, ok := node.children[text[i]]
+		if !ok {
+			break
+		}
+		node = next
+		if node.terminal {
+			best = replacementPoint{startPos: pos, endPos: i + 1, keyword: node.keyword, replacement: node.value}
+			found = true
+		}
+	}
+
+	// A placeholder wins over a verbatim keyword of the same or shorter length
+	left, _ := r.syntax.Delimiters()
+	if strings.HasPrefix(text[pos:], left) {
+		if expr, end, ok := r.syntax.placeholderAt(text, pos); ok && r.knownFilters(expr.filters) {
+			entry, exists := r.placeholders[expr.key]
+			if !exists && hasFilter(expr.filters, "default") {
+				entry, exists = placeholderValue{keyword: r.syntax.Wrap(expr.key)}, true
+			}
+			if !exists && hasFilter(expr.filters, "table") {
+				// A table can be built from an array in the data
+				if value, ok := r.arrayTable(expr.key); ok {
+					entry, exists = placeholderValue{keyword: r.syntax.Wrap(expr.key), value: value}, true
+				}
+			}
+			if exists && (!found || end >= best.endPos) {
+				best = replacementPoint{startPos: pos, endPos: end, keyword: entry.keyword, replacement: entry.value, filters: expr.filters}
+				found = true
+			}
+		}
+	}
+	return best, found
+}
+
+// knownFilters reports whether every filter of a placeholder is registered.
+// Placeholders using an unknown filter are left untouched.
+func (r *Replacer) knownFilters(calls []filterCall) bool {
+	for _, call := range calls {
+		if _, ok := r.filters[call.name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// applyFilters runs value through the filter chain. A failing filter is logged and the
+// value it received is kept, so one malformed value doesn't abort the whole document.
+func (r *Replacer) applyFilters(value string, calls []filterCall) string {
+	for _, call := range calls {
+		formatted, err := r.filters[call.name](value, call.args...)
+		if err != nil {
+			log.Printf("Warning: filter %q failed: %v", call.name, err)
+			continue
+		}
+		value = formatted
+	}
+	return value
+}
+
+// richFilter returns the last filter of a placeholder when it is the built-in markdown, html
+// or link filter. Custom filters registered under those names are applied as plain filters.
+func (r *Replacer) richFilter(calls []filterCall) (filterCall, bool) {
+	if len(calls) == 0 {
+		return filterCall{}, false
+	}
+	call := calls[len(calls)-1]
+	if _, custom := r.opts.Filters[call.name]; custom || !isRichFilter(call.name) {
+		return filterCall{}, false
+	}
+	return call, true
+}
+
+func hasFilter(calls []filterCall, name string) bool {
+	for _, call := range calls {
+		if call.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainsAnyKeyword checks if text contains any of the keywords known to the replacer.
+func (r *Replacer) ContainsAnyKeyword(text string) bool {
+	for pos := 0; pos < len(text); pos++ {
+		if r.startBytes[text[pos]] {
+			if _, ok := r.longestMatchAt(text, pos); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Replace returns text with every keyword replaced by its value.
+func (r *Replacer) Replace(text string) string {
+	points := r.FindReplacementPoints(text)
+
+	var result strings.Builder
+	cursor := 0
+	for _, rp := range points {
+		result.WriteString(text[cursor:rp.startPos])
+		result.WriteString(rp.replacement)
+		cursor = rp.endPos
+	}
+	result.WriteString(text[cursor:])
+	return result.String()
+}
@@ -0,0 +1,737 @@
+package internal
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// richRun is a piece of formatted text within a rich paragraph
+type richRun struct {
+	text      string
+	bold      bool
+	italic    bool
+	underline bool
+	link      string
+	lineBreak bool
+	tab       bool
+}
+
+// List kinds of a rich paragraph
+const (
+	listNone = iota
+	listBullet
+	listNumber
+)
+
+// richParagraph is a paragraph or list item of a rich text value. Items of the same
+// list share a listID; nested items have a level above 0.
+type richParagraph struct {
+	runs   []richRun
+	list   int
+	level  int
+	listID int
+}
+
+// richValue is a value rendered with formatting: paragraphs of runs, or a table
+type richValue struct {
+	paragraphs []richParagraph
+	table      *Table
+}
+
+// plainText is the value without formatting; see richPlainText.
+func (v richValue) plainText() string {
+	if v.table != nil {
+		return v.table.plainText()
+	}
+	return richPlainText(v.paragraphs)
+}
+
+// isRichFilter reports whether a filter name marks the value as rich text
+func isRichFilter(name string) bool {
+	return name == "markdown" || name == "html" || name == "link" || name == "table"
+}
+
+// parseRichText parses a value as the rich filter call says: Markdown, HTML, a link or a table.
+func parseRichText(call filterCall, value string) (richValue, error) {
+	switch call.name {
+	case "html":
+		return richValue{paragraphs: parseHTML(value)}, nil
+	case "link":
+		return richValue{paragraphs: parseLink(value, call.args)}, nil
+	case "table":
+		table, err := parseTable(value, call.args)
+		if err != nil {
+			return richValue{}, err
+		}
+		return richValue{table: &table}, nil
+	}
+	return richValue{paragraphs: parseMarkdown(value)}, nil
+}
+
+// richPlainText is the text of a rich value without formatting, used where runs can't be
+// formatted (spreadsheet cells, file names). List items keep a bullet or number.
+func richPlainText(paragraphs []richParagraph) string {
+	var b strings.Builder
+	numbers := make(map[[2]int]int)
+
+	for i, paragraph := range paragraphs {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		switch paragraph.list {
+		case listBullet:
+			b.WriteString(strings.Repeat("  ", paragraph.level) + "• ")
+		case listNumber:
+			key := [2]int{paragraph.listID, paragraph.level}
+			numbers[key]++
+			b.WriteString(strings.Repeat("  ", paragraph.level) + strconv.Itoa(numbers[key]) + ". ")
+		}
+		for _, run := range paragraph.runs {
+			if run.lineBreak {
+				b.WriteString("\n")
+			} else {
+				b.WriteString(run.text)
+			}
+		}
+	}
+	return b.String()
+}
+
+func filterMarkdown(value string, args ...string) (string, error) {
+	return richPlainText(parseMarkdown(value)), nil
+}
+
+func filterHTML(value string, args ...string) (string, error) {
+	return richPlainText(parseHTML(value)), nil
+}
+
+func filterLink(value string, args ...string) (string, error) {
+	return richPlainText(parseLink(value, args)), nil
+}
+
+var linkValuePattern = regexp.MustCompile(`^\[(.*)\]\((\S+)\)$`)
+
+// parseLink reads a link value: a URL, or "[text](url)" as written by Link. An argument sets
+// the text, e.g. {{PORTAL | link:"Customer portal"}}. Without text, the URL is shown
+// without a mailto: or tel: prefix. Unsafe URLs are shown as text without a link.
+func parseLink(value string, args []string) []richParagraph {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+
+	text, target := "", value
+	if match := linkValuePattern.FindStringSubmatch(value); match != nil {
+		text, target = unescapeLinkText(match[1]), match[2]
+	}
+	if len(args) > 0 && args[0] != "" {
+		text = args[0]
+	}
+
+	link, safe := safeLink(target)
+	if text == "" {
+		text = target
+		if safe {
+			if scheme, rest, found := strings.Cut(target, ":"); found && (strings.EqualFold(scheme, "mailto") || strings.EqualFold(scheme, "tel")) {
+				text = rest
+			}
+		}
+	}
+	return []richParagraph{{runs: []richRun{{text: text, link: link}}}}
+}
+
+func unescapeLinkText(text string) string {
+	return strings.NewReplacer(`\\`, `\`, `\[`, "[", `\]`, "]").Replace(text)
+}
+
+// safeLink returns the link target if it uses a scheme that is safe to put in a document.
+func safeLink(target string) (string, bool) {
+	target = strings.TrimSpace(target)
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return "", false
+	}
+	switch strings.ToLower(parsed.Scheme) {
+	case "http", "https", "mailto", "tel", "ftp":
+		return target, true
+	}
+	return "", false
+}
+
+var markdownListPattern = regexp.MustCompile(`^(\s*)([-*+]|\d{1,9}[.)])\s+(.*)$`)
+
+// parseMarkdown reads a Markdown subset: paragraphs, line breaks, **bold**, *italic*,
+// <u>underline</u>, [links](https://...) and bulleted or numbered lists.
+func parseMarkdown(text string) []richParagraph {
+	var paragraphs []richParagraph
+	var current *richParagraph
+	listID := 0
+	inList := false
+
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			current = nil
+			inList = false
+			continue
+		}
+
+		if match := markdownListPattern.FindStringSubmatch(line); match != nil {
+			if !inList {
+				listID++
+				inList = true
+			}
+			kind := listBullet
+			if unicode.IsDigit(rune(match[2][0])) {
+				kind = listNumber
+			}
+			level := min(indentWidth(match[1])/2, 8)
+			paragraphs = append(paragraphs, richParagraph{runs: parseMarkdownInline(match[3], richRun{}), list: kind, level: level, listID: listID})
+			current = &paragraphs[len(paragraphs)-1]
+			continue
+		}
+
+		// Continuation lines are joined with a line break, also within a list item
+		if current != nil {
+			current.runs = append(current.runs, richRun{lineBreak: true})
+			current.runs = append(current.runs, parseMarkdownInline(strings.TrimSpace(line), richRun{})...)
+			continue
+		}
+		paragraphs = append(paragraphs, richParagraph{runs: parseMarkdownInline(strings.TrimSpace(line), richRun{})})
+		current = &paragraphs[len(paragraphs)-1]
+	}
+	return paragraphs
+}
+
+func indentWidth(indent string) int {
+	width := 0
+	for _, r := range indent {
+		if r == '\t' {
+			width += 4
+		} else {
+			width++
+		}
+	}
+	return width
+}
+
+// parseMarkdownInline parses inline formatting, starting from the formatting in style.
+func parseMarkdownInline(text string, style richRun) []richRun {
+	var runs []richRun
+	var plain strings.Builder
+
+	flush := func() {
+		if plain.Len() > 0 {
+			run := style
+			run.text = plain.String()
+			runs = append(runs, run)
+			plain.Reset()
+		}
+	}
+
+	for i := 0; i < len(text); {
+		rest := text[i:]
+
+		switch {
+		case rest[0] == '\\' && len(rest) > 1 && strings.ContainsRune(`\*_[]()<>#+-.!`+"`", rune(rest[1])):
+			plain.WriteByte(rest[1])
+			i += 2
+			continue
+
+		case strings.HasPrefix(rest, "**") || strings.HasPrefix(rest, "__"):
+			if inner, ok := delimited(rest, rest[:2]); ok {
+				flush()
+				inside := style
+				inside.bold = true
+				runs = append(runs, parseMarkdownInline(inner, inside)...)
+				i += len(inner) + 4
+				continue
+			}
+
+		case rest[0] == '*' || (rest[0] == '_' && (i == 0 || !isWordByte(text[i-1]))):
+			if inner, ok := delimited(rest, rest[:1]); ok {
+				flush()
+				inside := style
+				inside.italic = true
+				runs = append(runs, parseMarkdownInline(inner, inside)...)
+				i += len(inner) + 2
+				continue
+			}
+
+		case strings.HasPrefix(rest, "<u>"):
+			if end := strings.Index(rest, "</u>"); end > len("<u>") {
+				flush()
+				inside := style
+				inside.underline = true
+				runs = append(runs, parseMarkdownInline(rest[len("<u>"):end], inside)...)
+				i += end + len("</u>")
+				continue
+			}
+
+		case rest[0] == '[':
+			if label, target, length, ok := markdownLink(rest); ok {
+				flush()
+				inside := style
+				if link, safe := safeLink(target); safe {
+					inside.link = link
+				}
+				runs = append(runs, parseMarkdownInline(label, inside)...)
+				i += length
+				continue
+			}
+		}
+
+		_, size := utf8.DecodeRuneInString(rest)
+		plain.WriteString(rest[:size])
+		i += size
+	}
+	flush()
+	return runs
+}
+
+// delimited returns the text between an opening marker at the start of s and the next
+// closing marker. The content may not start or end with a space, so "2 * 3 * 4" stays literal.
+func delimited(s, marker string) (string, bool) {
+	end := strings.Index(s[len(marker):], marker)
+	if end <= 0 {
+		return "", false
+	}
+	inner := s[len(marker) : len(marker)+end]
+	if strings.TrimSpace(inner) != inner {
+		return "", false
+	}
+	return inner, true
+}
+
+// markdownLink parses "[label](target)" at the start of s.
+func markdownLink(s string) (label, target string, length int, ok bool) {
+	closeLabel := strings.Index(s, "](")
+	if closeLabel < 0 {
+		return "", "", 0, false
+	}
+	closeTarget := strings.IndexByte(s[closeLabel+2:], ')')
+	if closeTarget < 0 {
+		return "", "", 0, false
+	}
+	label = s[1:closeLabel]
+	target = s[closeLabel+2 : closeLabel+2+closeTarget]
+	if label == "" || strings.ContainsAny(target, " \t\n") {
+		return "", "", 0, false
+	}
+	return label, target, closeLabel + 2 + closeTarget + 1, true
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= 0x80 || unicode.IsLetter(rune(b)) || unicode.IsDigit(rune(b))
+}
+
+var (
+	htmlTagPattern   = regexp.MustCompile(`^<(/?)([a-zA-Z][a-zA-Z0-9]*)([^>]*)>`)
+	htmlHrefPattern  = regexp.MustCompile(`(?i)\bhref\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+	htmlSpacePattern = regexp.MustCompile(`[ \t\r\n\f]+`)
+)
+
+// parseHTML reads a limited HTML subset: b/strong, i/em, u, a, br, p/div and ul/ol/li.
+// Other tags are ignored and their text is kept, except for script and style elements,
+// which are dropped with their content.
+func parseHTML(text string) []richParagraph {
+	p := htmlParser{}
+
+	for i := 0; i < len(text); {
+		if text[i] == '<' {
+			if match := htmlTagPattern.FindStringSubmatch(text[i:]); match != nil {
+				name, closing := strings.ToLower(match[2]), match[1] == "/"
+				i += len(match[0])
+				if !closing && (name == "script" || name == "style") {
+					i = skipRawText(text, i, name)
+					continue
+				}
+				p.tag(name, closing, match[3])
+				continue
+			}
+		}
+
+		end := strings.IndexByte(text[i+1:], '<')
+		if end < 0 {
+			end = len(text)
+		} else {
+			end += i + 1
+		}
+		p.text(html.UnescapeString(htmlSpacePattern.ReplaceAllString(text[i:end], " ")))
+		i = end
+	}
+	return p.finish()
+}
+
+// skipRawText returns the offset following the end tag of the script or style element whose
+// content starts at text[start:], or the end of text if the element isn't closed.
+func skipRawText(text string, start int, name string) int {
+	for i := start; ; {
+		end := strings.Index(text[i:], "</")
+		if end < 0 {
+			return len(text)
+		}
+		end += i
+		if closing := text[end+2:]; len(closing) >= len(name) && strings.EqualFold(closing[:len(name)], name) {
+			if tagEnd := strings.IndexByte(text[end:], '>'); tagEnd >= 0 {
+				return end + tagEnd + 1
+			}
+			return len(text)
+		}
+		i = end + 2
+	}
+}
+
+type htmlParser struct {
+	paragraphs []richParagraph
+	current    *richParagraph
+	bold       int
+	italic     int
+	underline  int
+	links      []string
+	lists      []int
+	listIDs    []int
+	nextListID int
+}
+
+func (p *htmlParser) tag(name string, closing bool, attrs string) {
+	delta := 1
+	if closing {
+		delta = -1
+	}
+
+	switch name {
+	case "b", "strong":
+		p.bold = max(p.bold+delta, 0)
+	case "i", "em":
+		p.italic = max(p.italic+delta, 0)
+	case "u":
+		p.underline = max(p.underline+delta, 0)
+	case "a":
+		if closing {
+			if len(p.links) > 0 {
+				p.links = p.links[:len(p.links)-1]
+			}
+			return
+		}
+		link := ""
+		if match := htmlHrefPattern.FindStringSubmatch(attrs); match != nil {
+			link, _ = safeLink(html.UnescapeString(match[1] + match[2] + match[3]))
+		}
+		p.links = append(p.links, link)
+	case "br":
+		p.ensureParagraph()
+		p.current.runs = append(p.current.runs, richRun{lineBreak: true})
+	case "p", "div":
+		p.current = nil
+	case "ul", "ol":
+		if closing {
+			if len(p.lists) > 0 {
+				p.lists = p.lists[:len(p.lists)-1]
+				p.listIDs = p.listIDs[:len(p.listIDs)-1]
+			}
+		} else {
+			kind := listBullet
+			if name == "ol" {
+				kind = listNumber
+			}
+			id := p.nextListID + 1
+			if len(p.listIDs) > 0 {
+				id = p.listIDs[0] // nested lists belong to the outer list
+			} else {
+				p.nextListID++
+			}
+			p.lists = append(p.lists, kind)
+			p.listIDs = append(p.listIDs, id)
+		}
+		p.current = nil
+	case "li":
+		p.current = nil
+		if !closing {
+			paragraph := richParagraph{list: listBullet}
+			if n := len(p.lists); n > 0 {
+				paragraph = richParagraph{list: p.lists[n-1], level: min(n-1, 8), listID: p.listIDs[n-1]}
+			}
+			p.paragraphs = append(p.paragraphs, paragraph)
+			p.current = &p.paragraphs[len(p.paragraphs)-1]
+		}
+	}
+}
+
+func (p *htmlParser) text(text string) {
+	if p.current == nil || len(p.current.runs) == 0 || p.current.runs[len(p.current.runs)-1].lineBreak {
+		text = strings.TrimLeft(text, " ")
+	}
+	if text == "" {
+		return
+	}
+
+	p.ensureParagraph()
+	run := richRun{text: text, bold: p.bold > 0, italic: p.italic > 0, underline: p.underline > 0}
+	if len(p.links) > 0 {
+		run.link = p.links[len(p.links)-1]
+	}
+	p.current.runs = append(p.current.runs, run)
+}
+
+func (p *htmlParser) ensureParagraph() {
+	if p.current == nil {
+		p.paragraphs = append(p.paragraphs, richParagraph{})
+		p.current = &p.paragraphs[len(p.paragraphs)-1]
+	}
+}
+
+// finish trims trailing spaces and drops paragraphs left empty by the markup.
+func (p *htmlParser) finish() []richParagraph {
+	var paragraphs []richParagraph
+	for _, paragraph := range p.paragraphs {
+		if n := len(paragraph.runs); n > 0 && !paragraph.runs[n-1].lineBreak {
+			paragraph.runs[n-1].text = strings.TrimRight(paragraph.runs[n-1].text, " ")
+		}
+		if len(paragraph.runs) > 0 || paragraph.list != listNone {
+			paragraphs = append(paragraphs, paragraph)
+		}
+	}
+	return paragraphs
+}
+
+// richMarker is the text standing in for the i-th rich value of an element until the value
+// is rendered. Private use characters never occur in template text.
+func richMarker(i int) string {
+	return "\uE000" + strconv.Itoa(i) + "\uE001"
+}
+
+// runMarkup names the run, run properties and text elements of WordprocessingML (w:r, w:rPr,
+// w:t) or DrawingML (a:r, a:rPr, a:t).
+type runMarkup struct {
+	run, props, text string
+}
+
+// markerRun is the run holding a rich value marker, split around the marker.
+type markerRun struct {
+	start, end int    // offsets of the run element
+	props      string // run properties, empty if the run has none
+	head       string // from the run start tag up to the text element
+	plainHead  bool   // head holds only the start tag and properties
+	before     string // escaped text before the marker
+	after      string // escaped text after the marker
+	tail       string // from the end of the text element through the run end tag
+}
+
+// findMarkerRun locates the run of element whose text holds element[pos:end].
+func findMarkerRun(element string, pos, end int, markup runMarkup) (markerRun, bool) {
+	runStart := max(strings.LastIndex(element[:pos], "<"+markup.run+">"), strings.LastIndex(element[:pos], "<"+markup.run+" "))
+	textStart := max(strings.LastIndex(element[:pos], "<"+markup.text+">"), strings.LastIndex(element[:pos], "<"+markup.text+" "))
+	if runStart < 0 || textStart < runStart {
+		return markerRun{}, false
+	}
+	textOpenEnd := strings.IndexByte(element[textStart:], '>') + textStart + 1
+	textClose := strings.Index(element[end:], "</"+markup.text+">")
+	runClose := strings.Index(element[end:], "</"+markup.run+">")
+	if textClose < 0 || runClose < textClose {
+		return markerRun{}, false
+	}
+	textClose += end
+	runEnd := runClose + end + len("</"+markup.run+">")
+
+	runOpenEnd := strings.IndexByte(element[runStart:], '>') + runStart + 1
+	head := element[runStart:textStart]
+	props := ""
+	if rest := element[runOpenEnd:textStart]; strings.HasPrefix(rest, "<"+markup.props) {
+		props = leadingElement(rest, markup.props)
+	}
+
+	return markerRun{
+		start:     runStart,
+		end:       runEnd,
+		props:     props,
+		head:      head,
+		plainHead: runOpenEnd+len(props) == textStart,
+		before:    element[textOpenEnd:pos],
+		after:     element[end:textClose],
+		tail:      element[textClose+len("</"+markup.text+">") : runEnd],
+	}, true
+}
+
+// splitMarkerRun returns the markup replacing the run: the text before the marker keeps the
+// run, then come the rich runs, then a copy of the run with the text after the marker.
+func splitMarkerRun(run markerRun, runs string, markup runMarkup) string {
+	var b strings.Builder
+	closeRun := "</" + markup.run + ">"
+
+	if run.before != "" {
+		b.WriteString(run.head + textStartTag(markup.text, run.before) + run.before + "</" + markup.text + ">" + closeRun)
+	} else if !run.plainHead {
+		b.WriteString(run.head + closeRun)
+	}
+
+	b.WriteString(runs)
+
+	if run.after != "" {
+		b.WriteString("<" + markup.run + ">" + run.props + textStartTag(markup.text, run.after) + run.after + "</" + markup.text + ">" + run.tail)
+	} else if run.tail != closeRun {
+		b.WriteString("<" + markup.run + ">" + run.props + run.tail)
+	}
+	return b.String()
+}
+
+func textStartTag(name, content string) string {
+	tag := "<" + name + ">"
+	if needsSpacePreserve(tag, content) {
+		tag = addSpacePreserve(tag)
+	}
+	return tag
+}
+
+// leadingElement returns the element named name at the start of markup, either self-closing
+// or up to its matching end tag.
+func leadingElement(markup, name string) string {
+	tagEnd := strings.IndexByte(markup, '>')
+	if tagEnd < 0 {
+		return ""
+	}
+	if markup[tagEnd-1] == '/' {
+		return markup[:tagEnd+1]
+	}
+	if children := xmlChildren(markup); len(children) > 0 && elementName(children[0]) == name {
+		return children[0]
+	}
+	return ""
+}
+
+// xmlChildren splits the content of an element into its top-level child elements.
+func xmlChildren(content string) []string {
+	var children []string
+	depth, start := 0, 0
+
+	for i := 0; i < len(content); {
+		open := strings.IndexByte(content[i:], '<')
+		if open < 0 {
+			break
+		}
+		open += i
+		closeTag := strings.IndexByte(content[open:], '>')
+		if closeTag < 0 {
+			break
+		}
+		closeTag += open
+		tag := content[open : closeTag+1]
+
+		switch {
+		case strings.HasPrefix(tag, "</"):
+			depth--
+			if depth == 0 {
+				children = append(children, content[start:closeTag+1])
+			}
+		case strings.HasSuffix(tag, "/>"):
+			if depth == 0 {
+				children = append(children, tag)
+			}
+		default:
+			if depth == 0 {
+				start = open
+			}
+			depth++
+		}
+		i = closeTag + 1
+	}
+	return children
+}
+
+// elementName returns the qualified name of the element starting markup.
+func elementName(markup string) string {
+	end := strings.IndexAny(markup, " />\t\r\n")
+	if end < 1 {
+		return ""
+	}
+	return markup[1:end]
+}
+
+// setChild returns the property element props (e.g. a w:rPr) with child replacing the child
+// of the same name, or inserted where order (the schema sequence) puts it.
+func setChild(props, name, child string, order []string) string {
+	start, children := splitProps(props, name)
+	rank := childRank(elementName(child), order)
+
+	var b strings.Builder
+	b.WriteString(start)
+	inserted := false
+	for _, existing := range children {
+		existingName := elementName(existing)
+		if existingName == elementName(child) {
+			continue
+		}
+		if !inserted && childRank(existingName, order) > rank {
+			b.WriteString(child)
+			inserted = true
+		}
+		b.WriteString(existing)
+	}
+	if !inserted {
+		b.WriteString(child)
+	}
+	b.WriteString("</" + name + ">")
+	return b.String()
+}
+
+// removeChild returns props without its children named childName.
+func removeChild(props, name, childName string) string {
+	if props == "" {
+		return ""
+	}
+	start, children := splitProps(props, name)
+
+	var b strings.Builder
+	b.WriteString(start)
+	for _, child := range children {
+		if elementName(child) != childName {
+			b.WriteString(child)
+		}
+	}
+	b.WriteString("</" + name + ">")
+	return b.String()
+}
+
+// splitProps returns the start tag and the children of props, which may be empty or self-closing.
+func splitProps(props, name string) (string, []string) {
+	if props == "" {
+		return "<" + name + ">", nil
+	}
+	tagEnd := strings.IndexByte(props, '>')
+	if props[tagEnd-1] == '/' {
+		return strings.TrimSuffix(props[:tagEnd-1], " ") + ">", nil
+	}
+	return props[:tagEnd+1], xmlChildren(props[tagEnd+1 : len(props)-len("</"+name+">")])
+}
+
+// childRank is the position of name in the schema sequence; unknown elements go last.
+func childRank(name string, order []string) int {
+	for i, known := range order {
+		if known == name {
+			return i
+		}
+	}
+	return len(order)
+}
+
+// setAttr sets an attribute on the start tag of markup.
+func setAttr(markup, attr, value string) string {
+	tagEnd := strings.IndexByte(markup, '>')
+	if tagEnd < 0 {
+		return markup
+	}
+	tag := markup[:tagEnd+1]
+	pattern := regexp.MustCompile(`\s` + regexp.QuoteMeta(attr) + `="[^"]*"`)
+	if pattern.MatchString(tag) {
+		tag = pattern.ReplaceAllString(tag, " "+attr+`="`+EscapeXMLAttr(value)+`"`)
+	} else {
+		nameEnd := len(elementName(tag)) + 1
+		tag = tag[:nameEnd] + " " + attr + `="` + EscapeXMLAttr(value) + `"` + tag[nameEnd:]
+	}
+	return tag + markup[tagEnd+1:]
+}
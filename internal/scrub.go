@@ -0,0 +1,495 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Relationship types of the parts a scrub removes
+const (
+	RelTypeThumbnail          = "http://schemas.openxmlformats.org/package/2006/relationships/metadata/thumbnail"
+	RelTypeCustomXML          = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/customXml"
+	RelTypeCommentsExtended   = "http://schemas.microsoft.com/office/2011/relationships/commentsExtended"
+	RelTypeCommentsIDs        = "http://schemas.microsoft.com/office/2016/09/relationships/commentsIds"
+	RelTypeCommentsExtensible = "http://schemas.microsoft.com/office/2018/08/relationships/commentsExtensible"
+	RelTypePeople             = "http://schemas.microsoft.com/office/2011/relationships/people"
+	RelTypeCommentAuthors     = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/commentAuthors"
+	RelTypeModernComments     = "http://schemas.microsoft.com/office/2018/10/relationships/comments"
+	RelTypeCommentAuthorsNew  = "http://schemas.microsoft.com/office/2018/10/relationships/authors"
+	RelTypeRevisionInfo       = "http://schemas.microsoft.com/office/2015/10/relationships/revisionInfo"
+	RelTypeVMLDrawing         = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/vmlDrawing"
+	RelTypeThreadedComment    = "http://schemas.microsoft.com/office/2017/10/relationships/threadedComment"
+	RelTypePerson             = "http://schemas.microsoft.com/office/2017/10/relationships/person"
+	RelTypeRevisionHeaders    = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/revisionHeaders"
+)
+
+var (
+	wordCommentCount     = regexp.MustCompile(`<w:comment\b`)
+	drawingCommentCount  = regexp.MustCompile(`<(?:p:cm|p188:cm)\b`)
+	sheetCommentCount    = regexp.MustCompile(`<(?:comment|threadedComment)\b`)
+	vmlObjectTypePattern = regexp.MustCompile(`ObjectType="(\w+)"`)
+	sheetLegacyDrawing   = regexp.MustCompile(`<legacyDrawing\b[^>]*/>`)
+
+	// Properties naming the people who worked on a file
+	personalProperties = []string{"core:creator", "core:lastModifiedBy", "core:lastPrinted", "app:Manager"}
+)
+
+// ScrubOptions selects what Scrub leaves in a file; the zero value removes everything it can.
+type ScrubOptions struct {
+	// KeepPersonalInfo keeps the author, last editor, print date and manager properties and
+	// the list of people who commented or made changes.
+	KeepPersonalInfo bool
+
+	// KeepComments keeps comments and their authors.
+	KeepComments bool
+
+	// KeepRevisions keeps tracked changes, revision IDs and revision logs.
+	KeepRevisions bool
+
+	// RejectRevisions rejects tracked changes instead of accepting them (DOCX).
+	RejectRevisions bool
+
+	// KeepHiddenText keeps text formatted as hidden (DOCX).
+	KeepHiddenText bool
+
+	// KeepCustomXML keeps custom XML data parts and the content controls bound to them.
+	KeepCustomXML bool
+
+	// KeepThumbnail keeps the preview image of the first page or slide.
+	KeepThumbnail bool
+}
+
+// ScrubItem is a kind of content a scrub removed, with how much of it.
+type ScrubItem struct {
+	What  string
+	Count int
+}
+
+// ScrubReport lists what a scrub removed, in the order it was removed.
+type ScrubReport struct {
+	Items []ScrubItem
+}
+
+func (r *ScrubReport) add(what string, count int) {
+	if count > 0 {
+		r.Items = append(r.Items, ScrubItem{What: what, Count: count})
+	}
+}
+
+// String returns the report a line per item, e.g. "comments: 3".
+func (r ScrubReport) String() string {
+	if len(r.Items) == 0 {
+		return "nothing to remove\n"
+	}
+	var b strings.Builder
+	for _, item := range r.Items {
+		fmt.Fprintf(&b, "%s: %d\n", item.What, item.Count)
+	}
+	return b.String()
+}
+
+// ScrubDocx removes authoring traces from a DOCX package: personal properties, tracked changes
+// (accepted or rejected), revision IDs, comments, hidden text, the people part, custom XML data
+// and the thumbnail.
+func ScrubDocx(pkg *Package, opts ScrubOptions) (ScrubReport, error) {
+	var report ScrubReport
+
+	if !opts.KeepRevisions {
+		count, err := ResolveDocxRevisions(pkg, !opts.RejectRevisions)
+		if err != nil {
+			return report, err
+		}
+		if opts.RejectRevisions {
+			report.add("tracked changes rejected", count)
+		} else {
+			report.add("tracked changes accepted", count)
+		}
+
+		count = 0
+		err = rewriteParts(pkg, isWordXMLPart, func(name, content string) string {
+			count += len(wordRsidAttrPattern.FindAllStringIndex(content, -1))
+			content = wordRsidAttrPattern.ReplaceAllString(content, "")
+			if name == "word/settings.xml" {
+				content = removeElements(content, "w:rsids")
+			}
+			return content
+		})
+		if err != nil {
+			return report, err
+		}
+		report.add("revision IDs", count)
+	}
+
+	if !opts.KeepComments {
+		count := 0
+		if part, ok := pkg.relatedPart(documentPartName, RelTypeComments); ok {
+			content, err := pkg.Read(part)
+			if err != nil {
+				return report, err
+			}
+			count = len(wordCommentCount.FindAllStringIndex(string(content), -1))
+		}
+		err := rewriteParts(pkg, IsWordStoryPart, func(name, content string) string {
+			content, _ = removeRuns(content, isCommentReferenceRun)
+			return wordCommentPattern.ReplaceAllString(content, "")
+		})
+		if err != nil {
+			return report, err
+		}
+		if _, err := removeRelatedParts(pkg, documentPartName, RelTypeComments, RelTypeCommentsExtended,
+			RelTypeCommentsIDs, RelTypeCommentsExtensible); err != nil {
+			return report, err
+		}
+		report.add("comments", count)
+	}
+
+	if !opts.KeepHiddenText {
+		count := 0
+		err := rewriteParts(pkg, IsWordStoryPart, func(name, content string) string {
+			content, removed := removeRuns(content, isHiddenRun)
+			count += removed
+			return content
+		})
+		if err != nil {
+			return report, err
+		}
+		report.add("hidden text runs", count)
+	}
+
+	if !opts.KeepPersonalInfo {
+		if err := scrubPersonalInfo(pkg, &report, documentPartName, RelTypePeople); err != nil {
+			return report, err
+		}
+	}
+
+	if !opts.KeepCustomXML {
+		count := 0
+		err := rewriteParts(pkg, IsWordStoryPart, func(name, content string) string {
+			count += strings.Count(content, "<w:dataBinding")
+			return removeElements(content, "w:dataBinding")
+		})
+		if err != nil {
+			return report, err
+		}
+		report.add("data bindings", count)
+		if err := scrubParts(pkg, &report, "custom XML parts", documentPartName, RelTypeCustomXML); err != nil {
+			return report, err
+		}
+	}
+
+	if !opts.KeepThumbnail {
+		if err := scrubParts(pkg, &report, "thumbnails", packageRelsOwner, RelTypeThumbnail); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// ScrubPptx removes authoring traces from a PPTX package: personal properties, comments and
+// their authors, revision information, custom XML data and the thumbnail.
+func ScrubPptx(pkg *Package, opts ScrubOptions) (ScrubReport, error) {
+	var report ScrubReport
+
+	if !opts.KeepRevisions {
+		if err := scrubParts(pkg, &report, "revision information parts", presentationPartName, RelTypeRevisionInfo); err != nil {
+			return report, err
+		}
+	}
+
+	if !opts.KeepComments {
+		count := 0
+		for _, name := range pkg.Names() {
+			if !strings.HasPrefix(name, "ppt/slides/slide") || !strings.HasSuffix(name, ".xml") {
+				continue
+			}
+			removed, err := removeRelatedParts(pkg, name, RelTypeComments, RelTypeModernComments)
+			if err != nil {
+				return report, err
+			}
+			if len(removed) == 0 {
+				continue
+			}
+			for _, content := range removed {
+				count += len(drawingCommentCount.FindAllStringIndex(content, -1))
+			}
+
+			// Modern comments are also referred to from the slide's extension list
+			content, err := pkg.Read(name)
+			if err != nil {
+				return report, err
+			}
+			pkg.Write(name, []byte(removeExtensions(string(content), "p:ext", "commentRel")))
+		}
+		if _, err := removeRelatedParts(pkg, presentationPartName, RelTypeCommentAuthors, RelTypeCommentAuthorsNew); err != nil {
+			return report, err
+		}
+		report.add("comments", count)
+	}
+
+	if !opts.KeepPersonalInfo {
+		if err := scrubPersonalInfo(pkg, &report, presentationPartName); err != nil {
+			return report, err
+		}
+	}
+	if !opts.KeepCustomXML {
+		if err := scrubParts(pkg, &report, "custom XML parts", presentationPartName, RelTypeCustomXML); err != nil {
+			return report, err
+		}
+	}
+	if !opts.KeepThumbnail {
+		if err := scrubParts(pkg, &report, "thumbnails", packageRelsOwner, RelTypeThumbnail); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// ScrubXlsx removes authoring traces from an XLSX package: personal properties, comments,
+// threaded comments and their authors, the revision log of shared workbooks, custom XML data
+// and the thumbnail.
+func ScrubXlsx(pkg *Package, opts ScrubOptions) (ScrubReport, error) {
+	var report ScrubReport
+
+	if !opts.KeepRevisions {
+		if err := scrubParts(pkg, &report, "revision log parts", workbookPartName, RelTypeRevisionHeaders); err != nil {
+			return report, err
+		}
+	}
+
+	if !opts.KeepComments {
+		count := 0
+		for _, name := range pkg.Names() {
+			if !strings.HasPrefix(name, "xl/worksheets/") || !strings.HasSuffix(name, ".xml") || strings.Contains(name, "/_rels/") {
+				continue
+			}
+			removed, err := removeRelatedParts(pkg, name, RelTypeComments, RelTypeThreadedComment)
+			if err != nil {
+				return report, err
+			}
+			if len(removed) == 0 {
+				continue
+			}
+			for _, content := range removed {
+				count += len(sheetCommentCount.FindAllStringIndex(content, -1))
+			}
+			if err := removeNoteDrawings(pkg, name); err != nil {
+				return report, err
+			}
+		}
+		if _, err := removeRelatedParts(pkg, workbookPartName, RelTypePerson); err != nil {
+			return report, err
+		}
+		report.add("comments", count)
+	}
+
+	if !opts.KeepPersonalInfo {
+		if err := scrubPersonalInfo(pkg, &report, workbookPartName); err != nil {
+			return report, err
+		}
+	}
+	if !opts.KeepCustomXML {
+		if err := scrubParts(pkg, &report, "custom XML parts", workbookPartName, RelTypeCustomXML); err != nil {
+			return report, err
+		}
+	}
+	if !opts.KeepThumbnail {
+		if err := scrubParts(pkg, &report, "thumbnails", packageRelsOwner, RelTypeThumbnail); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// scrubPersonalInfo removes the personal properties and the parts listing people, related to
+// owner with relTypes.
+func scrubPersonalInfo(pkg *Package, report *ScrubReport, owner string, relTypes ...string) error {
+	props, err := ReadProperties(pkg)
+	if err != nil {
+		return err
+	}
+	personal := make(map[string]string)
+	for _, name := range personalProperties {
+		kind, key, _ := strings.Cut(name, ":")
+		values := props.Core
+		if kind == "app" {
+			values = props.App
+		}
+		if values[key] != "" {
+			personal[name] = ""
+		}
+	}
+	if err := SetProperties(pkg, personal); err != nil {
+		return err
+	}
+	report.add("personal properties", len(personal))
+
+	if len(relTypes) > 0 {
+		return scrubParts(pkg, report, "people parts", owner, relTypes...)
+	}
+	return nil
+}
+
+// scrubParts removes the parts related to owner with relTypes and reports how many there were.
+func scrubParts(pkg *Package, report *ScrubReport, what, owner string, relTypes ...string) error {
+	removed, err := removeRelatedParts(pkg, owner, relTypes...)
+	report.add(what, len(removed))
+	return err
+}
+
+// removeRelatedParts removes the relationships of owner with relTypes, and the parts they
+// pointed to unless other parts still refer to them. It returns the content of the parts by name.
+func removeRelatedParts(pkg *Package, owner string, relTypes ...string) (map[string]string, error) {
+	rels, err := pkg.readRelationships(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]bool)
+	removed := make(map[string]string)
+	var targets []string
+	for id, rel := range rels {
+		for _, relType := range relTypes {
+			if rel.relType != relType {
+				continue
+			}
+			ids[id] = true
+			if rel.external {
+				continue
+			}
+			target := resolveTarget(owner, rel.target)
+			if content, err := pkg.Read(target); err == nil {
+				removed[target] = string(content)
+			}
+			targets = append(targets, target)
+		}
+	}
+	if len(ids) == 0 {
+		return removed, nil
+	}
+	sort.Strings(targets)
+
+	if err := pkg.removeRelationships(owner, ids); err != nil {
+		return nil, err
+	}
+	return removed, pkg.deleteUnreferenced(targets)
+}
+
+// removeNoteDrawings removes the VML drawing showing the comments of a worksheet, unless it
+// also holds form controls or other shapes.
+func removeNoteDrawings(pkg *Package, sheet string) error {
+	part, ok := pkg.relatedPart(sheet, RelTypeVMLDrawing)
+	if !ok {
+		return nil
+	}
+	vml, err := pkg.Read(part)
+	if err != nil {
+		return err
+	}
+	for _, objectType := range vmlObjectTypePattern.FindAllStringSubmatch(string(vml), -1) {
+		if objectType[1] != "Note" {
+			return nil
+		}
+	}
+
+	if _, err := removeRelatedParts(pkg, sheet, RelTypeVMLDrawing); err != nil {
+		return err
+	}
+	content, err := pkg.Read(sheet)
+	if err != nil {
+		return err
+	}
+	pkg.Write(sheet, sheetLegacyDrawing.ReplaceAll(content, nil))
+	return nil
+}
+
+// removeRuns removes the runs for which remove reports true, given the run's children, and
+// returns how many there were.
+func removeRuns(content string, remove func(children []string) bool) (string, int) {
+	var b strings.Builder
+	count := 0
+	for {
+		start := indexElement(content, "w:r")
+		if start < 0 {
+			b.WriteString(content)
+			return b.String(), count
+		}
+		run := leadingElement(content[start:], "w:r")
+		tagEnd := strings.IndexByte(content[start:], '>') + 1
+		if run == "" || tagEnd >= len(run) {
+			// Unterminated or empty run; look past its start tag
+			b.WriteString(content[:start+tagEnd])
+			content = content[start+tagEnd:]
+			continue
+		}
+
+		if remove(xmlChildren(run[tagEnd : len(run)-len("</w:r>")])) {
+			b.WriteString(content[:start])
+			content = content[start+len(run):]
+			count++
+			continue
+		}
+		// Runs may hold text boxes with runs of their own
+		b.WriteString(content[:start+tagEnd])
+		content = content[start+tagEnd:]
+	}
+}
+
+// isHiddenRun reports whether a run is formatted as hidden text.
+func isHiddenRun(children []string) bool {
+	return len(children) > 0 && elementName(children[0]) == "w:rPr" && hasToggle(children[0], "w:vanish")
+}
+
+// isCommentReferenceRun reports whether a run holds nothing but a comment reference.
+func isCommentReferenceRun(children []string) bool {
+	if len(children) > 0 && elementName(children[0]) == "w:rPr" {
+		children = children[1:]
+	}
+	return len(children) == 1 && elementName(children[0]) == "w:commentReference"
+}
+
+// removeExtensions removes the extension elements (e.g. p:ext) mentioning marker.
+func removeExtensions(content, name, marker string) string {
+	var b strings.Builder
+	for {
+		start := indexElement(content, name)
+		if start < 0 {
+			b.WriteString(content)
+			return b.String()
+		}
+		ext := leadingElement(content[start:], name)
+		if ext == "" {
+			b.WriteString(content)
+			return b.String()
+		}
+		b.WriteString(content[:start])
+		if !strings.Contains(ext, marker) {
+			b.WriteString(ext)
+		}
+		content = content[start+len(ext):]
+	}
+}
+
+// rewriteParts rewrites the parts whose names match, writing back those that changed.
+func rewriteParts(pkg *Package, match func(string) bool, rewrite func(name, content string) string) error {
+	for _, name := range pkg.Names() {
+		if !match(name) {
+			continue
+		}
+		content, err := pkg.Read(name)
+		if err != nil {
+			return err
+		}
+		if rewritten := rewrite(name, string(content)); rewritten != string(content) {
+			pkg.Write(name, []byte(rewritten))
+		}
+	}
+	return nil
+}
+
+// isWordXMLPart reports whether a part is one of the XML parts of a Word document, such as
+// its text, styles or settings.
+func isWordXMLPart(name string) bool {
+	return strings.HasPrefix(name, "word/") && strings.HasSuffix(name, ".xml") && strings.Count(name, "/") == 1
+}
@@ -0,0 +1,206 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Syntax describes how placeholders are written in templates.
+// The zero value describes the default {{KEY}} syntax.
+type Syntax struct {
+	// Left and Right are the placeholder delimiters, e.g. "{{" and "}}", "${" and "}", "«" and "»".
+	Left  string
+	Right string
+
+	// KeyPattern is a regular expression a key must match in full, e.g. `[A-Za-z0-9_.]+`.
+	// Empty accepts any key that does not contain a delimiter.
+	KeyPattern string
+
+	// AllowSpaces accepts whitespace between the delimiters and the key, e.g. "{{ NAME }}".
+	AllowSpaces bool
+}
+
+// keyPatterns caches compiled Syntax.KeyPattern expressions
+var keyPatterns sync.Map
+
+// placeholderExpr is the parsed content of a placeholder: a key and optional filters,
+// e.g. {{AMOUNT | currency:"EUR"}}
+type placeholderExpr struct {
+	key     string
+	filters []filterCall
+}
+
+// ParseSyntax builds a Syntax from a delimiter pair written as "LEFT RIGHT" or "LEFT,RIGHT",
+// e.g. "[[ ]]", "${,}" or "« »".
+func ParseSyntax(delims string) (Syntax, error) {
+	parts := strings.Fields(delims)
+	if len(parts) == 1 {
+		parts = strings.Split(parts[0], ",")
+	}
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return Syntax{}, fmt.Errorf("delimiters must be given as \"LEFT RIGHT\" or \"LEFT,RIGHT\", got %q", delims)
+	}
+
+	syntax := Syntax{Left: parts[0], Right: parts[1]}
+	return syntax, syntax.Validate()
+}
+
+// Validate checks that both delimiters are set and that the key pattern compiles.
+func (s Syntax) Validate() error {
+	left, right := s.Delimiters()
+	if left == "" || right == "" {
+		return fmt.Errorf("placeholder syntax needs both a left and a right delimiter")
+	}
+	if s.KeyPattern != "" {
+		if _, err := regexp.Compile(s.KeyPattern); err != nil {
+			return fmt.Errorf("invalid key pattern %q: %v", s.KeyPattern, err)
+		}
+	}
+	return nil
+}
+
+// Delimiters returns the left and right delimiters, falling back to "{{" and "}}".
+func (s Syntax) Delimiters() (string, string) {
+	if s.Left == "" && s.Right == "" {
+		return "{{", "}}"
+	}
+	return s.Left, s.Right
+}
+
+// Wrap encloses a key in the delimiters: "NAME" -> "{{NAME}}".
+func (s Syntax) Wrap(key string) string {
+	left, right := s.Delimiters()
+	return left + key + right
+}
+
+// Key returns the key of a complete placeholder: "{{NAME}}" -> "NAME".
+func (s Syntax) Key(placeholder string) (string, bool) {
+	left, right := s.Delimiters()
+	if len(placeholder) < len(left)+len(right) || !strings.HasPrefix(placeholder, left) || !strings.HasSuffix(placeholder, right) {
+		return "", false
+	}
+	expr, ok := s.parsePlaceholder(placeholder[len(left) : len(placeholder)-len(right)])
+	return expr.key, ok
+}
+
+// NormalizeKey ensures a key is enclosed in the delimiters.
+// "CLIENT_NAME" -> "{{CLIENT_NAME}}", "{{ CLIENT_NAME }}" -> "{{CLIENT_NAME}}" when spaces are allowed.
+func (s Syntax) NormalizeKey(key string) string {
+	left, right := s.Delimiters()
+	if strings.HasPrefix(key, left) && strings.HasSuffix(key, right) && len(key) >= len(left)+len(right) {
+		if name, ok := s.Key(key); ok {
+			return s.Wrap(name)
+		}
+		return key
+	}
+	return s.Wrap(key)
+}
+
+// parsePlaceholder parses the text found between two delimiters into a key and its filters.
+func (s Syntax) parsePlaceholder(inner string) (placeholderExpr, bool) {
+	left, right := s.Delimiters()
+	if strings.Contains(inner, left) || strings.Contains(inner, right) {
+		return placeholderExpr{}, false
+	}
+
+	segments := splitPipes(inner)
+	key := segments[0]
+	if s.AllowSpaces || len(segments) > 1 {
+		key = strings.TrimSpace(key)
+	}
+	if !s.validKey(key) {
+		return placeholderExpr{}, false
+	}
+
+	filters, ok := parseFilters(segments[1:])
+	if !ok {
+		return placeholderExpr{}, false
+	}
+	return placeholderExpr{key: key, filters: filters}, true
+}
+
+// validKey checks a key against the syntax rules.
+func (s Syntax) validKey(inner string) bool {
+	left, right := s.Delimiters()
+	if inner == "" || strings.Contains(inner, left) || strings.Contains(inner, right) {
+		return false
+	}
+
+	if s.KeyPattern != "" {
+		pattern, ok := keyPatterns.Load(s.KeyPattern)
+		if !ok {
+			compiled, err := regexp.Compile(`^(?:` + s.KeyPattern + `)$`)
+			if err != nil {
+				return false
+			}
+			pattern, _ = keyPatterns.LoadOrStore(s.KeyPattern, compiled)
+		}
+		return pattern.(*regexp.Regexp).MatchString(inner)
+	}
+	return true
+}
+
+// splitPipes splits a placeholder on the "|" characters that separate filters,
+// ignoring any inside quoted filter arguments.
+func splitPipes(inner string) []string {
+	var segments []string
+	var opening rune
+	start := 0
+
+	for i, r := range inner {
+		switch {
+		case opening != 0:
+			if isClosingQuote(opening, r) {
+				opening = 0
+			}
+		case r == '"' || r == '\'' || r == '“' || r == '‘' || r == '„':
+			opening = r
+		case r == '|':
+			segments = append(segments, inner[start:i])
+			start = i + 1
+		}
+	}
+	return append(segments, inner[start:])
+}
+
+// placeholderAt parses the placeholder starting at text[start:], which must begin with the
+// left delimiter. It returns the parsed placeholder and its end offset.
+func (s Syntax) placeholderAt(text string, start int) (placeholderExpr, int, bool) {
+	left, right := s.Delimiters()
+	keyStart := start + len(left)
+
+	closing := strings.Index(text[keyStart:], right)
+	if closing < 0 {
+		return placeholderExpr{}, 0, false
+	}
+
+	expr, ok := s.parsePlaceholder(text[keyStart : keyStart+closing])
+	if !ok {
+		return placeholderExpr{}, 0, false
+	}
+	return expr, keyStart + closing + len(right), true
+}
+
+// FindPlaceholders returns every well-formed placeholder in text, in order of appearance.
+func (s Syntax) FindPlaceholders(text string) []string {
+	left, _ := s.Delimiters()
+
+	var placeholders []string
+	pos := 0
+	for {
+		i := strings.Index(text[pos:], left)
+		if i < 0 {
+			return placeholders
+		}
+		start := pos + i
+
+		if _, end, ok := s.placeholderAt(text, start); ok {
+			placeholders = append(placeholders, text[start:end])
+			pos = end
+		} else {
+			pos = start + 1
+		}
+	}
+}
@@ -0,0 +1,364 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Table is a table value. Placeholders using the table filter, e.g. {{ITEMS | table}}, are
+// replaced by a table in Word and PowerPoint; elsewhere the rows are written as tab-separated text.
+type Table struct {
+	Header []string   `json:"header,omitempty"`
+	Rows   [][]string `json:"rows,omitempty"`
+
+	// Widths are relative column widths, e.g. {3, 1, 1}. Columns are equally wide by default.
+	Widths []float64 `json:"widths,omitempty"`
+
+	// Style names a table style of the template: a style name or ID from styles.xml in Word,
+	// a style name or GUID in PowerPoint.
+	Style string `json:"style,omitempty"`
+
+	// Banded shades alternate rows.
+	Banded bool `json:"banded,omitempty"`
+}
+
+// String returns the table as JSON, the form read by the table filter.
+func (t Table) String() string {
+	content, _ := json.Marshal(t)
+	return string(content)
+}
+
+// TableFromRows builds a table whose first row is the header.
+func TableFromRows(rows [][]string) Table {
+	if len(rows) == 0 {
+		return Table{}
+	}
+	return Table{Header: rows[0], Rows: rows[1:]}
+}
+
+// TableFromCSV reads a table from CSV; the first record is the header.
+func TableFromCSV(r io.Reader) (Table, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return Table{}, err
+	}
+	return TableFromRows(rows), nil
+}
+
+// TableFromJSON reads a table from a JSON array: either an array of arrays whose first row is
+// the header, or an array of objects whose keys, in order of appearance, become the columns.
+func TableFromJSON(content []byte) (Table, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		return Table{}, fmt.Errorf("table JSON must be an array")
+	}
+
+	var rows [][]string
+	var objects []map[string]string
+	var columns []string
+	seen := make(map[string]bool)
+
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return Table{}, err
+		}
+
+		switch token {
+		case json.Delim('['):
+			if objects != nil {
+				return Table{}, fmt.Errorf("table JSON mixes arrays and objects")
+			}
+			var row []string
+			for decoder.More() {
+				var value any
+				if err := decoder.Decode(&value); err != nil {
+					return Table{}, err
+				}
+				row = append(row, tableCell(value))
+			}
+			rows = append(rows, row)
+
+		case json.Delim('{'):
+			if rows != nil {
+				return Table{}, fmt.Errorf("table JSON mixes arrays and objects")
+			}
+			object := make(map[string]string)
+			for decoder.More() {
+				keyToken, err := decoder.Token()
+				if err != nil {
+					return Table{}, err
+				}
+				key := keyToken.(string)
+				var value any
+				if err := decoder.Decode(&value); err != nil {
+					return Table{}, err
+				}
+				object[key] = tableCell(value)
+				if !seen[key] {
+					seen[key] = true
+					columns = append(columns, key)
+				}
+			}
+			objects = append(objects, object)
+
+		default:
+			return Table{}, fmt.Errorf("table rows must be arrays or objects")
+		}
+
+		// Closing bracket of the row
+		if _, err := decoder.Token(); err != nil {
+			return Table{}, err
+		}
+	}
+
+	if objects == nil {
+		return TableFromRows(rows), nil
+	}
+	return tableFromObjects(columns, objects), nil
+}
+
+func tableFromObjects(columns []string, objects []map[string]string) Table {
+	table := Table{Header: columns}
+	for _, object := range objects {
+		row := make([]string, len(columns))
+		for i, column := range columns {
+			row[i] = object[column]
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table
+}
+
+// tableCell formats a JSON value for a cell; nested values are written as JSON.
+func tableCell(value any) string {
+	switch value.(type) {
+	case map[string]any, []any:
+		content, _ := json.Marshal(value)
+		return string(content)
+	}
+	return FormatValue(value)
+}
+
+// parseTable reads a table value: Table JSON, a JSON array or CSV. The table filter
+// arguments adjust it:
+//
+//	columns=name,qty      keep these columns, in this order
+//	header=Product,Qty    relabel the header
+//	widths=3,1            relative column widths
+//	style=Grid Table 4    table style
+//	banded                shade alternate rows
+func parseTable(value string, args []string) (Table, error) {
+	var table Table
+	var err error
+
+	trimmed := strings.TrimSpace(value)
+	switch {
+	case strings.HasPrefix(trimmed, "{"):
+		err = json.Unmarshal([]byte(trimmed), &table)
+	case strings.HasPrefix(trimmed, "["):
+		table, err = TableFromJSON([]byte(trimmed))
+	case trimmed == "":
+	default:
+		table, err = TableFromCSV(strings.NewReader(value))
+	}
+	if err != nil {
+		return Table{}, fmt.Errorf("invalid table: %v", err)
+	}
+
+	for _, arg := range args {
+		name, option, _ := strings.Cut(arg, "=")
+		switch strings.TrimSpace(name) {
+		case "columns":
+			if table, err = table.selectColumns(splitList(option)); err != nil {
+				return Table{}, err
+			}
+		case "header":
+			table.Header = splitList(option)
+		case "widths":
+			table.Widths = nil
+			for _, width := range splitList(option) {
+				w, err := strconv.ParseFloat(width, 64)
+				if err != nil || w <= 0 {
+					return Table{}, fmt.Errorf("invalid column width %q", width)
+				}
+				table.Widths = append(table.Widths, w)
+			}
+		case "style":
+			table.Style = strings.TrimSpace(option)
+		case "banded":
+			table.Banded = option == "" || option == "true"
+		default:
+			return Table{}, fmt.Errorf("unknown table option %q", arg)
+		}
+	}
+	return table, nil
+}
+
+func splitList(list string) []string {
+	items := strings.Split(list, ",")
+	for i, item := range items {
+		items[i] = strings.TrimSpace(item)
+	}
+	return items
+}
+
+// selectColumns keeps the named columns in the given order.
+func (t Table) selectColumns(names []string) (Table, error) {
+	indexes := make([]int, len(names))
+	for i, name := range names {
+		indexes[i] = -1
+		for j, header := range t.Header {
+			if header == name {
+				indexes[i] = j
+				break
+			}
+		}
+		if indexes[i] < 0 {
+			return Table{}, fmt.Errorf("table has no column %q", name)
+		}
+	}
+
+	selected := t
+	selected.Header = names
+	selected.Rows = make([][]string, len(t.Rows))
+	for i, row := range t.Rows {
+		selected.Rows[i] = make([]string, len(indexes))
+		for j, index := range indexes {
+			if index < len(row) {
+				selected.Rows[i][j] = row[index]
+			}
+		}
+	}
+	return selected, nil
+}
+
+// columnCount is the number of columns of the widest row.
+func (t Table) columnCount() int {
+	count := len(t.Header)
+	for _, row := range t.Rows {
+		count = max(count, len(row))
+	}
+	return count
+}
+
+// allRows returns the header, if any, followed by the rows, each padded to count cells.
+func (t Table) allRows(count int) [][]string {
+	var rows [][]string
+	if len(t.Header) > 0 {
+		rows = append(rows, t.Header)
+	}
+	rows = append(rows, t.Rows...)
+
+	padded := make([][]string, len(rows))
+	for i, row := range rows {
+		padded[i] = make([]string, count)
+		copy(padded[i], row)
+	}
+	return padded
+}
+
+// columnWidths splits total among count columns by the relative widths.
+func (t Table) columnWidths(count, total int) []int {
+	weights := make([]float64, count)
+	sum := 0.0
+	for i := range weights {
+		weights[i] = 1
+		if i < len(t.Widths) {
+			weights[i] = t.Widths[i]
+		}
+		sum += weights[i]
+	}
+
+	widths := make([]int, count)
+	for i, weight := range weights {
+		widths[i] = int(float64(total) * weight / sum)
+	}
+	return widths
+}
+
+// plainText writes the table as tab-separated lines.
+func (t Table) plainText() string {
+	lines := make([]string, 0, len(t.Rows)+1)
+	for _, row := range t.allRows(t.columnCount()) {
+		lines = append(lines, strings.Join(row, "\t"))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func filterTable(value string, args ...string) (string, error) {
+	table, err := parseTable(value, args)
+	if err != nil {
+		return "", err
+	}
+	return table.plainText(), nil
+}
+
+// arrayTable builds a table from an array in the data, e.g. {{items | table}} for
+// {{items[0].name}}, {{items[0].qty}}, ... Objects give one column per field in
+// alphabetical order; arrays of arrays use their first row as the header.
+func (r *Replacer) arrayTable(path string) (string, bool) {
+	count := r.arrayLen(path)
+	if count == 0 {
+		return "", false
+	}
+
+	objects := make([]map[string]string, count)
+	rows := make([][]string, count)
+	columns := make(map[string]bool)
+	nested := false
+
+	for key, entry := range r.placeholders {
+		index, rest, ok := arrayElement(key, path)
+		if !ok {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(rest, "["):
+			nested = true
+			closing := strings.IndexByte(rest, ']')
+			column, err := strconv.Atoi(rest[1:max(closing, 1)])
+			if err != nil || column < 0 || closing != len(rest)-1 {
+				continue
+			}
+			for len(rows[index]) <= column {
+				rows[index] = append(rows[index], "")
+			}
+			rows[index][column] = entry.value
+		default:
+			if objects[index] == nil {
+				objects[index] = make(map[string]string)
+			}
+			column := strings.TrimPrefix(rest, ".")
+			objects[index][column] = entry.value
+			columns[column] = true
+		}
+	}
+
+	if nested {
+		return TableFromRows(rows).String(), true
+	}
+
+	names := make([]string, 0, len(columns))
+	for column := range columns {
+		names = append(names, column)
+	}
+	sort.Strings(names)
+	if len(names) == 1 && names[0] == "" {
+		// An array of scalars is a single column without header
+		table := tableFromObjects(names, objects)
+		table.Header = nil
+		return table.String(), true
+	}
+	return tableFromObjects(names, objects).String(), true
+}
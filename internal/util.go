@@ -4,7 +4,6 @@ import (
 	"archive/zip"
 	"fmt"
 	"io"
-	"regexp"
 	"strings"
 )
 
@@ -18,12 +17,13 @@ const (
 )
 
 // DetectPatternType determines what type of pattern string is being used
-func DetectPatternType(pattern string) PatternType {
+func DetectPatternType(pattern string, syntax Syntax) PatternType {
 	if pattern == "" {
 		return PatternTypeUnknown
 	}
 
-	hasPlaceholders := strings.Contains(pattern, "{") && strings.Contains(pattern, "}")
+	left, right := syntax.Delimiters()
+	hasPlaceholders := strings.Contains(pattern, left) && strings.Contains(pattern, right)
 	hasFormatVerb := strings.Contains(pattern, "%d")
 
 	if hasPlaceholders {
@@ -37,17 +37,21 @@ func DetectPatternType(pattern string) PatternType {
 }
 
 // ReplacePlaceholders replaces {{FIELD}} placeholders with values from the record
-// Supports {{FIELD}} for any record key and {{INDEX}} for the record number
-func ReplacePlaceholders(pattern string, record map[string]string, index int) string {
-	result := pattern
-
-	// Replace all {KEY} placeholders with corresponding record values
+// Supports {{FIELD}} for any record key, {{INDEX}} for the record number and filters such as {{NAME | upper}}
+func ReplacePlaceholders(pattern string, record map[string]string, index int, opts Options) string {
+	syntax := opts.Syntax
+	values := make(map[string]string, len(record)+1)
 	for key, value := range record {
-		result = strings.ReplaceAll(result, key, value)
+		values[key] = value
 	}
 
-	// Support {INDEX} for the record number
-	result = strings.ReplaceAll(result, "{{INDEX}}", fmt.Sprintf("%d", index))
+	// Support {{INDEX}} for the record number unless the record has its own INDEX field
+	replacer := NewReplacer(values, opts)
+	if _, exists := replacer.Lookup("INDEX"); !exists {
+		values[syntax.Wrap("INDEX")] = fmt.Sprintf("%d", index)
+		replacer = NewReplacer(values, opts)
+	}
+	result := replacer.Replace(pattern)
 
 	// Sanitize the filename
 	result = SanitizeFilename(result)
@@ -78,29 +82,26 @@ func SanitizeFilename(filename string) string {
 	return result
 }
 
-// ExtractPlaceholders returns all placeholder names found in a pattern
-// Example: "{{NAME}}_{{ID}}.docx" returns ["NAME", "ID"]
-func ExtractPlaceholders(pattern string) []string {
-	re := regexp.MustCompile(`\{\{([^}]+)\}\}`)
-	matches := re.FindAllStringSubmatch(pattern, -1)
-
-	placeholders := make([]string, 0, len(matches))
-	for _, match := range matches {
-		if len(match) > 1 {
-			// Return just the field name without braces
-			placeholders = append(placeholders, "{{"+match[1]+"}}")
-		}
+// ExtractPlaceholders returns all placeholders found in a pattern in their normalized form
+// Example: "{{NAME}}_{{ID}}.docx" returns ["{{NAME}}", "{{ID}}"]
+func ExtractPlaceholders(pattern string, syntax Syntax) []string {
+	found := syntax.FindPlaceholders(pattern)
+
+	placeholders := make([]string, 0, len(found))
+	for _, placeholder := range found {
+		placeholders = append(placeholders, syntax.NormalizeKey(placeholder))
 	}
 	return placeholders
 }
 
 // ValidatePattern checks if a pattern is valid and returns an error if not
-func ValidatePattern(pattern string, sampleRecord map[string]string) error {
+func ValidatePattern(pattern string, sampleRecord map[string]string, opts Options) error {
+	syntax := opts.Syntax
 	if pattern == "" {
 		return nil // Empty pattern is valid (will use default)
 	}
 
-	patternType := DetectPatternType(pattern)
+	patternType := DetectPatternType(pattern, syntax)
 
 	switch patternType {
 	case PatternTypeSequential:
@@ -112,17 +113,24 @@ func ValidatePattern(pattern string, sampleRecord map[string]string) error {
 
 	case PatternTypeData:
 		// Check that all placeholders exist in the sample record
-		placeholders := ExtractPlaceholders(pattern)
+		placeholders := ExtractPlaceholders(pattern, syntax)
 		if len(placeholders) == 0 {
-			return fmt.Errorf("data pattern must contain at least one {{FIELD}} placeholder")
+			return fmt.Errorf("data pattern must contain at least one %s placeholder", syntax.Wrap("FIELD"))
 		}
 
 		// Validate placeholders against sample record (skip {{INDEX}} as it's built-in)
-		for _, placeholder := range placeholders {
-			if placeholder == "INDEX" {
+		replacer := NewReplacer(sampleRecord, opts)
+		for _, placeholder := range syntax.FindPlaceholders(pattern) {
+			expr, _, _ := syntax.placeholderAt(placeholder, 0)
+			for _, call := range expr.filters {
+				if _, known := replacer.filters[call.name]; !known {
+					return fmt.Errorf("placeholder %s uses unknown filter %q", placeholder, call.name)
+				}
+			}
+			if expr.key == "INDEX" || hasFilter(expr.filters, "default") {
 				continue
 			}
-			if _, exists := sampleRecord[placeholder]; !exists {
+			if _, exists := replacer.Lookup(expr.key); !exists {
 				return fmt.Errorf("placeholder %s not found in data fields. Available fields: %v",
 					placeholder, getMapKeys(sampleRecord))
 			}
@@ -130,14 +138,15 @@ func ValidatePattern(pattern string, sampleRecord map[string]string) error {
 		return nil
 
 	case PatternTypeUnknown:
-		return fmt.Errorf("pattern must contain: {{FIELD}}, {{INDEX}} placeholders or %%d for sequential numbering")
+		return fmt.Errorf("pattern must contain: %s, %s placeholders or %%d for sequential numbering",
+			syntax.Wrap("FIELD"), syntax.Wrap("INDEX"))
 	}
 
 	return nil
 }
 
 // CreateNamingFunction creates the appropriate naming function based on the pattern
-func createNamingFunction(pattern, baseName, extension string) func(map[string]string, int) string {
+func createNamingFunction(pattern, baseName, extension string, opts Options) func(map[string]string, int) string {
 	if pattern == "" {
 		// Default naming using the provided base and extension
 		return func(record map[string]string, index int) string {
@@ -145,7 +154,7 @@ func createNamingFunction(pattern, baseName, extension string) func(map[string]s
 		}
 	}
 
-	patternType := DetectPatternType(pattern)
+	patternType := DetectPatternType(pattern, opts.Syntax)
 
 	switch patternType {
 	case PatternTypeSequential:
@@ -155,7 +164,7 @@ func createNamingFunction(pattern, baseName, extension string) func(map[string]s
 
 	case PatternTypeData:
 		return func(record map[string]string, index int) string {
-			return ReplacePlaceholders(pattern, record, index)
+			return ReplacePlaceholders(pattern, record, index, opts.ForRecord(record))
 		}
 
 	default:
@@ -166,16 +175,16 @@ func createNamingFunction(pattern, baseName, extension string) func(map[string]s
 	}
 }
 
-func CreateDocxNamingFunction(pattern string) func(map[string]string, int) string {
-	return createNamingFunction(pattern, "document", ".docx")
+func CreateDocxNamingFunction(pattern string, opts Options) func(map[string]string, int) string {
+	return createNamingFunction(pattern, "document", ".docx", opts)
 }
 
-func CreatePptxNamingFunction(pattern string) func(map[string]string, int) string {
-	return createNamingFunction(pattern, "presentation", ".pptx")
+func CreatePptxNamingFunction(pattern string, opts Options) func(map[string]string, int) string {
+	return createNamingFunction(pattern, "presentation", ".pptx", opts)
 }
 
-func CreateXlsxNamingFunction(pattern string) func(map[string]string, int) string {
-	return createNamingFunction(pattern, "spreadsheet", ".xlsx")
+func CreateXlsxNamingFunction(pattern string, opts Options) func(map[string]string, int) string {
+	return createNamingFunction(pattern, "spreadsheet", ".xlsx", opts)
 }
 
 // getMapKeys returns all keys from a map as a slice (helper for error messages)
@@ -187,7 +196,7 @@ func getMapKeys(m map[string]string) []string {
 	return keys
 }
 
-func ValidateDocxKeywords(inputPath string, keywords []string) (map[string]bool, error) {
+func ValidateDocxKeywords(inputPath string, keywords []string, syntax Syntax) (map[string]bool, error) {
 	reader, err := zip.OpenReader(inputPath)
 	if err != nil {
 		return nil, err
@@ -195,9 +204,12 @@ func ValidateDocxKeywords(inputPath string, keywords []string) (map[string]bool,
 	defer reader.Close()
 
 	results := make(map[string]bool)
+	lookup := make(map[string]string, len(keywords))
 	for _, k := range keywords {
 		results[k] = false
+		lookup[k] = k
 	}
+	replacer := NewReplacer(lookup, Options{Syntax: syntax})
 
 	for _, file := range reader.File {
 		// Optimization: only read text-heavy XML files
@@ -212,17 +224,15 @@ func ValidateDocxKeywords(inputPath string, keywords []string) (map[string]bool,
 		content, _ := io.ReadAll(rc)
 		rc.Close()
 
-		contentStr := string(content)
-		for _, k := range keywords {
-			if !results[k] && strings.Contains(contentStr, k) {
-				results[k] = true
-			}
+		text := containerText(splitIntoParagraphs(string(content)), extractTextFromParagraph)
+		for _, rp := range replacer.FindReplacementPoints(text) {
+			results[rp.keyword] = true
 		}
 	}
 	return results, nil
 }
 
-func ValidatePptxKeywords(inputPath string, keywords []string) (map[string]bool, error) {
+func ValidatePptxKeywords(inputPath string, keywords []string, syntax Syntax) (map[string]bool, error) {
 	reader, err := zip.OpenReader(inputPath)
 	if err != nil {
 		return nil, err
@@ -230,9 +240,12 @@ func ValidatePptxKeywords(inputPath string, keywords []string) (map[string]bool,
 	defer reader.Close()
 
 	results := make(map[string]bool)
+	lookup := make(map[string]string, len(keywords))
 	for _, k := range keywords {
 		results[k] = false
+		lookup[k] = k
 	}
+	replacer := NewReplacer(lookup, Options{Syntax: syntax})
 
 	for _, file := range reader.File {
 		// PPTX slide content is inside the "ppt/slides/" folder
@@ -247,17 +260,15 @@ func ValidatePptxKeywords(inputPath string, keywords []string) (map[string]bool,
 		content, _ := io.ReadAll(rc)
 		rc.Close()
 
-		contentStr := string(content)
-		for _, k := range keywords {
-			if !results[k] && strings.Contains(contentStr, k) {
-				results[k] = true
-			}
+		text := containerText(splitIntoTextFrames(string(content)), extractTextFromFrame)
+		for _, rp := range replacer.FindReplacementPoints(text) {
+			results[rp.keyword] = true
 		}
 	}
 	return results, nil
 }
 
-func ValidateXlsxKeywords(inputPath string, keywords []string) (map[string]bool, error) {
+func ValidateXlsxKeywords(inputPath string, keywords []string, syntax Syntax) (map[string]bool, error) {
 	reader, err := zip.OpenReader(inputPath)
 	if err != nil {
 		return nil, err
@@ -265,9 +276,12 @@ func ValidateXlsxKeywords(inputPath string, keywords []string) (map[string]bool,
 	defer reader.Close()
 
 	results := make(map[string]bool)
+	lookup := make(map[string]string, len(keywords))
 	for _, k := range keywords {
 		results[k] = false
+		lookup[k] = k
 	}
+	replacer := NewReplacer(lookup, Options{Syntax: syntax})
 
 	for _, file := range reader.File {
 		// Check both individual worksheets and the shared strings table
@@ -285,34 +299,37 @@ func ValidateXlsxKeywords(inputPath string, keywords []string) (map[string]bool,
 		content, _ := io.ReadAll(rc)
 		rc.Close()
 
-		contentStr := string(content)
-		for _, k := range keywords {
-			if !results[k] && strings.Contains(contentStr, k) {
-				results[k] = true
-			}
+		var text string
+		if isWorksheet {
+			// Inline strings live directly in the sheet
+			text = containerText(splitIntoInlineStrings(string(content)), extractTextFromStringItem)
+		} else {
+			text = containerText(splitIntoStringItems(string(content)), extractTextFromStringItem)
+		}
+		for _, rp := range replacer.FindReplacementPoints(text) {
+			results[rp.keyword] = true
 		}
 	}
 	return results, nil
 }
 
-// NormalizeKey ensures a key has double braces
-// "CLIENT_NAME" -> "{{CLIENT_NAME}}"
-// "{{CLIENT_NAME}}" -> "{{CLIENT_NAME}}"
-func NormalizeKey(key string) string {
-	// Already has braces
-	if strings.HasPrefix(key, "{{") && strings.HasSuffix(key, "}}") {
-		return key
+// containerText joins the decoded text of each paragraph-like container, one per line,
+// so keywords are found even when split across runs but never across paragraphs.
+func containerText(containers []string, extract func(string) string) string {
+	var text strings.Builder
+	for _, container := range containers {
+		text.WriteString(extract(container))
+		text.WriteString("\n")
 	}
-	// Add braces
-	return fmt.Sprintf("{{%s}}", key)
+	return text.String()
 }
 
-// NormalizeReplacements converts all keys in a map to have double braces
+// NormalizeReplacements converts all keys in a map to be enclosed in the syntax delimiters
 // This allows users to provide data with or without braces
-func NormalizeReplacements(replacements map[string]string) map[string]string {
+func NormalizeReplacements(replacements map[string]string, syntax Syntax) map[string]string {
 	normalized := make(map[string]string, len(replacements))
 	for key, value := range replacements {
-		normalized[NormalizeKey(key)] = value
+		normalized[syntax.NormalizeKey(key)] = value
 	}
 	return normalized
 }
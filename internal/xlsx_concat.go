@@ -0,0 +1,880 @@
+package internal
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Relationship types of workbooks
+const (
+	RelTypeWorksheet     = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet"
+	RelTypeSharedStrings = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/sharedStrings"
+	RelTypeCalcChain     = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/calcChain"
+	RelTypeTable         = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/table"
+	RelTypePivotTable    = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/pivotTable"
+)
+
+const (
+	workbookPartName         = "xl/workbook.xml"
+	sharedStringsContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sharedStrings+xml"
+	spreadsheetNamespace     = "http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+
+	// Excel limits sheet names to 31 characters
+	maxSheetNameLength = 31
+)
+
+// The lists of styles.xml in schema order
+var stylesLists = []string{"numFmts", "fonts", "fills", "borders", "cellStyleXfs", "cellXfs", "cellStyles", "dxfs", "tableStyles", "colors", "extLst"}
+
+// xfReferences are the attributes of a cell format referring to other style lists
+var xfReferences = []struct{ attr, list string }{
+	{"fontId", "fonts"},
+	{"fillId", "fills"},
+	{"borderId", "borders"},
+	{"xfId", "cellStyleXfs"},
+}
+
+var (
+	workbookSheetPattern       = regexp.MustCompile(`<sheet\b[^>]*/>`)
+	workbookDefinedNamePattern = regexp.MustCompile(`<definedName\b[^>]*>.*?</definedName>|<definedName\b[^>]*/>`)
+	sheetStyledTagPattern      = regexp.MustCompile(`<(?:c|row)\s[^>]*\ss="\d+"[^>]*>`)
+	sheetStyleAttrPattern      = regexp.MustCompile(`(\ss=")(\d+)(")`)
+	sheetColumnTagPattern      = regexp.MustCompile(`<col\s[^>]*\sstyle="\d+"[^>]*>`)
+	sheetColumnStylePattern    = regexp.MustCompile(`(\sstyle=")(\d+)(")`)
+	sheetRuleTagPattern        = regexp.MustCompile(`<cfRule\s[^>]*\sdxfId="\d+"[^>]*>`)
+	sheetRuleDxfPattern        = regexp.MustCompile(`(\sdxfId=")(\d+)(")`)
+	sheetFormulaPattern        = regexp.MustCompile(`(<(?:f|formula|formula1|formula2)\b[^>/]*>)([^<]*)(</(?:f|formula|formula1|formula2)>)`)
+	formulaSheetRefPattern     = regexp.MustCompile(`('(?:[^']|'')+'|[\p{L}_][\p{L}\p{N}_.]*)!`)
+	formulaTableRefPattern     = regexp.MustCompile(`([\p{L}_\\][\p{L}\p{N}_.]*)\[`)
+	appSheetTitlesPattern      = regexp.MustCompile(`<HeadingPairs>.*?</HeadingPairs>|<TitlesOfParts>.*?</TitlesOfParts>`)
+)
+
+// selectSheets returns the positions of the named sheets among names, matched without regard
+// to case; nil selects all sheets.
+func selectSheets(names, selected []string) ([]int, error) {
+	if selected == nil {
+		positions := make([]int, len(names))
+		for i := range positions {
+			positions[i] = i
+		}
+		return positions, nil
+	}
+
+	positions := make([]int, 0, len(selected))
+	taken := make(map[int]bool)
+	for _, name := range selected {
+		found := -1
+		for i, sheet := range names {
+			if strings.EqualFold(sheet, strings.TrimSpace(name)) {
+				found = i
+				break
+			}
+		}
+		if found < 0 {
+			return nil, fmt.Errorf("workbook has no sheet %q", name)
+		}
+		if !taken[found] {
+			taken[found] = true
+			positions = append(positions, found)
+		}
+	}
+	return positions, nil
+}
+
+// XlsxMerger combines workbooks: the sheets appended to it are added with the parts they use,
+// their shared strings and styles merged into the workbook's and their cells remapped.
+type XlsxMerger struct {
+	pkg          *Package
+	workbook     string
+	sheets       []string // entries of the sheets list
+	names        map[string]bool
+	definedNames []string
+	nextSheetID  int
+	strings      *sharedStringTable
+	styles       *workbookStyles
+}
+
+// NewXlsxMerger starts a combined workbook from pkg, keeping the named sheets in that order;
+// nil keeps all of them.
+func NewXlsxMerger(pkg *Package, sheets []string) (*XlsxMerger, error) {
+	content, err := pkg.Read(workbookPartName)
+	if err != nil {
+		return nil, err
+	}
+	m := &XlsxMerger{pkg: pkg, workbook: string(content), names: make(map[string]bool), nextSheetID: 1}
+
+	entries := workbookSheetPattern.FindAllString(m.workbook, -1)
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = UnescapeXMLText(attrValue(entry, "name"))
+		id, _ := strconv.Atoi(attrValue(entry, "sheetId"))
+		m.nextSheetID = max(m.nextSheetID, id+1)
+	}
+	definedNames := workbookDefinedNamePattern.FindAllString(m.workbook, -1)
+
+	positions, err := selectSheets(names, sheets)
+	if err != nil {
+		return nil, err
+	}
+	moved := make(map[int]int, len(positions))
+	for i, position := range positions {
+		moved[position] = i
+		m.sheets = append(m.sheets, entries[position])
+		m.names[strings.ToLower(names[position])] = true
+	}
+
+	// Local names follow their sheet; those of removed sheets go with them
+	for _, entry := range definedNames {
+		local := attrValue(entry[:strings.IndexByte(entry, '>')], "localSheetId")
+		if local == "" {
+			m.definedNames = append(m.definedNames, entry)
+			continue
+		}
+		position, _ := strconv.Atoi(local)
+		if i, ok := moved[position]; ok {
+			m.definedNames = append(m.definedNames, setAttr(entry, "localSheetId", strconv.Itoa(i)))
+		}
+	}
+
+	if len(positions) == len(entries) {
+		return m, nil
+	}
+	rels, err := pkg.readRelationships(workbookPartName)
+	if err != nil {
+		return nil, err
+	}
+	removed := make(map[string]bool)
+	var targets []string
+	for i, entry := range entries {
+		if _, ok := moved[i]; ok {
+			continue
+		}
+		id := attrValue(entry, "r:id")
+		removed[id] = true
+		if rel, ok := rels[id]; ok {
+			targets = append(targets, resolveTarget(workbookPartName, rel.target))
+		}
+	}
+
+	// The calculation chain refers to the sheets by position; Excel rebuilds it when missing
+	for id, rel := range rels {
+		if rel.relType == RelTypeCalcChain {
+			removed[id] = true
+			targets = append(targets, resolveTarget(workbookPartName, rel.target))
+		}
+	}
+	if err := pkg.removeRelationships(workbookPartName, removed); err != nil {
+		return nil, err
+	}
+	return m, pkg.deleteUnreferenced(targets)
+}
+
+// Append adds the named sheets of src, in that order; nil adds all of them. Sheets named like
+// one the workbook has are renamed "Name (2)" and so on.
+func (m *XlsxMerger) Append(src *Package, sheets []string) error {
+	content, err := src.Read(workbookPartName)
+	if err != nil {
+		return err
+	}
+	srcWorkbook := string(content)
+	entries := workbookSheetPattern.FindAllString(srcWorkbook, -1)
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = UnescapeXMLText(attrValue(entry, "name"))
+	}
+	positions, err := selectSheets(names, sheets)
+	if err != nil {
+		return err
+	}
+	rels, err := src.readRelationships(workbookPartName)
+	if err != nil {
+		return err
+	}
+
+	merge := &xlsxMerge{
+		merger:  m,
+		src:     src,
+		parts:   make(map[string]string),
+		same:    newSharedParts(src, m.pkg),
+		renames: make(map[string]string),
+		tables:  make(map[string]string),
+	}
+	if err := merge.load(); err != nil {
+		return err
+	}
+
+	// Name the new sheets first, so formulas referring to renamed sheets can follow them
+	sheetNames := make([]string, len(positions))
+	for i, position := range positions {
+		sheetNames[i] = m.uniqueSheetName(names[position])
+		m.names[strings.ToLower(sheetNames[i])] = true
+		if sheetNames[i] != names[position] {
+			merge.renames[strings.ToLower(names[position])] = sheetNames[i]
+		}
+	}
+
+	srcDefinedNames := workbookDefinedNamePattern.FindAllString(srcWorkbook, -1)
+	for i, position := range positions {
+		entry := entries[position]
+		rel, ok := rels[attrValue(entry, "r:id")]
+		if !ok || rel.external {
+			return fmt.Errorf("sheet %q has no part", names[position])
+		}
+		sheet := resolveTarget(workbookPartName, rel.target)
+		copied := nextPartName(m.pkg, "xl/worksheets/sheet1.xml")
+		m.pkg.Write(copied, nil)
+		merge.parts[sheet] = copied
+		merge.worksheets = append(merge.worksheets, copied)
+		if err := merge.copyContent(sheet, copied); err != nil {
+			return fmt.Errorf("failed to copy sheet %q: %v", names[position], err)
+		}
+
+		id, err := m.pkg.AddRelationship(workbookPartName, RelTypeWorksheet, relativeTarget(workbookPartName, copied), false)
+		if err != nil {
+			return err
+		}
+		entry = setAttr(entry, "name", sheetNames[i])
+		entry = setAttr(entry, "sheetId", strconv.Itoa(m.nextSheetID))
+		entry = setAttr(entry, "r:id", id)
+		m.nextSheetID++
+
+		// Local names such as print areas come along with their sheet
+		for _, definedName := range srcDefinedNames {
+			if attrValue(definedName[:strings.IndexByte(definedName, '>')], "localSheetId") == strconv.Itoa(position) {
+				definedName = setAttr(definedName, "localSheetId", strconv.Itoa(len(m.sheets)))
+				m.definedNames = append(m.definedNames, merge.formulas(definedName))
+			}
+		}
+		m.sheets = append(m.sheets, entry)
+	}
+
+	// Cells are remapped once every sheet and table has its combined name
+	for _, name := range merge.worksheets {
+		content, err := m.pkg.Read(name)
+		if err != nil {
+			return err
+		}
+		m.pkg.Write(name, []byte(merge.formulas(merge.worksheet(string(content)))))
+	}
+	return nil
+}
+
+// Finish writes the combined workbook to the package.
+func (m *XlsxMerger) Finish() error {
+	workbook := m.workbook
+	start := indexElement(workbook, "sheets")
+	if start < 0 {
+		return fmt.Errorf("%s has no sheets", workbookPartName)
+	}
+	list := leadingElement(workbook[start:], "sheets")
+	workbook = workbook[:start] + "<sheets>" + strings.Join(m.sheets, "") + "</sheets>" + workbook[start+len(list):]
+
+	workbook = removeElements(workbook, "definedNames")
+	if len(m.definedNames) > 0 {
+		at := indexElement(workbook, "sheets")
+		at += len(leadingElement(workbook[at:], "sheets"))
+		if functionGroups := indexElement(workbook, "functionGroups"); functionGroups >= 0 {
+			// Function groups sit between the sheets and their external references
+			at = functionGroups + len(leadingElement(workbook[functionGroups:], "functionGroups"))
+		}
+		if references := indexElement(workbook, "externalReferences"); references >= 0 {
+			at = references + len(leadingElement(workbook[references:], "externalReferences"))
+		}
+		workbook = workbook[:at] + "<definedNames>" + strings.Join(m.definedNames, "") + "</definedNames>" + workbook[at:]
+	}
+
+	// The first and active tabs may refer to sheets that were removed
+	if view := indexElement(workbook, "workbookView"); view >= 0 {
+		end := view + strings.IndexByte(workbook[view:], '>') + 1
+		tag := workbook[view:end]
+		for _, attr := range []string{"activeTab", "firstSheet"} {
+			if tab, err := strconv.Atoi(attrValue(tag, attr)); err == nil && tab >= len(m.sheets) {
+				tag = setAttr(tag, attr, "0")
+			}
+		}
+		workbook = workbook[:view] + tag + workbook[end:]
+	}
+	m.pkg.Write(workbookPartName, []byte(workbook))
+
+	if m.strings != nil {
+		if err := m.strings.finish(m.pkg); err != nil {
+			return err
+		}
+	}
+	if m.styles != nil {
+		m.styles.finish(m.pkg)
+	}
+
+	// The sheet titles listed in the document properties are rebuilt by Excel when missing
+	if m.pkg.Has("docProps/app.xml") {
+		content, err := m.pkg.Read("docProps/app.xml")
+		if err != nil {
+			return err
+		}
+		m.pkg.Write("docProps/app.xml", appSheetTitlesPattern.ReplaceAll(content, nil))
+	}
+	return nil
+}
+
+// uniqueSheetName returns name, or "name (2)" and so on when the workbook has a sheet of that
+// name, shortened to the length Excel allows.
+func (m *XlsxMerger) uniqueSheetName(name string) string {
+	name = truncateRunes(name, maxSheetNameLength)
+	if !m.names[strings.ToLower(name)] {
+		return name
+	}
+	for n := 2; ; n++ {
+		suffix := " (" + strconv.Itoa(n) + ")"
+		candidate := truncateRunes(name, maxSheetNameLength-len(suffix)) + suffix
+		if !m.names[strings.ToLower(candidate)] {
+			return candidate
+		}
+	}
+}
+
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// sharedStringTable is the shared strings part of the combined workbook.
+type sharedStringTable struct {
+	part  string
+	xml   string
+	items []string
+	index map[string]int
+	added int // references to the table from appended cells
+}
+
+func loadSharedStrings(pkg *Package) (*sharedStringTable, error) {
+	table := &sharedStringTable{index: make(map[string]int)}
+	part, ok := pkg.relatedPart(workbookPartName, RelTypeSharedStrings)
+	if !ok {
+		table.xml = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" + `<sst xmlns="` + spreadsheetNamespace + `"></sst>`
+		return table, nil
+	}
+	content, err := pkg.Read(part)
+	if err != nil {
+		return nil, err
+	}
+	table.part = part
+	table.xml = string(content)
+	table.items = sharedStringItems(table.xml)
+	for i, item := range table.items {
+		if _, ok := table.index[item]; !ok {
+			table.index[item] = i
+		}
+	}
+	return table, nil
+}
+
+// add returns the index of a string item, adding it to the table if it has no equal one.
+func (t *sharedStringTable) add(item string) int {
+	t.added++
+	if i, ok := t.index[item]; ok {
+		return i
+	}
+	t.items = append(t.items, item)
+	t.index[item] = len(t.items) - 1
+	return len(t.items) - 1
+}
+
+func (t *sharedStringTable) finish(pkg *Package) error {
+	if t.part == "" && len(t.items) == 0 {
+		return nil
+	}
+	root := rootElementStart(t.xml)
+	if root < 0 {
+		return fmt.Errorf("shared strings have no root element")
+	}
+	tag, _ := splitProps(leadingElement(t.xml[root:], "sst"), "sst")
+	count, _ := strconv.Atoi(attrValue(tag, "count"))
+	tag = setAttr(tag, "count", strconv.Itoa(count+t.added))
+	tag = setAttr(tag, "uniqueCount", strconv.Itoa(len(t.items)))
+	extensions := ""
+	if start := indexElement(t.xml, "extLst"); start >= 0 {
+		extensions = leadingElement(t.xml[start:], "extLst")
+	}
+	content := t.xml[:root] + tag + strings.Join(t.items, "") + extensions + "</sst>"
+
+	if t.part == "" {
+		t.part = "xl/sharedStrings.xml"
+		if _, err := pkg.AddRelationship(workbookPartName, RelTypeSharedStrings, relativeTarget(workbookPartName, t.part), false); err != nil {
+			return err
+		}
+		if err := pkg.AddContentTypeOverride(t.part, sharedStringsContentType); err != nil {
+			return err
+		}
+	}
+	pkg.Write(t.part, []byte(content))
+	return nil
+}
+
+// sharedStringItems returns the string items of a shared strings part.
+func sharedStringItems(sst string) []string {
+	var items []string
+	for _, part := range splitIntoStringItems(sst) {
+		if strings.HasPrefix(part, "<si") {
+			items = append(items, part)
+		}
+	}
+	return items
+}
+
+// workbookStyles is the styles part of the combined workbook, with its lists split into
+// their entries.
+type workbookStyles struct {
+	part       string
+	xml        string
+	lists      map[string][]string
+	index      map[string]map[string]int
+	numFmts    map[string]string // IDs of custom number formats by format code
+	nextNumFmt int
+	changed    map[string]bool
+}
+
+func parseStyles(content string) map[string][]string {
+	lists := make(map[string][]string)
+	for _, name := range stylesLists {
+		if start := indexElement(content, name); start >= 0 {
+			_, lists[name] = splitProps(leadingElement(content[start:], name), name)
+		}
+	}
+	return lists
+}
+
+func loadStyles(pkg *Package) (*workbookStyles, error) {
+	part, ok := pkg.relatedPart(workbookPartName, RelTypeStyles)
+	if !ok {
+		return nil, nil
+	}
+	content, err := pkg.Read(part)
+	if err != nil {
+		return nil, err
+	}
+	s := &workbookStyles{
+		part:       part,
+		xml:        string(content),
+		index:      make(map[string]map[string]int),
+		numFmts:    make(map[string]string),
+		nextNumFmt: 164, // the first ID of custom formats
+		changed:    make(map[string]bool),
+	}
+	s.lists = parseStyles(s.xml)
+	for name, entries := range s.lists {
+		s.index[name] = make(map[string]int)
+		for i, entry := range entries {
+			if _, ok := s.index[name][entry]; !ok {
+				s.index[name][entry] = i
+			}
+		}
+	}
+	for _, numFmt := range s.lists["numFmts"] {
+		id := attrValue(numFmt, "numFmtId")
+		s.numFmts[attrValue(numFmt, "formatCode")] = id
+		if n, err := strconv.Atoi(id); err == nil {
+			s.nextNumFmt = max(s.nextNumFmt, n+1)
+		}
+	}
+	return s, nil
+}
+
+// add returns the index of an entry of a list, adding it if the list has no equal one.
+func (s *workbookStyles) add(list, entry string) int {
+	if s.index[list] == nil {
+		s.index[list] = make(map[string]int)
+	}
+	if i, ok := s.index[list][entry]; ok {
+		return i
+	}
+	s.lists[list] = append(s.lists[list], entry)
+	s.index[list][entry] = len(s.lists[list]) - 1
+	s.changed[list] = true
+	return len(s.lists[list]) - 1
+}
+
+// numFmt returns the ID of a custom number format, adding it if the styles have none of the
+// same format code.
+func (s *workbookStyles) numFmt(formatCode string) string {
+	if id, ok := s.numFmts[formatCode]; ok {
+		return id
+	}
+	id := strconv.Itoa(s.nextNumFmt)
+	s.nextNumFmt++
+	s.numFmts[formatCode] = id
+	s.add("numFmts", `<numFmt numFmtId="`+id+`" formatCode="`+formatCode+`"/>`)
+	return id
+}
+
+func (s *workbookStyles) finish(pkg *Package) {
+	content := s.xml
+	for i, name := range stylesLists {
+		if !s.changed[name] {
+			continue
+		}
+		start := indexElement(content, name)
+		existing := ""
+		if start >= 0 {
+			existing = leadingElement(content[start:], name)
+		}
+		tag, _ := splitProps(existing, name)
+		list := setAttr(tag, "count", strconv.Itoa(len(s.lists[name]))) + strings.Join(s.lists[name], "") + `</` + name + `>`
+		if start >= 0 {
+			content = content[:start] + list + content[start+len(existing):]
+			continue
+		}
+
+		at := strings.LastIndex(content, "</styleSheet>")
+		for _, following := range stylesLists[i+1:] {
+			if start := indexElement(content, following); start >= 0 {
+				at = start
+				break
+			}
+		}
+		if at >= 0 {
+			content = content[:at] + list + content[at:]
+		}
+	}
+	pkg.Write(s.part, []byte(content))
+}
+
+// xlsxMerge copies the sheets of one workbook into the combined one.
+type xlsxMerge struct {
+	merger     *XlsxMerger
+	src        *Package
+	parts      map[string]string // combined part names of copied parts
+	same       *sharedParts
+	worksheets []string          // the copied sheets
+	renames    map[string]string // new names of renamed sheets by lower-cased name
+	tables     map[string]string // new names of renamed tables
+
+	strings []string    // string items of the source
+	mapped  map[int]int // combined indexes of the source's strings
+	styles  map[string][]string
+	xfs     map[string]map[int]int // combined indexes of the source's style entries
+}
+
+// load reads the shared strings and styles of the source and of the combined workbook.
+func (m *xlsxMerge) load() error {
+	m.mapped = make(map[int]int)
+	m.xfs = make(map[string]map[int]int)
+
+	if part, ok := m.src.relatedPart(workbookPartName, RelTypeSharedStrings); ok {
+		content, err := m.src.Read(part)
+		if err != nil {
+			return err
+		}
+		m.strings = sharedStringItems(string(content))
+		if m.merger.strings == nil {
+			if m.merger.strings, err = loadSharedStrings(m.merger.pkg); err != nil {
+				return err
+			}
+		}
+		m.merger.strings.xml = mergeRootNamespaces(m.merger.strings.xml, rootTag(string(content)))
+	}
+
+	part, ok := m.src.relatedPart(workbookPartName, RelTypeStyles)
+	if !ok {
+		return nil
+	}
+	content, err := m.src.Read(part)
+	if err != nil {
+		return err
+	}
+	if m.merger.styles == nil {
+		if _, ok := m.merger.pkg.relatedPart(workbookPartName, RelTypeStyles); !ok {
+			// A workbook without styles takes those of the first workbook that has them
+			if _, err := m.copyPart(part); err != nil {
+				return err
+			}
+			if _, err := m.merger.pkg.AddRelationship(workbookPartName, RelTypeStyles, relativeTarget(workbookPartName, m.parts[part]), false); err != nil {
+				return err
+			}
+		}
+		if m.merger.styles, err = loadStyles(m.merger.pkg); err != nil {
+			return err
+		}
+	}
+	m.merger.styles.xml = mergeRootNamespaces(m.merger.styles.xml, rootTag(string(content)))
+	m.styles = parseStyles(string(content))
+	return nil
+}
+
+// rootTag returns the start tag of the root element of an XML part.
+func rootTag(content string) string {
+	root := rootElementStart(content)
+	if root < 0 {
+		return ""
+	}
+	return content[root : root+strings.IndexByte(content[root:], '>')+1]
+}
+
+// copyContent copies a part's content and relationships to the combined part copied. The
+// relationships keep their IDs, so the content refers to them unchanged.
+func (m *xlsxMerge) copyContent(name, copied string) error {
+	content, err := m.src.Read(name)
+	if err != nil {
+		return err
+	}
+	rels, err := m.src.readRelationships(name)
+	if err != nil {
+		return err
+	}
+
+	kept := make(map[string]relationship, len(rels))
+	for id, rel := range rels {
+		if rel.external {
+			kept[id] = rel
+			continue
+		}
+		target := resolveTarget(name, rel.target)
+
+		var merged string
+		switch rel.relType {
+		case RelTypePivotTable:
+			// Pivot tables depend on caches registered with the workbook they came from
+			continue
+		case RelTypeTable:
+			merged, err = m.table(target)
+		default:
+			merged, err = m.copyPart(target)
+		}
+		if err != nil {
+			return err
+		}
+		rel.target = relativeTarget(copied, merged)
+		kept[id] = rel
+	}
+
+	m.merger.pkg.Write(copied, content)
+	if len(kept) > 0 {
+		m.merger.pkg.writeRelationships(copied, kept)
+	}
+
+	contentType, override := m.src.ContentType(name)
+	switch {
+	case contentType == "":
+		return nil
+	case override:
+		return m.merger.pkg.AddContentTypeOverride(copied, contentType)
+	default:
+		return m.merger.pkg.AddContentTypeDefault(strings.TrimPrefix(path.Ext(copied), "."), contentType)
+	}
+}
+
+// copyPart copies a part, renamed if the name is taken, and returns its name. Media the
+// combined workbook has as well are shared.
+func (m *xlsxMerge) copyPart(name string) (string, error) {
+	if copied, ok := m.parts[name]; ok {
+		return copied, nil
+	}
+	if strings.HasPrefix(name, "xl/media/") && m.same.has(name) {
+		m.parts[name] = name
+		return name, nil
+	}
+
+	copied := name
+	if m.merger.pkg.Has(name) {
+		copied = nextPartName(m.merger.pkg, name)
+	}
+	m.parts[name] = copied
+	m.merger.pkg.Write(copied, nil)
+	return copied, m.copyContent(name, copied)
+}
+
+// table copies a table part with an ID and a name the workbook doesn't use yet.
+func (m *xlsxMerge) table(name string) (string, error) {
+	if copied, ok := m.parts[name]; ok {
+		return copied, nil
+	}
+
+	ids := make(map[string]bool)
+	names := make(map[string]bool)
+	nextID := 1
+	for _, part := range m.merger.pkg.Names() {
+		if !strings.HasPrefix(part, "xl/tables/") || !strings.HasSuffix(part, ".xml") {
+			continue
+		}
+		content, err := m.merger.pkg.Read(part)
+		if err != nil || len(content) == 0 {
+			continue
+		}
+		tag := rootTag(string(content))
+		id, _ := strconv.Atoi(attrValue(tag, "id"))
+		nextID = max(nextID, id+1)
+		ids[attrValue(tag, "id")] = true
+		names[strings.ToLower(attrValue(tag, "displayName"))] = true
+	}
+
+	copied, err := m.copyPart(name)
+	if err != nil {
+		return "", err
+	}
+	content, err := m.merger.pkg.Read(copied)
+	if err != nil {
+		return "", err
+	}
+	table := string(content)
+	root := rootElementStart(table)
+	if root < 0 {
+		return copied, nil
+	}
+	tag := rootTag(table)
+	if ids[attrValue(tag, "id")] {
+		tag = setAttr(tag, "id", strconv.Itoa(nextID))
+	}
+	if displayName := attrValue(tag, "displayName"); names[strings.ToLower(displayName)] {
+		base := strings.TrimRight(displayName, "0123456789")
+		renamed := displayName
+		for n := 1; names[strings.ToLower(renamed)]; n++ {
+			renamed = base + strconv.Itoa(n)
+		}
+		m.tables[displayName] = renamed
+		tag = setAttr(setAttr(tag, "displayName", renamed), "name", renamed)
+	}
+	m.merger.pkg.Write(copied, []byte(table[:root]+tag+table[root+len(rootTag(table)):]))
+	return copied, nil
+}
+
+// worksheet remaps the shared strings and styles a copied sheet refers to.
+func (m *xlsxMerge) worksheet(sheet string) string {
+	if m.strings != nil {
+		sheet = sheetCellPattern.ReplaceAllStringFunc(sheet, func(cell string) string {
+			attrs := cell[:strings.IndexByte(cell, '>')]
+			if !strings.Contains(attrs, ` t="s"`) {
+				return cell
+			}
+			return sheetValuePattern.ReplaceAllStringFunc(cell, func(value string) string {
+				index, _ := strconv.Atoi(sheetValuePattern.FindStringSubmatch(value)[1])
+				return "<v>" + strconv.Itoa(m.sharedString(index)) + "</v>"
+			})
+		})
+	}
+
+	if m.styles != nil {
+		sheet = remapAttr(sheet, sheetStyledTagPattern, sheetStyleAttrPattern, func(i int) int { return m.style("cellXfs", i) })
+		sheet = remapAttr(sheet, sheetColumnTagPattern, sheetColumnStylePattern, func(i int) int { return m.style("cellXfs", i) })
+		sheet = remapAttr(sheet, sheetRuleTagPattern, sheetRuleDxfPattern, func(i int) int { return m.style("dxfs", i) })
+	}
+
+	// Only the first sheet of the workbook stays selected
+	return strings.ReplaceAll(sheet, ` tabSelected="1"`, "")
+}
+
+// remapAttr replaces the numbers captured by attr in the tags matched by tags.
+func remapAttr(content string, tags, attr *regexp.Regexp, remap func(int) int) string {
+	return tags.ReplaceAllStringFunc(content, func(tag string) string {
+		return attr.ReplaceAllStringFunc(tag, func(value string) string {
+			match := attr.FindStringSubmatch(value)
+			i, _ := strconv.Atoi(match[2])
+			return match[1] + strconv.Itoa(remap(i)) + match[3]
+		})
+	})
+}
+
+// sharedString returns the combined index of a string of the source.
+func (m *xlsxMerge) sharedString(index int) int {
+	if i, ok := m.mapped[index]; ok {
+		m.merger.strings.added++
+		return i
+	}
+	if index < 0 || index >= len(m.strings) {
+		return index
+	}
+	i := m.merger.strings.add(m.strings[index])
+	m.mapped[index] = i
+	return i
+}
+
+// style returns the combined index of an entry of a style list of the source, adding the
+// fonts, fills, borders and number formats it uses.
+func (m *xlsxMerge) style(list string, index int) int {
+	if i, ok := m.xfs[list][index]; ok {
+		return i
+	}
+	entries := m.styles[list]
+	if index < 0 || index >= len(entries) {
+		return index
+	}
+
+	entry := entries[index]
+	if list == "cellXfs" || list == "cellStyleXfs" {
+		tag := entry[:strings.IndexByte(entry, '>')+1]
+		if id := attrValue(tag, "numFmtId"); id != "" {
+			tag = setAttr(tag, "numFmtId", m.numFmt(id))
+		}
+		// In a fixed order, so the combined lists come out the same on every run
+		for _, ref := range xfReferences {
+			if list == "cellStyleXfs" && ref.attr == "xfId" {
+				continue
+			}
+			if id, err := strconv.Atoi(attrValue(tag, ref.attr)); err == nil {
+				tag = setAttr(tag, ref.attr, strconv.Itoa(m.style(ref.list, id)))
+			}
+		}
+		entry = tag + entry[strings.IndexByte(entry, '>')+1:]
+	}
+
+	i := m.merger.styles.add(list, entry)
+	if m.xfs[list] == nil {
+		m.xfs[list] = make(map[int]int)
+	}
+	m.xfs[list][index] = i
+	return i
+}
+
+// numFmt returns the combined ID of a number format of the source. Built-in formats keep
+// their ID.
+func (m *xlsxMerge) numFmt(id string) string {
+	for _, numFmt := range m.styles["numFmts"] {
+		if attrValue(numFmt, "numFmtId") == id {
+			return m.merger.styles.numFmt(attrValue(numFmt, "formatCode"))
+		}
+	}
+	return id
+}
+
+// formulas rewrites the references to renamed sheets and tables in the formulas of a part.
+func (m *xlsxMerge) formulas(content string) string {
+	if len(m.renames) == 0 && len(m.tables) == 0 {
+		return content
+	}
+	rewrite := func(formula string) string {
+		formula = formulaSheetRefPattern.ReplaceAllStringFunc(formula, func(ref string) string {
+			name := strings.TrimSuffix(ref, "!")
+			if strings.HasPrefix(name, "'") {
+				name = strings.ReplaceAll(name[1:len(name)-1], "''", "'")
+			}
+			renamed, ok := m.renames[strings.ToLower(UnescapeXMLText(name))]
+			if !ok {
+				return ref
+			}
+			return "'" + EscapeXMLText(strings.ReplaceAll(renamed, "'", "''")) + "'!"
+		})
+		return formulaTableRefPattern.ReplaceAllStringFunc(formula, func(ref string) string {
+			if renamed, ok := m.tables[strings.TrimSuffix(ref, "[")]; ok {
+				return renamed + "["
+			}
+			return ref
+		})
+	}
+
+	if strings.HasPrefix(content, "<definedName") {
+		end := strings.IndexByte(content, '>') + 1
+		closing := strings.LastIndex(content, "</definedName>")
+		if closing < end {
+			return content
+		}
+		return content[:end] + rewrite(content[end:closing]) + content[closing:]
+	}
+	return sheetFormulaPattern.ReplaceAllStringFunc(content, func(element string) string {
+		match := sheetFormulaPattern.FindStringSubmatch(element)
+		return match[1] + rewrite(match[2]) + match[3]
+	})
+}
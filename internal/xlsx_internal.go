@@ -1,38 +1,152 @@
 package internal
 
 import (
-	"archive/zip"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
-// ProcessXlsxZipFile processes a single file from an XLSX zip archive.
-func ProcessXlsxZipFile(file *zip.File, zipWriter *zip.Writer, replacements map[string]string) error {
-	return ProcessZipFile(file, zipWriter, func(fileName string, content []byte) []byte {
-		// Process the shared strings XML file where most text is stored
-		if fileName == "xl/sharedStrings.xml" {
-			processedContent := processSharedStringsXML(string(content), replacements)
-			return []byte(processedContent)
-		}
-		// For other files, copy as-is
-		return content
-	})
+var (
+	// sharedTextPattern matches a shared string <t> element and captures its content.
+	sharedTextPattern = regexp.MustCompile(`<t(?:\s[^>]*)?>(.*?)</t>`)
+
+	sheetCellPattern  = regexp.MustCompile(`<c\b([^>/]*)>(.*?)</c>`)
+	sheetValuePattern = regexp.MustCompile(`<v>(\d+)</v>`)
+	// Worksheet elements that follow hyperlinks in the schema
+	sheetAfterHyperlinksPattern = regexp.MustCompile(`<(?:printOptions|pageMargins|pageSetup|headerFooter|rowBreaks|colBreaks|customProperties|cellWatches|ignoredErrors|smartTags|drawing|legacyDrawing|legacyDrawingHF|drawingHF|picture|oleObjects|controls|webPublishItems|tableParts|extLst)\b`)
+)
+
+// ProcessXlsxPackage replaces the keywords in the shared strings of an XLSX package, where
+// most text is stored, and in its hyperlink targets. Rich text values are written as plain
+// text; cells showing a link value get a hyperlink.
+func ProcessXlsxPackage(pkg *Package, replacer *Replacer) error {
+	if err := ProcessRelationships(pkg, replacer); err != nil {
+		return err
+	}
+	if err := applyTemplateProperties(pkg, replacer); err != nil {
+		return err
+	}
+	if !pkg.Has("xl/sharedStrings.xml") {
+		return nil
+	}
+	content, err := pkg.Read("xl/sharedStrings.xml")
+	if err != nil {
+		return err
+	}
+
+	processed, links := processSharedStringsXML(string(content), replacer)
+	pkg.Write("xl/sharedStrings.xml", []byte(processed))
+	return addCellHyperlinks(pkg, links)
 }
 
-func processSharedStringsXML(xmlContent string, replacements map[string]string) string {
+// processSharedStringsXML replaces the keywords in the shared strings table. It also returns
+// the target of the first link in each string item holding one, by string index.
+func processSharedStringsXML(xmlContent string, replacer *Replacer) (string, map[int]string) {
 	stringItems := splitIntoStringItems(xmlContent)
+	links := make(map[int]string)
+	index := -1
 
 	for i, item := range stringItems {
+		if !strings.HasPrefix(item, "<si") {
+			continue
+		}
+		index++
+
 		if strings.Contains(item, "<t>") || strings.Contains(item, "<t ") {
 			plainText := extractTextFromStringItem(item)
 
-			if ContainsAnyKeyword(plainText, replacements) {
-				positionMap := buildStringItemPositionMap(item)
-				stringItems[i] = ApplyReplacements(item, plainText, replacements, positionMap)
+			if replacer.ContainsAnyKeyword(plainText) {
+				nodes := FindTextNodes(item, sharedTextPattern)
+				result, values := replaceInNodes(item, nodes, replacer, false)
+				if link := firstLink(values); link != "" {
+					links[index] = link
+				}
+				stringItems[i] = result
 			}
 		}
 	}
-	return strings.Join(stringItems, "")
+	return strings.Join(stringItems, ""), links
+}
+
+func firstLink(values []richValue) string {
+	for _, value := range values {
+		for _, paragraph := range value.paragraphs {
+			for _, run := range paragraph.runs {
+				if run.link != "" {
+					return run.link
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// addCellHyperlinks links every worksheet cell showing one of the given shared strings.
+func addCellHyperlinks(pkg *Package, links map[int]string) error {
+	if len(links) == 0 {
+		return nil
+	}
+
+	for _, name := range pkg.Names() {
+		if !strings.HasPrefix(name, "xl/worksheets/") || !strings.HasSuffix(name, ".xml") || strings.Contains(name, "/_rels/") {
+			continue
+		}
+		content, err := pkg.Read(name)
+		if err != nil {
+			return err
+		}
+
+		sheet := string(content)
+		var hyperlinks strings.Builder
+		ids := make(map[string]string)
+
+		for _, cell := range sheetCellPattern.FindAllStringSubmatch(sheet, -1) {
+			ref := attrValue(cell[1], "r")
+			value := sheetValuePattern.FindStringSubmatch(cell[2])
+			if attrValue(cell[1], "t") != "s" || ref == "" || value == nil || strings.Contains(sheet, `<hyperlink ref="`+ref+`"`) {
+				continue
+			}
+			index, _ := strconv.Atoi(value[1])
+			target, ok := links[index]
+			if !ok {
+				continue
+			}
+
+			id, ok := ids[target]
+			if !ok {
+				if id, err = pkg.AddRelationship(name, RelTypeHyperlink, target, true); err != nil {
+					return err
+				}
+				ids[target] = id
+			}
+			hyperlinks.WriteString(`<hyperlink ref="` + ref + `" r:id="` + id + `"/>`)
+		}
+
+		if hyperlinks.Len() > 0 {
+			sheet = insertHyperlinks(EnsureNamespace(sheet, "r", officeRelNamespace), hyperlinks.String())
+			pkg.Write(name, []byte(sheet))
+		}
+	}
+	return nil
+}
+
+// insertHyperlinks adds hyperlink elements to a worksheet, creating the hyperlinks element
+// where the schema puts it when the sheet has none.
+func insertHyperlinks(sheet, hyperlinks string) string {
+	if end := strings.Index(sheet, "</hyperlinks>"); end >= 0 {
+		return sheet[:end] + hyperlinks + sheet[end:]
+	}
+
+	at := strings.LastIndex(sheet, "</worksheet>")
+	if dataEnd := strings.Index(sheet, "</sheetData>"); dataEnd >= 0 {
+		if loc := sheetAfterHyperlinksPattern.FindStringIndex(sheet[dataEnd:]); loc != nil {
+			at = dataEnd + loc[0]
+		}
+	}
+	if at < 0 {
+		return sheet
+	}
+	return sheet[:at] + "<hyperlinks>" + hyperlinks + "</hyperlinks>" + sheet[at:]
 }
 
 func splitIntoStringItems(xmlContent string) []string {
@@ -60,38 +174,26 @@ func splitIntoStringItems(xmlContent string) []string {
 func extractTextFromStringItem(item string) string {
 	// Extract text from <t> tags within string items
 	// Note: XLSX can have <t> tags with attributes like xml:space="preserve"
-	re := regexp.MustCompile(`<t(?:\s[^>]*)?>(.*?)</t>`)
-	matches := re.FindAllStringSubmatch(item, -1)
+	matches := sharedTextPattern.FindAllStringSubmatch(item, -1)
 
 	var text strings.Builder
 	for _, match := range matches {
 		if len(match) > 1 {
-			text.WriteString(match[1])
+			text.WriteString(UnescapeXMLText(match[1]))
 		}
 	}
 
 	return text.String()
 }
 
-func buildStringItemPositionMap(item string) map[int]int {
-	positionMap := make(map[int]int)
-
-	// Build position map for <t> tags in string items
-	re := regexp.MustCompile(`<t(?:\s[^>]*)?>(.*?)</t>`)
-	matches := re.FindAllStringSubmatchIndex(item, -1)
-
-	plainPos := 0
-	for _, match := range matches {
-		if len(match) >= 4 {
-			contentStart := match[2] // Start of content group
-			contentEnd := match[3]   // End of content group
+func splitIntoInlineStrings(xmlContent string) []string {
+	// Match <is> elements (inline strings) in worksheet cells
+	re := regexp.MustCompile(`(<is\b[^>]*>(?:.*?)</is>)`)
+	var result []string
 
-			textContent := item[contentStart:contentEnd]
-			for i := 0; i < len(textContent); i++ {
-				positionMap[plainPos+i] = contentStart + i
-			}
-			plainPos += len(textContent)
-		}
+	for _, item := range re.FindAllStringIndex(xmlContent, -1) {
+		result = append(result, xmlContent[item[0]:item[1]])
 	}
-	return positionMap
+
+	return result
 }
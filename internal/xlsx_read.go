@@ -0,0 +1,423 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CellKind is the type of value a worksheet cell holds.
+type CellKind int
+
+const (
+	// CellEmpty is a cell without a value.
+	CellEmpty CellKind = iota
+	// CellString is text, shared or inline, or the text result of a formula.
+	CellString
+	// CellNumber is a number not formatted as a date or time.
+	CellNumber
+	// CellBool is TRUE or FALSE.
+	CellBool
+	// CellDate is a date or time, from a date serial number or an ISO 8601 cell.
+	CellDate
+	// CellError is a formula error such as #N/A.
+	CellError
+)
+
+// SheetCell is a cell value read by ReadSheet. Text is the value as a string: numbers in plain
+// decimal form, booleans as TRUE or FALSE and dates in ISO 8601 ("2024-03-01",
+// "2024-03-01 14:30:00" or "14:30:00", as the cell's number format shows them).
+type SheetCell struct {
+	Text string
+	Kind CellKind
+}
+
+var (
+	sheetRowPattern       = regexp.MustCompile(`(?s)<row\b[^>]*/>|<row\b[^>]*>.*?</row>`)
+	cellElementPattern    = regexp.MustCompile(`(?s)<c\b[^>]*/>|<c\b[^>]*>.*?</c>`)
+	cellValuePattern      = regexp.MustCompile(`(?s)<v(?:\s[^>]*)?>(.*?)</v>`)
+	stringTextPattern     = regexp.MustCompile(`(?s)<t(?:\s[^>]*)?>(.*?)</t>`)
+	stringPhoneticPattern = regexp.MustCompile(`(?s)<rPh\b.*?</rPh>`)
+	cellColumnPattern     = regexp.MustCompile(`^([A-Za-z]+)(\d*)$`)
+	formatLiteralPattern  = regexp.MustCompile(`"[^"]*"|\\.|_.|\*.|\[[^\]]*\]`)
+	workbookDate1904      = regexp.MustCompile(`<workbookPr\b[^>]*\sdate1904="(?:1|true)"`)
+)
+
+// ReadSheet returns the cell values of a worksheet by row, the first row and column of the
+// sheet first. sheet names the worksheet without regard to case; empty reads the first one.
+// Empty rows between rows with values are kept, trailing empty cells are not.
+func ReadSheet(pkg *Package, sheet string) ([][]SheetCell, error) {
+	content, err := pkg.Read(workbookPartName)
+	if err != nil {
+		return nil, err
+	}
+	workbook := string(content)
+	entries := workbookSheetPattern.FindAllString(workbook, -1)
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("workbook has no sheets")
+	}
+	position := 0
+	if sheet != "" {
+		names := make([]string, len(entries))
+		for i, entry := range entries {
+			names[i] = UnescapeXMLText(attrValue(entry, "name"))
+		}
+		positions, err := selectSheets(names, []string{sheet})
+		if err != nil {
+			return nil, err
+		}
+		position = positions[0]
+	}
+
+	rels, err := pkg.readRelationships(workbookPartName)
+	if err != nil {
+		return nil, err
+	}
+	rel, ok := rels[attrValue(entries[position], "r:id")]
+	if !ok || rel.relType != RelTypeWorksheet {
+		return nil, fmt.Errorf("sheet %q is not a worksheet", UnescapeXMLText(attrValue(entries[position], "name")))
+	}
+	worksheet, err := pkg.Read(resolveTarget(workbookPartName, rel.target))
+	if err != nil {
+		return nil, err
+	}
+
+	r := &sheetReader{date1904: workbookDate1904.MatchString(workbook)}
+	if err := r.load(pkg); err != nil {
+		return nil, err
+	}
+	return r.rows(string(worksheet)), nil
+}
+
+// sheetReader reads cell values with the shared strings and number formats of a workbook.
+type sheetReader struct {
+	strings  []string
+	formats  []string // number format codes by cell format
+	date1904 bool
+}
+
+func (r *sheetReader) load(pkg *Package) error {
+	if part, ok := pkg.relatedPart(workbookPartName, RelTypeSharedStrings); ok {
+		content, err := pkg.Read(part)
+		if err != nil {
+			return err
+		}
+		for _, item := range sharedStringItems(string(content)) {
+			r.strings = append(r.strings, stringItemText(item))
+		}
+	}
+
+	part, ok := pkg.relatedPart(workbookPartName, RelTypeStyles)
+	if !ok {
+		return nil
+	}
+	content, err := pkg.Read(part)
+	if err != nil {
+		return err
+	}
+	lists := parseStyles(string(content))
+	custom := make(map[string]string)
+	for _, numFmt := range lists["numFmts"] {
+		custom[attrValue(numFmt, "numFmtId")] = UnescapeXMLText(attrValue(numFmt, "formatCode"))
+	}
+	for _, xf := range lists["cellXfs"] {
+		id := attrValue(xf[:strings.IndexByte(xf, '>')], "numFmtId")
+		code, ok := custom[id]
+		if !ok {
+			code = builtinDateFormat(id)
+		}
+		r.formats = append(r.formats, code)
+	}
+	return nil
+}
+
+func (r *sheetReader) rows(worksheet string) [][]SheetCell {
+	var rows [][]SheetCell
+	next := 0
+	for _, row := range sheetRowPattern.FindAllString(worksheet, -1) {
+		tag := row[:strings.IndexByte(row, '>')]
+		index := next
+		if number, err := strconv.Atoi(attrValue(tag, "r")); err == nil && number > index {
+			index = number - 1
+		}
+		next = index + 1
+
+		var cells []SheetCell
+		column := 0
+		for _, cell := range cellElementPattern.FindAllString(row, -1) {
+			cellTag := cell[:strings.IndexByte(cell, '>')]
+			if match := cellColumnPattern.FindStringSubmatch(attrValue(cellTag, "r")); match != nil {
+				column = columnIndex(match[1])
+			}
+			value := r.cell(cell, strings.TrimSuffix(cellTag, "/"))
+			if value.Kind != CellEmpty {
+				for len(cells) < column {
+					cells = append(cells, SheetCell{})
+				}
+				cells = append(cells[:column], value)
+			}
+			column++
+		}
+		if len(cells) == 0 {
+			continue
+		}
+		for len(rows) < index {
+			rows = append(rows, nil)
+		}
+		rows = append(rows, cells)
+	}
+	return rows
+}
+
+func (r *sheetReader) cell(cell, tag string) SheetCell {
+	kind := attrValue(tag, "t")
+	if kind == "inlineStr" {
+		if start := indexElement(cell, "is"); start >= 0 {
+			return SheetCell{Text: stringItemText(cell[start:]), Kind: CellString}
+		}
+		return SheetCell{}
+	}
+
+	match := cellValuePattern.FindStringSubmatch(cell)
+	if match == nil {
+		return SheetCell{}
+	}
+	value := UnescapeXMLText(match[1])
+	switch kind {
+	case "s":
+		i, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || i < 0 || i >= len(r.strings) {
+			return SheetCell{}
+		}
+		return SheetCell{Text: r.strings[i], Kind: CellString}
+	case "str":
+		return SheetCell{Text: value, Kind: CellString}
+	case "b":
+		if strings.TrimSpace(value) == "1" {
+			return SheetCell{Text: "TRUE", Kind: CellBool}
+		}
+		return SheetCell{Text: "FALSE", Kind: CellBool}
+	case "e":
+		return SheetCell{Text: value, Kind: CellError}
+	case "d":
+		value = strings.TrimSuffix(strings.TrimSuffix(value, "Z"), "T00:00:00")
+		return SheetCell{Text: strings.Replace(value, "T", " ", 1), Kind: CellDate}
+	}
+
+	number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return SheetCell{Text: value, Kind: CellString}
+	}
+	if style, err := strconv.Atoi(attrValue(tag, "s")); err == nil && style >= 0 && style < len(r.formats) {
+		if layout := dateLayout(r.formats[style]); layout != "" {
+			return SheetCell{Text: r.date(number).Format(layout), Kind: CellDate}
+		}
+	}
+	// Stored values carry binary noise such as 0.30000000000000004; Excel shows 15 digits
+	number, _ = strconv.ParseFloat(strconv.FormatFloat(number, 'g', 15, 64), 64)
+	return SheetCell{Text: strconv.FormatFloat(number, 'f', -1, 64), Kind: CellNumber}
+}
+
+// date converts a date serial number to a time, to the nearest second.
+func (r *sheetReader) date(serial float64) time.Time {
+	base := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	switch {
+	case r.date1904:
+		base = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	case serial < 61:
+		// Excel counts a 29 February 1900 that never was; serials before it are a day off
+		base = base.AddDate(0, 0, 1)
+	}
+	return base.Add(time.Duration(math.Round(serial*86400)) * time.Second)
+}
+
+// dateLayout returns the layout to write a value of a number format with, or "" for formats
+// that are not dates or times.
+func dateLayout(code string) string {
+	// Only the first section formats positive values; literals and colors don't count
+	section := strings.SplitN(code, ";", 2)[0]
+	elapsed := strings.Contains(section, "[h") || strings.Contains(section, "[m") || strings.Contains(section, "[s")
+	section = strings.ToLower(formatLiteralPattern.ReplaceAllString(section, ""))
+	if section == "general" || elapsed {
+		return ""
+	}
+	date := strings.ContainsAny(section, "yd")
+	clock := strings.ContainsAny(section, "hs")
+	switch {
+	case date && clock:
+		return "2006-01-02 15:04:05"
+	case clock:
+		return "15:04:05"
+	case date, strings.Contains(section, "m"):
+		return "2006-01-02"
+	}
+	return ""
+}
+
+// builtinDateFormat returns a format code with the date and time parts of a built-in number
+// format, or "" for built-in formats that are not dates.
+func builtinDateFormat(id string) string {
+	n, _ := strconv.Atoi(id)
+	switch {
+	case n >= 14 && n <= 17, n >= 27 && n <= 31, n >= 34 && n <= 36, n >= 50 && n <= 58:
+		return "yyyy-mm-dd"
+	case n == 22:
+		return "yyyy-mm-dd hh:mm:ss"
+	case n >= 18 && n <= 21, n == 32, n == 33, n == 45, n == 47:
+		return "hh:mm:ss"
+	}
+	return ""
+}
+
+// columnIndex returns the 0-based index of a column name such as "A" or "AB".
+func columnIndex(name string) int {
+	index := 0
+	for _, letter := range strings.ToUpper(name) {
+		index = index*26 + int(letter-'A'+1)
+	}
+	return index - 1
+}
+
+// stringItemText returns the text of a string item, without its phonetic runs.
+func stringItemText(item string) string {
+	item = stringPhoneticPattern.ReplaceAllString(item, "")
+	var text strings.Builder
+	for _, match := range stringTextPattern.FindAllStringSubmatch(item, -1) {
+		text.WriteString(UnescapeXMLText(match[1]))
+	}
+	return text.String()
+}
+
+// SheetRecords reads rows as records keyed by the headings in the first row with values.
+// Columns without a heading and rows without values are left out.
+func SheetRecords(rows [][]SheetCell) ([]map[string]string, error) {
+	headers, rows := sheetHeaders(rows)
+	if headers == nil {
+		return nil, fmt.Errorf("sheet has no header row")
+	}
+	var records []map[string]string
+	for _, row := range rows {
+		if len(row) == 0 {
+			continue
+		}
+		record := make(map[string]string, len(headers))
+		for i, header := range headers {
+			if header == "" {
+				continue
+			}
+			record[header] = ""
+			if i < len(row) {
+				record[header] = row[i].Text
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func sheetHeaders(rows [][]SheetCell) ([]string, [][]SheetCell) {
+	for i, row := range rows {
+		if len(row) == 0 {
+			continue
+		}
+		headers := make([]string, len(row))
+		for j, cell := range row {
+			headers[j] = strings.TrimSpace(cell.Text)
+		}
+		return headers, rows[i+1:]
+	}
+	return nil, nil
+}
+
+// WriteSheetCSV writes rows as CSV, padding rows to the same number of fields.
+func WriteSheetCSV(w io.Writer, rows [][]SheetCell) error {
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+	writer := csv.NewWriter(w)
+	for _, row := range rows {
+		fields := make([]string, columns)
+		for i, cell := range row {
+			fields[i] = cell.Text
+		}
+		if err := writer.Write(fields); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteSheetJSON writes rows as a JSON array of records keyed by the headings in the first row,
+// keeping the column order. Numbers and booleans are written as JSON numbers and booleans and
+// empty cells as null.
+func WriteSheetJSON(w io.Writer, rows [][]SheetCell) error {
+	headers, rows := sheetHeaders(rows)
+	if headers == nil {
+		return fmt.Errorf("sheet has no header row")
+	}
+
+	var b bytes.Buffer
+	b.WriteString("[")
+	first := true
+	for _, row := range rows {
+		if len(row) == 0 {
+			continue
+		}
+		if !first {
+			b.WriteString(",")
+		}
+		first = false
+		b.WriteString("\n  {")
+		written := 0
+		for i, header := range headers {
+			if header == "" {
+				continue
+			}
+			if written > 0 {
+				b.WriteString(", ")
+			}
+			written++
+			writeJSONString(&b, header)
+			b.WriteString(": ")
+
+			var cell SheetCell
+			if i < len(row) {
+				cell = row[i]
+			}
+			switch cell.Kind {
+			case CellEmpty:
+				b.WriteString("null")
+			case CellNumber:
+				b.WriteString(cell.Text)
+			case CellBool:
+				b.WriteString(strings.ToLower(cell.Text))
+			default:
+				writeJSONString(&b, cell.Text)
+			}
+		}
+		b.WriteString("}")
+	}
+	if !first {
+		b.WriteString("\n")
+	}
+	b.WriteString("]\n")
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// writeJSONString writes a JSON string without escaping the characters HTML treats specially.
+func writeJSONString(b *bytes.Buffer, s string) {
+	encoder := json.NewEncoder(b)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+	b.Truncate(b.Len() - 1) // the newline Encode ends with
+}
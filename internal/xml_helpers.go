@@ -3,20 +3,21 @@ package internal
 import (
 	"archive/zip"
 	"io"
-	"log"
-	"sort"
+	"regexp"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
-type replacementPoint struct {
-	startPos    int
-	endPos      int
-	replacement string
-}
-
 // ProcessZipFile processes a single file from a zip archive and writes it to the output zip.
-// fileProcessor is a function that processes the content if needed, otherwise returns it unchanged.
-func ProcessZipFile(file *zip.File, zipWriter *zip.Writer, fileProcessor func(string, []byte) []byte) error {
+// Files for which processes returns false are copied in their compressed form without being
+// read. The content of the others is passed to fileProcessor; if it comes back unchanged the
+// file is copied too, otherwise it is rewritten keeping its header fields.
+func ProcessZipFile(file *zip.File, zipWriter *zip.Writer, processes func(string) bool, fileProcessor func(string, []byte) []byte) error {
+	if !processes(file.Name) {
+		return zipWriter.Copy(file)
+	}
+
 	// Open the file from the zip
 	rc, err := file.Open()
 	if err != nil {
@@ -30,131 +31,276 @@ func ProcessZipFile(file *zip.File, zipWriter *zip.Writer, fileProcessor func(st
 		return err
 	}
 
+	// Process the content, copying the file as it is when nothing changed
+	processedContent := fileProcessor(file.Name, content)
+	if sameZipContent(file, processedContent) {
+		return zipWriter.Copy(file)
+	}
+
 	// Create corresponding file in output zip
-	writer, err := zipWriter.Create(file.Name)
+	writer, err := zipWriter.CreateHeader(partHeader(file.Name, file))
 	if err != nil {
 		return err
 	}
-
-	// Process the content (or pass through unchanged)
-	processedContent := fileProcessor(file.Name, content)
 	_, err = writer.Write(processedContent)
 
 	return err
 }
 
-// FindReplacementPoints identifies all positions in the text where keywords should be replaced.
-func FindReplacementPoints(text string, replacements map[string]string) []replacementPoint {
-	var points []replacementPoint
-	for keyword, replacement := range replacements {
-		index := 0
-		for {
-			pos := strings.Index(text[index:], keyword)
-			if pos == -1 {
-				break
-			}
+// TextNode locates the content of a single text element (<w:t>, <a:t> or <t>)
+// inside a paragraph or string item.
+type TextNode struct {
+	Start        int // Offset of the opening tag
+	ContentStart int // Offset of the first content byte
+	ContentEnd   int // Offset of the closing tag
+}
 
-			startPos := index + pos
-			endPos := startPos + len(keyword)
+// FindTextNodes returns every text element matched by textPattern in element.
+// textPattern must capture the element content in its first group.
+func FindTextNodes(element string, textPattern *regexp.Regexp) []TextNode {
+	matches := textPattern.FindAllStringSubmatchIndex(element, -1)
 
-			points = append(points, replacementPoint{
-				startPos:    startPos,
-				endPos:      endPos,
-				replacement: replacement,
+	nodes := make([]TextNode, 0, len(matches))
+	for _, match := range matches {
+		if len(match) >= 4 {
+			nodes = append(nodes, TextNode{
+				Start:        match[0],
+				ContentStart: match[2],
+				ContentEnd:   match[3],
 			})
-			index = endPos
 		}
 	}
-	return points
+	return nodes
+}
+
+// ApplyReplacements applies replacements to a paragraph/element whose text is spread over nodes.
+// Keywords are matched against the decoded text of the nodes, so entities and character
+// references never shift positions. Each replacement is written into the text node where its
+// keyword starts, so the value takes that run's formatting. The remainder of a keyword spanning
+// several runs is removed from the following text nodes only, which keeps every tag in between
+// (run properties, proofing marks, bookmarks) intact and the XML well-formed.
+func ApplyReplacements(element string, nodes []TextNode, replacer *Replacer) string {
+	result, _ := replaceInNodes(element, nodes, replacer, false)
+	return result
 }
 
-// ApplyReplacements applies replacement points to a paragraph/element using a position map.
-func ApplyReplacements(element string, plainText string, replacements map[string]string, positionMap map[int]int) string {
-	replacementPoints := FindReplacementPoints(plainText, replacements)
-	elementLength := len(element)
+// replaceInNodes implements ApplyReplacements and also returns the values parsed as rich text,
+// in order. With markers set, those values are written as markers (see richMarker) for the
+// caller to render; otherwise they are written as plain text.
+func replaceInNodes(element string, nodes []TextNode, replacer *Replacer, markers bool) (string, []richValue) {
+	plainText, offsets := nodesPlainText(element, nodes)
+	points := replacer.FindReplacementPoints(plainText)
+	if len(points) == 0 {
+		return element, nil
+	}
 
-	// Sort replacement points by position (descending) to avoid position shifts
-	sort.Slice(replacementPoints, func(i, j int) bool {
-		return replacementPoints[i].startPos > replacementPoints[j].startPos
-	})
+	var values []richValue
+	for i, rp := range points {
+		if rp.rich != nil {
+			if markers {
+				points[i].replacement = richMarker(len(values))
+			}
+			values = append(values, *rp.rich)
+		}
+	}
 
-	for _, rp := range replacementPoints {
-		xmlStartPos, xmlEndPos := FindXMLPositions(rp.startPos, rp.endPos, positionMap)
+	// Rebuild nodes from the last to the first so earlier offsets stay valid
+	for i := len(nodes) - 1; i >= 0; i-- {
+		node := nodes[i]
 
-		if xmlStartPos < 0 || xmlEndPos <= xmlStartPos {
-			log.Printf("Skipping invalid replacement: start=%d, end=%d", xmlStartPos, xmlEndPos)
+		text, changed := rewriteNodeText(plainText, offsets[i], offsets[i+1], points)
+		if !changed {
 			continue
 		}
 
-		if xmlStartPos >= elementLength {
-			log.Printf("Start position %d out of bounds (length: %d)", xmlStartPos, elementLength)
-			continue
+		content := EscapeXMLText(text)
+		openTag := element[node.Start:node.ContentStart]
+		if needsSpacePreserve(openTag, content) {
+			openTag = addSpacePreserve(openTag)
 		}
+		element = element[:node.Start] + openTag + content + element[node.ContentEnd:]
+	}
+	return element, values
+}
 
-		if xmlEndPos > elementLength {
-			log.Printf("Adjusting end position from %d to %d (element length)", xmlEndPos, elementLength)
-			xmlEndPos = elementLength
+// nodesPlainText returns the decoded text of nodes, with the offset of each node's text in it
+// followed by the total length.
+func nodesPlainText(element string, nodes []TextNode) (string, []int) {
+	var plain strings.Builder
+	offsets := make([]int, len(nodes)+1)
+	for i, node := range nodes {
+		offsets[i] = plain.Len()
+		plain.WriteString(UnescapeXMLText(element[node.ContentStart:node.ContentEnd]))
+	}
+	offsets[len(nodes)] = plain.Len()
+	return plain.String(), offsets
+}
+
+// rewriteNodeText returns the new decoded text of the node covering plainText[nodeStart:nodeEnd].
+func rewriteNodeText(plainText string, nodeStart, nodeEnd int, points []replacementPoint) (string, bool) {
+	var content strings.Builder
+	cursor := nodeStart
+	changed := false
+
+	for _, rp := range points {
+		if rp.endPos <= nodeStart || rp.startPos >= nodeEnd {
+			continue
 		}
+		changed = true
 
-		// Double-check to avoid slice bounds error
-		if xmlStartPos <= xmlEndPos && xmlStartPos < elementLength && xmlEndPos <= elementLength {
-			element = element[:xmlStartPos] + rp.replacement + element[xmlEndPos:]
-		} else {
-			log.Printf("Skipping replacement due to invalid positions: start=%d, end=%d, length=%d",
-				xmlStartPos, xmlEndPos, elementLength)
+		if rp.startPos > cursor {
+			content.WriteString(plainText[cursor:rp.startPos])
 		}
+		// Only the node holding the first character of the keyword receives the value
+		if rp.startPos >= nodeStart {
+			content.WriteString(rp.replacement)
+		}
+		cursor = min(rp.endPos, nodeEnd)
+	}
+
+	if !changed {
+		return "", false
 	}
-	return element
+	content.WriteString(plainText[cursor:nodeEnd])
+	return content.String(), true
 }
 
-// FindXMLPositions converts plain text positions to XML positions using a position map.
-func FindXMLPositions(plainStartPos, plainEndPos int, positionMap map[int]int) (int, int) {
-	// Find the actual positions in the map
-	xmlStartPos := -1
-	xmlEndPos := -1
-
-	// Find the exact start position or nearest position
-	for i := plainStartPos; i < plainEndPos; i++ {
-		if pos, exists := positionMap[i]; exists {
-			xmlStartPos = pos
-			break
-		}
+// needsSpacePreserve reports whether content would lose leading or trailing whitespace
+// without an xml:space="preserve" attribute on its element.
+func needsSpacePreserve(openTag, content string) bool {
+	// DrawingML text (<a:t>) always preserves whitespace
+	if strings.HasPrefix(openTag, "<a:") || strings.Contains(openTag, "xml:space") || content == "" {
+		return false
 	}
+	return strings.TrimSpace(content[:1]) == "" || strings.TrimSpace(content[len(content)-1:]) == ""
+}
+
+func addSpacePreserve(openTag string) string {
+	return strings.TrimSuffix(openTag, ">") + ` xml:space="preserve">`
+}
 
-	if xmlStartPos == -1 {
-		log.Printf("No start position found for plaintext range %d:%d", plainStartPos, plainEndPos)
-		return -1, -1
+// UnescapeXMLText decodes the predefined entities and character references in XML text content.
+// Unknown entities are left as they are.
+func UnescapeXMLText(s string) string {
+	if !strings.Contains(s, "&") {
+		return s
 	}
 
-	// Find the exact end position or nearest position before the end
-	for i := plainEndPos - 1; i >= plainStartPos; i-- {
-		if pos, exists := positionMap[i]; exists {
-			xmlEndPos = pos + 1 // +1 because end position is exclusive
-			break
+	var b strings.Builder
+	b.Grow(len(s))
+	for {
+		amp := strings.IndexByte(s, '&')
+		if amp < 0 {
+			b.WriteString(s)
+			return b.String()
 		}
+		b.WriteString(s[:amp])
+		s = s[amp:]
+
+		semi := strings.IndexByte(s, ';')
+		if semi < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+
+		if r, ok := decodeEntity(s[1:semi]); ok {
+			b.WriteRune(r)
+		} else {
+			b.WriteString(s[:semi+1])
+		}
+		s = s[semi+1:]
 	}
+}
 
-	// If no end position found, use start position + 1 as fallback
-	if xmlEndPos == -1 {
-		log.Printf("No end position found, using start+1 as fallback")
-		xmlEndPos = xmlStartPos + 1
+func decodeEntity(name string) (rune, bool) {
+	switch name {
+	case "amp":
+		return '&', true
+	case "lt":
+		return '<', true
+	case "gt":
+		return '>', true
+	case "quot":
+		return '"', true
+	case "apos":
+		return '\'', true
 	}
 
-	// Final safety check
-	if xmlEndPos <= xmlStartPos {
-		log.Printf("XML positions invalid: start=%d, end=%d, adjusting end", xmlStartPos, xmlEndPos)
-		xmlEndPos = xmlStartPos + 1
+	if !strings.HasPrefix(name, "#") {
+		return 0, false
 	}
 
-	return xmlStartPos, xmlEndPos
+	var code int64
+	var err error
+	if strings.HasPrefix(name, "#x") || strings.HasPrefix(name, "#X") {
+		code, err = strconv.ParseInt(name[2:], 16, 32)
+	} else {
+		code, err = strconv.ParseInt(name[1:], 10, 32)
+	}
+	if err != nil || !utf8.ValidRune(rune(code)) {
+		return 0, false
+	}
+	return rune(code), true
 }
 
-// ContainsAnyKeyword checks if text contains any of the keywords from the replacements map.
-func ContainsAnyKeyword(text string, replacements map[string]string) bool {
-	for keyword := range replacements {
-		if strings.Contains(text, keyword) {
-			return true
+// EscapeXMLText escapes s for use as XML text content.
+// Characters that are not allowed in XML 1.0 documents are dropped.
+func EscapeXMLText(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		s = s[size:]
+
+		switch {
+		case r == utf8.RuneError && size == 1:
+			// Invalid UTF-8 byte
+		case r == '&':
+			b.WriteString("&amp;")
+		case r == '<':
+			b.WriteString("&lt;")
+		case r == '>':
+			b.WriteString("&gt;")
+		case isXMLChar(r):
+			b.WriteRune(r)
 		}
 	}
+	return b.String()
+}
+
+// isXMLChar reports whether r is in the XML 1.0 Char production.
+func isXMLChar(r rune) bool {
+	switch {
+	case r == 0x09 || r == 0x0A || r == 0x0D:
+		return true
+	case r >= 0x20 && r <= 0xD7FF:
+		return true
+	case r >= 0xE000 && r <= 0xFFFD:
+		return true
+	case r >= 0x10000 && r <= 0x10FFFF:
+		return true
+	}
 	return false
 }
+
+// attrValue returns the value of an attribute in a start tag or attribute list.
+func attrValue(attrs, name string) string {
+	prefix := name + `="`
+	for offset := 0; ; {
+		i := strings.Index(attrs[offset:], prefix)
+		if i < 0 {
+			return ""
+		}
+		start := offset + i
+		offset = start + len(prefix)
+		// The name must be a whole attribute name, not the end of a longer one
+		if start == 0 || !strings.ContainsRune(" \t\r\n", rune(attrs[start-1])) {
+			continue
+		}
+		end := strings.IndexByte(attrs[offset:], '"')
+		if end < 0 {
+			return ""
+		}
+		return attrs[offset : offset+end]
+	}
+}
@@ -0,0 +1,71 @@
+package pptx
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/siliconcatalyst/officeforge/internal"
+)
+
+// Deck is a presentation to concatenate with the slides to take from it.
+type Deck struct {
+	Input io.Reader
+
+	// Slides lists 1-based slides and ranges, e.g. "3-7", "1,4-6" or "9-" for slide 9 to the
+	// end. Empty takes all slides.
+	Slides string
+}
+
+// Concat writes the slides of the presentations read from inputs, in order, as a single
+// presentation to w; see ConcatDecks.
+func Concat(w io.Writer, inputs ...io.Reader) error {
+	decks := make([]Deck, len(inputs))
+	for i, input := range inputs {
+		decks[i] = Deck{Input: input}
+	}
+	return ConcatDecks(w, decks...)
+}
+
+// ConcatDecks writes the selected slides of each deck, in order, as a single presentation to w.
+// Slides bring the layouts and masters they use; a master identical to one already in the
+// presentation, theme included, is shared. Notes, media and charts are copied under new part
+// names. The result uses the slide size and properties of the first deck, and section
+// groupings are dropped.
+func ConcatDecks(w io.Writer, decks ...Deck) error {
+	if len(decks) == 0 {
+		return fmt.Errorf("no presentations to concatenate")
+	}
+
+	var merger *internal.PptxMerger
+	var host *internal.Package
+	for i, deck := range decks {
+		pkg, err := internal.ReadPackage(deck.Input)
+		if err != nil {
+			return fmt.Errorf("failed to open presentation %d: %v", i+1, err)
+		}
+		slides, err := internal.PresentationSlides(pkg)
+		if err != nil {
+			return fmt.Errorf("failed to open presentation %d: %v", i+1, err)
+		}
+		selected, err := internal.ParseSlideRange(deck.Slides, len(slides))
+		if err != nil {
+			return fmt.Errorf("presentation %d: %v", i+1, err)
+		}
+
+		if merger == nil {
+			host = pkg
+			if merger, err = internal.NewPptxMerger(pkg, selected); err != nil {
+				return fmt.Errorf("failed to open presentation 1: %v", err)
+			}
+			continue
+		}
+		if err := merger.Append(pkg, selected); err != nil {
+			return fmt.Errorf("failed to append presentation %d: %v", i+1, err)
+		}
+	}
+
+	if err := merger.Finish(); err != nil {
+		return err
+	}
+	return host.SaveTo(w)
+}
@@ -0,0 +1,39 @@
+package pptx
+
+import (
+	"fmt"
+
+	"github.com/siliconcatalyst/officeforge/internal"
+)
+
+// ExtractText returns the text of the presentation at inputPath, a line per paragraph.
+// Table cells are separated by tabs and list items keep their bullets or numbers.
+func ExtractText(inputPath string) (string, error) {
+	return export(inputPath, internal.ExportText)
+}
+
+// ExportMarkdown returns the presentation at inputPath as Markdown, with headings, lists,
+// tables, bold and italic text and hyperlinks. Slide titles become headings and body
+// placeholders lists; slides are separated by a rule and speaker notes are left out.
+func ExportMarkdown(inputPath string) (string, error) {
+	return export(inputPath, internal.ExportMarkdown)
+}
+
+// ExportHTML returns the presentation at inputPath as an HTML fragment with the same
+// structure as ExportMarkdown. Only web, mail and phone links are kept.
+func ExportHTML(inputPath string) (string, error) {
+	return export(inputPath, internal.ExportHTML)
+}
+
+func export(inputPath string, format internal.ExportFormat) (string, error) {
+	pkg, err := internal.OpenPackage(inputPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open input file: %v", err)
+	}
+	defer pkg.Close()
+	text, err := internal.ExportPptx(pkg, format)
+	if err != nil {
+		return "", fmt.Errorf("failed to export presentation: %v", err)
+	}
+	return text, nil
+}
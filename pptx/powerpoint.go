@@ -1,8 +1,8 @@
 package pptx
 
 import (
-	"archive/zip"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
@@ -10,65 +10,77 @@ import (
 	"github.com/siliconcatalyst/officeforge/internal"
 )
 
-// ProcessPptxSingle performs a single keyword replacement in a PPTX file
-func ProcessPptxSingle(inputPath, outputPath, keyword, replacement string) error {
-	reader, err := zip.OpenReader(inputPath)
-	if err != nil {
-		return fmt.Errorf("failed to open input file: %v", err)
-	}
-	defer reader.Close()
+// Options controls how templates are rendered.
+type Options = internal.Options
 
-	outputFile, err := os.Create(outputPath)
-	if err != nil {
-		return fmt.Errorf("failed to create output file: %v", err)
-	}
-	defer outputFile.Close()
+// Syntax describes how placeholders are written in templates.
+type Syntax = internal.Syntax
 
-	zipWriter := zip.NewWriter(outputFile)
-	defer zipWriter.Close()
+// FilterFunc formats a placeholder value, e.g. the currency filter in {{AMOUNT | currency:"EUR"}}.
+type FilterFunc = internal.FilterFunc
 
-	// Create replacements map
-	replacements := map[string]string{keyword: replacement}
+// FuncMap registers custom placeholder filters by name.
+type FuncMap = internal.FuncMap
 
-	// Process each file in the PPTX
-	for _, file := range reader.File {
-		err := internal.ProcessPptxZipFile(file, zipWriter, replacements)
-		if err != nil {
-			return fmt.Errorf("failed to process file %s: %v", file.Name, err)
-		}
-	}
+// Data holds typed record values: strings, numbers, bools, nested objects addressed as
+// {{customer.address.city}} and arrays addressed as {{items[0].name}}.
+type Data = internal.Data
 
-	return nil
+// Link is a hyperlink value shown with the link filter, e.g. {{PORTAL | link}}.
+type Link = internal.Link
+
+// Table is a table value shown with the table filter, e.g. {{ITEMS | table}}; the placeholder's
+// paragraph is replaced by a PowerPoint table.
+type Table = internal.Table
+
+// TableFromRows builds a table whose first row is the header.
+func TableFromRows(rows [][]string) Table {
+	return internal.TableFromRows(rows)
+}
+
+// TableFromCSV reads a table from CSV; the first record is the header.
+func TableFromCSV(r io.Reader) (Table, error) {
+	return internal.TableFromCSV(r)
+}
+
+// TableFromJSON reads a table from a JSON array of arrays (header first) or of objects.
+func TableFromJSON(content []byte) (Table, error) {
+	return internal.TableFromJSON(content)
+}
+
+// ProcessPptxSingle performs a single keyword replacement in a PPTX file
+func ProcessPptxSingle(inputPath, outputPath, keyword, replacement string) error {
+	// Create replacements map
+	replacements := map[string]string{keyword: replacement}
+	return ProcessPptxMultiWithOptions(inputPath, outputPath, replacements, Options{})
 }
 
 // ProcessPptxMulti performs multiple keyword replacements in a PPTX file
 func ProcessPptxMulti(inputPath, outputPath string, replacements map[string]string) error {
-	reader, err := zip.OpenReader(inputPath)
-	if err != nil {
-		return fmt.Errorf("failed to open input file: %v", err)
+	return ProcessPptxMultiWithOptions(inputPath, outputPath, replacements, Options{})
+}
+
+// ProcessPptxMultiWithOptions performs multiple keyword replacements using the given options
+func ProcessPptxMultiWithOptions(inputPath, outputPath string, replacements map[string]string, opts Options) error {
+	if err := opts.Validate(); err != nil {
+		return err
 	}
-	defer reader.Close()
 
-	outputFile, err := os.Create(outputPath)
+	pkg, err := internal.OpenPackage(inputPath)
 	if err != nil {
-		return fmt.Errorf("failed to create output file: %v", err)
+		return fmt.Errorf("failed to open input file: %v", err)
 	}
-	defer outputFile.Close()
+	defer pkg.Close()
+	pkg.SetCompressionLevel(opts.CompressionLevel)
 
-	// Create zip writer for output
-	zipWriter := zip.NewWriter(outputFile)
-	defer zipWriter.Close()
+	replacer := internal.NewReplacer(replacements, opts)
 
-	// Process each file in the PPTX
-	for _, file := range reader.File {
-		err := internal.ProcessPptxZipFile(file, zipWriter, replacements)
-		if err != nil {
-			return fmt.Errorf("failed to process file %s: %v", file.Name, err)
-		}
+	if err := internal.ProcessPptxPackage(pkg, replacer); err != nil {
+		return fmt.Errorf("failed to process presentation: %v", err)
 	}
 
 	// log.Printf("Successfully processed %d replacements in %s", len(replacements), outputPath)
-	return nil
+	return pkg.Save(outputPath)
 }
 
 // ProcessPptxMultipleRecords generates multiple PPTX files using a naming pattern
@@ -77,27 +89,36 @@ func ProcessPptxMulti(inputPath, outputPath string, replacements map[string]stri
 //   - Data-based: "{CLIENT}_presentation.pptx" (uses record fields)
 //   - Empty: defaults to "presentation_%d.pptx"
 func ProcessPptxMultipleRecords(inputPath, outputDir string, records []map[string]string, fileNamePattern string) error {
+	return ProcessPptxMultipleRecordsWithOptions(inputPath, outputDir, records, fileNamePattern, Options{})
+}
+
+// ProcessPptxMultipleRecordsWithOptions generates multiple presentations using a naming pattern and the given options
+func ProcessPptxMultipleRecordsWithOptions(inputPath, outputDir string, records []map[string]string, fileNamePattern string, opts Options) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
 		return fmt.Errorf("failed to create output directory: %v", err)
 	}
 
 	// Validate pattern with first record
 	if len(records) > 0 && fileNamePattern != "" {
-		if err := internal.ValidatePattern(fileNamePattern, records[0]); err != nil {
+		if err := internal.ValidatePattern(fileNamePattern, records[0], opts); err != nil {
 			return fmt.Errorf("invalid pattern: %v", err)
 		}
 	}
 
 	// Create naming function based on pattern
-	nameFunc := internal.CreatePptxNamingFunction(fileNamePattern)
+	nameFunc := internal.CreatePptxNamingFunction(fileNamePattern, opts)
 
 	for i, record := range records {
 		// Generate filename using the naming function
 		fileName := nameFunc(record, i+1)
 		outputPath := filepath.Join(outputDir, fileName)
 
-		// Process the presentation with this record's replacements
-		err := ProcessPptxMulti(inputPath, outputPath, record)
+		// Process the presentation with this record's replacements and locale
+		err := ProcessPptxMultiWithOptions(inputPath, outputPath, record, opts.ForRecord(record))
 		if err != nil {
 			log.Printf("Failed to process record %d: %v", i+1, err)
 			continue
@@ -133,3 +154,17 @@ func ProcessPptxMultipleRecordsWithNames(inputPath, outputDir string, records []
 	// log.Printf("Successfully processed %d records", len(records))
 	return nil
 }
+
+// ProcessPptxData replaces placeholders with values from data, which may be nested and typed
+func ProcessPptxData(inputPath, outputPath string, data Data, opts Options) error {
+	return ProcessPptxMultiWithOptions(inputPath, outputPath, data.Flatten(opts.Syntax), opts)
+}
+
+// ProcessPptxDataRecords generates one presentation per record using a naming pattern; see ProcessPptxMultipleRecords
+func ProcessPptxDataRecords(inputPath, outputDir string, records []Data, fileNamePattern string, opts Options) error {
+	flat := make([]map[string]string, len(records))
+	for i, record := range records {
+		flat[i] = record.Flatten(opts.Syntax)
+	}
+	return ProcessPptxMultipleRecordsWithOptions(inputPath, outputDir, flat, fileNamePattern, opts)
+}
@@ -1,7 +1,10 @@
 package benchmarks
 
 import (
+	"archive/zip"
 	"fmt"
+	"io"
+	"math/rand"
 	"os"
 	"testing"
 	"time"
@@ -93,3 +96,76 @@ func BenchmarkDocxContract(b *testing.B) {
 		}
 	}
 }
+
+// Benchmark for a template with large embedded media, which is copied without being
+// decompressed and compressed again
+func BenchmarkDocxLargeMedia(b *testing.B) {
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	const mediaSize = 50 << 20
+	templatePath := "testdata/output/media_template.docx"
+	if err := writeMediaTemplate("../testdata/template.docx", templatePath, mediaSize); err != nil {
+		b.Fatal(err)
+	}
+
+	replacements := map[string]string{
+		"{{NAME}}":    "John Doe",
+		"{{COMPANY}}": "Acme Corporation",
+	}
+
+	b.SetBytes(mediaSize)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := docx.ProcessDocxMulti(templatePath, "testdata/output/media.docx", replacements)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// writeMediaTemplate copies a template and adds incompressible media of the given size, split
+// over a video and a few pictures.
+func writeMediaTemplate(templatePath, outputPath string, size int) error {
+	reader, err := zip.OpenReader(templatePath)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	outputFile, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer outputFile.Close()
+
+	zipWriter := zip.NewWriter(outputFile)
+	for _, file := range reader.File {
+		if err := zipWriter.Copy(file); err != nil {
+			return err
+		}
+	}
+
+	random := rand.New(rand.NewSource(1))
+	media := []struct {
+		name string
+		size int
+	}{
+		{"word/media/video1.mp4", size * 7 / 10},
+		{"word/media/image1.jpeg", size / 10},
+		{"word/media/image2.jpeg", size / 10},
+		{"word/media/image3.png", size - size*9/10},
+	}
+	for _, m := range media {
+		writer, err := zipWriter.Create(m.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.CopyN(writer, random, int64(m.size)); err != nil {
+			return err
+		}
+	}
+	return zipWriter.Close()
+}
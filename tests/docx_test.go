@@ -1,11 +1,18 @@
 package tests
 
 import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"image"
+	"image/png"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/siliconcatalyst/officeforge/docx"
 )
@@ -377,6 +384,2612 @@ func TestProcessDocxSpecialCharacters(t *testing.T) {
 	t.Logf("\033[32m✓ Special characters test passed\033[0m")
 }
 
+func TestProcessDocxPlaceholderSplitAcrossRuns(t *testing.T) {
+	templatePath := "testdata/output/split_runs_template.docx"
+	outputPath := "testdata/output/split_runs_output.docx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	// {{CLIENT_NAME}} is split by a spell-check mark and a formatting change
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		`<w:p><w:r><w:t xml:space="preserve">Client: </w:t></w:r>` +
+		`<w:r><w:rPr><w:b/></w:rPr><w:t>{{CLIENT</w:t></w:r>` +
+		`<w:proofErr w:type="spellStart"/>` +
+		`<w:r><w:rPr><w:i/></w:rPr><w:t>_NAME</w:t></w:r>` +
+		`<w:proofErr w:type="spellEnd"/>` +
+		`<w:r><w:rPr><w:u w:val="single"/></w:rPr><w:t>}} signed.</w:t></w:r></w:p>` +
+		`</w:body></w:document>`
+
+	if err := writeTemplateWithPart("testdata/template.docx", templatePath, "word/document.xml", document); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	err := docx.ProcessDocxMulti(templatePath, outputPath, map[string]string{"{{CLIENT_NAME}}": "Jane Smith"})
+	if err != nil {
+		t.Fatalf("ProcessDocxMulti failed: %v", err)
+	}
+
+	content, err := readDocxContent(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output content: %v", err)
+	}
+
+	if err := checkWellFormedXML(content); err != nil {
+		t.Fatalf("Output is not well-formed XML: %v", err)
+	}
+
+	// The value takes the formatting of the run holding the start of the placeholder
+	if !strings.Contains(content, `<w:r><w:rPr><w:b/></w:rPr><w:t>Jane Smith</w:t></w:r>`) {
+		t.Errorf("Replacement not placed in the first run of the placeholder:\n%s", content)
+	}
+
+	// The following runs keep their properties and only lose the placeholder text
+	expectedFragments := []string{
+		`<w:r><w:rPr><w:i/></w:rPr><w:t></w:t></w:r>`,
+		`<w:r><w:rPr><w:u w:val="single"/></w:rPr><w:t xml:space="preserve"> signed.</w:t></w:r>`,
+		`<w:proofErr w:type="spellStart"/>`,
+		`<w:proofErr w:type="spellEnd"/>`,
+	}
+	for _, fragment := range expectedFragments {
+		if !strings.Contains(content, fragment) {
+			t.Errorf("Expected fragment %s not found in output", fragment)
+		}
+	}
+
+	t.Logf("\033[32m✓ Split placeholder test passed\033[0m")
+}
+
+func TestNormalizeDocx(t *testing.T) {
+	templatePath := "testdata/output/fragmented_template.docx"
+	outputPath := "testdata/output/normalized_template.docx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	// The way Word typically stores a placeholder after editing and spell-checking
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		`<w:p w:rsidR="00A1" w:rsidRDefault="00B2"><w:r w:rsidRPr="00C3"><w:rPr><w:b/></w:rPr><w:t>Dear {{</w:t></w:r>` +
+		`<w:proofErr w:type="spellStart"/>` +
+		`<w:r w:rsidR="00D4"><w:rPr><w:b/></w:rPr><w:t>CLIENT_NAME</w:t></w:r>` +
+		`<w:proofErr w:type="spellEnd"/>` +
+		`<w:r><w:rPr><w:b/></w:rPr><w:lastRenderedPageBreak/><w:t>}}</w:t></w:r>` +
+		`<w:r><w:rPr><w:b/></w:rPr><w:t>,</w:t></w:r></w:p>` +
+		`</w:body></w:document>`
+
+	if err := writeTemplateWithPart("testdata/template.docx", templatePath, "word/document.xml", document); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	split, err := docx.Normalize(templatePath, outputPath)
+	if err != nil {
+		t.Fatalf("Normalize failed: %v", err)
+	}
+
+	if len(split) != 1 || split[0] != "{{CLIENT_NAME}}" {
+		t.Errorf("Expected [{{CLIENT_NAME}}] reported as split, got %v", split)
+	}
+
+	content, err := readDocxContent(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output content: %v", err)
+	}
+
+	if err := checkWellFormedXML(content); err != nil {
+		t.Fatalf("Output is not well-formed XML: %v", err)
+	}
+
+	expected := `<w:p><w:r><w:rPr><w:b/></w:rPr><w:t>Dear {{CLIENT_NAME}},</w:t></w:r></w:p>`
+	if !strings.Contains(content, expected) {
+		t.Errorf("Expected %s in output:\n%s", expected, content)
+	}
+
+	for _, noise := range []string{"w:rsid", "w:proofErr", "w:lastRenderedPageBreak"} {
+		if strings.Contains(content, noise) {
+			t.Errorf("Expected %s to be removed from output", noise)
+		}
+	}
+
+	t.Logf("\033[32m✓ Normalize test passed\033[0m")
+}
+
+func TestNormalizeDocxKeepsTextlessRuns(t *testing.T) {
+	templatePath := "testdata/output/tabbed_template.docx"
+	outputPath := "testdata/output/tabbed_normalized.docx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	// Tab and break runs between text runs of the same formatting
+	bold := `<w:rPr><w:b/></w:rPr>`
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		`<w:p><w:r>` + bold + `<w:tab/></w:r><w:r>` + bold + `<w:t>Name:</w:t></w:r>` +
+		`<w:r>` + bold + `<w:tab/></w:r><w:r>` + bold + `<w:t>{{NAME}}</w:t></w:r>` +
+		`<w:r>` + bold + `<w:br/></w:r><w:r>` + bold + `<w:t>Role:</w:t></w:r><w:r>` + bold + `<w:t xml:space="preserve"> {{ROLE}}</w:t></w:r></w:p>` +
+		`</w:body></w:document>`
+
+	if err := writeTemplateWithPart("testdata/template.docx", templatePath, "word/document.xml", document); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	if _, err := docx.Normalize(templatePath, outputPath); err != nil {
+		t.Fatalf("Normalize failed: %v", err)
+	}
+
+	content, err := readDocxContent(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output content: %v", err)
+	}
+	if err := checkWellFormedXML(content); err != nil {
+		t.Fatalf("Output is not well-formed XML: %v", err)
+	}
+
+	expected := `<w:p><w:r>` + bold + `<w:tab/></w:r><w:r>` + bold + `<w:t>Name:</w:t></w:r>` +
+		`<w:r>` + bold + `<w:tab/></w:r><w:r>` + bold + `<w:t>{{NAME}}</w:t></w:r>` +
+		`<w:r>` + bold + `<w:br/></w:r><w:r>` + bold + `<w:t>Role: {{ROLE}}</w:t></w:r></w:p>`
+	if !strings.Contains(content, expected) {
+		t.Errorf("Expected %s in output:\n%s", expected, content)
+	}
+
+	t.Logf("\033[32m✓ Normalize text-less runs test passed\033[0m")
+}
+
+func TestNormalizeDocxCustomSyntax(t *testing.T) {
+	templatePath := "testdata/output/fragmented_syntax_template.docx"
+	outputPath := "testdata/output/normalized_syntax_template.docx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		`<w:p><w:r><w:t>Dear [[</w:t></w:r><w:r><w:t>CLIENT_NAME]], ref [[ID]] and {{</w:t></w:r><w:r><w:t>OTHER}}</w:t></w:r></w:p>` +
+		`</w:body></w:document>`
+
+	if err := writeTemplateWithPart("testdata/template.docx", templatePath, "word/document.xml", document); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	opts := docx.Options{Syntax: docx.Syntax{Left: "[[", Right: "]]"}}
+	split, err := docx.NormalizeWithOptions(templatePath, outputPath, opts)
+	if err != nil {
+		t.Fatalf("NormalizeWithOptions failed: %v", err)
+	}
+	if len(split) != 1 || split[0] != "[[CLIENT_NAME]]" {
+		t.Errorf("Expected [[[CLIENT_NAME]]] reported as split, got %v", split)
+	}
+
+	content, err := readDocxContent(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output content: %v", err)
+	}
+	expected := `<w:p><w:r><w:t>Dear [[CLIENT_NAME]], ref [[ID]] and {{OTHER}}</w:t></w:r></w:p>`
+	if !strings.Contains(content, expected) {
+		t.Errorf("Expected %s in output:\n%s", expected, content)
+	}
+
+	t.Logf("\033[32m✓ Normalize custom syntax test passed\033[0m")
+}
+
+func TestNormalizeDocxCopiesOtherParts(t *testing.T) {
+	templatePath := "testdata/output/media_template.docx"
+	outputPath := "testdata/output/media_normalized.docx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	reader, err := zip.OpenReader("testdata/template.docx")
+	if err != nil {
+		t.Fatalf("Failed to open template: %v", err)
+	}
+	defer reader.Close()
+	outputFile, err := os.Create(templatePath)
+	if err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+	zipWriter := zip.NewWriter(outputFile)
+	for _, file := range reader.File {
+		if err := zipWriter.Copy(file); err != nil {
+			t.Fatalf("Failed to copy %s: %v", file.Name, err)
+		}
+	}
+
+	// A checksum that doesn't match the data fails any attempt to read the entry
+	media := []byte("not really a picture")
+	writer, err := zipWriter.CreateRaw(&zip.FileHeader{
+		Name: "word/media/image1.png", Method: zip.Store, CRC32: 1,
+		CompressedSize64: uint64(len(media)), UncompressedSize64: uint64(len(media)),
+	})
+	if err != nil {
+		t.Fatalf("Failed to add media: %v", err)
+	}
+	if _, err := writer.Write(media); err != nil {
+		t.Fatalf("Failed to add media: %v", err)
+	}
+	if err := zipWriter.Close(); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+	outputFile.Close()
+
+	if _, err := docx.Normalize(templatePath, outputPath); err != nil {
+		t.Fatalf("Normalize read a part it doesn't process: %v", err)
+	}
+
+	normalized, err := zip.OpenReader(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to open output: %v", err)
+	}
+	defer normalized.Close()
+	found := false
+	for _, file := range normalized.File {
+		if file.Name != "word/media/image1.png" {
+			continue
+		}
+		found = true
+		raw, err := file.OpenRaw()
+		if err != nil {
+			t.Fatalf("Failed to open media: %v", err)
+		}
+		content, err := io.ReadAll(raw)
+		if err != nil {
+			t.Fatalf("Failed to read media: %v", err)
+		}
+		if !bytes.Equal(content, media) || file.CRC32 != 1 {
+			t.Errorf("Expected the media to be copied as it was, got %q with CRC %d", content, file.CRC32)
+		}
+	}
+	if !found {
+		t.Errorf("Expected word/media/image1.png in output")
+	}
+
+	t.Logf("\033[32m✓ Normalize copy test passed\033[0m")
+}
+
+func TestProcessDocxCustomSyntax(t *testing.T) {
+	templatePath := "testdata/output/syntax_template.docx"
+	outputPath := "testdata/output/syntax_output.docx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		`<w:p><w:r><w:t xml:space="preserve">Dear ${ NAME }, ref ${ID} and {{NAME}} stay as typed.</w:t></w:r></w:p>` +
+		`</w:body></w:document>`
+
+	if err := writeTemplateWithPart("testdata/template.docx", templatePath, "word/document.xml", document); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	opts := docx.Options{Syntax: docx.Syntax{Left: "${", Right: "}", KeyPattern: `[A-Z_]+`, AllowSpaces: true}}
+	replacements := map[string]string{"${NAME}": "Jane", "${ID}": "42"}
+
+	if err := docx.ProcessDocxMultiWithOptions(templatePath, outputPath, replacements, opts); err != nil {
+		t.Fatalf("ProcessDocxMultiWithOptions failed: %v", err)
+	}
+
+	content, err := readDocxContent(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output content: %v", err)
+	}
+
+	expected := "Dear Jane, ref 42 and {{NAME}} stay as typed."
+	if !strings.Contains(content, expected) {
+		t.Errorf("Expected %q in output:\n%s", expected, content)
+	}
+
+	// An invalid key pattern is reported before any file is written
+	opts.Syntax.KeyPattern = "[A-Z"
+	if err := docx.ProcessDocxMultiWithOptions(templatePath, outputPath, replacements, opts); err == nil {
+		t.Errorf("Expected error for invalid key pattern, got nil")
+	}
+
+	t.Logf("\033[32m✓ Custom syntax test passed\033[0m")
+}
+
+func TestProcessDocxMultipleRecordsCustomSyntaxPattern(t *testing.T) {
+	templatePath := "testdata/template.docx"
+	outputDir := "testdata/output/batch_syntax"
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	records := []map[string]string{
+		{"[[NAME]]": "Alice"},
+		{"[[NAME]]": "Bob"},
+	}
+	opts := docx.Options{Syntax: docx.Syntax{Left: "[[", Right: "]]"}}
+
+	err := docx.ProcessDocxMultipleRecordsWithOptions(templatePath, outputDir, records, "[[NAME]]_[[INDEX]].docx", opts)
+	if err != nil {
+		t.Fatalf("ProcessDocxMultipleRecordsWithOptions failed: %v", err)
+	}
+
+	expectedFiles := []string{"Alice_1.docx", "Bob_2.docx"}
+	for _, filename := range expectedFiles {
+		path := filepath.Join(outputDir, filename)
+		if !fileExists(path) {
+			t.Errorf("Expected output file not created: %s", path)
+		}
+	}
+
+	t.Logf("\033[32m✓ Custom syntax pattern test passed\033[0m")
+}
+
+func TestProcessDocxOverlappingKeywordsDeterministic(t *testing.T) {
+	templatePath := "testdata/output/overlap_template.docx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		`<w:p><w:r><w:t>{{NAME}} | {{NAME_FULL}} | NAME | {{NA</w:t></w:r><w:r><w:t>ME_FULL}} | ACME Corp</w:t></w:r></w:p>` +
+		`</w:body></w:document>`
+
+	if err := writeTemplateWithPart("testdata/template.docx", templatePath, "word/document.xml", document); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	// Overlapping placeholders and un-normalized raw keys, as the library API allows
+	replacements := map[string]string{
+		"{{NAME}}":      "Jane",
+		"{{NAME_FULL}}": "Jane Smith",
+		"NAME":          "name",
+		"ACME":          "Acme",
+		"ACME Corp":     "Acme Corporation",
+		"ME":            "me",
+	}
+
+	expected := `<w:t>Jane | Jane Smith | name | Jane Smith</w:t></w:r><w:r><w:t xml:space="preserve"> | Acme Corporation</w:t>`
+
+	var first string
+	for i := 0; i < 50; i++ {
+		outputPath := fmt.Sprintf("testdata/output/overlap_%d.docx", i)
+		if err := docx.ProcessDocxMulti(templatePath, outputPath, replacements); err != nil {
+			t.Fatalf("ProcessDocxMulti failed: %v", err)
+		}
+
+		content, err := readDocxContent(outputPath)
+		if err != nil {
+			t.Fatalf("Failed to read output content: %v", err)
+		}
+
+		if i == 0 {
+			first = content
+			if !strings.Contains(content, expected) {
+				t.Fatalf("Expected longest matches %s in output:\n%s", expected, content)
+			}
+			continue
+		}
+		if content != first {
+			t.Fatalf("Run %d produced different output than the first run", i)
+		}
+	}
+
+	t.Logf("\033[32m✓ Deterministic overlapping keywords test passed\033[0m")
+}
+
+func TestProcessDocxFilters(t *testing.T) {
+	templatePath := "testdata/output/filters_template.docx"
+	outputPath := "testdata/output/filters_output.docx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	// Word turns straight quotes into typographic ones while typing, both must work
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		`<w:p><w:r><w:t>{{NAME | upper}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{AMOUNT | currency:"EUR"}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{AMOUNT|number:2}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{DUE | date:“02 Jan 2006”}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{NOTE | default:"N/A"}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{CITY | trim | title}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{DESCRIPTION | truncate:10}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{PHONE | replace:"-"," "}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{NAME | initials}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{NAME | shout}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{CITY | </w:t></w:r><w:r><w:t>lower}}</w:t></w:r></w:p>` +
+		`</w:body></w:document>`
+
+	if err := writeTemplateWithPart("testdata/template.docx", templatePath, "word/document.xml", document); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	replacements := map[string]string{
+		"{{NAME}}":        "jane doe",
+		"{{AMOUNT}}":      "1234.5",
+		"{{DUE}}":         "2025-03-01",
+		"{{CITY}}":        "  new york ",
+		"{{DESCRIPTION}}": "A very long description",
+		"{{PHONE}}":       "555-123-4567",
+	}
+	opts := docx.Options{Filters: docx.FuncMap{
+		"initials": func(value string, args ...string) (string, error) {
+			var initials strings.Builder
+			for _, word := range strings.Fields(value) {
+				initials.WriteString(strings.ToUpper(word[:1]) + ".")
+			}
+			return initials.String(), nil
+		},
+	}}
+
+	if err := docx.ProcessDocxMultiWithOptions(templatePath, outputPath, replacements, opts); err != nil {
+		t.Fatalf("ProcessDocxMultiWithOptions failed: %v", err)
+	}
+
+	content, err := readDocxContent(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output content: %v", err)
+	}
+
+	expected := []string{
+		"<w:t>JANE DOE</w:t>",
+		"<w:t>€1,234.50</w:t>",
+		"<w:t>1,234.50</w:t>",
+		"<w:t>01 Mar 2025</w:t>",
+		"<w:t>N/A</w:t>",
+		"<w:t>New York</w:t>",
+		"<w:t>A very lo…</w:t>",
+		"<w:t>555 123 4567</w:t>",
+		"<w:t>J.D.</w:t>",
+		// Unknown filters leave the placeholder untouched
+		"<w:t>{{NAME | shout}}</w:t>",
+		`<w:t xml:space="preserve">  new york </w:t>`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(content, e) {
+			t.Errorf("Expected %s in output:\n%s", e, content)
+		}
+	}
+
+	// Custom filters must have a usable name and an implementation
+	opts.Filters["bad name"] = opts.Filters["initials"]
+	if err := docx.ProcessDocxMultiWithOptions(templatePath, outputPath, replacements, opts); err == nil {
+		t.Errorf("Expected error for invalid filter name, got nil")
+	}
+
+	t.Logf("\033[32m✓ Filters test passed\033[0m")
+}
+
+func TestProcessDocxMultipleRecordsFilterPattern(t *testing.T) {
+	templatePath := "testdata/template.docx"
+	outputDir := "testdata/output/batch_filters"
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	records := []map[string]string{
+		{"{{NAME}}": "alice", "{{DATE}}": "2025-03-01"},
+		{"{{NAME}}": "bob", "{{DATE}}": "2025-04-15"},
+	}
+
+	err := docx.ProcessDocxMultipleRecords(templatePath, outputDir, records, `{{NAME | upper}}_{{DATE | date:"20060102"}}.docx`)
+	if err != nil {
+		t.Fatalf("ProcessDocxMultipleRecords failed: %v", err)
+	}
+
+	for _, name := range []string{"ALICE_20250301.docx", "BOB_20250415.docx"} {
+		if _, err := os.Stat(filepath.Join(outputDir, name)); err != nil {
+			t.Errorf("Expected output file %s: %v", name, err)
+		}
+	}
+
+	// Unknown filters are reported up front
+	err = docx.ProcessDocxMultipleRecords(templatePath, outputDir, records, "{{NAME | shout}}.docx")
+	if err == nil {
+		t.Errorf("Expected error for unknown filter in pattern, got nil")
+	}
+
+	t.Logf("\033[32m✓ Filter pattern test passed\033[0m")
+}
+
+func TestProcessDocxLocale(t *testing.T) {
+	templatePath := "testdata/output/locale_template.docx"
+	outputPath := "testdata/output/locale_output.docx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		`<w:p><w:r><w:t>{{TOTAL | currency}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{TOTAL | currency:"USD"}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{TOTAL | number:1}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{DUE | date}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{DUE | date:"Monday, 2 January 2006"}}</w:t></w:r></w:p>` +
+		`</w:body></w:document>`
+
+	if err := writeTemplateWithPart("testdata/template.docx", templatePath, "word/document.xml", document); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	replacements := map[string]string{"{{TOTAL}}": "1234.5", "{{DUE}}": "2025-03-01"}
+
+	tests := []struct {
+		locale   string
+		expected []string
+	}{
+		{"de-DE", []string{"1.234,50 €", "1.234,50 $", "1.234,5", "01.03.2025", "Samstag, 1 März 2025"}},
+		{"fr_fr", []string{"1\u202f234,50 €", "1\u202f234,5", "01/03/2025", "samedi, 1 mars 2025"}},
+		{"en-GB", []string{"£1,234.50", "$1,234.50", "01/03/2025", "Saturday, 1 March 2025"}},
+		{"", []string{"$1,234.50", "1,234.5", "2025-03-01"}},
+	}
+
+	for _, tt := range tests {
+		opts := docx.Options{Locale: tt.locale}
+		if err := docx.ProcessDocxMultiWithOptions(templatePath, outputPath, replacements, opts); err != nil {
+			t.Fatalf("ProcessDocxMultiWithOptions failed for locale %q: %v", tt.locale, err)
+		}
+
+		content, err := readDocxContent(outputPath)
+		if err != nil {
+			t.Fatalf("Failed to read output content: %v", err)
+		}
+
+		for _, e := range tt.expected {
+			if !strings.Contains(content, "<w:t>"+e+"</w:t>") {
+				t.Errorf("Locale %q: expected %q in output:\n%s", tt.locale, e, content)
+			}
+		}
+	}
+
+	if err := docx.ProcessDocxMultiWithOptions(templatePath, outputPath, replacements, docx.Options{Locale: "xx-YY"}); err == nil {
+		t.Errorf("Expected error for unknown locale, got nil")
+	}
+
+	t.Logf("\033[32m✓ Locale test passed\033[0m")
+}
+
+func TestProcessDocxMultipleRecordsLocaleField(t *testing.T) {
+	templatePath := "testdata/output/locale_field_template.docx"
+	outputDir := "testdata/output/batch_locale"
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		`<w:p><w:r><w:t>{{TOTAL | currency:"EUR"}}</w:t></w:r></w:p>` +
+		`</w:body></w:document>`
+
+	if err := writeTemplateWithPart("testdata/template.docx", templatePath, "word/document.xml", document); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	records := []map[string]string{
+		{"{{NAME}}": "berlin", "{{LANG}}": "de-DE", "{{TOTAL}}": "1234.5"},
+		{"{{NAME}}": "dublin", "{{LANG}}": "", "{{TOTAL}}": "1234.5"},
+	}
+	opts := docx.Options{Locale: "en-IE", LocaleField: "LANG"}
+
+	err := docx.ProcessDocxMultipleRecordsWithOptions(templatePath, outputDir, records, `{{NAME}}_{{TOTAL | number:2}}.docx`, opts)
+	if err != nil {
+		t.Fatalf("ProcessDocxMultipleRecordsWithOptions failed: %v", err)
+	}
+
+	expected := map[string]string{
+		"berlin_1.234,50.docx": "1.234,50 €",
+		"dublin_1,234.50.docx": "€1,234.50",
+	}
+	for name, amount := range expected {
+		content, err := readDocxContent(filepath.Join(outputDir, name))
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", name, err)
+		}
+		if !strings.Contains(content, "<w:t>"+amount+"</w:t>") {
+			t.Errorf("Expected %q in %s:\n%s", amount, name, content)
+		}
+	}
+
+	t.Logf("\033[32m✓ Locale field test passed\033[0m")
+}
+
+func TestProcessDocxData(t *testing.T) {
+	templatePath := "testdata/output/data_template.docx"
+	outputPath := "testdata/output/data_output.docx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		`<w:p><w:r><w:t>{{customer.name}} lives in {{customer.address.city}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{items[0].name}} x{{items[0].qty}}, {{items[1].name}} at {{items[1].price | currency}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{paid}}/{{total}}/{{big}}/{{note}}/{{tags[1]}}</w:t></w:r></w:p>` +
+		`</w:body></w:document>`
+
+	if err := writeTemplateWithPart("testdata/template.docx", templatePath, "word/document.xml", document); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	// Values as decoded from JSON, mixed with values built in Go
+	var data docx.Data
+	err := json.Unmarshal([]byte(`{
+		"customer": {"name": "Jane", "address": {"city": "Lyon"}},
+		"items": [{"name": "Widget", "qty": 3}, {"name": "Gadget", "price": 19.9}],
+		"paid": true,
+		"big": 1e21,
+		"note": null
+	}`), &data)
+	if err != nil {
+		t.Fatalf("Failed to decode data: %v", err)
+	}
+	data["{{total}}"] = 1234.5
+	data["tags"] = []string{"new", "vip"}
+
+	if err := docx.ProcessDocxData(templatePath, outputPath, data, docx.Options{}); err != nil {
+		t.Fatalf("ProcessDocxData failed: %v", err)
+	}
+
+	content, err := readDocxContent(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output content: %v", err)
+	}
+
+	expected := []string{
+		"Jane lives in Lyon",
+		"Widget x3, Gadget at $19.90",
+		"true/1234.5/1000000000000000000000//vip",
+	}
+	for _, e := range expected {
+		if !strings.Contains(content, e) {
+			t.Errorf("Expected %q in output:\n%s", e, content)
+		}
+	}
+
+	t.Logf("\033[32m✓ Data test passed\033[0m")
+}
+
+func TestProcessDocxEachLoop(t *testing.T) {
+	templatePath := "testdata/output/each_template.docx"
+	outputPath := "testdata/output/each_output.docx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	numPr := `<w:pPr><w:numPr><w:ilvl w:val="0"/><w:numId w:val="3"/></w:numPr></w:pPr>`
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:w14="http://schemas.microsoft.com/office/word/2010/wordml"><w:body>` +
+		`<w:p><w:r><w:t>Deliverables for {{client}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{#each </w:t></w:r><w:r><w:t>deliverables}}</w:t></w:r></w:p>` +
+		`<w:p w14:paraId="1A2B3C4D"><w:bookmarkStart w:id="0" w:name="item"/><w:r><w:t>{{@index}}. {{title | upper}} ({{@first}}/{{@last}})</w:t></w:r><w:bookmarkEnd w:id="0"/></w:p>` +
+		`<w:p><w:r><w:t>{{description}} for {{client}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{ #each tasks }}</w:t></w:r></w:p>` +
+		`<w:p>` + numPr + `<w:r><w:t>{{this}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{/each}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{/each}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{#each missing}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>never shown</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{/each}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>End</w:t></w:r></w:p>` +
+		`</w:body></w:document>`
+
+	if err := writeTemplateWithPart("testdata/template.docx", templatePath, "word/document.xml", document); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	data := docx.Data{
+		"client": "Acme",
+		"deliverables": []map[string]any{
+			{"title": "Design", "description": "Wireframes", "tasks": []string{"Sketch", "Review"}},
+			{"title": "Build", "description": "Implementation", "tasks": []string{"Code"}},
+		},
+	}
+
+	if err := docx.ProcessDocxData(templatePath, outputPath, data, docx.Options{}); err != nil {
+		t.Fatalf("ProcessDocxData failed: %v", err)
+	}
+
+	content, err := readDocxContent(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output content: %v", err)
+	}
+
+	text := extractParagraphTexts(content)
+	expected := []string{
+		"Deliverables for Acme",
+		"0. DESIGN (true/false)",
+		"Wireframes for Acme",
+		"Sketch",
+		"Review",
+		"1. BUILD (false/true)",
+		"Implementation for Acme",
+		"Code",
+		"End",
+	}
+	if strings.Join(text, "|") != strings.Join(expected, "|") {
+		t.Errorf("Unexpected paragraphs:\n got: %q\nwant: %q", text, expected)
+	}
+
+	if count := strings.Count(content, numPr); count != 3 {
+		t.Errorf("Expected numbering on 3 list paragraphs, found %d", count)
+	}
+	if count := strings.Count(content, `w14:paraId="1A2B3C4D"`); count != 1 {
+		t.Errorf("Expected paragraph ID to stay unique, found %d copies", count)
+	}
+	if count := strings.Count(content, "<w:bookmarkStart"); count != 1 {
+		t.Errorf("Expected bookmark to stay unique, found %d copies", count)
+	}
+	if err := checkWellFormedXML(content); err != nil {
+		t.Errorf("Output is not well-formed: %v", err)
+	}
+
+	t.Logf("\033[32m✓ Each loop test passed\033[0m")
+}
+
+func TestProcessDocxEachLoopEmptyCell(t *testing.T) {
+	templatePath := "testdata/output/each_cell_template.docx"
+	outputPath := "testdata/output/each_cell_output.docx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	loop := func(path string) string {
+		return `<w:p><w:r><w:t>{{#each ` + path + `}}</w:t></w:r></w:p><w:p><w:r><w:t>{{this}}</w:t></w:r></w:p><w:p><w:r><w:t>{{/each}}</w:t></w:r></w:p>`
+	}
+	tcPr := `<w:tcPr><w:tcW w:w="2000" w:type="dxa"/></w:tcPr>`
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		`<w:tbl><w:tr><w:tc>` + tcPr + loop("missing") + `</w:tc><w:tc>` + loop("empty") + `</w:tc><w:tc>` + loop("items") + `</w:tc></w:tr></w:tbl>` +
+		`<w:sectPr/></w:body></w:document>`
+
+	if err := writeTemplateWithPart("testdata/template.docx", templatePath, "word/document.xml", document); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	data := docx.Data{"empty": []string{}, "items": []string{"One"}}
+	if err := docx.ProcessDocxData(templatePath, outputPath, data, docx.Options{}); err != nil {
+		t.Fatalf("ProcessDocxData failed: %v", err)
+	}
+
+	content, err := readDocxContent(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output content: %v", err)
+	}
+	expected := `<w:tr><w:tc>` + tcPr + `<w:p/></w:tc><w:tc><w:p/></w:tc><w:tc><w:p><w:r><w:t>One</w:t></w:r></w:p></w:tc></w:tr>`
+	if !strings.Contains(content, expected) {
+		t.Errorf("Expected %s in output:\n%s", expected, content)
+	}
+	if err := checkWellFormedXML(content); err != nil {
+		t.Errorf("Output is not well-formed: %v", err)
+	}
+
+	t.Logf("\033[32m✓ Each loop empty cell test passed\033[0m")
+}
+
+func TestProcessDocxRichText(t *testing.T) {
+	templatePath := "testdata/output/rich_template.docx"
+	outputPath := "testdata/output/rich_output.docx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		`<w:p><w:pPr><w:jc w:val="both"/></w:pPr><w:r><w:rPr><w:sz w:val="22"/></w:rPr><w:t>{{NOTES | markdown}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:rPr><w:i/></w:rPr><w:t xml:space="preserve">Summary: {{SUMMARY | markdown}} (end)</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{BODY | html}}</w:t></w:r></w:p>` +
+		`</w:body></w:document>`
+
+	if err := writeTemplateWithPart("testdata/template.docx", templatePath, "word/document.xml", document); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	replacements := map[string]string{
+		"{{NOTES}}":   "Intro with **bold** and *italic* text.\n\n- First [link](https://example.com/a?x=1&y=2)\n- Second\n  - Nested\n\n1. One\n2. Two",
+		"{{SUMMARY}}": "**Done** on time\nNext: [docs](https://example.com/docs)",
+		"{{BODY}}":    `<p>Hello <u>there</u> &amp; <a href="javascript:alert(1)">bye</a></p><ol><li>Alpha</li><li><em>Beta</em></li></ol>`,
+	}
+	if err := docx.ProcessDocxMulti(templatePath, outputPath, replacements); err != nil {
+		t.Fatalf("ProcessDocxMulti failed: %v", err)
+	}
+
+	content, err := readDocxContent(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output content: %v", err)
+	}
+
+	text := extractParagraphTexts(content)
+	expected := []string{
+		"Intro with bold and italic text.",
+		"First link",
+		"Second",
+		"Nested",
+		"One",
+		"Two",
+		"Summary: Done on timeNext: docs (end)",
+		"Hello there &amp; bye",
+		"Alpha",
+		"Beta",
+	}
+	if strings.Join(text, "|") != strings.Join(expected, "|") {
+		t.Errorf("Unexpected paragraphs:\n got: %q\nwant: %q", text, expected)
+	}
+
+	for _, e := range []string{
+		// Formatting is added to the run properties of the placeholder
+		`<w:r><w:rPr><w:b/><w:bCs/><w:sz w:val="22"/></w:rPr><w:t>bold</w:t></w:r>`,
+		`<w:r><w:rPr><w:i/><w:iCs/><w:sz w:val="22"/></w:rPr><w:t>italic</w:t></w:r>`,
+		// List items keep the paragraph properties and get numbering
+		`<w:pPr><w:numPr><w:ilvl w:val="1"/>`,
+		`</w:numPr><w:jc w:val="both"/></w:pPr>`,
+		// Inline values split the run and keep its formatting
+		`<w:t xml:space="preserve">Summary: </w:t></w:r><w:r><w:rPr><w:b/><w:bCs/><w:i/></w:rPr><w:t>Done</w:t></w:r>`,
+		`<w:r><w:rPr><w:i/></w:rPr><w:br/></w:r>`,
+		`<w:r><w:rPr><w:i/></w:rPr><w:t xml:space="preserve"> (end)</w:t></w:r>`,
+		`<w:r><w:rPr><w:u w:val="single"/></w:rPr><w:t>there</w:t></w:r>`,
+		`<w:hyperlink r:id="rId6" w:history="1"><w:r><w:rPr><w:color w:val="0563C1"/><w:sz w:val="22"/><w:u w:val="single"/></w:rPr><w:t>link</w:t></w:r></w:hyperlink>`,
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`,
+	} {
+		if !strings.Contains(content, e) {
+			t.Errorf("Expected %s in output:\n%s", e, content)
+		}
+	}
+	if strings.Contains(content, "javascript") {
+		t.Errorf("Unsafe link should be written as text")
+	}
+	if err := checkWellFormedXML(content); err != nil {
+		t.Errorf("Output is not well-formed: %v", err)
+	}
+
+	rels, err := readZipPart(outputPath, "word/_rels/document.xml.rels")
+	if err != nil {
+		t.Fatalf("Failed to read relationships: %v", err)
+	}
+	for _, e := range []string{
+		`<Relationship Id="rId6" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="https://example.com/a?x=1&amp;y=2" TargetMode="External"/>`,
+		`Target="https://example.com/docs" TargetMode="External"`,
+		`Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/numbering" Target="numbering.xml"`,
+	} {
+		if !strings.Contains(rels, e) {
+			t.Errorf("Expected %s in relationships:\n%s", e, rels)
+		}
+	}
+
+	numbering, err := readZipPart(outputPath, "word/numbering.xml")
+	if err != nil {
+		t.Fatalf("Failed to read numbering: %v", err)
+	}
+	if strings.Count(numbering, "<w:abstractNum ") != 2 || strings.Count(numbering, "<w:num ") != 3 {
+		t.Errorf("Expected a bullet and a decimal definition used by 3 lists:\n%s", numbering)
+	}
+	if err := checkWellFormedXML(numbering); err != nil {
+		t.Errorf("Numbering is not well-formed: %v", err)
+	}
+
+	contentTypes, err := readZipPart(outputPath, "[Content_Types].xml")
+	if err != nil {
+		t.Fatalf("Failed to read content types: %v", err)
+	}
+	if !strings.Contains(contentTypes, `PartName="/word/numbering.xml"`) {
+		t.Errorf("Expected numbering content type:\n%s", contentTypes)
+	}
+
+	t.Logf("\033[32m✓ Rich text test passed\033[0m")
+}
+
+func TestProcessDocxHTMLDropsScripts(t *testing.T) {
+	templatePath := "testdata/output/html_script_template.docx"
+	outputPath := "testdata/output/html_script_output.docx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		`<w:p><w:r><w:t>{{BODY | html}}</w:t></w:r></w:p>` +
+		`</w:body></w:document>`
+
+	if err := writeTemplateWithPart("testdata/template.docx", templatePath, "word/document.xml", document); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	// Unsupported elements are unwrapped, but script and style content is never text
+	replacements := map[string]string{
+		"{{BODY}}": `<p>Before<script type="text/javascript">if (a < b) alert("x")</script> <STYLE>p { color: red }</Style>after</p>` +
+			`<span>Kept</span><script>never closed`,
+	}
+	if err := docx.ProcessDocxMulti(templatePath, outputPath, replacements); err != nil {
+		t.Fatalf("ProcessDocxMulti failed: %v", err)
+	}
+
+	content, err := readDocxContent(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output content: %v", err)
+	}
+	text := extractParagraphTexts(content)
+	expected := []string{"Before after", "Kept"}
+	if strings.Join(text, "|") != strings.Join(expected, "|") {
+		t.Errorf("Unexpected paragraphs:\n got: %q\nwant: %q", text, expected)
+	}
+
+	t.Logf("\033[32m✓ HTML script test passed\033[0m")
+}
+
+func TestProcessDocxLinks(t *testing.T) {
+	documentTemplate := "testdata/output/links_document.docx"
+	templatePath := "testdata/output/links_template.docx"
+	outputPath := "testdata/output/links_output.docx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><w:body>` +
+		`<w:p><w:r><w:rPr><w:b/></w:rPr><w:t xml:space="preserve">Sign in at {{PORTAL | link:"the customer portal"}}.</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{EMAIL | link}}</w:t></w:r></w:p>` +
+		`<w:p><w:hyperlink r:id="rId9"><w:r><w:t>Your invoices</w:t></w:r></w:hyperlink></w:p>` +
+		`</w:body></w:document>`
+	rels := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+		`<Relationship Id="rId9" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="https://billing.example.com/%7B%7BCUSTOMER_ID%7D%7D/invoices?ref={{REF}}" TargetMode="External"/>` +
+		`</Relationships>`
+
+	if err := writeTemplateWithPart("testdata/template.docx", documentTemplate, "word/document.xml", document); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+	if err := writeTemplateWithPart(documentTemplate, templatePath, "word/_rels/document.xml.rels", rels); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	data := docx.Data{
+		"PORTAL":      "https://portal.example.com/acme",
+		"EMAIL":       docx.Link{URL: "mailto:billing@acme.example"},
+		"CUSTOMER_ID": 4711,
+		"REF":         "a&b",
+	}
+	if err := docx.ProcessDocxData(templatePath, outputPath, data, docx.Options{}); err != nil {
+		t.Fatalf("ProcessDocxData failed: %v", err)
+	}
+
+	content, err := readDocxContent(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output content: %v", err)
+	}
+
+	for _, e := range []string{
+		`<w:t xml:space="preserve">Sign in at </w:t></w:r><w:hyperlink r:id="rId10" w:history="1"><w:r><w:rPr><w:b/><w:color w:val="0563C1"/><w:u w:val="single"/></w:rPr><w:t>the customer portal</w:t></w:r></w:hyperlink><w:r><w:rPr><w:b/></w:rPr><w:t>.</w:t></w:r>`,
+		`<w:hyperlink r:id="rId11" w:history="1"><w:r><w:rPr><w:color w:val="0563C1"/><w:u w:val="single"/></w:rPr><w:t>billing@acme.example</w:t></w:r></w:hyperlink>`,
+	} {
+		if !strings.Contains(content, e) {
+			t.Errorf("Expected %s in output:\n%s", e, content)
+		}
+	}
+	if err := checkWellFormedXML(content); err != nil {
+		t.Errorf("Output is not well-formed: %v", err)
+	}
+
+	outputRels, err := readZipPart(outputPath, "word/_rels/document.xml.rels")
+	if err != nil {
+		t.Fatalf("Failed to read relationships: %v", err)
+	}
+	for _, e := range []string{
+		`Target="https://billing.example.com/4711/invoices?ref=a%26b" TargetMode="External"`,
+		`<Relationship Id="rId10" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="https://portal.example.com/acme" TargetMode="External"/>`,
+		`<Relationship Id="rId11" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="mailto:billing@acme.example" TargetMode="External"/>`,
+	} {
+		if !strings.Contains(outputRels, e) {
+			t.Errorf("Expected %s in relationships:\n%s", e, outputRels)
+		}
+	}
+
+	t.Logf("\033[32m✓ Links test passed\033[0m")
+}
+
+func TestProcessDocxLinkTargetEscaping(t *testing.T) {
+	templatePath := "testdata/output/link_targets_template.docx"
+	outputPath := "testdata/output/link_targets_output.docx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	hyperlink := func(id, target string) string {
+		return `<Relationship Id="` + id + `" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="` + target + `" TargetMode="External"/>`
+	}
+	rels := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+		hyperlink("rId9", "https://example.com/files/{{NAME}}?q={{NAME}}&amp;page=2#{{NAME}}") +
+		hyperlink("rId10", "mailto:{{USER}}@example.com?subject={{NAME}}") +
+		hyperlink("rId11", "{{URL}}") +
+		hyperlink("rId12", "https://{{TENANT}}.example.com/") +
+		`</Relationships>`
+
+	if err := writeTemplateWithPart("testdata/template.docx", templatePath, "word/_rels/document.xml.rels", rels); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	data := docx.Data{
+		"NAME":   "Q1 R&D #2",
+		"USER":   "jane doe",
+		"URL":    "https://example.com/a b?x=1&y=2",
+		"TENANT": "acme",
+	}
+	if err := docx.ProcessDocxData(templatePath, outputPath, data, docx.Options{}); err != nil {
+		t.Fatalf("ProcessDocxData failed: %v", err)
+	}
+
+	outputRels, err := readZipPart(outputPath, "word/_rels/document.xml.rels")
+	if err != nil {
+		t.Fatalf("Failed to read relationships: %v", err)
+	}
+	for _, e := range []string{
+		hyperlink("rId9", "https://example.com/files/Q1%20R&amp;D%20%232?q=Q1+R%26D+%232&amp;page=2#Q1%20R&amp;D%20%232"),
+		hyperlink("rId10", "mailto:jane%20doe@example.com?subject=Q1%20R%26D%20%232"),
+		hyperlink("rId11", "https://example.com/a b?x=1&amp;y=2"),
+		hyperlink("rId12", "https://acme.example.com/"),
+	} {
+		if !strings.Contains(outputRels, e) {
+			t.Errorf("Expected %s in relationships:\n%s", e, outputRels)
+		}
+	}
+
+	t.Logf("\033[32m✓ Link target escaping test passed\033[0m")
+}
+
+func TestProcessDocxTables(t *testing.T) {
+	documentTemplate := "testdata/output/tables_document.docx"
+	templatePath := "testdata/output/tables_template.docx"
+	outputPath := "testdata/output/tables_output.docx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		`<w:p><w:r><w:t>Summary</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{SUMMARY | table}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{items | table:"columns=name,qty", "header=Product,Qty", "widths=3,1", "banded"}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t xml:space="preserve">Inline: {{SUMMARY | table}}</w:t></w:r></w:p>` +
+		`<w:sectPr><w:pgSz w:w="11906" w:h="16838"/><w:pgMar w:top="851" w:right="1134" w:bottom="851" w:left="851"/></w:sectPr>` +
+		`</w:body></w:document>`
+	styles, err := readZipPart("testdata/template.docx", "word/styles.xml")
+	if err != nil {
+		t.Fatalf("Failed to read styles: %v", err)
+	}
+	styles = strings.Replace(styles, "</w:styles>",
+		`<w:style w:type="table" w:styleId="TableGrid"><w:name w:val="Table Grid"/><w:basedOn w:val="TableNormal"/></w:style></w:styles>`, 1)
+
+	if err := writeTemplateWithPart("testdata/template.docx", documentTemplate, "word/document.xml", document); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+	if err := writeTemplateWithPart(documentTemplate, templatePath, "word/styles.xml", styles); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	data := docx.Data{
+		"SUMMARY": docx.TableFromRows([][]string{{"Quarter", "Revenue"}, {"Q1", "1,200"}, {"Q2", "1,450"}}),
+		"items": []any{
+			map[string]any{"name": "Widget", "qty": 3, "sku": "W-1"},
+			map[string]any{"name": "Gadget & Co", "qty": 1, "sku": "G-7"},
+		},
+	}
+	if err := docx.ProcessDocxData(templatePath, outputPath, data, docx.Options{}); err != nil {
+		t.Fatalf("ProcessDocxData failed: %v", err)
+	}
+
+	content, err := readDocxContent(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output content: %v", err)
+	}
+
+	for _, e := range []string{
+		`<w:tblStyle w:val="TableGrid"/>`,
+		`<w:trPr><w:tblHeader/></w:trPr>`,
+		`<w:t>Revenue</w:t>`,
+		`<w:t>Gadget &amp; Co</w:t>`,
+		`<w:gridCol w:w="7440"/><w:gridCol w:w="2480"/>`,
+		`<w:t xml:space="preserve">Inline: </w:t></w:r><w:r><w:t>Quarter</w:t></w:r><w:r><w:tab/></w:r>`,
+	} {
+		if !strings.Contains(content, e) {
+			t.Errorf("Expected %s in output:\n%s", e, content)
+		}
+	}
+	if count := strings.Count(content, "<w:tbl>"); count != 2 {
+		t.Errorf("Expected 2 tables, got %d", count)
+	}
+	if strings.Contains(content, "W-1") || strings.Contains(content, "{{") {
+		t.Errorf("Expected only the selected columns and no placeholders:\n%s", content)
+	}
+	if err := checkWellFormedXML(content); err != nil {
+		t.Errorf("Output is not well-formed: %v", err)
+	}
+
+	t.Logf("\033[32m✓ Tables test passed\033[0m")
+}
+
+func TestProcessDocxInclude(t *testing.T) {
+	templatePath := "testdata/output/include_template.docx"
+	clausePath := "testdata/output/clauses/liability.docx"
+	outputPath := "testdata/output/include_output.docx"
+
+	if err := os.MkdirAll("testdata/output/clauses", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	namespaces := `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+	host := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:document ` + namespaces + `><w:body>` +
+		`<w:p><w:bookmarkStart w:id="0" w:name="intro"/><w:r><w:t>Agreement with {{CLIENT}}</w:t></w:r><w:bookmarkEnd w:id="0"/></w:p>` +
+		`<w:p><w:r><w:t>{{include:clauses/liability.docx}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>Signed</w:t></w:r></w:p>` +
+		`<w:sectPr><w:pgSz w:w="11906" w:h="16838"/></w:sectPr></w:body></w:document>`
+	clause := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:document ` + namespaces + ` xmlns:wp="http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"><w:body>` +
+		`<w:p><w:pPr><w:pStyle w:val="ClauseHeading"/></w:pPr><w:r><w:t>Liability</w:t></w:r></w:p>` +
+		`<w:p><w:pPr><w:numPr><w:ilvl w:val="0"/><w:numId w:val="3"/></w:numPr></w:pPr><w:bookmarkStart w:id="0" w:name="cap"/><w:r><w:t>Liability of {{CLIENT}} is capped.</w:t></w:r><w:bookmarkEnd w:id="0"/></w:p>` +
+		`<w:p><w:hyperlink r:id="rId10"><w:r><w:t>Terms</w:t></w:r></w:hyperlink><w:r><w:footnoteReference w:id="1"/></w:r></w:p>` +
+		`<w:p><w:r><w:drawing><wp:inline><wp:docPr id="1" name="Seal"/><a:graphic><a:graphicData><a:blip r:embed="rId11"/></a:graphicData></a:graphic></wp:inline></w:drawing></w:r></w:p>` +
+		`<w:sectPr><w:pgSz w:w="12240" w:h="15840"/></w:sectPr></w:body></w:document>`
+
+	hostStyles, err := readZipPart("testdata/template.docx", "word/styles.xml")
+	if err != nil {
+		t.Fatalf("Failed to read styles: %v", err)
+	}
+	clauseStyles := strings.Replace(hostStyles, "</w:styles>",
+		`<w:style w:type="paragraph" w:styleId="ClauseBase"><w:name w:val="Clause Base"/></w:style>`+
+			`<w:style w:type="paragraph" w:styleId="ClauseHeading"><w:name w:val="Clause Heading"/><w:basedOn w:val="ClauseBase"/><w:rPr><w:b/></w:rPr></w:style>`+
+			`<w:style w:type="paragraph" w:styleId="Unused"><w:name w:val="Unused"/></w:style></w:styles>`, 1)
+	types, err := readZipPart("testdata/template.docx", "[Content_Types].xml")
+	if err != nil {
+		t.Fatalf("Failed to read content types: %v", err)
+	}
+	clauseTypes := strings.Replace(types, "</Types>", `<Default Extension="png" ContentType="image/png"/>`+
+		`<Override PartName="/word/numbering.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.numbering+xml"/>`+
+		`<Override PartName="/word/footnotes.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.footnotes+xml"/></Types>`, 1)
+	rels, err := readZipPart("testdata/template.docx", "word/_rels/document.xml.rels")
+	if err != nil {
+		t.Fatalf("Failed to read relationships: %v", err)
+	}
+	relType := "http://schemas.openxmlformats.org/officeDocument/2006/relationships/"
+	clauseRels := strings.Replace(rels, "</Relationships>",
+		`<Relationship Id="rId6" Type="`+relType+`numbering" Target="numbering.xml"/>`+
+			`<Relationship Id="rId7" Type="`+relType+`footnotes" Target="footnotes.xml"/>`+
+			`<Relationship Id="rId10" Type="`+relType+`hyperlink" Target="https://acme.example/terms" TargetMode="External"/>`+
+			`<Relationship Id="rId11" Type="`+relType+`image" Target="media/image1.png"/></Relationships>`, 1)
+	numbering := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?><w:numbering xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">` +
+		`<w:abstractNum w:abstractNumId="5"><w:nsid w:val="1A2B3C4D"/><w:lvl w:ilvl="0"><w:numFmt w:val="decimal"/><w:lvlText w:val="%1."/></w:lvl></w:abstractNum>` +
+		`<w:num w:numId="3"><w:abstractNumId w:val="5"/></w:num></w:numbering>`
+	footnotes := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?><w:footnotes ` + namespaces + `>` +
+		`<w:footnote w:type="separator" w:id="-1"><w:p><w:r><w:separator/></w:r></w:p></w:footnote>` +
+		`<w:footnote w:type="continuationSeparator" w:id="0"><w:p><w:r><w:continuationSeparator/></w:r></w:p></w:footnote>` +
+		`<w:footnote w:id="1"><w:p><w:r><w:t>Subject to applicable law.</w:t></w:r></w:p></w:footnote></w:footnotes>`
+
+	if err := writeTemplateWithParts("testdata/template.docx", templatePath, map[string]string{
+		"word/document.xml":     host,
+		"word/media/image1.png": "host image",
+	}); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+	if err := writeTemplateWithParts("testdata/template.docx", clausePath, map[string]string{
+		"word/document.xml":            clause,
+		"word/styles.xml":              clauseStyles,
+		"word/numbering.xml":           numbering,
+		"word/footnotes.xml":           footnotes,
+		"word/media/image1.png":        "clause image",
+		"word/_rels/document.xml.rels": clauseRels,
+		"[Content_Types].xml":          clauseTypes,
+	}); err != nil {
+		t.Fatalf("Failed to create clause: %v", err)
+	}
+
+	replacements := map[string]string{"{{CLIENT}}": "Acme"}
+	if err := docx.ProcessDocxMultiWithOptions(templatePath, outputPath, replacements, docx.Options{}); err != nil {
+		t.Fatalf("ProcessDocxMultiWithOptions failed: %v", err)
+	}
+
+	content, err := readDocxContent(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output content: %v", err)
+	}
+	for _, e := range []string{
+		`<w:t>Agreement with Acme</w:t>`,
+		`<w:pStyle w:val="ClauseHeading"/>`,
+		`<w:numId w:val="1"/></w:numPr></w:pPr><w:bookmarkStart w:id="1" w:name="cap"/><w:r><w:t>Liability of Acme is capped.</w:t></w:r><w:bookmarkEnd w:id="1"/>`,
+		`<w:hyperlink r:id="rId6">`,
+		`<w:footnoteReference w:id="1"/>`,
+		`<wp:docPr id="1" name="Seal"/>`,
+		`<a:blip r:embed="rId7"/>`,
+		`xmlns:wp="http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing"`,
+		`<w:t>Signed</w:t>`,
+	} {
+		if !strings.Contains(content, e) {
+			t.Errorf("Expected %s in output:\n%s", e, content)
+		}
+	}
+	if strings.Contains(content, "include:") || strings.Count(content, "<w:sectPr>") != 1 || strings.Contains(content, "12240") {
+		t.Errorf("Expected the include tag and the clause's section to be gone:\n%s", content)
+	}
+	if err := checkWellFormedXML(content); err != nil {
+		t.Errorf("Output is not well-formed: %v", err)
+	}
+
+	for part, expected := range map[string][]string{
+		"word/styles.xml":    {`w:styleId="ClauseHeading"`, `w:styleId="ClauseBase"`},
+		"word/numbering.xml": {`<w:abstractNum w:abstractNumId="0"><w:lvl w:ilvl="0">`, `<w:num w:numId="1"><w:abstractNumId w:val="0"/></w:num>`},
+		"word/footnotes.xml": {`<w:footnote w:type="separator" w:id="-1">`, `<w:footnote w:id="1"><w:p><w:r><w:t>Subject to applicable law.</w:t>`},
+		"word/_rels/document.xml.rels": {
+			`Target="https://acme.example/terms" TargetMode="External"`,
+			`Id="rId7" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/image" Target="media/image1_2.png"`,
+			`Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/numbering" Target="numbering.xml"`,
+			`Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/footnotes" Target="footnotes.xml"`,
+		},
+		"[Content_Types].xml":     {`<Default Extension="png" ContentType="image/png"/>`, `<Override PartName="/word/footnotes.xml"`},
+		"word/media/image1_2.png": {"clause image"},
+	} {
+		partContent, err := readZipPart(outputPath, part)
+		if err != nil {
+			t.Errorf("Failed to read %s: %v", part, err)
+			continue
+		}
+		for _, e := range expected {
+			if !strings.Contains(partContent, e) {
+				t.Errorf("Expected %s in %s:\n%s", e, part, partContent)
+			}
+		}
+		if part == "word/styles.xml" && (strings.Contains(partContent, `w:styleId="Unused"`) || strings.Count(partContent, `w:styleId="Normal"`) != 1) {
+			t.Errorf("Expected only the missing styles in use to be added")
+		}
+	}
+
+	// A document including itself is an error
+	cyclePath := "testdata/output/clauses/cycle.docx"
+	cycle := strings.Replace(host, "clauses/liability.docx", "cycle.docx", 1)
+	if err := writeTemplateWithPart("testdata/template.docx", cyclePath, "word/document.xml", cycle); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+	err = docx.ProcessDocxMultiWithOptions(cyclePath, outputPath, replacements, docx.Options{})
+	if err == nil || !strings.Contains(err.Error(), "include cycle") {
+		t.Errorf("Expected an include cycle error, got %v", err)
+	}
+
+	t.Logf("\033[32m✓ Include test passed\033[0m")
+}
+
+func TestProcessDocxIncludeAltChunk(t *testing.T) {
+	templatePath := "testdata/output/altchunk_template.docx"
+	outputPath := "testdata/output/altchunk_output.docx"
+
+	if err := os.MkdirAll("testdata/output/clauses", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	host := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		`<w:tbl><w:tr><w:tc><w:p><w:r><w:t>{{include:confidentiality.docx}}</w:t></w:r></w:p></w:tc></w:tr></w:tbl>` +
+		`<w:sectPr/></w:body></w:document>`
+	clause := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		`<w:p><w:r><w:t>{{CLIENT}} keeps this confidential.</w:t></w:r></w:p></w:body></w:document>`
+
+	if err := writeTemplateWithPart("testdata/template.docx", templatePath, "word/document.xml", host); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+	if err := writeTemplateWithPart("testdata/template.docx", "testdata/output/clauses/confidentiality.docx", "word/document.xml", clause); err != nil {
+		t.Fatalf("Failed to create clause: %v", err)
+	}
+
+	opts := docx.Options{IncludeDir: "testdata/output/clauses", IncludeAltChunk: true}
+	if err := docx.ProcessDocxMultiWithOptions(templatePath, outputPath, map[string]string{"{{CLIENT}}": "Acme"}, opts); err != nil {
+		t.Fatalf("ProcessDocxMultiWithOptions failed: %v", err)
+	}
+
+	content, err := readDocxContent(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output content: %v", err)
+	}
+	expected := `<w:tc><w:altChunk r:id="rId6"/><w:p/></w:tc>`
+	if !strings.Contains(content, expected) || !strings.Contains(content, `xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`) {
+		t.Errorf("Expected %s in output:\n%s", expected, content)
+	}
+
+	rels, err := readZipPart(outputPath, "word/_rels/document.xml.rels")
+	if err != nil {
+		t.Fatalf("Failed to read relationships: %v", err)
+	}
+	if !strings.Contains(rels, `Id="rId6" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/aFChunk" Target="include.docx"`) {
+		t.Errorf("Expected altChunk relationship:\n%s", rels)
+	}
+
+	// The embedded document is rendered with the same values
+	chunkPath := "testdata/output/chunk.docx"
+	chunk, err := readZipPart(outputPath, "word/include.docx")
+	if err != nil {
+		t.Fatalf("Failed to read embedded document: %v", err)
+	}
+	if err := os.WriteFile(chunkPath, []byte(chunk), 0644); err != nil {
+		t.Fatalf("Failed to write embedded document: %v", err)
+	}
+	chunkContent, err := readDocxContent(chunkPath)
+	if err != nil {
+		t.Fatalf("Failed to read embedded document: %v", err)
+	}
+	if !strings.Contains(chunkContent, "Acme keeps this confidential.") {
+		t.Errorf("Expected the embedded document to be rendered:\n%s", chunkContent)
+	}
+
+	t.Logf("\033[32m✓ Include altChunk test passed\033[0m")
+}
+
+func TestProcessDocxIncludeOutsideDir(t *testing.T) {
+	templatePath := "testdata/output/outside_template.docx"
+	outputPath := "testdata/output/outside_output.docx"
+
+	if err := os.MkdirAll("testdata/output/clauses", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	outside, err := filepath.Abs("testdata/output/outside.docx")
+	if err != nil {
+		t.Fatalf("Failed to resolve path: %v", err)
+	}
+	clause := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		`<w:p><w:r><w:t>Outside clause.</w:t></w:r></w:p></w:body></w:document>`
+	if err := writeTemplateWithPart("testdata/template.docx", outside, "word/document.xml", clause); err != nil {
+		t.Fatalf("Failed to create clause: %v", err)
+	}
+
+	for _, target := range []string{"../outside.docx", "../clauses/../outside.docx", outside} {
+		host := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+			`<w:p><w:r><w:t>{{include:` + target + `}}</w:t></w:r></w:p><w:sectPr/></w:body></w:document>`
+		if err := writeTemplateWithPart("testdata/template.docx", templatePath, "word/document.xml", host); err != nil {
+			t.Fatalf("Failed to create template: %v", err)
+		}
+
+		opts := docx.Options{IncludeDir: "testdata/output/clauses"}
+		err := docx.ProcessDocxMultiWithOptions(templatePath, outputPath, map[string]string{}, opts)
+		if err == nil || !strings.Contains(err.Error(), "outside the include directory") {
+			t.Errorf("Expected including %s to be rejected, got %v", target, err)
+		}
+
+		opts.IncludeOutsideDir = true
+		if err := docx.ProcessDocxMultiWithOptions(templatePath, outputPath, map[string]string{}, opts); err != nil {
+			t.Fatalf("Including %s with IncludeOutsideDir failed: %v", target, err)
+		}
+		content, err := readDocxContent(outputPath)
+		if err != nil {
+			t.Fatalf("Failed to read output content: %v", err)
+		}
+		if !strings.Contains(content, "Outside clause.") {
+			t.Errorf("Expected %s to be included:\n%s", target, content)
+		}
+	}
+
+	t.Logf("\033[32m✓ Include outside directory test passed\033[0m")
+}
+
+func TestRenderDocxMerged(t *testing.T) {
+	templatePath := "testdata/output/merge_template.docx"
+	outputPath := "testdata/output/merged.docx"
+	breaksPath := "testdata/output/merged_breaks.docx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	namespaces := `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:document ` + namespaces + ` xmlns:wp="http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing"><w:body>` +
+		`<w:p><w:pPr><w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr></w:pPr><w:bookmarkStart w:id="0" w:name="greeting"/><w:r><w:t>Dear {{NAME}},</w:t></w:r><w:bookmarkEnd w:id="0"/></w:p>` +
+		`<w:p><w:commentRangeStart w:id="0"/><w:r><w:t>See the terms.</w:t></w:r><w:commentRangeEnd w:id="0"/><w:r><w:commentReference w:id="0"/></w:r><w:r><w:footnoteReference w:id="1"/></w:r></w:p>` +
+		`<w:p><w:r><w:drawing><wp:inline><wp:docPr id="1" name="Logo"/></wp:inline></w:drawing></w:r></w:p>` +
+		`<w:sectPr><w:headerReference w:type="default" r:id="rId6"/><w:footerReference w:type="default" r:id="rId7"/><w:pgSz w:w="11906" w:h="16838"/></w:sectPr></w:body></w:document>`
+	header := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?><w:hdr ` + namespaces + `><w:p><w:r><w:t>Customer letter</w:t></w:r></w:p></w:hdr>`
+	footer := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?><w:ftr ` + namespaces + `><w:p><w:r><w:t>Acme Ltd</w:t></w:r></w:p></w:ftr>`
+	numbering := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?><w:numbering xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">` +
+		`<w:abstractNum w:abstractNumId="0"><w:nsid w:val="1A2B3C4D"/><w:lvl w:ilvl="0"><w:start w:val="1"/><w:numFmt w:val="decimal"/><w:lvlText w:val="%1."/></w:lvl></w:abstractNum>` +
+		`<w:num w:numId="1"><w:abstractNumId w:val="0"/></w:num></w:numbering>`
+	footnotes := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?><w:footnotes ` + namespaces + `>` +
+		`<w:footnote w:type="separator" w:id="-1"><w:p><w:r><w:separator/></w:r></w:p></w:footnote>` +
+		`<w:footnote w:type="continuationSeparator" w:id="0"><w:p><w:r><w:continuationSeparator/></w:r></w:p></w:footnote>` +
+		`<w:footnote w:id="1"><w:p><w:r><w:t>Terms apply.</w:t></w:r></w:p></w:footnote></w:footnotes>`
+	comments := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?><w:comments ` + namespaces + `>` +
+		`<w:comment w:id="0" w:author="Legal"><w:p><w:r><w:t>Check with legal.</w:t></w:r></w:p></w:comment></w:comments>`
+
+	types, err := readZipPart("testdata/template.docx", "[Content_Types].xml")
+	if err != nil {
+		t.Fatalf("Failed to read content types: %v", err)
+	}
+	wordType := "application/vnd.openxmlformats-officedocument.wordprocessingml."
+	types = strings.Replace(types, "</Types>",
+		`<Override PartName="/word/header1.xml" ContentType="`+wordType+`header+xml"/>`+
+			`<Override PartName="/word/footer1.xml" ContentType="`+wordType+`footer+xml"/>`+
+			`<Override PartName="/word/numbering.xml" ContentType="`+wordType+`numbering+xml"/>`+
+			`<Override PartName="/word/footnotes.xml" ContentType="`+wordType+`footnotes+xml"/>`+
+			`<Override PartName="/word/comments.xml" ContentType="`+wordType+`comments+xml"/></Types>`, 1)
+	rels, err := readZipPart("testdata/template.docx", "word/_rels/document.xml.rels")
+	if err != nil {
+		t.Fatalf("Failed to read relationships: %v", err)
+	}
+	relType := "http://schemas.openxmlformats.org/officeDocument/2006/relationships/"
+	rels = strings.Replace(rels, "</Relationships>",
+		`<Relationship Id="rId6" Type="`+relType+`header" Target="header1.xml"/>`+
+			`<Relationship Id="rId7" Type="`+relType+`footer" Target="footer1.xml"/>`+
+			`<Relationship Id="rId8" Type="`+relType+`numbering" Target="numbering.xml"/>`+
+			`<Relationship Id="rId9" Type="`+relType+`footnotes" Target="footnotes.xml"/>`+
+			`<Relationship Id="rId10" Type="`+relType+`comments" Target="comments.xml"/></Relationships>`, 1)
+
+	if err := writeTemplateWithParts("testdata/template.docx", templatePath, map[string]string{
+		"word/document.xml":            document,
+		"word/header1.xml":             header,
+		"word/footer1.xml":             footer,
+		"word/numbering.xml":           numbering,
+		"word/footnotes.xml":           footnotes,
+		"word/comments.xml":            comments,
+		"word/_rels/document.xml.rels": rels,
+		"[Content_Types].xml":          types,
+	}); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	records := []docx.Data{{"NAME": "Alice"}, {"NAME": "Bob"}, {"NAME": "Carol"}}
+	if err := docx.RenderMerged(templatePath, outputPath, records, docx.Options{}); err != nil {
+		t.Fatalf("RenderMerged failed: %v", err)
+	}
+
+	content, err := readDocxContent(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output content: %v", err)
+	}
+	for _, name := range []string{"Alice", "Bob", "Carol"} {
+		if !strings.Contains(content, "Dear "+name+",") {
+			t.Errorf("Expected the copy for %s in output:\n%s", name, content)
+		}
+	}
+	if count := strings.Count(content, "<w:sectPr>"); count != 3 {
+		t.Errorf("Expected a section per copy, got %d:\n%s", count, content)
+	}
+	for _, expected := range []string{
+		`<w:bookmarkStart w:id="2" w:name="greeting"/>`,
+		`<w:commentReference w:id="2"/>`,
+		`<w:footnoteReference w:id="3"/>`,
+		`<wp:docPr id="3" name="Logo"/>`,
+		`<w:numId w:val="3"/>`,
+	} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("Expected %s in output:\n%s", expected, content)
+		}
+	}
+
+	// Headers and footers that are the same in every copy are shared
+	outputRels, err := readZipPart(outputPath, "word/_rels/document.xml.rels")
+	if err != nil {
+		t.Fatalf("Failed to read relationships: %v", err)
+	}
+	if strings.Count(outputRels, relType+"header") != 1 || strings.Count(outputRels, relType+"footer") != 1 {
+		t.Errorf("Expected a single header and footer relationship:\n%s", outputRels)
+	}
+	if strings.Count(content, `<w:headerReference w:type="default" r:id="rId6"/><w:footerReference w:type="default" r:id="rId7"/>`) != 3 {
+		t.Errorf("Expected all sections to use the same header and footer:\n%s", content)
+	}
+
+	notes, err := readZipPart(outputPath, "word/footnotes.xml")
+	if err != nil || !strings.Contains(notes, `<w:footnote w:id="3"><w:p><w:r><w:t>Terms apply.</w:t>`) {
+		t.Errorf("Expected renumbered footnotes, got %q (%v)", notes, err)
+	}
+	commentsXML, err := readZipPart(outputPath, "word/comments.xml")
+	if err != nil || strings.Count(commentsXML, "<w:comment ") != 3 {
+		t.Errorf("Expected a comment per copy, got %q (%v)", commentsXML, err)
+	}
+
+	// The list definition is shared, each copy's list starting over at 1
+	numberingXML, err := readZipPart(outputPath, "word/numbering.xml")
+	if err != nil {
+		t.Fatalf("Failed to read numbering: %v", err)
+	}
+	if count := strings.Count(numberingXML, "<w:abstractNum "); count != 1 {
+		t.Errorf("Expected a single list definition, got %d:\n%s", count, numberingXML)
+	}
+	expected := `<w:num w:numId="3"><w:abstractNumId w:val="0"/><w:lvlOverride w:ilvl="0"><w:startOverride w:val="1"/></w:lvlOverride></w:num>`
+	if !strings.Contains(numberingXML, expected) {
+		t.Errorf("Expected %s in numbering:\n%s", expected, numberingXML)
+	}
+
+	// With page breaks, all copies share the last section
+	if err := docx.RenderMerged(templatePath, breaksPath, records, docx.Options{MergePageBreaks: true}); err != nil {
+		t.Fatalf("RenderMerged with page breaks failed: %v", err)
+	}
+	content, err = readDocxContent(breaksPath)
+	if err != nil {
+		t.Fatalf("Failed to read output content: %v", err)
+	}
+	if strings.Count(content, "<w:sectPr>") != 1 || strings.Count(content, `<w:br w:type="page"/>`) != 2 {
+		t.Errorf("Expected copies separated by page breaks:\n%s", content)
+	}
+
+	if err := docx.RenderMerged(templatePath, outputPath, nil, docx.Options{}); err == nil {
+		t.Error("Expected an error without records")
+	}
+
+	t.Logf("\033[32m✓ Render merged test passed\033[0m")
+}
+
+func TestConcatDocx(t *testing.T) {
+	coverPath := "testdata/output/cover.docx"
+	annexPath := "testdata/output/annex.docx"
+	outputPath := "testdata/output/bundle.docx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	namespaces := `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+	cover := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:document ` + namespaces + `><w:body>` +
+		`<w:p><w:bookmarkStart w:id="0" w:name="cover"/><w:r><w:t>Cover letter</w:t></w:r><w:bookmarkEnd w:id="0"/></w:p>` +
+		`<w:sectPr><w:pgSz w:w="11906" w:h="16838"/></w:sectPr></w:body></w:document>`
+	annex := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:document ` + namespaces + ` xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"><w:body>` +
+		`<w:p><w:pPr><w:pStyle w:val="AnnexTitle"/></w:pPr><w:bookmarkStart w:id="0" w:name="annex"/><w:r><w:t>Annex A</w:t></w:r><w:bookmarkEnd w:id="0"/></w:p>` +
+		`<w:p><w:r><w:drawing><a:blip r:embed="rId6"/></w:drawing></w:r></w:p>` +
+		`<w:sectPr><w:pgSz w:w="16838" w:h="11906" w:orient="landscape"/></w:sectPr></w:body></w:document>`
+
+	styles, err := readZipPart("testdata/template.docx", "word/styles.xml")
+	if err != nil {
+		t.Fatalf("Failed to read styles: %v", err)
+	}
+	types, err := readZipPart("testdata/template.docx", "[Content_Types].xml")
+	if err != nil {
+		t.Fatalf("Failed to read content types: %v", err)
+	}
+	rels, err := readZipPart("testdata/template.docx", "word/_rels/document.xml.rels")
+	if err != nil {
+		t.Fatalf("Failed to read relationships: %v", err)
+	}
+
+	if err := writeTemplateWithPart("testdata/template.docx", coverPath, "word/document.xml", cover); err != nil {
+		t.Fatalf("Failed to create cover: %v", err)
+	}
+	if err := writeTemplateWithParts("testdata/template.docx", annexPath, map[string]string{
+		"word/document.xml":    annex,
+		"word/media/chart.png": "chart",
+		"word/styles.xml":      strings.Replace(styles, "</w:styles>", `<w:style w:type="paragraph" w:styleId="AnnexTitle"><w:name w:val="Annex Title"/></w:style></w:styles>`, 1),
+		"[Content_Types].xml":  strings.Replace(types, "</Types>", `<Default Extension="png" ContentType="image/png"/></Types>`, 1),
+		"word/_rels/document.xml.rels": strings.Replace(rels, "</Relationships>",
+			`<Relationship Id="rId6" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/image" Target="media/chart.png"/></Relationships>`, 1),
+	}); err != nil {
+		t.Fatalf("Failed to create annex: %v", err)
+	}
+
+	coverFile, err := os.Open(coverPath)
+	if err != nil {
+		t.Fatalf("Failed to open cover: %v", err)
+	}
+	defer coverFile.Close()
+	annexFile, err := os.Open(annexPath)
+	if err != nil {
+		t.Fatalf("Failed to open annex: %v", err)
+	}
+	defer annexFile.Close()
+
+	var buf bytes.Buffer
+	if err := docx.Concat(&buf, coverFile, annexFile); err != nil {
+		t.Fatalf("Concat failed: %v", err)
+	}
+	if err := os.WriteFile(outputPath, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write output: %v", err)
+	}
+
+	content, err := readDocxContent(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output content: %v", err)
+	}
+	expected := `<w:p><w:pPr><w:sectPr><w:pgSz w:w="11906" w:h="16838"/></w:sectPr></w:pPr></w:p>` +
+		`<w:p><w:pPr><w:pStyle w:val="AnnexTitle"/></w:pPr><w:bookmarkStart w:id="1" w:name="annex"/>`
+	if !strings.Contains(content, expected) || !strings.Contains(content, `w:orient="landscape"/></w:sectPr></w:body>`) {
+		t.Errorf("Expected the annex in its own landscape section:\n%s", content)
+	}
+	if !strings.Contains(content, `<a:blip r:embed="rId6"/>`) || !strings.Contains(content, `xmlns:a=`) {
+		t.Errorf("Expected the annex image:\n%s", content)
+	}
+
+	outputRels, err := readZipPart(outputPath, "word/_rels/document.xml.rels")
+	if err != nil || !strings.Contains(outputRels, `Id="rId6" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/image" Target="media/chart.png"`) {
+		t.Errorf("Expected the image relationship, got %q (%v)", outputRels, err)
+	}
+	if image, err := readZipPart(outputPath, "word/media/chart.png"); err != nil || image != "chart" {
+		t.Errorf("Expected the image part, got %q (%v)", image, err)
+	}
+	outputTypes, err := readZipPart(outputPath, "[Content_Types].xml")
+	if err != nil || !strings.Contains(outputTypes, `<Default Extension="png" ContentType="image/png"/>`) {
+		t.Errorf("Expected the png content type, got %q (%v)", outputTypes, err)
+	}
+	outputStyles, err := readZipPart(outputPath, "word/styles.xml")
+	if err != nil || strings.Count(outputStyles, `w:styleId="AnnexTitle"`) != 1 {
+		t.Errorf("Expected the annex style to be added once (%v)", err)
+	}
+
+	if err := docx.Concat(&buf); err == nil {
+		t.Error("Expected an error without documents")
+	}
+
+	t.Logf("\033[32m✓ Concat test passed\033[0m")
+}
+
+func TestSplitDocx(t *testing.T) {
+	inputPath := "testdata/output/report.docx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	namespaces := `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:document ` + namespaces + ` xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"><w:body>` +
+		`<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t>Summary</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>Overview</w:t></w:r><w:r><w:footnoteReference w:id="1"/></w:r></w:p>` +
+		`<w:p><w:pPr><w:sectPr><w:headerReference w:type="default" r:id="rId6"/><w:pgSz w:w="11906" w:h="16838"/></w:sectPr></w:pPr></w:p>` +
+		`<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t>Figures</w:t></w:r></w:p>` +
+		`<w:p><w:pPr><w:pStyle w:val="Heading2"/></w:pPr><w:r><w:t>Sales</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:drawing><a:blip r:embed="rId7"/></w:drawing></w:r><w:r><w:footnoteReference w:id="2"/></w:r></w:p>` +
+		`<w:sectPr><w:pgSz w:w="16838" w:h="11906" w:orient="landscape"/></w:sectPr></w:body></w:document>`
+	header := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?><w:hdr ` + namespaces + `><w:p><w:r><w:t>Report</w:t></w:r></w:p></w:hdr>`
+	footnotes := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?><w:footnotes ` + namespaces + `>` +
+		`<w:footnote w:type="separator" w:id="-1"><w:p><w:r><w:separator/></w:r></w:p></w:footnote>` +
+		`<w:footnote w:id="1"><w:p><w:r><w:t>First note</w:t></w:r></w:p></w:footnote>` +
+		`<w:footnote w:id="2"><w:p><w:r><w:t>Second note</w:t></w:r></w:p></w:footnote></w:footnotes>`
+
+	styles, err := readZipPart("testdata/template.docx", "word/styles.xml")
+	if err != nil {
+		t.Fatalf("Failed to read styles: %v", err)
+	}
+	types, err := readZipPart("testdata/template.docx", "[Content_Types].xml")
+	if err != nil {
+		t.Fatalf("Failed to read content types: %v", err)
+	}
+	rels, err := readZipPart("testdata/template.docx", "word/_rels/document.xml.rels")
+	if err != nil {
+		t.Fatalf("Failed to read relationships: %v", err)
+	}
+	relType := "http://schemas.openxmlformats.org/officeDocument/2006/relationships/"
+	if err := writeTemplateWithParts("testdata/template.docx", inputPath, map[string]string{
+		"word/document.xml":    document,
+		"word/header1.xml":     header,
+		"word/footnotes.xml":   footnotes,
+		"word/media/sales.png": "sales",
+		"word/styles.xml": strings.Replace(styles, "</w:styles>",
+			`<w:style w:type="paragraph" w:styleId="Heading1"><w:name w:val="heading 1"/><w:pPr><w:outlineLvl w:val="0"/></w:pPr></w:style>`+
+				`<w:style w:type="paragraph" w:styleId="Heading2"><w:name w:val="heading 2"/><w:pPr><w:outlineLvl w:val="1"/></w:pPr></w:style></w:styles>`, 1),
+		"[Content_Types].xml": strings.Replace(types, "</Types>", `<Default Extension="png" ContentType="image/png"/>`+
+			`<Override PartName="/word/header1.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.header+xml"/>`+
+			`<Override PartName="/word/footnotes.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.footnotes+xml"/></Types>`, 1),
+		"word/_rels/document.xml.rels": strings.Replace(rels, "</Relationships>",
+			`<Relationship Id="rId6" Type="`+relType+`header" Target="header1.xml"/>`+
+				`<Relationship Id="rId7" Type="`+relType+`image" Target="media/sales.png"/>`+
+				`<Relationship Id="rId8" Type="`+relType+`footnotes" Target="footnotes.xml"/></Relationships>`, 1),
+	}); err != nil {
+		t.Fatalf("Failed to create document: %v", err)
+	}
+
+	for _, by := range []docx.SplitBy{docx.SplitSections, docx.SplitHeadings} {
+		input, err := os.Open(inputPath)
+		if err != nil {
+			t.Fatalf("Failed to open document: %v", err)
+		}
+		parts, err := docx.Split(input, by)
+		input.Close()
+		if err != nil {
+			t.Fatalf("Split failed: %v", err)
+		}
+		if len(parts) != 2 {
+			t.Fatalf("Expected 2 parts, got %d", len(parts))
+		}
+
+		paths := make([]string, len(parts))
+		for i, part := range parts {
+			paths[i] = fmt.Sprintf("testdata/output/part_%d_%d.docx", by, i+1)
+			if err := os.WriteFile(paths[i], part, 0644); err != nil {
+				t.Fatalf("Failed to write part: %v", err)
+			}
+		}
+
+		first, err := readDocxContent(paths[0])
+		if err != nil {
+			t.Fatalf("Failed to read part: %v", err)
+		}
+		expected := `<w:r><w:footnoteReference w:id="1"/></w:r></w:p>` +
+			`<w:sectPr><w:headerReference w:type="default" r:id="rId6"/><w:pgSz w:w="11906" w:h="16838"/></w:sectPr></w:body>`
+		if !strings.Contains(first, expected) || strings.Contains(first, "Figures") {
+			t.Errorf("Expected the first chapter in its portrait section:\n%s", first)
+		}
+		if fileRels, _ := readZipPart(paths[0], "word/_rels/document.xml.rels"); strings.Contains(fileRels, "sales.png") {
+			t.Errorf("Expected the unused image relationship to be removed:\n%s", fileRels)
+		}
+		if image, _ := readZipPart(paths[0], "word/media/sales.png"); image != "" {
+			t.Error("Expected the unused image to be removed")
+		}
+		if notes, _ := readZipPart(paths[0], "word/footnotes.xml"); !strings.Contains(notes, "First note") || strings.Contains(notes, "Second note") || !strings.Contains(notes, "w:separator") {
+			t.Errorf("Expected only the first chapter's footnote:\n%s", notes)
+		}
+
+		second, err := readDocxContent(paths[1])
+		if err != nil {
+			t.Fatalf("Failed to read part: %v", err)
+		}
+		if !strings.Contains(second, "Figures") || !strings.Contains(second, "Sales") || strings.Contains(second, "Summary") ||
+			!strings.Contains(second, `w:orient="landscape"/></w:sectPr></w:body>`) {
+			t.Errorf("Expected the second chapter in its landscape section:\n%s", second)
+		}
+		if header, _ := readZipPart(paths[1], "word/header1.xml"); header != "" {
+			t.Error("Expected the unused header to be removed")
+		}
+		if secondTypes, _ := readZipPart(paths[1], "[Content_Types].xml"); strings.Contains(secondTypes, "header1.xml") {
+			t.Errorf("Expected the header's content type to be removed:\n%s", secondTypes)
+		}
+		if image, _ := readZipPart(paths[1], "word/media/sales.png"); image != "sales" {
+			t.Error("Expected the image to be kept")
+		}
+	}
+
+	t.Logf("\033[32m✓ Split test passed\033[0m")
+}
+
+func TestExportDocx(t *testing.T) {
+	inputPath := "testdata/output/guide.docx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	namespaces := `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:document ` + namespaces + `><w:body>` +
+		`<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t>Getting started</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t xml:space="preserve">Read the </w:t></w:r><w:r><w:rPr><w:b/></w:rPr><w:t>whole</w:t></w:r>` +
+		`<w:r><w:t xml:space="preserve"> guide, </w:t></w:r><w:r><w:rPr><w:i/></w:rPr><w:t>then</w:t></w:r>` +
+		`<w:r><w:t xml:space="preserve"> visit </w:t></w:r><w:hyperlink r:id="rId6"><w:r><w:t>our site</w:t></w:r></w:hyperlink>` +
+		`<w:r><w:rPr><w:b w:val="0"/></w:rPr><w:t>.</w:t></w:r></w:p>` +
+		`<w:p><w:pPr><w:pStyle w:val="Heading2"/></w:pPr><w:r><w:t>Steps</w:t></w:r></w:p>` +
+		`<w:p><w:pPr><w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t>Install</w:t></w:r></w:p>` +
+		`<w:p><w:pPr><w:numPr><w:ilvl w:val="1"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t>Check the version</w:t></w:r></w:p>` +
+		`<w:p><w:pPr><w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t>Run</w:t></w:r></w:p>` +
+		`<w:tbl><w:tr><w:trPr><w:tblHeader/></w:trPr><w:tc><w:p><w:r><w:t>Option</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Default</w:t></w:r></w:p></w:tc></w:tr>` +
+		`<w:tr><w:tc><w:p><w:r><w:t>--format</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>txt</w:t></w:r></w:p></w:tc></w:tr></w:tbl>` +
+		`<w:p><w:r><w:t>Fish &amp; chips</w:t></w:r><w:del><w:r><w:delText>removed</w:delText></w:r></w:del></w:p>` +
+		`<w:sectPr/></w:body></w:document>`
+	numbering := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?><w:numbering ` + namespaces + `>` +
+		`<w:abstractNum w:abstractNumId="0"><w:lvl w:ilvl="0"><w:numFmt w:val="decimal"/></w:lvl><w:lvl w:ilvl="1"><w:numFmt w:val="bullet"/></w:lvl></w:abstractNum>` +
+		`<w:num w:numId="1"><w:abstractNumId w:val="0"/></w:num></w:numbering>`
+
+	styles, err := readZipPart("testdata/template.docx", "word/styles.xml")
+	if err != nil {
+		t.Fatalf("Failed to read styles: %v", err)
+	}
+	types, err := readZipPart("testdata/template.docx", "[Content_Types].xml")
+	if err != nil {
+		t.Fatalf("Failed to read content types: %v", err)
+	}
+	rels, err := readZipPart("testdata/template.docx", "word/_rels/document.xml.rels")
+	if err != nil {
+		t.Fatalf("Failed to read relationships: %v", err)
+	}
+	relType := "http://schemas.openxmlformats.org/officeDocument/2006/relationships/"
+	if err := writeTemplateWithParts("testdata/template.docx", inputPath, map[string]string{
+		"word/document.xml":  document,
+		"word/numbering.xml": numbering,
+		"word/styles.xml": strings.Replace(styles, "</w:styles>",
+			`<w:style w:type="paragraph" w:styleId="Heading1"><w:name w:val="heading 1"/><w:pPr><w:outlineLvl w:val="0"/></w:pPr></w:style>`+
+				`<w:style w:type="paragraph" w:styleId="Heading2"><w:name w:val="heading 2"/><w:pPr><w:outlineLvl w:val="1"/></w:pPr></w:style></w:styles>`, 1),
+		"[Content_Types].xml": strings.Replace(types, "</Types>",
+			`<Override PartName="/word/numbering.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.numbering+xml"/></Types>`, 1),
+		"word/_rels/document.xml.rels": strings.Replace(rels, "</Relationships>",
+			`<Relationship Id="rId6" Type="`+relType+`hyperlink" Target="https://example.com/docs" TargetMode="External"/>`+
+				`<Relationship Id="rId7" Type="`+relType+`numbering" Target="numbering.xml"/></Relationships>`, 1),
+	}); err != nil {
+		t.Fatalf("Failed to create document: %v", err)
+	}
+
+	text, err := docx.ExtractText(inputPath)
+	if err != nil {
+		t.Fatalf("ExtractText failed: %v", err)
+	}
+	expectedText := "Getting started\nRead the whole guide, then visit our site.\nSteps\n" +
+		"1. Install\n  • Check the version\n2. Run\nOption\tDefault\n--format\ttxt\nFish & chips\n"
+	if text != expectedText {
+		t.Errorf("Expected text:\n%q\ngot:\n%q", expectedText, text)
+	}
+
+	markdown, err := docx.ExportMarkdown(inputPath)
+	if err != nil {
+		t.Fatalf("ExportMarkdown failed: %v", err)
+	}
+	expectedMarkdown := "# Getting started\n\n" +
+		"Read the **whole** guide, *then* visit [our site](https://example.com/docs).\n\n" +
+		"## Steps\n\n1. Install\n    - Check the version\n2. Run\n\n" +
+		"| Option | Default |\n| --- | --- |\n| --format | txt |\n\nFish & chips\n"
+	if markdown != expectedMarkdown {
+		t.Errorf("Expected Markdown:\n%s\ngot:\n%s", expectedMarkdown, markdown)
+	}
+
+	html, err := docx.ExportHTML(inputPath)
+	if err != nil {
+		t.Fatalf("ExportHTML failed: %v", err)
+	}
+	for _, expected := range []string{
+		"<h1>Getting started</h1>",
+		`<p>Read the <strong>whole</strong> guide, <em>then</em> visit <a href="https://example.com/docs">our site</a>.</p>`,
+		"<h2>Steps</h2>",
+		"<ol>\n<li>Install<ul>\n<li>Check the version</li>\n</ul>\n</li>\n<li>Run</li>\n</ol>",
+		"<tr><th>Option</th><th>Default</th></tr>\n<tr><td>--format</td><td>txt</td></tr>",
+		"<p>Fish &amp; chips</p>",
+	} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("Expected HTML to contain %q:\n%s", expected, html)
+		}
+	}
+
+	t.Logf("\033[32m✓ Export test passed\033[0m")
+}
+
+func TestConvertDocxPdf(t *testing.T) {
+	inputPath := "testdata/output/agreement.docx"
+	outputPath := "testdata/output/agreement.pdf"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	namespaces := `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"` +
+		` xmlns:wp="http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"` +
+		` xmlns:pic="http://schemas.openxmlformats.org/drawingml/2006/picture"`
+	var rows strings.Builder
+	for i := 1; i <= 80; i++ {
+		rows.WriteString(fmt.Sprintf(`<w:tr><w:tc><w:p><w:r><w:t>Item %d</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>%d.00</w:t></w:r></w:p></w:tc></w:tr>`, i, i*10))
+	}
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:document ` + namespaces + `><w:body>` +
+		`<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t>Service Agreement</w:t></w:r></w:p>` +
+		`<w:p><w:pPr><w:jc w:val="both"/></w:pPr><w:r><w:t xml:space="preserve">This agreement is made with </w:t></w:r>` +
+		`<w:r><w:rPr><w:b/><w:color w:val="1F4E79"/></w:rPr><w:t>{{CLIENT}}</w:t></w:r>` +
+		`<w:r><w:t xml:space="preserve"> for the services listed below, which are provided each month at the rates agreed between the parties.</w:t></w:r></w:p>` +
+		`<w:p><w:pPr><w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t>Hosting</w:t></w:r></w:p>` +
+		`<w:p><w:pPr><w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t>Support</w:t></w:r></w:p>` +
+		`<w:p><w:pPr><w:tabs><w:tab w:val="right" w:leader="dot" w:pos="9000"/></w:tabs></w:pPr><w:r><w:t>Total</w:t></w:r><w:r><w:tab/><w:t>4,050.00</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:drawing><wp:inline><wp:extent cx="914400" cy="457200"/><a:graphic><a:graphicData><pic:pic><pic:blipFill>` +
+		`<a:blip r:embed="rId22"/></pic:blipFill></pic:pic></a:graphicData></a:graphic></wp:inline></w:drawing></w:r></w:p>` +
+		`<w:tbl><w:tblPr><w:tblBorders><w:top w:val="single" w:sz="4"/><w:bottom w:val="single" w:sz="4"/><w:insideH w:val="single" w:sz="4"/></w:tblBorders></w:tblPr>` +
+		`<w:tblGrid><w:gridCol w:w="4500"/><w:gridCol w:w="4500"/></w:tblGrid>` +
+		`<w:tr><w:trPr><w:tblHeader/></w:trPr><w:tc><w:p><w:r><w:t>Item</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Amount</w:t></w:r></w:p></w:tc></w:tr>` +
+		rows.String() + `</w:tbl>` +
+		`<w:p><w:r><w:br w:type="page"/></w:r><w:r><w:t>Signed by {{CLIENT}}</w:t></w:r></w:p>` +
+		`<w:sectPr><w:headerReference w:type="default" r:id="rId20"/><w:footerReference w:type="default" r:id="rId21"/>` +
+		`<w:pgSz w:w="11906" w:h="16838"/><w:pgMar w:top="1440" w:right="1440" w:bottom="1440" w:left="1440" w:header="708" w:footer="708" w:gutter="0"/></w:sectPr>` +
+		`</w:body></w:document>`
+	header := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?><w:hdr ` + namespaces + `>` +
+		`<w:p><w:pPr><w:jc w:val="right"/></w:pPr><w:r><w:t>Acme Services</w:t></w:r></w:p></w:hdr>`
+	footer := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?><w:ftr ` + namespaces + `>` +
+		`<w:p><w:pPr><w:jc w:val="center"/></w:pPr><w:r><w:t xml:space="preserve">Page </w:t></w:r>` +
+		`<w:fldSimple w:instr=" PAGE "><w:r><w:t>1</w:t></w:r></w:fldSimple><w:r><w:t xml:space="preserve"> of </w:t></w:r>` +
+		`<w:r><w:fldChar w:fldCharType="begin"/></w:r><w:r><w:instrText xml:space="preserve"> NUMPAGES </w:instrText></w:r>` +
+		`<w:r><w:fldChar w:fldCharType="separate"/></w:r><w:r><w:t>1</w:t></w:r><w:r><w:fldChar w:fldCharType="end"/></w:r></w:p></w:ftr>`
+	numbering := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?><w:numbering ` + namespaces + `>` +
+		`<w:abstractNum w:abstractNumId="0"><w:lvl w:ilvl="0"><w:start w:val="1"/><w:numFmt w:val="decimal"/><w:lvlText w:val="%1."/>` +
+		`<w:pPr><w:ind w:left="720" w:hanging="360"/></w:pPr></w:lvl></w:abstractNum>` +
+		`<w:num w:numId="1"><w:abstractNumId w:val="0"/></w:num></w:numbering>`
+
+	logo := image.NewRGBA(image.Rect(0, 0, 8, 4))
+	for i := range logo.Pix {
+		logo.Pix[i] = 0x80
+	}
+	var picture bytes.Buffer
+	if err := png.Encode(&picture, logo); err != nil {
+		t.Fatalf("Failed to encode picture: %v", err)
+	}
+
+	styles, err := readZipPart("testdata/template.docx", "word/styles.xml")
+	if err != nil {
+		t.Fatalf("Failed to read styles: %v", err)
+	}
+	rels, err := readZipPart("testdata/template.docx", "word/_rels/document.xml.rels")
+	if err != nil {
+		t.Fatalf("Failed to read relationships: %v", err)
+	}
+	relType := "http://schemas.openxmlformats.org/officeDocument/2006/relationships/"
+	if err := writeTemplateWithParts("testdata/template.docx", inputPath, map[string]string{
+		"word/document.xml":   document,
+		"word/header1.xml":    header,
+		"word/footer1.xml":    footer,
+		"word/numbering.xml":  numbering,
+		"word/media/logo.png": picture.String(),
+		"word/styles.xml": strings.Replace(styles, "</w:styles>",
+			`<w:style w:type="paragraph" w:styleId="Heading1"><w:name w:val="heading 1"/><w:pPr><w:keepNext/><w:outlineLvl w:val="0"/></w:pPr></w:style></w:styles>`, 1),
+		"word/_rels/document.xml.rels": strings.Replace(rels, "</Relationships>",
+			`<Relationship Id="rId20" Type="`+relType+`header" Target="header1.xml"/>`+
+				`<Relationship Id="rId21" Type="`+relType+`footer" Target="footer1.xml"/>`+
+				`<Relationship Id="rId22" Type="`+relType+`image" Target="media/logo.png"/>`+
+				`<Relationship Id="rId23" Type="`+relType+`numbering" Target="numbering.xml"/></Relationships>`, 1),
+	}); err != nil {
+		t.Fatalf("Failed to create document: %v", err)
+	}
+
+	if err := docx.ConvertToPDF(inputPath, outputPath); err != nil {
+		t.Fatalf("ConvertToPDF failed: %v", err)
+	}
+	data, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read PDF: %v", err)
+	}
+	if !bytes.HasPrefix(data, []byte("%PDF-")) || !bytes.HasSuffix(data, []byte("%%EOF\n")) {
+		t.Fatalf("Output is not a PDF file")
+	}
+	if !bytes.Contains(data, []byte("/MediaBox [0 0 595.3 841.9]")) {
+		t.Errorf("Expected A4 pages from the section properties")
+	}
+	if !bytes.Contains(data, []byte("/Subtype /Image /Width 8 /Height 4")) {
+		t.Errorf("Expected the picture to be embedded")
+	}
+
+	pages, err := readPDFPages(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read PDF pages: %v", err)
+	}
+	if len(pages) < 3 {
+		t.Fatalf("Expected the table and page break to fill at least 3 pages, got %d", len(pages))
+	}
+	for i, page := range pages {
+		if !strings.Contains(page, "(Acme Services)") {
+			t.Errorf("Page %d: expected the header", i+1)
+		}
+		if footer := fmt.Sprintf("(Page %d of %d)", i+1, len(pages)); !strings.Contains(page, footer) {
+			t.Errorf("Page %d: expected footer %q", i+1, footer)
+		}
+	}
+	for _, expected := range []string{"(Service Agreement)", "(1.)", "(2.)", "(Hosting)", "(4,050.00)", "/Im1 Do", "(Item 1)"} {
+		if !strings.Contains(pages[0], expected) {
+			t.Errorf("Expected the first page to contain %q", expected)
+		}
+	}
+	if !strings.Contains(pages[1], "(Amount)") || !strings.Contains(pages[1], "(Item 80)") && !strings.Contains(pages[2], "(Item 80)") {
+		t.Errorf("Expected the table to continue with its header row repeated")
+	}
+	if last := pages[len(pages)-1]; !strings.Contains(last, "(Signed by {{CLIENT}})") || strings.Contains(last, "(Item ") {
+		t.Errorf("Expected the page break to start a page with the signature")
+	}
+
+	// Rendered documents are written as PDF when the output path ends in .pdf
+	renderedPath := "testdata/output/globex.pdf"
+	if err := docx.ProcessDocxMultiWithOptions(inputPath, renderedPath, map[string]string{"{{CLIENT}}": "Globex"}, docx.Options{}); err != nil {
+		t.Fatalf("ProcessDocxMultiWithOptions failed: %v", err)
+	}
+	rendered, err := readPDFPages(renderedPath)
+	if err != nil {
+		t.Fatalf("Failed to read rendered PDF: %v", err)
+	}
+	if !strings.Contains(rendered[0], "(Globex)") || !strings.Contains(rendered[len(rendered)-1], "(Signed by Globex)") {
+		t.Errorf("Expected the rendered PDF to contain the replaced values")
+	}
+
+	t.Logf("\033[32m✓ PDF conversion test passed\033[0m")
+}
+
+func TestDocxProperties(t *testing.T) {
+	templatePath := "testdata/template.docx"
+	outputDir := "testdata/output"
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll(outputDir)
+
+	records := []docx.Data{
+		{"ID": "C-17", "NAME": "Alice"},
+		{"ID": "C-18", "NAME": "Bob & Co"},
+	}
+	opts := docx.Options{Properties: map[string]string{
+		"title":      "Contract {{ID}}",
+		"author":     "{{NAME | upper}}",
+		"keywords":   "contract, {{ID}}",
+		"modified":   "2026-01-02",
+		"Company":    "Acme",
+		"ContractId": "{{ID}}",
+	}}
+	if err := docx.ProcessDocxDataRecords(templatePath, outputDir, records, "{{ID}}.docx", opts); err != nil {
+		t.Fatalf("ProcessDocxDataRecords failed: %v", err)
+	}
+
+	props, err := docx.ReadProperties(filepath.Join(outputDir, "C-18.docx"))
+	if err != nil {
+		t.Fatalf("ReadProperties failed: %v", err)
+	}
+	expected := map[string]string{
+		"title":    "Contract C-18",
+		"creator":  "BOB & CO",
+		"keywords": "contract, C-18",
+		"modified": "2026-01-02T00:00:00Z",
+		"revision": "5",
+	}
+	for name, value := range expected {
+		if props.Core[name] != value {
+			t.Errorf("Expected core property %s %q, got %q", name, value, props.Core[name])
+		}
+	}
+	if props.App["Company"] != "Acme" {
+		t.Errorf("Expected Company Acme, got %q", props.App["Company"])
+	}
+	if props.Custom["ContractId"] != "C-18" {
+		t.Errorf("Expected ContractId C-18, got %v", props.Custom)
+	}
+
+	// The custom part is added along with its relationship and content type
+	outputPath := filepath.Join(outputDir, "C-17.docx")
+	for part, want := range map[string]string{
+		"_rels/.rels":         `Target="docProps/custom.xml"`,
+		"[Content_Types].xml": `<Override PartName="/docProps/custom.xml" ContentType="application/vnd.openxmlformats-officedocument.custom-properties+xml"/>`,
+		"docProps/custom.xml": `<property fmtid="{D5CDD505-2E9C-101B-9397-08002B2CF9AE}" pid="2" name="ContractId"><vt:lpwstr>C-17</vt:lpwstr></property>`,
+		"docProps/core.xml":   `<dcterms:modified xsi:type="dcterms:W3CDTF">2026-01-02T00:00:00Z</dcterms:modified>`,
+	} {
+		content, err := readZipPart(outputPath, part)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", part, err)
+		}
+		if !strings.Contains(content, want) {
+			t.Errorf("Expected %s to contain %s, got:\n%s", part, want, content)
+		}
+		if err := checkWellFormedXML(content); err != nil {
+			t.Errorf("%s is not well-formed: %v", part, err)
+		}
+	}
+
+	// Setting properties on a finished document: removing one and adding another
+	updatedPath := filepath.Join(outputDir, "updated.docx")
+	if err := docx.SetProperties(outputPath, updatedPath, map[string]string{"ContractId": "", "custom:Reviewed": "yes", "core:title": "Final"}); err != nil {
+		t.Fatalf("SetProperties failed: %v", err)
+	}
+	props, err = docx.ReadProperties(updatedPath)
+	if err != nil {
+		t.Fatalf("ReadProperties failed: %v", err)
+	}
+	if len(props.Custom) != 1 || props.Custom["Reviewed"] != "yes" || props.Core["title"] != "Final" {
+		t.Errorf("Expected only Reviewed and the new title, got %v %q", props.Custom, props.Core["title"])
+	}
+
+	if err := docx.SetProperties(outputPath, updatedPath, map[string]string{"modified": "yesterday"}); err == nil {
+		t.Error("Expected an error for an invalid date")
+	}
+	if err := (docx.Options{Properties: map[string]string{"core:colour": "red"}}).Validate(); err == nil {
+		t.Error("Expected an error for an unknown core property")
+	}
+
+	t.Logf("\033[32m✓ Properties test passed\033[0m")
+}
+
+func TestScrubDocx(t *testing.T) {
+	inputPath := "testdata/output/review.docx"
+	outputPath := "testdata/output/clean.docx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	read := func(path, part string) string {
+		content, err := readZipPart(path, part)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", part, err)
+		}
+		return content
+	}
+
+	namespaces := `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+	revision := ` w:author="Legal" w:date="2026-01-05T10:00:00Z"`
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:document ` + namespaces + `><w:body>` +
+		`<w:p w:rsidR="00A1" w:rsidRDefault="00A1"><w:r w:rsidR="00A1"><w:t xml:space="preserve">The term is </w:t></w:r>` +
+		`<w:del w:id="1"` + revision + `><w:r><w:delText>two</w:delText></w:r></w:del>` +
+		`<w:ins w:id="2"` + revision + `><w:r><w:t>three</w:t></w:r></w:ins><w:r><w:t xml:space="preserve"> years.</w:t></w:r></w:p>` +
+		`<w:p><w:pPr><w:rPr><w:del w:id="3"` + revision + `/></w:rPr></w:pPr><w:commentRangeStart w:id="0"/>` +
+		`<w:r><w:t xml:space="preserve">Payment is due </w:t></w:r><w:commentRangeEnd w:id="0"/>` +
+		`<w:r><w:rPr><w:rStyle w:val="CommentReference"/></w:rPr><w:commentReference w:id="0"/></w:r></w:p>` +
+		`<w:p><w:r><w:t>monthly.</w:t></w:r><w:r><w:rPr><w:vanish/></w:rPr><w:t xml:space="preserve"> Internal: check rate</w:t></w:r></w:p>` +
+		`<w:sdt><w:sdtPr><w:dataBinding w:xpath="/root/client" w:storeItemID="{11111111-2222-3333-4444-555555555555}"/></w:sdtPr>` +
+		`<w:sdtContent><w:p><w:r><w:t>Acme</w:t></w:r></w:p></w:sdtContent></w:sdt>` +
+		`<w:sectPr w:rsidR="00A1"/></w:body></w:document>`
+
+	relType := "http://schemas.openxmlformats.org/officeDocument/2006/relationships/"
+	if err := writeTemplateWithParts("testdata/template.docx", inputPath, map[string]string{
+		"word/document.xml": document,
+		"word/comments.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?><w:comments ` + namespaces + `>` +
+			`<w:comment w:id="0" w:author="Legal" w:initials="L"><w:p><w:r><w:t>Confirm with finance</w:t></w:r></w:p></w:comment></w:comments>`,
+		"word/people.xml":        `<?xml version="1.0" encoding="UTF-8" standalone="yes"?><w15:people xmlns:w15="http://schemas.microsoft.com/office/word/2012/wordml"><w15:person w15:author="Legal"/></w15:people>`,
+		"customXml/item1.xml":    `<root><client>Acme</client></root>`,
+		"docProps/thumbnail.png": "not really a picture",
+		"word/_rels/document.xml.rels": strings.Replace(read("testdata/template.docx", "word/_rels/document.xml.rels"), "</Relationships>",
+			`<Relationship Id="rId90" Type="`+relType+`comments" Target="comments.xml"/>`+
+				`<Relationship Id="rId91" Type="http://schemas.microsoft.com/office/2011/relationships/people" Target="people.xml"/>`+
+				`<Relationship Id="rId92" Type="`+relType+`customXml" Target="../customXml/item1.xml"/></Relationships>`, 1),
+		"_rels/.rels": strings.Replace(read("testdata/template.docx", "_rels/.rels"), "</Relationships>",
+			`<Relationship Id="rId90" Type="http://schemas.openxmlformats.org/package/2006/relationships/metadata/thumbnail" Target="docProps/thumbnail.png"/></Relationships>`, 1),
+		"[Content_Types].xml": strings.Replace(read("testdata/template.docx", "[Content_Types].xml"), "</Types>",
+			`<Default Extension="png" ContentType="image/png"/>`+
+				`<Override PartName="/word/comments.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.comments+xml"/>`+
+				`<Override PartName="/word/people.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.people+xml"/></Types>`, 1),
+	}); err != nil {
+		t.Fatalf("Failed to create document: %v", err)
+	}
+
+	report, err := docx.Scrub(inputPath, outputPath, docx.ScrubOptions{})
+	if err != nil {
+		t.Fatalf("Scrub failed: %v", err)
+	}
+	expected := map[string]int{
+		"tracked changes accepted": 3,
+		"comments":                 1,
+		"hidden text runs":         1,
+		"personal properties":      2,
+		"people parts":             1,
+		"data bindings":            1,
+		"custom XML parts":         1,
+		"thumbnails":               1,
+	}
+	for _, item := range report.Items {
+		if want, ok := expected[item.What]; ok && want != item.Count {
+			t.Errorf("Expected %d %s, got %d", want, item.What, item.Count)
+		}
+		delete(expected, item.What)
+	}
+	if len(expected) > 0 {
+		t.Errorf("Expected the report to list %v, got:\n%s", expected, report)
+	}
+
+	content := read(outputPath, "word/document.xml")
+	if err := checkWellFormedXML(content); err != nil {
+		t.Fatalf("Scrubbed document is not well-formed: %v", err)
+	}
+	texts := extractParagraphTexts(content)
+	expectedTexts := []string{"The term is three years.", "Payment is due monthly.", "Acme"}
+	if fmt.Sprintf("%q", texts) != fmt.Sprintf("%q", expectedTexts) {
+		t.Errorf("Expected paragraphs %q, got %q", expectedTexts, texts)
+	}
+	for _, trace := range []string{"w:rsid", "<w:ins", "<w:del", "w:comment", "Internal", "w:dataBinding", "Legal"} {
+		if strings.Contains(content, trace) {
+			t.Errorf("Expected %s to be removed, got:\n%s", trace, content)
+		}
+	}
+
+	for _, part := range []string{"word/comments.xml", "word/people.xml", "customXml/item1.xml", "docProps/thumbnail.png"} {
+		if _, err := readZipPart(outputPath, part); err == nil {
+			t.Errorf("Expected %s to be removed", part)
+		}
+	}
+	for part, traces := range map[string][]string{
+		"word/_rels/document.xml.rels": {"comments.xml", "people.xml", "customXml"},
+		"_rels/.rels":                  {"thumbnail"},
+		"[Content_Types].xml":          {"comments.xml", "people.xml"},
+		"docProps/core.xml":            {"dc:creator", "cp:lastModifiedBy"},
+		"word/settings.xml":            {"w:rsid"},
+	} {
+		content := read(outputPath, part)
+		for _, trace := range traces {
+			if strings.Contains(content, trace) {
+				t.Errorf("Expected %s to be removed from %s", trace, part)
+			}
+		}
+	}
+
+	// Rejecting the changes instead, and keeping the comments
+	if _, err := docx.Scrub(inputPath, outputPath, docx.ScrubOptions{RejectRevisions: true, KeepComments: true}); err != nil {
+		t.Fatalf("Scrub failed: %v", err)
+	}
+	content = read(outputPath, "word/document.xml")
+	texts = extractParagraphTexts(content)
+	if len(texts) != 4 || texts[0] != "The term is two years." || texts[1] != "Payment is due " {
+		t.Errorf("Expected the original text, got %q", texts)
+	}
+	if !strings.Contains(content, `<w:commentReference w:id="0"/>`) {
+		t.Error("Expected the comments to be kept")
+	}
+
+	t.Logf("\033[32m✓ Scrub test passed\033[0m")
+}
+
+func TestScrubDocxKeepsFormattedRuns(t *testing.T) {
+	inputPath := "testdata/output/formatted.docx"
+	outputPath := "testdata/output/formatted_clean.docx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	// Formatted runs before a comment reference run, all on one line as Word writes them
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		`<w:p><w:r><w:rPr><w:b/></w:rPr><w:t>Bold heading</w:t></w:r></w:p>` +
+		`<w:p><w:commentRangeStart w:id="0"/><w:r><w:rPr><w:i/></w:rPr><w:t>Italic clause</w:t></w:r><w:commentRangeEnd w:id="0"/>` +
+		`<w:r><w:rPr><w:rStyle w:val="CommentReference"/></w:rPr><w:commentReference w:id="0"/></w:r></w:p>` +
+		`<w:sectPr/></w:body></w:document>`
+	if err := writeTemplateWithParts("testdata/template.docx", inputPath, map[string]string{"word/document.xml": document}); err != nil {
+		t.Fatalf("Failed to create document: %v", err)
+	}
+
+	if _, err := docx.Scrub(inputPath, outputPath, docx.ScrubOptions{}); err != nil {
+		t.Fatalf("Scrub failed: %v", err)
+	}
+
+	content, err := readZipPart(outputPath, "word/document.xml")
+	if err != nil {
+		t.Fatalf("Failed to read document: %v", err)
+	}
+	expected := `<w:p><w:r><w:rPr><w:b/></w:rPr><w:t>Bold heading</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:rPr><w:i/></w:rPr><w:t>Italic clause</w:t></w:r></w:p>`
+	if !strings.Contains(content, expected) {
+		t.Errorf("Expected the formatted runs to be kept and the reference run removed, got:\n%s", content)
+	}
+
+	t.Logf("\033[32m✓ Scrub formatted runs test passed\033[0m")
+}
+
+func TestDocxRevisions(t *testing.T) {
+	inputPath := "testdata/output/reviewed.docx"
+	acceptedPath := "testdata/output/accepted.docx"
+	rejectedPath := "testdata/output/rejected.docx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	revision := ` w:author="Legal" w:date="2026-01-05T10:00:00Z"`
+	cell := func(tcPr, text string) string {
+		return `<w:tc>` + tcPr + `<w:p><w:r><w:t>` + text + `</w:t></w:r></w:p></w:tc>`
+	}
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		// Inserted and deleted text
+		`<w:p><w:r><w:t xml:space="preserve">Dear </w:t></w:r>` +
+		`<w:ins w:id="1"` + revision + `><w:r><w:t>{{NAME}}</w:t></w:r></w:ins>` +
+		`<w:del w:id="2"` + revision + `><w:r><w:delText>Sir</w:delText></w:r></w:del><w:r><w:t>,</w:t></w:r></w:p>` +
+		// A move
+		`<w:p><w:moveFromRangeStart w:id="3" w:name="move1"` + revision + `/>` +
+		`<w:moveFrom w:id="4"` + revision + `><w:r><w:t>Moved clause.</w:t></w:r></w:moveFrom><w:moveFromRangeEnd w:id="3"/>` +
+		`<w:r><w:t xml:space="preserve"> Payment terms.</w:t></w:r></w:p>` +
+		`<w:p><w:moveToRangeStart w:id="5" w:name="move1"` + revision + `/>` +
+		`<w:moveTo w:id="6"` + revision + `><w:r><w:t>Moved clause.</w:t></w:r></w:moveTo><w:moveToRangeEnd w:id="5"/></w:p>` +
+		// Formatting changes of a paragraph and a run
+		`<w:p><w:pPr><w:jc w:val="center"/><w:pPrChange w:id="7"` + revision + `><w:pPr><w:jc w:val="left"/></w:pPr></w:pPrChange></w:pPr>` +
+		`<w:r><w:rPr><w:b/><w:rPrChange w:id="8"` + revision + `><w:rPr><w:i/></w:rPr></w:rPrChange></w:rPr><w:t>Total</w:t></w:r></w:p>` +
+		// A deleted paragraph mark
+		`<w:p><w:pPr><w:rPr><w:del w:id="9"` + revision + `/></w:rPr></w:pPr><w:r><w:t xml:space="preserve">First half </w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>second half.</w:t></w:r></w:p>` +
+		// Table rows and cells
+		`<w:tbl><w:tblPr><w:tblW w:w="0" w:type="auto"/></w:tblPr><w:tblGrid><w:gridCol w:w="2000"/><w:gridCol w:w="2000"/></w:tblGrid>` +
+		`<w:tr>` + cell(`<w:tcPr><w:shd w:val="clear" w:fill="FF0000"/><w:tcPrChange w:id="10"`+revision+`><w:tcPr/></w:tcPrChange></w:tcPr>`, "Kept") +
+		cell(`<w:tcPr><w:cellIns w:id="11"`+revision+`/></w:tcPr>`, "New cell") + `</w:tr>` +
+		`<w:tr><w:trPr><w:del w:id="12"` + revision + `/></w:trPr>` + cell("", "Old row") + `</w:tr>` +
+		`<w:tr><w:trPr><w:ins w:id="13"` + revision + `/></w:trPr>` + cell("", "New row") + `</w:tr></w:tbl>` +
+		`<w:sectPr/></w:body></w:document>`
+
+	if err := writeTemplateWithParts("testdata/template.docx", inputPath, map[string]string{"word/document.xml": document}); err != nil {
+		t.Fatalf("Failed to create document: %v", err)
+	}
+
+	check := func(path string, expectedTexts []string, present, absent []string) {
+		t.Helper()
+		content, err := readZipPart(path, "word/document.xml")
+		if err != nil {
+			t.Fatalf("Failed to read document: %v", err)
+		}
+		if err := checkWellFormedXML(content); err != nil {
+			t.Fatalf("Document is not well-formed: %v", err)
+		}
+		if texts := extractParagraphTexts(content); fmt.Sprintf("%q", texts) != fmt.Sprintf("%q", expectedTexts) {
+			t.Errorf("Expected paragraphs %q, got %q", expectedTexts, texts)
+		}
+		for _, markup := range present {
+			if !strings.Contains(content, markup) {
+				t.Errorf("Expected %s in %s", markup, path)
+			}
+		}
+		for _, markup := range absent {
+			if strings.Contains(content, markup) {
+				t.Errorf("Expected no %s in %s", markup, path)
+			}
+		}
+	}
+	noRevisions := []string{"<w:ins", "<w:del", "<w:move", "Change", "<w:cellIns"}
+
+	count, err := docx.AcceptAllRevisions(inputPath, acceptedPath)
+	if err != nil {
+		t.Fatalf("AcceptAllRevisions failed: %v", err)
+	}
+	if count != 11 {
+		t.Errorf("Expected 11 changes accepted, got %d", count)
+	}
+	check(acceptedPath,
+		[]string{"Dear {{NAME}},", " Payment terms.", "Moved clause.", "Total", "First half second half.", "Kept", "New cell", "New row"},
+		[]string{`<w:jc w:val="center"/>`, `<w:rPr><w:b/></w:rPr>`, `<w:shd w:val="clear" w:fill="FF0000"/>`},
+		append(noRevisions, "<w:i/>"))
+
+	count, err = docx.RejectAllRevisions(inputPath, rejectedPath)
+	if err != nil {
+		t.Fatalf("RejectAllRevisions failed: %v", err)
+	}
+	if count != 11 {
+		t.Errorf("Expected 11 changes rejected, got %d", count)
+	}
+	check(rejectedPath,
+		[]string{"Dear Sir,", "Moved clause. Payment terms.", "", "Total", "First half ", "second half.", "Kept", "Old row"},
+		[]string{`<w:jc w:val="left"/>`, `<w:rPr><w:i/></w:rPr>`, `<w:tcPr></w:tcPr>`},
+		append(noRevisions, "<w:b/>", "FF0000", "{{NAME}}"))
+
+	// Resolving the changes before rendering
+	if err := docx.ProcessDocxMultiWithOptions(inputPath, acceptedPath, map[string]string{"{{NAME}}": "Ms Smith"}, docx.Options{Revisions: "accept"}); err != nil {
+		t.Fatalf("Rendering failed: %v", err)
+	}
+	content, err := readZipPart(acceptedPath, "word/document.xml")
+	if err != nil {
+		t.Fatalf("Failed to read document: %v", err)
+	}
+	if texts := extractParagraphTexts(content); len(texts) == 0 || texts[0] != "Dear Ms Smith," {
+		t.Errorf("Expected the inserted placeholder to be rendered, got %q", texts)
+	}
+	if strings.Contains(content, "<w:ins") {
+		t.Error("Expected no revision marks in the rendered document")
+	}
+
+	if err := docx.ProcessDocxMultiWithOptions(inputPath, acceptedPath, nil, docx.Options{Revisions: "approve"}); err == nil {
+		t.Error("Expected an error for an invalid revisions mode")
+	}
+
+	t.Logf("\033[32m✓ Revisions test passed\033[0m")
+}
+
+func TestDocxTrackChanges(t *testing.T) {
+	inputPath := "testdata/output/renewal.docx"
+	outputPath := "testdata/output/renewal_tracked.docx"
+	acceptedPath := "testdata/output/renewal_accepted.docx"
+	rejectedPath := "testdata/output/renewal_rejected.docx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		`<w:p><w:bookmarkStart w:id="7" w:name="start"/><w:bookmarkEnd w:id="7"/>` +
+		`<w:r><w:rPr><w:b/></w:rPr><w:t xml:space="preserve">Client: {{NAME}} </w:t><w:tab/></w:r></w:p>` +
+		// A placeholder split over runs with different formatting
+		`<w:p><w:r><w:t xml:space="preserve">Renewal until {{</w:t></w:r><w:r><w:rPr><w:i/></w:rPr><w:t>END}}.</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{NOTE}}</w:t></w:r></w:p>` +
+		`<w:sectPr/></w:body></w:document>`
+
+	if err := writeTemplateWithParts("testdata/template.docx", inputPath, map[string]string{"word/document.xml": document}); err != nil {
+		t.Fatalf("Failed to create document: %v", err)
+	}
+
+	date := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	err := docx.ProcessDocxMultiWithOptions(inputPath, outputPath, map[string]string{
+		"{{NAME}}": "Acme & Co",
+		"{{END}}":  "2027-03-01",
+		"{{NOTE}}": "",
+	}, docx.Options{TrackChanges: true, RevisionAuthor: "Contract Bot", RevisionDate: date})
+	if err != nil {
+		t.Fatalf("Rendering failed: %v", err)
+	}
+
+	content, err := readZipPart(outputPath, "word/document.xml")
+	if err != nil {
+		t.Fatalf("Failed to read document: %v", err)
+	}
+	if err := checkWellFormedXML(content); err != nil {
+		t.Fatalf("Tracked document is not well-formed: %v", err)
+	}
+
+	attrs := ` w:author="Contract Bot" w:date="2026-03-01T09:30:00Z">`
+	for _, expected := range []string{
+		`<w:r><w:rPr><w:b/></w:rPr><w:t xml:space="preserve">Client: </w:t></w:r>` +
+			`<w:del w:id="8"` + attrs + `<w:r><w:rPr><w:b/></w:rPr><w:delText>{{NAME}}</w:delText></w:r></w:del>` +
+			`<w:ins w:id="9"` + attrs + `<w:r><w:rPr><w:b/></w:rPr><w:t>Acme &amp; Co</w:t></w:r></w:ins>` +
+			`<w:r><w:rPr><w:b/></w:rPr><w:t xml:space="preserve"> </w:t><w:tab/></w:r>`,
+		`<w:del w:id="10"` + attrs + `<w:r><w:delText>{{</w:delText></w:r></w:del><w:del w:id="11"` + attrs,
+		`<w:r><w:rPr><w:i/></w:rPr><w:delText>END}}</w:delText></w:r></w:del>` +
+			`<w:ins w:id="12"` + attrs + `<w:r><w:t>2027-03-01</w:t></w:r></w:ins><w:r><w:rPr><w:i/></w:rPr><w:t>.</w:t></w:r>`,
+		`<w:p><w:del w:id="13"` + attrs + `<w:r><w:delText>{{NOTE}}</w:delText></w:r></w:del></w:p>`,
+	} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("Expected %s in:\n%s", expected, content)
+		}
+	}
+	if strings.Count(content, "<w:ins ") != 2 || strings.Count(content, "<w:del ") != 4 {
+		t.Errorf("Expected 2 insertions and 4 deletions, got:\n%s", content)
+	}
+
+	// Accepting the changes gives the rendered document, rejecting them the template
+	if _, err := docx.AcceptAllRevisions(outputPath, acceptedPath); err != nil {
+		t.Fatalf("AcceptAllRevisions failed: %v", err)
+	}
+	if _, err := docx.RejectAllRevisions(outputPath, rejectedPath); err != nil {
+		t.Fatalf("RejectAllRevisions failed: %v", err)
+	}
+	for path, expectedTexts := range map[string][]string{
+		acceptedPath: {"Client: Acme &amp; Co ", "Renewal until 2027-03-01.", ""},
+		rejectedPath: {"Client: {{NAME}} ", "Renewal until {{END}}.", "{{NOTE}}"},
+	} {
+		content, err := readZipPart(path, "word/document.xml")
+		if err != nil {
+			t.Fatalf("Failed to read document: %v", err)
+		}
+		if texts := extractParagraphTexts(content); fmt.Sprintf("%q", texts) != fmt.Sprintf("%q", expectedTexts) {
+			t.Errorf("Expected paragraphs %q in %s, got %q", expectedTexts, path, texts)
+		}
+	}
+
+	t.Logf("\033[32m✓ Track changes test passed\033[0m")
+}
+
+func TestDocxPreservesZipEntries(t *testing.T) {
+	inputPath := "testdata/output/media.docx"
+	outputPath := "testdata/output/media_out.docx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	// A template whose entries carry a comment and a modification time, with stored media
+	modified := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	media := make([]byte, 1<<20)
+	for i := range media {
+		media[i] = byte(i * 7919 >> 3)
+	}
+	reader, err := zip.OpenReader("testdata/template.docx")
+	if err != nil {
+		t.Fatalf("Failed to open template: %v", err)
+	}
+	defer reader.Close()
+	outputFile, err := os.Create(inputPath)
+	if err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+	zipWriter := zip.NewWriter(outputFile)
+	for _, file := range reader.File {
+		writer, err := zipWriter.CreateHeader(&zip.FileHeader{Name: file.Name, Method: zip.Deflate, Modified: modified, Comment: "from template"})
+		if err != nil {
+			t.Fatalf("Failed to write %s: %v", file.Name, err)
+		}
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", file.Name, err)
+		}
+		_, err = io.Copy(writer, rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("Failed to copy %s: %v", file.Name, err)
+		}
+	}
+	writer, err := zipWriter.CreateHeader(&zip.FileHeader{Name: "word/media/clip.mp4", Method: zip.Store, Modified: modified, Comment: "clip"})
+	if err != nil {
+		t.Fatalf("Failed to write media: %v", err)
+	}
+	if _, err := writer.Write(media); err != nil {
+		t.Fatalf("Failed to write media: %v", err)
+	}
+	if err := zipWriter.Close(); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+	outputFile.Close()
+
+	err = docx.ProcessDocxMultiWithOptions(inputPath, outputPath, map[string]string{"{{NAME}}": "John Doe"}, docx.Options{CompressionLevel: 9})
+	if err != nil {
+		t.Fatalf("ProcessDocxMultiWithOptions failed: %v", err)
+	}
+
+	rawContent := func(path, name string) (*zip.File, []byte) {
+		t.Helper()
+		reader, err := zip.OpenReader(path)
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", path, err)
+		}
+		defer reader.Close()
+		for _, file := range reader.File {
+			if file.Name != name {
+				continue
+			}
+			rc, err := file.OpenRaw()
+			if err != nil {
+				t.Fatalf("Failed to read %s: %v", name, err)
+			}
+			content, err := io.ReadAll(rc)
+			if err != nil {
+				t.Fatalf("Failed to read %s: %v", name, err)
+			}
+			return file, content
+		}
+		t.Fatalf("%s not found in %s", name, path)
+		return nil, nil
+	}
+
+	// Untouched parts are copied as they are stored
+	for _, name := range []string{"word/media/clip.mp4", "word/styles.xml"} {
+		source, sourceContent := rawContent(inputPath, name)
+		copied, copiedContent := rawContent(outputPath, name)
+		if !bytes.Equal(sourceContent, copiedContent) {
+			t.Errorf("Expected %s to be copied without recompression", name)
+		}
+		if copied.Method != source.Method || copied.Comment != source.Comment || !copied.Modified.Equal(source.Modified) {
+			t.Errorf("Expected the header of %s to be kept, got method %d, comment %q, modified %v",
+				name, copied.Method, copied.Comment, copied.Modified)
+		}
+	}
+
+	// Rewritten parts keep their header fields
+	document, _ := rawContent(outputPath, "word/document.xml")
+	if document.Method != zip.Deflate || document.Comment != "from template" || !document.Modified.Equal(modified) {
+		t.Errorf("Expected the header of the document to be kept, got method %d, comment %q, modified %v",
+			document.Method, document.Comment, document.Modified)
+	}
+	content, err := readZipPart(outputPath, "word/document.xml")
+	if err != nil {
+		t.Fatalf("Failed to read document: %v", err)
+	}
+	if !strings.Contains(content, "John Doe") {
+		t.Error("Expected the placeholder to be replaced")
+	}
+
+	if err := docx.ProcessDocxMultiWithOptions(inputPath, outputPath, nil, docx.Options{CompressionLevel: 12}); err == nil {
+		t.Error("Expected an error for an invalid compression level")
+	}
+
+	t.Logf("\033[32m✓ Zip entries test passed\033[0m")
+}
+
 // Benchmark tests
 func BenchmarkProcessDocxSingle(b *testing.B) {
 	templatePath := "testdata/template.docx"
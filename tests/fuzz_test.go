@@ -0,0 +1,243 @@
+package tests
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/siliconcatalyst/officeforge/docx"
+	"github.com/siliconcatalyst/officeforge/pptx"
+	"github.com/siliconcatalyst/officeforge/xlsx"
+)
+
+// fuzzPlaceholder is inserted in the middle of the fuzzed text of every template
+const fuzzPlaceholder = "{{NAME}}"
+
+func addFuzzSeeds(f *testing.F) {
+	f.Add("Hello world", []byte{3, 9}, "Jane Smith")
+	f.Add("Müller & Co. <GmbH> – “quoted”", []byte{1, 5, 17, 22}, "José García-O'Brien")
+	f.Add("東京都 渋谷区 の 契約書", []byte{2, 4, 6, 8, 10}, "山田 太郎")
+	f.Add("Emoji 😀👍🏽 and flags 🇩🇪", []byte{0, 7, 13, 21}, "🎉 & <party>")
+	f.Add("Tabs\tand\nnewlines 'apostrophes'", []byte{250, 1, 128}, "  padded value  ")
+	f.Add("", []byte{}, "")
+}
+
+// sanitizeFuzzText keeps only characters an XML document can hold. Carriage returns are
+// dropped because XML parsers normalize them to line feeds. Placeholder delimiters are
+// removed too, leaving at most single braces, so that the fuzzed text can't form
+// placeholders of its own such as {{NAME|upper}}.
+func sanitizeFuzzText(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t' || r == '\n':
+			return r
+		case r >= 0x20 && r <= 0xD7FF, r >= 0xE000 && r <= 0xFFFD, r >= 0x10000 && r <= 0x10FFFF:
+			return r
+		}
+		return -1
+	}, strings.ToValidUTF8(s, ""))
+	return strings.NewReplacer("{{", "", "}}", "").Replace(s)
+}
+
+// encodeFuzzText escapes text for XML, choosing between named entities, decimal and
+// hexadecimal character references depending on mode.
+func encodeFuzzText(text string, mode byte) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case mode%3 == 1:
+			fmt.Fprintf(&b, "&#%d;", r)
+		case mode%3 == 2 && r > 0x7F:
+			fmt.Fprintf(&b, "&#x%X;", r)
+		case r == '&':
+			b.WriteString("&amp;")
+		case r == '<':
+			b.WriteString("&lt;")
+		case r == '>':
+			b.WriteString("&gt;")
+		case r == '\'':
+			b.WriteString("&apos;")
+		case r == '"':
+			b.WriteString("&quot;")
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// buildFuzzRuns inserts the placeholder into text, cuts the result into runs at the
+// positions given by cuts and wraps each encoded piece with run markup.
+func buildFuzzRuns(text string, cuts []byte, wrapRun func(content string, index int) string) (string, string) {
+	middle := len(text) / 2
+	for middle > 0 && !utf8.RuneStart(text[middle]) {
+		middle--
+	}
+	full := text[:middle] + fuzzPlaceholder + text[middle:]
+
+	var runs strings.Builder
+	start := 0
+	for i, cut := range cuts {
+		if start >= len(full) {
+			break
+		}
+		end := start + 1 + int(cut)%(len(full)-start)
+		for end < len(full) && !utf8.RuneStart(full[end]) {
+			end++
+		}
+		runs.WriteString(wrapRun(encodeFuzzText(full[start:end], cut), i))
+		start = end
+	}
+	if start < len(full) {
+		runs.WriteString(wrapRun(encodeFuzzText(full[start:], 0), len(cuts)))
+	}
+
+	return runs.String(), full
+}
+
+// extractFuzzText returns the decoded content of every text element (<w:t>, <a:t>, <t>),
+// failing if the XML is not well-formed.
+func extractFuzzText(content string) (string, error) {
+	decoder := xml.NewDecoder(strings.NewReader(content))
+	var text strings.Builder
+	inText := false
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return text.String(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch tok := token.(type) {
+		case xml.StartElement:
+			inText = tok.Name.Local == "t"
+		case xml.EndElement:
+			inText = false
+		case xml.CharData:
+			if inText {
+				text.Write(tok)
+			}
+		}
+	}
+}
+
+func checkFuzzOutput(t *testing.T, content, full, value string) {
+	t.Helper()
+
+	text, err := extractFuzzText(content)
+	if err != nil {
+		t.Fatalf("Output is not well-formed XML: %v\n%s", err, content)
+	}
+
+	expected := strings.ReplaceAll(full, fuzzPlaceholder, value)
+	if text != expected {
+		t.Fatalf("Unexpected text after replacement:\n got: %q\nwant: %q", text, expected)
+	}
+}
+
+func FuzzProcessDocxRuns(f *testing.F) {
+	addFuzzSeeds(f)
+
+	f.Fuzz(func(t *testing.T, text string, cuts []byte, value string) {
+		text, value = sanitizeFuzzText(text), sanitizeFuzzText(value)
+
+		runs, full := buildFuzzRuns(text, cuts, func(content string, index int) string {
+			run := `<w:r><w:rPr><w:sz w:val="` + fmt.Sprint(20+index%3) + `"/></w:rPr><w:t xml:space="preserve">` + content + `</w:t></w:r>`
+			if index%2 == 1 {
+				run = `<w:proofErr w:type="spellStart"/>` + run + `<w:proofErr w:type="spellEnd"/>`
+			}
+			return run
+		})
+		document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+			`<w:p>` + runs + `</w:p></w:body></w:document>`
+
+		dir := t.TempDir()
+		templatePath := filepath.Join(dir, "template.docx")
+		outputPath := filepath.Join(dir, "output.docx")
+
+		if err := writeTemplateWithPart("testdata/template.docx", templatePath, "word/document.xml", document); err != nil {
+			t.Fatalf("Failed to create template: %v", err)
+		}
+		if err := docx.ProcessDocxMulti(templatePath, outputPath, map[string]string{fuzzPlaceholder: value}); err != nil {
+			t.Fatalf("ProcessDocxMulti failed: %v", err)
+		}
+
+		content, err := readDocxContent(outputPath)
+		if err != nil {
+			t.Fatalf("Failed to read output content: %v", err)
+		}
+		checkFuzzOutput(t, content, full, value)
+	})
+}
+
+func FuzzProcessPptxRuns(f *testing.F) {
+	addFuzzSeeds(f)
+
+	f.Fuzz(func(t *testing.T, text string, cuts []byte, value string) {
+		text, value = sanitizeFuzzText(text), sanitizeFuzzText(value)
+
+		runs, full := buildFuzzRuns(text, cuts, func(content string, index int) string {
+			return `<a:r><a:rPr lang="ja-JP" sz="` + fmt.Sprint(1800+index%3) + `"/><a:t>` + content + `</a:t></a:r>`
+		})
+		slide := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<p:sld xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main">` +
+			`<p:cSld><p:spTree><p:sp><p:txBody><a:bodyPr/><a:p>` + runs + `</a:p></p:txBody></p:sp></p:spTree></p:cSld></p:sld>`
+
+		dir := t.TempDir()
+		templatePath := filepath.Join(dir, "template.pptx")
+		outputPath := filepath.Join(dir, "output.pptx")
+
+		if err := writeTemplateWithPart("testdata/template.pptx", templatePath, "ppt/slides/slide1.xml", slide); err != nil {
+			t.Fatalf("Failed to create template: %v", err)
+		}
+		if err := pptx.ProcessPptxMulti(templatePath, outputPath, map[string]string{fuzzPlaceholder: value}); err != nil {
+			t.Fatalf("ProcessPptxMulti failed: %v", err)
+		}
+
+		content, err := readPptxContent(outputPath)
+		if err != nil {
+			t.Fatalf("Failed to read output content: %v", err)
+		}
+		checkFuzzOutput(t, content, full, value)
+	})
+}
+
+func FuzzProcessXlsxRuns(f *testing.F) {
+	addFuzzSeeds(f)
+
+	f.Fuzz(func(t *testing.T, text string, cuts []byte, value string) {
+		text, value = sanitizeFuzzText(text), sanitizeFuzzText(value)
+
+		runs, full := buildFuzzRuns(text, cuts, func(content string, index int) string {
+			return `<r><rPr><sz val="` + fmt.Sprint(11+index%3) + `"/></rPr><t xml:space="preserve">` + content + `</t></r>`
+		})
+		sharedStrings := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="1" uniqueCount="1">` +
+			`<si>` + runs + `</si></sst>`
+
+		dir := t.TempDir()
+		templatePath := filepath.Join(dir, "template.xlsx")
+		outputPath := filepath.Join(dir, "output.xlsx")
+
+		if err := writeTemplateWithPart("testdata/template.xlsx", templatePath, "xl/sharedStrings.xml", sharedStrings); err != nil {
+			t.Fatalf("Failed to create template: %v", err)
+		}
+		if err := xlsx.ProcessXlsxMulti(templatePath, outputPath, map[string]string{fuzzPlaceholder: value}); err != nil {
+			t.Fatalf("ProcessXlsxMulti failed: %v", err)
+		}
+
+		content, err := readXlsxContent(outputPath)
+		if err != nil {
+			t.Fatalf("Failed to read output content: %v", err)
+		}
+		checkFuzzOutput(t, content, full, value)
+	})
+}
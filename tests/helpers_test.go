@@ -2,9 +2,14 @@ package tests
 
 import (
 	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"encoding/xml"
 	"fmt"
 	"io"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -99,3 +104,203 @@ func readXlsxContent(path string) (string, error) {
 	}
 	return "", fmt.Errorf("sharedStrings.xml not found")
 }
+
+// Helper function to read any part of an Office file
+func readZipPart(path, partName string) (string, error) {
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	for _, file := range reader.File {
+		if file.Name == partName {
+			rc, err := file.Open()
+			if err != nil {
+				return "", err
+			}
+			defer rc.Close()
+
+			content, err := io.ReadAll(rc)
+			if err != nil {
+				return "", err
+			}
+			return string(content), nil
+		}
+	}
+	return "", fmt.Errorf("%s not found", partName)
+}
+
+// Helper function to copy a template while replacing the content of one of its parts
+func writeTemplateWithPart(templatePath, outputPath, partName, content string) error {
+	reader, err := zip.OpenReader(templatePath)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	outputFile, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer outputFile.Close()
+
+	zipWriter := zip.NewWriter(outputFile)
+	defer zipWriter.Close()
+
+	for _, file := range reader.File {
+		writer, err := zipWriter.Create(file.Name)
+		if err != nil {
+			return err
+		}
+
+		if file.Name == partName {
+			if _, err := writer.Write([]byte(content)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(writer, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Helper function to copy a template while replacing or adding parts
+func writeTemplateWithParts(templatePath, outputPath string, parts map[string]string) error {
+	reader, err := zip.OpenReader(templatePath)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	outputFile, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer outputFile.Close()
+
+	zipWriter := zip.NewWriter(outputFile)
+	defer zipWriter.Close()
+
+	written := make(map[string]bool)
+	for _, file := range reader.File {
+		writer, err := zipWriter.Create(file.Name)
+		if err != nil {
+			return err
+		}
+
+		if content, ok := parts[file.Name]; ok {
+			written[file.Name] = true
+			if _, err := writer.Write([]byte(content)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(writer, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	for name, content := range parts {
+		if written[name] {
+			continue
+		}
+		writer, err := zipWriter.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := writer.Write([]byte(content)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Helper function to read the content streams of the pages of a PDF file, in page order
+func readPDFPages(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var pages []string
+	streams := regexp.MustCompile(`<< /Filter /FlateDecode /Length (\d+) >>\nstream\n`)
+	for _, match := range streams.FindAllSubmatchIndex(data, -1) {
+		length, _ := strconv.Atoi(string(data[match[2]:match[3]]))
+		reader, err := zlib.NewReader(bytes.NewReader(data[match[1] : match[1]+length]))
+		if err != nil {
+			return nil, err
+		}
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, string(content))
+	}
+	return pages, nil
+}
+
+// Helper function to check that content is well-formed XML
+func checkWellFormedXML(content string) error {
+	decoder := xml.NewDecoder(strings.NewReader(content))
+	for {
+		_, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// extractParagraphTexts returns the text of every <w:p> element in document order
+func extractParagraphTexts(content string) []string {
+	var texts []string
+	for _, paragraph := range strings.Split(content, "</w:p>") {
+		start := strings.LastIndex(paragraph, "<w:p>")
+		if alt := strings.LastIndex(paragraph, "<w:p "); alt > start {
+			start = alt
+		}
+		if start < 0 {
+			continue
+		}
+		var text strings.Builder
+		rest := paragraph[start:]
+		for {
+			open := strings.Index(rest, "<w:t")
+			if open < 0 {
+				break
+			}
+			rest = rest[open:]
+			if rest[len("<w:t")] != '>' && rest[len("<w:t")] != ' ' {
+				rest = rest[len("<w:t"):] // <w:tab/>, <w:tbl> and the like
+				continue
+			}
+			gt := strings.Index(rest, ">")
+			end := strings.Index(rest, "</w:t>")
+			if gt < 0 || end < 0 {
+				break
+			}
+			text.WriteString(rest[gt+1 : end])
+			rest = rest[end+len("</w:t>"):]
+		}
+		texts = append(texts, text.String())
+	}
+	return texts
+}
@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -377,6 +378,697 @@ func TestProcessPptxSpecialCharacters(t *testing.T) {
 	t.Logf("\033[32m✓ Special characters test passed\033[0m")
 }
 
+func TestProcessPptxPlaceholderSplitAcrossRuns(t *testing.T) {
+	templatePath := "testdata/output/split_runs_template.pptx"
+	outputPath := "testdata/output/split_runs_output.pptx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	// {{CLIENT_NAME}} is split over three differently formatted runs
+	slide := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<p:sld xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main">` +
+		`<p:cSld><p:spTree><p:sp><p:txBody><a:bodyPr/><a:p>` +
+		`<a:r><a:rPr lang="en-GB" b="1"/><a:t>{{CLIENT</a:t></a:r>` +
+		`<a:r><a:rPr lang="en-GB" i="1" err="1"/><a:t>_NAME</a:t></a:r>` +
+		`<a:r><a:rPr lang="en-GB"/><a:t>}} presents</a:t></a:r>` +
+		`</a:p></p:txBody></p:sp></p:spTree></p:cSld></p:sld>`
+
+	if err := writeTemplateWithPart("testdata/template.pptx", templatePath, "ppt/slides/slide1.xml", slide); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	err := pptx.ProcessPptxMulti(templatePath, outputPath, map[string]string{"{{CLIENT_NAME}}": "Jane Smith"})
+	if err != nil {
+		t.Fatalf("ProcessPptxMulti failed: %v", err)
+	}
+
+	content, err := readPptxContent(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output content: %v", err)
+	}
+
+	if err := checkWellFormedXML(content); err != nil {
+		t.Fatalf("Output is not well-formed XML: %v", err)
+	}
+
+	expectedFragments := []string{
+		`<a:r><a:rPr lang="en-GB" b="1"/><a:t>Jane Smith</a:t></a:r>`,
+		`<a:r><a:rPr lang="en-GB" i="1" err="1"/><a:t></a:t></a:r>`,
+		`<a:r><a:rPr lang="en-GB"/><a:t> presents</a:t></a:r>`,
+	}
+	for _, fragment := range expectedFragments {
+		if !strings.Contains(content, fragment) {
+			t.Errorf("Expected fragment %s not found in output:\n%s", fragment, content)
+		}
+	}
+
+	t.Logf("\033[32m✓ Split placeholder test passed\033[0m")
+}
+
+func TestNormalizePptx(t *testing.T) {
+	templatePath := "testdata/output/fragmented_template.pptx"
+	outputPath := "testdata/output/normalized_template.pptx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	slide := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<p:sld xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main">` +
+		`<p:cSld><p:spTree><p:sp><p:txBody><a:bodyPr/><a:p>` +
+		`<a:r><a:rPr lang="en-GB" dirty="0"/><a:t>{{CLIENT</a:t></a:r>` +
+		`<a:r><a:rPr lang="en-GB" err="1" dirty="0"/><a:t>_NAME}}</a:t></a:r>` +
+		`<a:endParaRPr lang="en-GB" dirty="0"/></a:p></p:txBody></p:sp></p:spTree></p:cSld></p:sld>`
+
+	if err := writeTemplateWithPart("testdata/template.pptx", templatePath, "ppt/slides/slide1.xml", slide); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	split, err := pptx.Normalize(templatePath, outputPath)
+	if err != nil {
+		t.Fatalf("Normalize failed: %v", err)
+	}
+
+	if len(split) != 1 || split[0] != "{{CLIENT_NAME}}" {
+		t.Errorf("Expected [{{CLIENT_NAME}}] reported as split, got %v", split)
+	}
+
+	content, err := readPptxContent(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output content: %v", err)
+	}
+
+	expected := `<a:p><a:r><a:rPr lang="en-GB"/><a:t>{{CLIENT_NAME}}</a:t></a:r><a:endParaRPr lang="en-GB"/></a:p>`
+	if !strings.Contains(content, expected) {
+		t.Errorf("Expected %s in output:\n%s", expected, content)
+	}
+
+	t.Logf("\033[32m✓ Normalize test passed\033[0m")
+}
+
+func TestProcessPptxFilters(t *testing.T) {
+	templatePath := "testdata/output/filters_template.pptx"
+	outputPath := "testdata/output/filters_output.pptx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	slide := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<p:sld xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main">` +
+		`<p:cSld><p:spTree><p:sp><p:txBody><a:bodyPr/>` +
+		`<a:p><a:r><a:rPr lang="en-US"/><a:t>{{TITLE | upper}} – {{TOTAL | currency:&quot;USD&quot;}}</a:t></a:r></a:p>` +
+		`<a:p><a:r><a:rPr lang="en-US"/><a:t>{{SUBTITLE | default:&quot;Draft&quot;}}</a:t></a:r></a:p>` +
+		`</p:txBody></p:sp></p:spTree></p:cSld></p:sld>`
+
+	if err := writeTemplateWithPart("testdata/template.pptx", templatePath, "ppt/slides/slide1.xml", slide); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	replacements := map[string]string{"{{TITLE}}": "quarterly review", "{{TOTAL}}": "-98765.432"}
+	if err := pptx.ProcessPptxMulti(templatePath, outputPath, replacements); err != nil {
+		t.Fatalf("ProcessPptxMulti failed: %v", err)
+	}
+
+	content, err := readPptxContent(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output content: %v", err)
+	}
+
+	for _, e := range []string{"<a:t>QUARTERLY REVIEW – -$98,765.43</a:t>", "<a:t>Draft</a:t>"} {
+		if !strings.Contains(content, e) {
+			t.Errorf("Expected %s in output:\n%s", e, content)
+		}
+	}
+
+	t.Logf("\033[32m✓ Filters test passed\033[0m")
+}
+
+func TestProcessPptxRichText(t *testing.T) {
+	templatePath := "testdata/output/rich_template.pptx"
+	outputPath := "testdata/output/rich_output.pptx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	slide := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<p:sld xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main">` +
+		`<p:cSld><p:spTree><p:sp><p:txBody><a:bodyPr/>` +
+		`<a:p><a:r><a:rPr lang="en-US" sz="2000"/><a:t>Status: {{STATUS | markdown}}</a:t></a:r></a:p>` +
+		`<a:p><a:pPr algn="l"/><a:r><a:rPr lang="en-US"/><a:t>{{POINTS | markdown}}</a:t></a:r><a:endParaRPr lang="en-US"/></a:p>` +
+		`</p:txBody></p:sp></p:spTree></p:cSld></p:sld>`
+
+	if err := writeTemplateWithPart("testdata/template.pptx", templatePath, "ppt/slides/slide1.xml", slide); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	replacements := map[string]string{
+		"{{STATUS}}": "**on track**, see [report](https://example.com/report)",
+		"{{POINTS}}": "- *Scope* agreed\n- Budget\n\n1. Kickoff",
+	}
+	if err := pptx.ProcessPptxMulti(templatePath, outputPath, replacements); err != nil {
+		t.Fatalf("ProcessPptxMulti failed: %v", err)
+	}
+
+	content, err := readPptxContent(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output content: %v", err)
+	}
+
+	for _, e := range []string{
+		`<a:r><a:rPr lang="en-US" sz="2000"/><a:t>Status: </a:t></a:r><a:r><a:rPr b="1" lang="en-US" sz="2000"/><a:t>on track</a:t></a:r>`,
+		`<a:r><a:rPr lang="en-US" sz="2000"><a:hlinkClick r:id="rId`,
+		`<a:p><a:pPr indent="-342900" marL="342900" algn="l"><a:buFont typeface="Arial"/><a:buChar char="•"/></a:pPr><a:r><a:rPr i="1" lang="en-US"/><a:t>Scope</a:t></a:r><a:r><a:rPr lang="en-US"/><a:t> agreed</a:t></a:r><a:endParaRPr lang="en-US"/></a:p>`,
+		`<a:buAutoNum type="arabicPeriod"/></a:pPr><a:r><a:rPr lang="en-US"/><a:t>Kickoff</a:t></a:r>`,
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`,
+	} {
+		if !strings.Contains(content, e) {
+			t.Errorf("Expected %s in output:\n%s", e, content)
+		}
+	}
+	if err := checkWellFormedXML(content); err != nil {
+		t.Errorf("Output is not well-formed: %v", err)
+	}
+
+	rels, err := readZipPart(outputPath, "ppt/slides/_rels/slide1.xml.rels")
+	if err != nil {
+		t.Fatalf("Failed to read relationships: %v", err)
+	}
+	if !strings.Contains(rels, `Target="https://example.com/report" TargetMode="External"`) {
+		t.Errorf("Expected hyperlink relationship:\n%s", rels)
+	}
+
+	t.Logf("\033[32m✓ Rich text test passed\033[0m")
+}
+
+func TestProcessPptxLinks(t *testing.T) {
+	templatePath := "testdata/output/links_template.pptx"
+	outputPath := "testdata/output/links_output.pptx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	slide := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<p:sld xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main">` +
+		`<p:cSld><p:spTree><p:sp><p:txBody><a:bodyPr/>` +
+		`<a:p><a:r><a:rPr lang="en-US" dirty="0"><a:solidFill><a:srgbClr val="FF0000"/></a:solidFill></a:rPr><a:t>More: {{SITE | link}}</a:t></a:r></a:p>` +
+		`</p:txBody></p:sp></p:spTree></p:cSld></p:sld>`
+
+	if err := writeTemplateWithPart("testdata/template.pptx", templatePath, "ppt/slides/slide1.xml", slide); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	data := pptx.Data{"SITE": pptx.Link{Text: "Acme [site]", URL: "https://acme.example"}}
+	if err := pptx.ProcessPptxData(templatePath, outputPath, data, pptx.Options{}); err != nil {
+		t.Fatalf("ProcessPptxData failed: %v", err)
+	}
+
+	content, err := readPptxContent(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output content: %v", err)
+	}
+
+	expected := `<a:r><a:rPr lang="en-US" dirty="0"><a:solidFill><a:srgbClr val="FF0000"/></a:solidFill><a:hlinkClick r:id="rId2"/></a:rPr><a:t>Acme [site]</a:t></a:r>`
+	if !strings.Contains(content, expected) {
+		t.Errorf("Expected %s in output:\n%s", expected, content)
+	}
+	if err := checkWellFormedXML(content); err != nil {
+		t.Errorf("Output is not well-formed: %v", err)
+	}
+
+	rels, err := readZipPart(outputPath, "ppt/slides/_rels/slide1.xml.rels")
+	if err != nil {
+		t.Fatalf("Failed to read relationships: %v", err)
+	}
+	if !strings.Contains(rels, `Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="https://acme.example" TargetMode="External"`) {
+		t.Errorf("Expected hyperlink relationship:\n%s", rels)
+	}
+
+	t.Logf("\033[32m✓ Links test passed\033[0m")
+}
+
+func TestProcessPptxTables(t *testing.T) {
+	templatePath := "testdata/output/tables_template.pptx"
+	outputPath := "testdata/output/tables_output.pptx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	slide := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<p:sld xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main">` +
+		`<p:cSld><p:spTree>` +
+		`<p:sp><p:nvSpPr><p:cNvPr id="3" name="Content"/><p:cNvSpPr/><p:nvPr/></p:nvSpPr>` +
+		`<p:spPr><a:xfrm><a:off x="838200" y="1825625"/><a:ext cx="9000000" cy="4351338"/></a:xfrm></p:spPr>` +
+		`<p:txBody><a:bodyPr/><a:p><a:r><a:rPr lang="en-US" sz="1400"/><a:t>{{SALES | table:"widths=2,1", "banded"}}</a:t></a:r></a:p></p:txBody></p:sp>` +
+		`</p:spTree></p:cSld></p:sld>`
+
+	if err := writeTemplateWithPart("testdata/template.pptx", templatePath, "ppt/slides/slide1.xml", slide); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	sales, err := pptx.TableFromCSV(strings.NewReader("Region,Sales\nNorth,120\nSouth & East,95\n"))
+	if err != nil {
+		t.Fatalf("TableFromCSV failed: %v", err)
+	}
+	data := pptx.Data{"SALES": sales}
+	if err := pptx.ProcessPptxData(templatePath, outputPath, data, pptx.Options{}); err != nil {
+		t.Fatalf("ProcessPptxData failed: %v", err)
+	}
+
+	content, err := readPptxContent(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output content: %v", err)
+	}
+
+	for _, e := range []string{
+		`<p:graphicFrame><p:nvGraphicFramePr><p:cNvPr id="3" name="Table 3"/>`,
+		`<p:xfrm><a:off x="838200" y="1825625"/><a:ext cx="9000000" cy="1112520"/></p:xfrm>`,
+		`<a:tblPr firstRow="1" bandRow="1"><a:tableStyleId>{5C22544A-7EE6-4342-B048-85BDC9FD1C3A}</a:tableStyleId></a:tblPr>`,
+		`<a:tblGrid><a:gridCol w="6000000"/><a:gridCol w="3000000"/></a:tblGrid>`,
+		`<a:r><a:rPr lang="en-US" sz="1400"/><a:t>South &amp; East</a:t></a:r>`,
+	} {
+		if !strings.Contains(content, e) {
+			t.Errorf("Expected %s in output:\n%s", e, content)
+		}
+	}
+	if strings.Contains(content, "<p:sp>") || strings.Contains(content, "{{") {
+		t.Errorf("Expected the shape to be replaced by the table:\n%s", content)
+	}
+	if err := checkWellFormedXML(content); err != nil {
+		t.Errorf("Output is not well-formed: %v", err)
+	}
+
+	t.Logf("\033[32m✓ Tables test passed\033[0m")
+}
+
+func TestConcatPptx(t *testing.T) {
+	libraryPath := "testdata/output/library.pptx"
+	brandedPath := "testdata/output/branded.pptx"
+	outputPath := "testdata/output/pitch.pptx"
+	rangePath := "testdata/output/range.pptx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	slide := func(body string) string {
+		return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<p:sld xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main">` +
+			`<p:cSld><p:spTree>` + body + `</p:spTree></p:cSld></p:sld>`
+	}
+	text := func(value, link string) string {
+		return `<p:sp><p:txBody><a:p><a:r><a:rPr lang="en-US">` + link + `</a:rPr><a:t>` + value + `</a:t></a:r></a:p></p:txBody></p:sp>`
+	}
+	relType := "http://schemas.openxmlformats.org/officeDocument/2006/relationships/"
+	rels := func(entries string) string {
+		return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` + entries + `</Relationships>`
+	}
+	slideType := "application/vnd.openxmlformats-officedocument.presentationml.slide+xml"
+
+	presentation, err := readZipPart("testdata/template.pptx", "ppt/presentation.xml")
+	if err != nil {
+		t.Fatalf("Failed to read presentation: %v", err)
+	}
+	presentationRels, err := readZipPart("testdata/template.pptx", "ppt/_rels/presentation.xml.rels")
+	if err != nil {
+		t.Fatalf("Failed to read relationships: %v", err)
+	}
+	types, err := readZipPart("testdata/template.pptx", "[Content_Types].xml")
+	if err != nil {
+		t.Fatalf("Failed to read content types: %v", err)
+	}
+	master, err := readZipPart("testdata/template.pptx", "ppt/slideMasters/slideMaster1.xml")
+	if err != nil {
+		t.Fatalf("Failed to read slide master: %v", err)
+	}
+
+	// A library of three slides: a cover, a chart with a picture and one linking to the cover
+	if err := writeTemplateWithParts("testdata/template.pptx", libraryPath, map[string]string{
+		"ppt/slides/slide1.xml": slide(text("Library cover", "")),
+		"ppt/slides/slide2.xml": slide(text("Sales", "") + `<p:pic><p:blipFill><a:blip r:embed="rId2"/></p:blipFill></p:pic>` +
+			`<p:graphicFrame><a:graphic><a:graphicData><c:chart xmlns:c="http://schemas.openxmlformats.org/drawingml/2006/chart" r:id="rId3"/></a:graphicData></a:graphic></p:graphicFrame>`),
+		"ppt/slides/slide3.xml":                         slide(text("Back to the cover", `<a:hlinkClick r:id="rId2" action="ppaction://hlinksldjump"/>`)),
+		"ppt/slides/_rels/slide2.xml.rels":              rels(`<Relationship Id="rId1" Type="` + relType + `slideLayout" Target="../slideLayouts/slideLayout2.xml"/><Relationship Id="rId2" Type="` + relType + `image" Target="../media/image1.png"/><Relationship Id="rId3" Type="` + relType + `chart" Target="../charts/chart1.xml"/>`),
+		"ppt/slides/_rels/slide3.xml.rels":              rels(`<Relationship Id="rId1" Type="` + relType + `slideLayout" Target="../slideLayouts/slideLayout2.xml"/><Relationship Id="rId2" Type="` + relType + `slide" Target="slide1.xml"/>`),
+		"ppt/media/image1.png":                          "logo",
+		"ppt/charts/chart1.xml":                         `<?xml version="1.0" encoding="UTF-8" standalone="yes"?><c:chartSpace xmlns:c="http://schemas.openxmlformats.org/drawingml/2006/chart" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><c:externalData r:id="rId1"/></c:chartSpace>`,
+		"ppt/charts/_rels/chart1.xml.rels":              rels(`<Relationship Id="rId1" Type="` + relType + `package" Target="../embeddings/Microsoft_Excel_Worksheet.xlsx"/>`),
+		"ppt/embeddings/Microsoft_Excel_Worksheet.xlsx": "workbook",
+		"ppt/presentation.xml":                          strings.Replace(presentation, `<p:sldId id="256" r:id="rId2"/>`, `<p:sldId id="256" r:id="rId2"/><p:sldId id="257" r:id="rId7"/><p:sldId id="258" r:id="rId8"/>`, 1),
+		"ppt/_rels/presentation.xml.rels": strings.Replace(presentationRels, "</Relationships>",
+			`<Relationship Id="rId7" Type="`+relType+`slide" Target="slides/slide2.xml"/><Relationship Id="rId8" Type="`+relType+`slide" Target="slides/slide3.xml"/></Relationships>`, 1),
+		"[Content_Types].xml": strings.Replace(types, "</Types>", `<Default Extension="png" ContentType="image/png"/>`+
+			`<Default Extension="xlsx" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"/>`+
+			`<Override PartName="/ppt/slides/slide2.xml" ContentType="`+slideType+`"/><Override PartName="/ppt/slides/slide3.xml" ContentType="`+slideType+`"/>`+
+			`<Override PartName="/ppt/charts/chart1.xml" ContentType="application/vnd.openxmlformats-officedocument.drawingml.chart+xml"/></Types>`, 1),
+	}); err != nil {
+		t.Fatalf("Failed to create library: %v", err)
+	}
+
+	// A deck whose master differs from the template's
+	if err := writeTemplateWithParts("testdata/template.pptx", brandedPath, map[string]string{
+		"ppt/slides/slide1.xml":             slide(text("Branded closing", "")),
+		"ppt/slideMasters/slideMaster1.xml": strings.Replace(master, "<p:sldMaster ", `<p:sldMaster preserve="1" `, 1),
+	}); err != nil {
+		t.Fatalf("Failed to create branded deck: %v", err)
+	}
+
+	open := func(path string) *os.File {
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", path, err)
+		}
+		return file
+	}
+	template, library, again, branded := open("testdata/template.pptx"), open(libraryPath), open(libraryPath), open(brandedPath)
+	defer template.Close()
+	defer library.Close()
+	defer again.Close()
+	defer branded.Close()
+
+	var buf bytes.Buffer
+	err = pptx.ConcatDecks(&buf,
+		pptx.Deck{Input: template},
+		pptx.Deck{Input: library, Slides: "2-3"},
+		pptx.Deck{Input: again, Slides: "2"},
+		pptx.Deck{Input: branded},
+	)
+	if err != nil {
+		t.Fatalf("ConcatDecks failed: %v", err)
+	}
+	if err := os.WriteFile(outputPath, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write output: %v", err)
+	}
+
+	read := func(path, part string) string {
+		content, err := readZipPart(path, part)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", part, err)
+		}
+		return content
+	}
+
+	outputPresentation := read(outputPath, "ppt/presentation.xml")
+	if !strings.Contains(outputPresentation, `<p:sldIdLst><p:sldId id="256" r:id="rId2"/><p:sldId id="257" r:id="rId7"/><p:sldId id="258" r:id="rId8"/><p:sldId id="259" r:id="rId9"/><p:sldId id="260" r:id="rId11"/></p:sldIdLst>`) {
+		t.Errorf("Expected five slides in order:\n%s", outputPresentation)
+	}
+	if !strings.Contains(outputPresentation, `<p:sldMasterId id="2147483648" r:id="rId1"/><p:sldMasterId id="2147483660" r:id="rId10"/></p:sldMasterIdLst>`) {
+		t.Errorf("Expected the branded master to be added:\n%s", outputPresentation)
+	}
+
+	// The library's master is the template's: its slides use the template's layouts
+	if slideRels := read(outputPath, "ppt/slides/_rels/slide2.xml.rels"); !strings.Contains(slideRels, `Target="../slideLayouts/slideLayout2.xml"`) ||
+		!strings.Contains(slideRels, `Target="../media/image1.png"`) || !strings.Contains(slideRels, `Id="rId3" Type="`+relType+`chart" Target="../charts/chart1.xml"`) {
+		t.Errorf("Expected the second slide's layout, picture and chart:\n%s", slideRels)
+	}
+
+	// The link to a slide that wasn't taken is removed
+	if slideRels := read(outputPath, "ppt/slides/_rels/slide3.xml.rels"); strings.Contains(slideRels, relType+"slide\"") {
+		t.Errorf("Expected the link to the library cover to be removed:\n%s", slideRels)
+	}
+	if content := read(outputPath, "ppt/slides/slide3.xml"); strings.Contains(content, "hlinkClick") || !strings.Contains(content, "Back to the cover") {
+		t.Errorf("Expected the slide without its link:\n%s", content)
+	}
+
+	// The second copy of the chart gets new parts; the identical picture is shared
+	if slideRels := read(outputPath, "ppt/slides/_rels/slide4.xml.rels"); !strings.Contains(slideRels, `Target="../charts/chart2.xml"`) || !strings.Contains(slideRels, `Target="../media/image1.png"`) {
+		t.Errorf("Expected a copy of the chart:\n%s", slideRels)
+	}
+	if chartRels := read(outputPath, "ppt/charts/_rels/chart2.xml.rels"); !strings.Contains(chartRels, `Target="../embeddings/Microsoft_Excel_Worksheet1.xlsx"`) {
+		t.Errorf("Expected a copy of the chart workbook:\n%s", chartRels)
+	}
+
+	// The branded master is copied with its theme and the one layout used
+	brandedMaster := read(outputPath, "ppt/slideMasters/slideMaster2.xml")
+	if !strings.Contains(brandedMaster, `<p:sldLayoutIdLst><p:sldLayoutId id="2147483661" r:id="rId13"/></p:sldLayoutIdLst>`) {
+		t.Errorf("Expected a single layout in the branded master:\n%s", brandedMaster)
+	}
+	if masterRels := read(outputPath, "ppt/slideMasters/_rels/slideMaster2.xml.rels"); !strings.Contains(masterRels, `Target="../theme/theme2.xml"`) || !strings.Contains(masterRels, `Target="../slideLayouts/slideLayout12.xml"`) {
+		t.Errorf("Expected the branded master's theme and layout:\n%s", masterRels)
+	}
+	if layoutRels := read(outputPath, "ppt/slideLayouts/_rels/slideLayout12.xml.rels"); !strings.Contains(layoutRels, `Target="../slideMasters/slideMaster2.xml"`) {
+		t.Errorf("Expected the layout to use the branded master:\n%s", layoutRels)
+	}
+
+	outputTypes := read(outputPath, "[Content_Types].xml")
+	for _, part := range []string{"/ppt/slides/slide5.xml", "/ppt/slideMasters/slideMaster2.xml", "/ppt/slideLayouts/slideLayout12.xml", "/ppt/theme/theme2.xml", "/ppt/charts/chart2.xml"} {
+		if !strings.Contains(outputTypes, `PartName="`+part+`"`) {
+			t.Errorf("Expected a content type for %s:\n%s", part, outputTypes)
+		}
+	}
+	if !strings.Contains(outputTypes, `Extension="png"`) || !strings.Contains(outputTypes, `Extension="xlsx"`) {
+		t.Errorf("Expected media content types:\n%s", outputTypes)
+	}
+	if app := read(outputPath, "docProps/app.xml"); !strings.Contains(app, "<Slides>5</Slides>") {
+		t.Errorf("Expected the slide count to be updated:\n%s", app)
+	}
+
+	// A range of the first deck drops its other slides and what only they used
+	library.Seek(0, 0)
+	template.Seek(0, 0)
+	buf.Reset()
+	if err := pptx.ConcatDecks(&buf, pptx.Deck{Input: library, Slides: "3"}, pptx.Deck{Input: template}); err != nil {
+		t.Fatalf("ConcatDecks with a range failed: %v", err)
+	}
+	if err := os.WriteFile(rangePath, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write output: %v", err)
+	}
+	content, err := readPptxContent(rangePath)
+	if err != nil {
+		t.Fatalf("Failed to read output content: %v", err)
+	}
+	if strings.Contains(content, "Library cover") || strings.Contains(content, "Sales") || !strings.Contains(content, "Back to the cover") || !strings.Contains(content, "{{NAME}}") {
+		t.Errorf("Expected the third library slide and the template slide:\n%s", content)
+	}
+	for _, part := range []string{"ppt/charts/chart1.xml", "ppt/media/image1.png", "ppt/embeddings/Microsoft_Excel_Worksheet.xlsx"} {
+		if _, err := readZipPart(rangePath, part); err == nil {
+			t.Errorf("Expected %s to be removed", part)
+		}
+	}
+
+	library.Seek(0, 0)
+	if err := pptx.ConcatDecks(&buf, pptx.Deck{Input: library, Slides: "2-9"}); err == nil {
+		t.Error("Expected an error for slides outside the deck")
+	}
+
+	t.Logf("\033[32m✓ Concat test passed\033[0m")
+}
+
+func TestExportPptx(t *testing.T) {
+	inputPath := "testdata/output/talk.pptx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	slide := func(body string) string {
+		return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<p:sld xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main">` +
+			`<p:cSld><p:spTree>` + body + `</p:spTree></p:cSld></p:sld>`
+	}
+	shape := func(placeholder, paragraphs string) string {
+		return `<p:sp><p:nvSpPr><p:cNvPr id="2" name="Shape"/><p:cNvSpPr/><p:nvPr>` + placeholder + `</p:nvPr></p:nvSpPr>` +
+			`<p:txBody><a:bodyPr/><a:lstStyle/>` + paragraphs + `</p:txBody></p:sp>`
+	}
+	relType := "http://schemas.openxmlformats.org/officeDocument/2006/relationships/"
+
+	presentation, err := readZipPart("testdata/template.pptx", "ppt/presentation.xml")
+	if err != nil {
+		t.Fatalf("Failed to read presentation: %v", err)
+	}
+	presentationRels, err := readZipPart("testdata/template.pptx", "ppt/_rels/presentation.xml.rels")
+	if err != nil {
+		t.Fatalf("Failed to read relationships: %v", err)
+	}
+	types, err := readZipPart("testdata/template.pptx", "[Content_Types].xml")
+	if err != nil {
+		t.Fatalf("Failed to read content types: %v", err)
+	}
+	slideRels, err := readZipPart("testdata/template.pptx", "ppt/slides/_rels/slide1.xml.rels")
+	if err != nil {
+		t.Fatalf("Failed to read slide relationships: %v", err)
+	}
+
+	// A title slide with bullets, then a slide with a table and a numbered list
+	if err := writeTemplateWithParts("testdata/template.pptx", inputPath, map[string]string{
+		"ppt/slides/slide1.xml": slide(shape(`<p:ph type="title"/>`, `<a:p><a:r><a:rPr lang="en-US"/><a:t>Quarterly review</a:t></a:r></a:p>`) +
+			shape(`<p:ph idx="1"/>`, `<a:p><a:r><a:rPr lang="en-US" b="1"/><a:t>Revenue</a:t></a:r><a:r><a:rPr lang="en-US"/><a:t xml:space="preserve"> up 8%</a:t></a:r></a:p>`+
+				`<a:p><a:pPr lvl="1"/><a:r><a:rPr lang="en-US"><a:hlinkClick r:id="rId2"/></a:rPr><a:t>See the report</a:t></a:r></a:p>`+
+				`<a:p><a:pPr><a:buNone/></a:pPr><a:r><a:rPr lang="en-US" i="1"/><a:t>Figures unaudited</a:t></a:r></a:p>`)),
+		"ppt/slides/_rels/slide1.xml.rels": strings.Replace(slideRels, "</Relationships>",
+			`<Relationship Id="rId2" Type="`+relType+`hyperlink" Target="https://example.com/q3" TargetMode="External"/></Relationships>`, 1),
+		"ppt/slides/slide2.xml": slide(shape(`<p:ph type="title"/>`, `<a:p><a:r><a:rPr lang="en-US"/><a:t>Next steps</a:t></a:r></a:p>`) +
+			`<p:graphicFrame><a:graphic><a:graphicData><a:tbl><a:tblPr firstRow="1"/><a:tblGrid><a:gridCol w="100"/><a:gridCol w="100"/></a:tblGrid>` +
+			`<a:tr h="10"><a:tc><a:txBody><a:bodyPr/><a:p><a:r><a:rPr lang="en-US"/><a:t>Owner</a:t></a:r></a:p></a:txBody></a:tc><a:tc><a:txBody><a:bodyPr/><a:p><a:r><a:rPr lang="en-US"/><a:t>Task</a:t></a:r></a:p></a:txBody></a:tc></a:tr>` +
+			`<a:tr h="10"><a:tc><a:txBody><a:bodyPr/><a:p><a:r><a:rPr lang="en-US"/><a:t>Ana</a:t></a:r></a:p></a:txBody></a:tc><a:tc><a:txBody><a:bodyPr/><a:p><a:r><a:rPr lang="en-US"/><a:t>Hire</a:t></a:r></a:p></a:txBody></a:tc></a:tr>` +
+			`</a:tbl></a:graphicData></a:graphic></p:graphicFrame>` +
+			shape(``, `<a:p><a:pPr><a:buAutoNum type="arabicPeriod"/></a:pPr><a:r><a:rPr lang="en-US"/><a:t>Plan</a:t></a:r></a:p>`+
+				`<a:p><a:pPr><a:buAutoNum type="arabicPeriod"/></a:pPr><a:r><a:rPr lang="en-US"/><a:t>Ship</a:t></a:r></a:p>`)),
+		"ppt/slides/_rels/slide2.xml.rels": slideRels,
+		"ppt/presentation.xml":             strings.Replace(presentation, `<p:sldId id="256" r:id="rId2"/>`, `<p:sldId id="256" r:id="rId2"/><p:sldId id="257" r:id="rId7"/>`, 1),
+		"ppt/_rels/presentation.xml.rels": strings.Replace(presentationRels, "</Relationships>",
+			`<Relationship Id="rId7" Type="`+relType+`slide" Target="slides/slide2.xml"/></Relationships>`, 1),
+		"[Content_Types].xml": strings.Replace(types, "</Types>",
+			`<Override PartName="/ppt/slides/slide2.xml" ContentType="application/vnd.openxmlformats-officedocument.presentationml.slide+xml"/></Types>`, 1),
+	}); err != nil {
+		t.Fatalf("Failed to create presentation: %v", err)
+	}
+
+	text, err := pptx.ExtractText(inputPath)
+	if err != nil {
+		t.Fatalf("ExtractText failed: %v", err)
+	}
+	expectedText := "Quarterly review\n• Revenue up 8%\n  • See the report\nFigures unaudited\n\n" +
+		"Next steps\nOwner\tTask\nAna\tHire\n1. Plan\n2. Ship\n"
+	if text != expectedText {
+		t.Errorf("Expected text:\n%q\ngot:\n%q", expectedText, text)
+	}
+
+	markdown, err := pptx.ExportMarkdown(inputPath)
+	if err != nil {
+		t.Fatalf("ExportMarkdown failed: %v", err)
+	}
+	expectedMarkdown := "## Quarterly review\n\n- **Revenue** up 8%\n    - [See the report](https://example.com/q3)\n\n*Figures unaudited*\n\n---\n\n" +
+		"## Next steps\n\n| Owner | Task |\n| --- | --- |\n| Ana | Hire |\n\n1. Plan\n2. Ship\n"
+	if markdown != expectedMarkdown {
+		t.Errorf("Expected Markdown:\n%s\ngot:\n%s", expectedMarkdown, markdown)
+	}
+
+	html, err := pptx.ExportHTML(inputPath)
+	if err != nil {
+		t.Fatalf("ExportHTML failed: %v", err)
+	}
+	for _, expected := range []string{
+		"<h2>Quarterly review</h2>\n<ul>\n<li><strong>Revenue</strong> up 8%",
+		`<li><a href="https://example.com/q3">See the report</a></li>`,
+		"<p><em>Figures unaudited</em></p>\n<hr>\n<h2>Next steps</h2>",
+		"<tr><th>Owner</th><th>Task</th></tr>",
+		"<ol>\n<li>Plan</li>\n<li>Ship</li>\n</ol>",
+	} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("Expected HTML to contain %q:\n%s", expected, html)
+		}
+	}
+
+	t.Logf("\033[32m✓ Export test passed\033[0m")
+}
+
+func TestPptxProperties(t *testing.T) {
+	templatePath := "testdata/template.pptx"
+	outputPath := "testdata/output/properties.pptx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	opts := pptx.Options{Properties: map[string]string{"title": "{{COMPANY}} pitch", "Manager": "{{NAME}}", "Region": "EMEA"}}
+	if err := pptx.ProcessPptxMultiWithOptions(templatePath, outputPath, map[string]string{"{{COMPANY}}": "Acme", "{{NAME}}": "Jane"}, opts); err != nil {
+		t.Fatalf("ProcessPptxMultiWithOptions failed: %v", err)
+	}
+
+	props, err := pptx.ReadProperties(outputPath)
+	if err != nil {
+		t.Fatalf("ReadProperties failed: %v", err)
+	}
+	if props.Core["title"] != "Acme pitch" || props.App["Manager"] != "Jane" || props.Custom["Region"] != "EMEA" {
+		t.Errorf("Expected the rendered properties, got %v %v %v", props.Core, props.App, props.Custom)
+	}
+	if props.App["Slides"] == "" {
+		t.Errorf("Expected the other extended properties to be kept, got %v", props.App)
+	}
+
+	t.Logf("\033[32m✓ Properties test passed\033[0m")
+}
+
+func TestScrubPptx(t *testing.T) {
+	inputPath := "testdata/output/review.pptx"
+	outputPath := "testdata/output/clean.pptx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	read := func(path, part string) string {
+		content, err := readZipPart(path, part)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", part, err)
+		}
+		return content
+	}
+
+	relType := "http://schemas.openxmlformats.org/officeDocument/2006/relationships/"
+	if err := writeTemplateWithParts("testdata/template.pptx", inputPath, map[string]string{
+		"ppt/comments/comment1.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<p:cmLst xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main">` +
+			`<p:cm authorId="0" idx="1"><p:pos x="10" y="10"/><p:text>Update the figures</p:text></p:cm>` +
+			`<p:cm authorId="0" idx="2"><p:pos x="20" y="20"/><p:text>Check the logo</p:text></p:cm></p:cmLst>`,
+		"ppt/commentAuthors.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<p:cmAuthorLst xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main">` +
+			`<p:cmAuthor id="0" name="Sales Lead" initials="SL" lastIdx="2" clrIdx="0"/></p:cmAuthorLst>`,
+		"ppt/slides/_rels/slide1.xml.rels": strings.Replace(read("testdata/template.pptx", "ppt/slides/_rels/slide1.xml.rels"), "</Relationships>",
+			`<Relationship Id="rId90" Type="`+relType+`comments" Target="../comments/comment1.xml"/></Relationships>`, 1),
+		"ppt/_rels/presentation.xml.rels": strings.Replace(read("testdata/template.pptx", "ppt/_rels/presentation.xml.rels"), "</Relationships>",
+			`<Relationship Id="rId90" Type="`+relType+`commentAuthors" Target="commentAuthors.xml"/></Relationships>`, 1),
+		"[Content_Types].xml": strings.Replace(read("testdata/template.pptx", "[Content_Types].xml"), "</Types>",
+			`<Override PartName="/ppt/comments/comment1.xml" ContentType="application/vnd.openxmlformats-officedocument.presentationml.comments+xml"/>`+
+				`<Override PartName="/ppt/commentAuthors.xml" ContentType="application/vnd.openxmlformats-officedocument.presentationml.commentAuthors+xml"/></Types>`, 1),
+	}); err != nil {
+		t.Fatalf("Failed to create presentation: %v", err)
+	}
+
+	report, err := pptx.Scrub(inputPath, outputPath, pptx.ScrubOptions{KeepPersonalInfo: true})
+	if err != nil {
+		t.Fatalf("Scrub failed: %v", err)
+	}
+	expected := map[string]int{"comments": 2, "thumbnails": 1}
+	for _, item := range report.Items {
+		if want, ok := expected[item.What]; ok && want != item.Count {
+			t.Errorf("Expected %d %s, got %d", want, item.What, item.Count)
+		}
+		delete(expected, item.What)
+	}
+	if len(expected) > 0 {
+		t.Errorf("Expected the report to list %v, got:\n%s", expected, report)
+	}
+
+	for _, part := range []string{"ppt/comments/comment1.xml", "ppt/commentAuthors.xml", "docProps/thumbnail.jpeg"} {
+		if _, err := readZipPart(outputPath, part); err == nil {
+			t.Errorf("Expected %s to be removed", part)
+		}
+	}
+	for part, trace := range map[string]string{
+		"ppt/slides/_rels/slide1.xml.rels": "comment1.xml",
+		"ppt/_rels/presentation.xml.rels":  "commentAuthors",
+		"_rels/.rels":                      "thumbnail",
+		"[Content_Types].xml":              "comment",
+	} {
+		if strings.Contains(read(outputPath, part), trace) {
+			t.Errorf("Expected %s to be removed from %s", trace, part)
+		}
+	}
+	if read(outputPath, "docProps/core.xml") != read(inputPath, "docProps/core.xml") {
+		t.Error("Expected the core properties to be kept")
+	}
+
+	t.Logf("\033[32m✓ Scrub test passed\033[0m")
+}
+
 // Benchmark tests
 func BenchmarkProcessPptxSingle(b *testing.B) {
 	templatePath := "testdata/template.pptx"
@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -377,6 +378,678 @@ func TestProcessXlsxSpecialCharacters(t *testing.T) {
 	t.Logf("\033[32m✓ Special characters test passed\033[0m")
 }
 
+func TestProcessXlsxPlaceholderSplitAcrossRuns(t *testing.T) {
+	templatePath := "testdata/output/split_runs_template.xlsx"
+	outputPath := "testdata/output/split_runs_output.xlsx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	// Rich text shared string with {{NAME}} split over two runs
+	sharedStrings := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="1" uniqueCount="1">` +
+		`<si><r><rPr><b/></rPr><t>{{NA</t></r><r><rPr><i/></rPr><t>ME}} joined</t></r></si>` +
+		`</sst>`
+
+	if err := writeTemplateWithPart("testdata/template.xlsx", templatePath, "xl/sharedStrings.xml", sharedStrings); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	err := xlsx.ProcessXlsxMulti(templatePath, outputPath, map[string]string{"{{NAME}}": "Jane Smith"})
+	if err != nil {
+		t.Fatalf("ProcessXlsxMulti failed: %v", err)
+	}
+
+	content, err := readXlsxContent(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output content: %v", err)
+	}
+
+	if err := checkWellFormedXML(content); err != nil {
+		t.Fatalf("Output is not well-formed XML: %v", err)
+	}
+
+	expected := `<si><r><rPr><b/></rPr><t>Jane Smith</t></r><r><rPr><i/></rPr><t xml:space="preserve"> joined</t></r></si>`
+	if !strings.Contains(content, expected) {
+		t.Errorf("Expected %s in output:\n%s", expected, content)
+	}
+
+	t.Logf("\033[32m✓ Split placeholder test passed\033[0m")
+}
+
+func TestProcessXlsxFilters(t *testing.T) {
+	templatePath := "testdata/output/filters_template.xlsx"
+	outputPath := "testdata/output/filters_output.xlsx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	sharedStrings := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="3" uniqueCount="3">` +
+		`<si><t>{{PRICE | currency:"JPY"}}</t></si>` +
+		`<si><t>{{CODE | lower}}</t></si>` +
+		`<si><t>{{PRICE | number}}</t></si>` +
+		`</sst>`
+
+	if err := writeTemplateWithPart("testdata/template.xlsx", templatePath, "xl/sharedStrings.xml", sharedStrings); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	replacements := map[string]string{"{{PRICE}}": "1500000", "{{CODE}}": "SKU-42"}
+	if err := xlsx.ProcessXlsxMulti(templatePath, outputPath, replacements); err != nil {
+		t.Fatalf("ProcessXlsxMulti failed: %v", err)
+	}
+
+	content, err := readXlsxContent(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output content: %v", err)
+	}
+
+	for _, e := range []string{"<t>¥1,500,000</t>", "<t>sku-42</t>", "<t>1,500,000</t>"} {
+		if !strings.Contains(content, e) {
+			t.Errorf("Expected %s in output:\n%s", e, content)
+		}
+	}
+
+	t.Logf("\033[32m✓ Filters test passed\033[0m")
+}
+
+func TestProcessXlsxLocale(t *testing.T) {
+	templatePath := "testdata/output/locale_template.xlsx"
+	outputPath := "testdata/output/locale_output.xlsx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	sharedStrings := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="2" uniqueCount="2">` +
+		`<si><t>{{PRICE | currency}}</t></si>` +
+		`<si><t>{{DAY | date:"2 January 2006"}}</t></si>` +
+		`</sst>`
+
+	if err := writeTemplateWithPart("testdata/template.xlsx", templatePath, "xl/sharedStrings.xml", sharedStrings); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	replacements := map[string]string{"{{PRICE}}": "-9876543.219", "{{DAY}}": "2025-05-17"}
+	if err := xlsx.ProcessXlsxMultiWithOptions(templatePath, outputPath, replacements, xlsx.Options{Locale: "pl"}); err != nil {
+		t.Fatalf("ProcessXlsxMultiWithOptions failed: %v", err)
+	}
+
+	content, err := readXlsxContent(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output content: %v", err)
+	}
+
+	for _, e := range []string{"<t>-9\u00a0876\u00a0543,22 zł</t>", "<t>17 maja 2025</t>"} {
+		if !strings.Contains(content, e) {
+			t.Errorf("Expected %q in output:\n%s", e, content)
+		}
+	}
+
+	t.Logf("\033[32m✓ Locale test passed\033[0m")
+}
+
+func TestProcessXlsxDataRecords(t *testing.T) {
+	templatePath := "testdata/output/data_template.xlsx"
+	outputDir := "testdata/output/data_records"
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	sharedStrings := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="1" uniqueCount="1">` +
+		`<si><t>{{order.id}}: {{order.lines[0].sku}} ({{order.rush}})</t></si>` +
+		`</sst>`
+
+	if err := writeTemplateWithPart("testdata/template.xlsx", templatePath, "xl/sharedStrings.xml", sharedStrings); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	records := []xlsx.Data{
+		{"order": map[string]any{"id": 1001, "rush": false, "lines": []map[string]any{{"sku": "A-1"}}}},
+		{"order": map[string]any{"id": 1002, "rush": true, "lines": []map[string]any{{"sku": "B-2"}}}},
+	}
+
+	if err := xlsx.ProcessXlsxDataRecords(templatePath, outputDir, records, "order_{{order.id}}.xlsx", xlsx.Options{}); err != nil {
+		t.Fatalf("ProcessXlsxDataRecords failed: %v", err)
+	}
+
+	expected := map[string]string{
+		"order_1001.xlsx": "1001: A-1 (false)",
+		"order_1002.xlsx": "1002: B-2 (true)",
+	}
+	for name, text := range expected {
+		content, err := readXlsxContent(filepath.Join(outputDir, name))
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", name, err)
+		}
+		if !strings.Contains(content, text) {
+			t.Errorf("Expected %q in %s:\n%s", text, name, content)
+		}
+	}
+
+	t.Logf("\033[32m✓ Data records test passed\033[0m")
+}
+
+func TestProcessXlsxRichText(t *testing.T) {
+	templatePath := "testdata/output/rich_template.xlsx"
+	outputPath := "testdata/output/rich_output.xlsx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	sharedStrings := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="2" uniqueCount="2">` +
+		`<si><t>{{NOTES | markdown}}</t></si>` +
+		`<si><t>{{BODY | html}}</t></si>` +
+		`</sst>`
+
+	if err := writeTemplateWithPart("testdata/template.xlsx", templatePath, "xl/sharedStrings.xml", sharedStrings); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	replacements := map[string]string{
+		"{{NOTES}}": "**Paid** via [portal](https://example.com)\n\n1. Invoice\n2. Receipt",
+		"{{BODY}}":  "<b>Hi</b> &lt;team&gt;<br>Thanks",
+	}
+	if err := xlsx.ProcessXlsxMulti(templatePath, outputPath, replacements); err != nil {
+		t.Fatalf("ProcessXlsxMulti failed: %v", err)
+	}
+
+	content, err := readXlsxContent(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output content: %v", err)
+	}
+
+	// Cells can't hold formatted runs here, so the values are written as plain text
+	for _, e := range []string{"<t>Paid via portal\n1. Invoice\n2. Receipt</t>", "<t>Hi &lt;team&gt;\nThanks</t>"} {
+		if !strings.Contains(content, e) {
+			t.Errorf("Expected %s in output:\n%s", e, content)
+		}
+	}
+
+	t.Logf("\033[32m✓ Rich text test passed\033[0m")
+}
+
+func TestProcessXlsxLinks(t *testing.T) {
+	templatePath := "testdata/output/links_template.xlsx"
+	outputPath := "testdata/output/links_output.xlsx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	// Cells A1 and B1 of the template show strings 0 and 1
+	sharedStrings := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="2" uniqueCount="2">` +
+		`<si><t>{{PORTAL | link:"Portal"}}</t></si>` +
+		`<si><t>{{EMAIL | link}}</t></si>` +
+		`</sst>`
+
+	if err := writeTemplateWithPart("testdata/template.xlsx", templatePath, "xl/sharedStrings.xml", sharedStrings); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	replacements := map[string]string{"{{PORTAL}}": "https://portal.example.com", "{{EMAIL}}": "mailto:jane@example.com"}
+	if err := xlsx.ProcessXlsxMulti(templatePath, outputPath, replacements); err != nil {
+		t.Fatalf("ProcessXlsxMulti failed: %v", err)
+	}
+
+	content, err := readXlsxContent(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output content: %v", err)
+	}
+	for _, e := range []string{"<t>Portal</t>", "<t>jane@example.com</t>"} {
+		if !strings.Contains(content, e) {
+			t.Errorf("Expected %s in output:\n%s", e, content)
+		}
+	}
+
+	sheet, err := readZipPart(outputPath, "xl/worksheets/sheet1.xml")
+	if err != nil {
+		t.Fatalf("Failed to read worksheet: %v", err)
+	}
+	expected := `</sheetData><hyperlinks><hyperlink ref="A1" r:id="rId1"/><hyperlink ref="B1" r:id="rId2"/></hyperlinks><pageMargins`
+	if !strings.Contains(sheet, expected) {
+		t.Errorf("Expected %s in worksheet:\n%s", expected, sheet)
+	}
+	if err := checkWellFormedXML(sheet); err != nil {
+		t.Errorf("Worksheet is not well-formed: %v", err)
+	}
+
+	rels, err := readZipPart(outputPath, "xl/worksheets/_rels/sheet1.xml.rels")
+	if err != nil {
+		t.Fatalf("Failed to read worksheet relationships: %v", err)
+	}
+	for _, e := range []string{`Target="https://portal.example.com" TargetMode="External"`, `Target="mailto:jane@example.com" TargetMode="External"`} {
+		if !strings.Contains(rels, e) {
+			t.Errorf("Expected %s in relationships:\n%s", e, rels)
+		}
+	}
+
+	t.Logf("\033[32m✓ Links test passed\033[0m")
+}
+
+func TestConcatXlsx(t *testing.T) {
+	regionPath := "testdata/output/north.xlsx"
+	outputPath := "testdata/output/all.xlsx"
+	selectedPath := "testdata/output/selected.xlsx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	read := func(path, part string) string {
+		content, err := readZipPart(path, part)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", part, err)
+		}
+		return content
+	}
+	workbook := read("testdata/template.xlsx", "xl/workbook.xml")
+	styles := read("testdata/template.xlsx", "xl/styles.xml")
+	workbookRels := read("testdata/template.xlsx", "xl/_rels/workbook.xml.rels")
+	types := read("testdata/template.xlsx", "[Content_Types].xml")
+
+	sheet := func(data string) string {
+		return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheetViews><sheetView tabSelected="1" workbookViewId="0"/></sheetViews><sheetData>` + data + `</sheetData></worksheet>`
+	}
+
+	// A regional report: a summary in a custom number format and bold font, and its detail
+	if err := writeTemplateWithParts("testdata/template.xlsx", regionPath, map[string]string{
+		"xl/sharedStrings.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="3" uniqueCount="2"><si><t>North</t></si><si><t>Total</t></si></sst>`,
+		"xl/styles.xml": strings.NewReplacer(
+			`<fonts count="1" x14ac:knownFonts="1">`, `<numFmts count="1"><numFmt numFmtId="164" formatCode="#,##0.00\ &quot;€&quot;"/></numFmts><fonts count="2" x14ac:knownFonts="1">`,
+			`</font></fonts>`, `</font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>`,
+			`<cellXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/></cellXfs>`,
+			`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="164" fontId="1" fillId="0" borderId="0" xfId="0" applyNumberFormat="1" applyFont="1"/></cellXfs>`,
+		).Replace(styles),
+		"xl/worksheets/sheet1.xml": sheet(`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" s="1"><v>1200</v></c></row>` +
+			`<row r="2"><c r="A2" t="s"><v>1</v></c><c r="B2" s="1"><f>Detail!B1*2</f><v>1200</v></c></row>`),
+		"xl/worksheets/sheet2.xml": sheet(`<row r="1"><c r="A1" t="s"><v>1</v></c><c r="B1"><v>600</v></c></row>`),
+		"xl/workbook.xml": strings.Replace(workbook, `<sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets>`,
+			`<sheet name="Summary" sheetId="1" r:id="rId1"/><sheet name="Detail" sheetId="2" r:id="rId5"/></sheets>`+
+				`<definedNames><definedName name="_xlnm.Print_Area" localSheetId="0">Summary!$A$1:$B$2</definedName></definedNames>`, 1),
+		"xl/_rels/workbook.xml.rels": strings.Replace(workbookRels, "</Relationships>",
+			`<Relationship Id="rId5" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet2.xml"/></Relationships>`, 1),
+		"[Content_Types].xml": strings.Replace(types, "</Types>",
+			`<Override PartName="/xl/worksheets/sheet2.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`, 1),
+	}); err != nil {
+		t.Fatalf("Failed to create workbook: %v", err)
+	}
+
+	open := func(path string) *os.File {
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", path, err)
+		}
+		return file
+	}
+	template, region, again := open("testdata/template.xlsx"), open(regionPath), open(regionPath)
+	defer template.Close()
+	defer region.Close()
+	defer again.Close()
+
+	var buf bytes.Buffer
+	if err := xlsx.Concat(&buf, template, region, again); err != nil {
+		t.Fatalf("Concat failed: %v", err)
+	}
+	if err := os.WriteFile(outputPath, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write output: %v", err)
+	}
+
+	outputWorkbook := read(outputPath, "xl/workbook.xml")
+	expected := `<sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/><sheet name="Summary" sheetId="2" r:id="rId5"/><sheet name="Detail" sheetId="3" r:id="rId6"/>` +
+		`<sheet name="Summary (2)" sheetId="4" r:id="rId7"/><sheet name="Detail (2)" sheetId="5" r:id="rId8"/></sheets>` +
+		`<definedNames><definedName name="_xlnm.Print_Area" localSheetId="1">Summary!$A$1:$B$2</definedName>` +
+		`<definedName name="_xlnm.Print_Area" localSheetId="3">'Summary (2)'!$A$1:$B$2</definedName></definedNames>`
+	if !strings.Contains(outputWorkbook, expected) {
+		t.Errorf("Expected %s in workbook:\n%s", expected, outputWorkbook)
+	}
+
+	// Both copies share the merged strings and styles, and the second refers to its own detail
+	outputStrings := read(outputPath, "xl/sharedStrings.xml")
+	if !strings.Contains(outputStrings, `count="13" uniqueCount="9"`) || !strings.HasSuffix(outputStrings, `<si><t>{{SALARY}}</t></si><si><t>North</t></si><si><t>Total</t></si></sst>`) {
+		t.Errorf("Expected the region's strings to be added once:\n%s", outputStrings)
+	}
+	for _, part := range []string{"xl/worksheets/sheet2.xml", "xl/worksheets/sheet4.xml"} {
+		summary := read(outputPath, part)
+		for _, e := range []string{`<c r="A1" t="s"><v>7</v></c><c r="B1" s="1"><v>1200</v></c>`, `<c r="A2" t="s"><v>8</v></c>`} {
+			if !strings.Contains(summary, e) {
+				t.Errorf("Expected %s in %s:\n%s", e, part, summary)
+			}
+		}
+		if strings.Contains(summary, "tabSelected") {
+			t.Errorf("Expected %s not to be selected:\n%s", part, summary)
+		}
+	}
+	if summary := read(outputPath, "xl/worksheets/sheet2.xml"); !strings.Contains(summary, "<f>Detail!B1*2</f>") {
+		t.Errorf("Expected the formula to refer to the detail sheet:\n%s", summary)
+	}
+	if summary := read(outputPath, "xl/worksheets/sheet4.xml"); !strings.Contains(summary, "<f>'Detail (2)'!B1*2</f>") {
+		t.Errorf("Expected the formula to refer to the renamed detail sheet:\n%s", summary)
+	}
+
+	outputStyles := read(outputPath, "xl/styles.xml")
+	for _, e := range []string{
+		`<numFmts count="1"><numFmt numFmtId="164" formatCode="#,##0.00\ &quot;€&quot;"/></numFmts><fonts count="2" x14ac:knownFonts="1">`,
+		`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="164" fontId="1" fillId="0" borderId="0" xfId="0" applyNumberFormat="1" applyFont="1"/></cellXfs>`,
+	} {
+		if !strings.Contains(outputStyles, e) {
+			t.Errorf("Expected %s in styles:\n%s", e, outputStyles)
+		}
+	}
+	if err := checkWellFormedXML(outputStyles); err != nil {
+		t.Errorf("Styles are not well-formed: %v", err)
+	}
+
+	outputTypes := read(outputPath, "[Content_Types].xml")
+	for n := 2; n <= 5; n++ {
+		if !strings.Contains(outputTypes, fmt.Sprintf(`PartName="/xl/worksheets/sheet%d.xml"`, n)) {
+			t.Errorf("Expected a content type for sheet %d:\n%s", n, outputTypes)
+		}
+	}
+	if app := read(outputPath, "docProps/app.xml"); strings.Contains(app, "TitlesOfParts") {
+		t.Errorf("Expected the stale sheet titles to be removed:\n%s", app)
+	}
+
+	// Chosen sheets of the first workbook drop the others
+	region.Seek(0, 0)
+	template.Seek(0, 0)
+	buf.Reset()
+	if err := xlsx.ConcatWorkbooks(&buf, xlsx.Workbook{Input: region, Sheets: []string{"detail"}}, xlsx.Workbook{Input: template}); err != nil {
+		t.Fatalf("ConcatWorkbooks failed: %v", err)
+	}
+	if err := os.WriteFile(selectedPath, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write output: %v", err)
+	}
+	selectedWorkbook := read(selectedPath, "xl/workbook.xml")
+	if !strings.Contains(selectedWorkbook, `<sheets><sheet name="Detail" sheetId="2" r:id="rId5"/><sheet name="Sheet1" sheetId="3" r:id="rId6"/></sheets>`) || strings.Contains(selectedWorkbook, "definedName") {
+		t.Errorf("Expected the detail and template sheets only:\n%s", selectedWorkbook)
+	}
+	if _, err := readZipPart(selectedPath, "xl/worksheets/sheet1.xml"); err != nil {
+		t.Errorf("Expected the template sheet to take the free part name: %v", err)
+	}
+
+	region.Seek(0, 0)
+	if err := xlsx.ConcatWorkbooks(&buf, xlsx.Workbook{Input: region, Sheets: []string{"Missing"}}); err == nil {
+		t.Error("Expected an error for a missing sheet")
+	}
+
+	t.Logf("\033[32m✓ Concat test passed\033[0m")
+}
+
+func TestReadXlsx(t *testing.T) {
+	inputPath := "testdata/output/contacts.xlsx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	read := func(part string) string {
+		content, err := readZipPart("testdata/template.xlsx", part)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", part, err)
+		}
+		return content
+	}
+	sheet := func(data string) string {
+		return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` + data + `</sheetData></worksheet>`
+	}
+
+	// Shared and inline strings, a date in a built-in format, times and amounts in custom
+	// formats, booleans, an empty row and a missing cell
+	if err := writeTemplateWithParts("testdata/template.xlsx", inputPath, map[string]string{
+		"xl/sharedStrings.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="5" uniqueCount="5"><si><t>Name</t></si><si><t>Joined</t></si>` +
+			`<si><t>Active</t></si><si><t>Time</t></si><si><r><t>Ana</t></r><r><rPr><b/></rPr><t xml:space="preserve"> &amp; Co</t></r><rPh sb="0" eb="3"><t>アナ</t></rPh></si></sst>`,
+		"xl/styles.xml": strings.NewReplacer(
+			`<fonts count="1"`, `<numFmts count="3"><numFmt numFmtId="164" formatCode="[$-409]h:mm\ AM/PM;@"/>`+
+				`<numFmt numFmtId="165" formatCode="yyyy\-mm\-dd\ hh:mm"/><numFmt numFmtId="166" formatCode="#,##0.00\ &quot;€&quot;"/></numFmts><fonts count="1"`,
+			`<cellXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/></cellXfs>`,
+			`<cellXfs count="5"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="14" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>`+
+				`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/><xf numFmtId="165" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>`+
+				`<xf numFmtId="166" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/></cellXfs>`,
+		).Replace(read("xl/styles.xml")),
+		"xl/worksheets/sheet1.xml": sheet(`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="inlineStr"><is><t>Amount</t></is></c>` +
+			`<c r="D1" t="s"><v>2</v></c><c r="E1" t="s"><v>3</v></c></row>` +
+			`<row r="2"><c r="A2" t="s"><v>4</v></c><c r="B2" s="1"><v>45352</v></c><c r="C2"><v>0.30000000000000004</v></c>` +
+			`<c r="D2" t="b"><v>1</v></c><c r="E2" s="2"><v>0.5</v></c></row>` +
+			`<row r="3"><c r="A3" s="1"/></row>` +
+			`<row r="4"><c r="A4" t="inlineStr"><is><t>Ben</t></is></c><c r="C4" s="4"><v>1234.5</v></c>` +
+			`<c r="D4" t="b"><v>0</v></c><c r="E4" s="3"><v>45352.75</v></c></row>`),
+		"xl/worksheets/sheet2.xml": sheet(`<row r="2"><c r="B2" t="inlineStr"><is><t>Order</t></is></c></row>` +
+			`<row r="3"><c r="B3" t="inlineStr"><is><t>A-1</t></is></c></row>`),
+		"xl/workbook.xml": strings.Replace(read("xl/workbook.xml"), `<sheet name="Sheet1" sheetId="1" r:id="rId1"/>`,
+			`<sheet name="Contacts" sheetId="1" r:id="rId1"/><sheet name="Orders" sheetId="2" r:id="rId5"/>`, 1),
+		"xl/_rels/workbook.xml.rels": strings.Replace(read("xl/_rels/workbook.xml.rels"), "</Relationships>",
+			`<Relationship Id="rId5" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet2.xml"/></Relationships>`, 1),
+		"[Content_Types].xml": strings.Replace(read("[Content_Types].xml"), "</Types>",
+			`<Override PartName="/xl/worksheets/sheet2.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`, 1),
+	}); err != nil {
+		t.Fatalf("Failed to create workbook: %v", err)
+	}
+
+	rows, err := xlsx.ReadRows(inputPath, "")
+	if err != nil {
+		t.Fatalf("ReadRows failed: %v", err)
+	}
+	expectedRows := `[["Name" "Joined" "Amount" "Active" "Time"] ["Ana & Co" "2024-03-01" "0.3" "TRUE" "12:00:00"] [] ["Ben" "" "1234.5" "FALSE" "2024-03-01 18:00:00"]]`
+	if actual := fmt.Sprintf("%q", rows); actual != expectedRows {
+		t.Errorf("Expected rows %s, got %s", expectedRows, actual)
+	}
+
+	records, err := xlsx.ReadRecords(inputPath, "orders")
+	if err != nil {
+		t.Fatalf("ReadRecords failed: %v", err)
+	}
+	if len(records) != 1 || records[0]["Order"] != "A-1" {
+		t.Errorf("Expected one order record, got %v", records)
+	}
+	if _, err := xlsx.ReadRecords(inputPath, "Invoices"); err == nil {
+		t.Error("Expected an error for a missing sheet")
+	}
+
+	var csv bytes.Buffer
+	if err := xlsx.ExportCSV(&csv, inputPath, "Contacts"); err != nil {
+		t.Fatalf("ExportCSV failed: %v", err)
+	}
+	expectedCSV := "Name,Joined,Amount,Active,Time\nAna & Co,2024-03-01,0.3,TRUE,12:00:00\n,,,,\nBen,,1234.5,FALSE,2024-03-01 18:00:00\n"
+	if csv.String() != expectedCSV {
+		t.Errorf("Expected CSV:\n%s\ngot:\n%s", expectedCSV, csv.String())
+	}
+
+	var json bytes.Buffer
+	if err := xlsx.ExportJSON(&json, inputPath, ""); err != nil {
+		t.Fatalf("ExportJSON failed: %v", err)
+	}
+	expectedJSON := "[\n" +
+		`  {"Name": "Ana & Co", "Joined": "2024-03-01", "Amount": 0.3, "Active": true, "Time": "12:00:00"},` + "\n" +
+		`  {"Name": "Ben", "Joined": null, "Amount": 1234.5, "Active": false, "Time": "2024-03-01 18:00:00"}` + "\n]\n"
+	if json.String() != expectedJSON {
+		t.Errorf("Expected JSON:\n%s\ngot:\n%s", expectedJSON, json.String())
+	}
+
+	t.Logf("\033[32m✓ Read test passed\033[0m")
+}
+
+func TestReadXlsxClosesFile(t *testing.T) {
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skip("Open file descriptors can't be counted on this system")
+	}
+	before := len(fds)
+
+	// Reading a data file once per batch record must not keep it open
+	for i := 0; i < 200; i++ {
+		if _, err := xlsx.ReadRecords("testdata/template.xlsx", ""); err != nil {
+			t.Fatalf("ReadRecords failed: %v", err)
+		}
+		var csv bytes.Buffer
+		if err := xlsx.ExportCSV(&csv, "testdata/template.xlsx", ""); err != nil {
+			t.Fatalf("ExportCSV failed: %v", err)
+		}
+	}
+
+	fds, err = os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Fatalf("Failed to count open files: %v", err)
+	}
+	if len(fds) > before+2 {
+		t.Errorf("Expected the workbook to be closed after reading, %d files were left open", len(fds)-before)
+	}
+
+	t.Logf("\033[32m✓ Read close test passed\033[0m")
+}
+
+func TestXlsxProperties(t *testing.T) {
+	inputPath := "testdata/output/typed.xlsx"
+	outputPath := "testdata/output/properties.xlsx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	// A workbook with typed custom properties
+	rels, err := readZipPart("testdata/template.xlsx", "_rels/.rels")
+	if err != nil {
+		t.Fatalf("Failed to read relationships: %v", err)
+	}
+	types, err := readZipPart("testdata/template.xlsx", "[Content_Types].xml")
+	if err != nil {
+		t.Fatalf("Failed to read content types: %v", err)
+	}
+	if err := writeTemplateWithParts("testdata/template.xlsx", inputPath, map[string]string{
+		"docProps/custom.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<Properties xmlns="http://schemas.openxmlformats.org/officeDocument/2006/custom-properties" xmlns:vt="http://schemas.openxmlformats.org/officeDocument/2006/docPropsVTypes">` +
+			`<property fmtid="{D5CDD505-2E9C-101B-9397-08002B2CF9AE}" pid="2" name="Quarter"><vt:i4>1</vt:i4></property>` +
+			`<property fmtid="{D5CDD505-2E9C-101B-9397-08002B2CF9AE}" pid="5" name="Final"><vt:bool>false</vt:bool></property></Properties>`,
+		"_rels/.rels": strings.Replace(rels, "</Relationships>",
+			`<Relationship Id="rId9" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/custom-properties" Target="docProps/custom.xml"/></Relationships>`, 1),
+		"[Content_Types].xml": strings.Replace(types, "</Types>",
+			`<Override PartName="/docProps/custom.xml" ContentType="application/vnd.openxmlformats-officedocument.custom-properties+xml"/></Types>`, 1),
+	}); err != nil {
+		t.Fatalf("Failed to create workbook: %v", err)
+	}
+
+	if err := xlsx.SetProperties(inputPath, outputPath, map[string]string{"Quarter": "3", "Final": "TRUE", "Owner": "Finance"}); err != nil {
+		t.Fatalf("SetProperties failed: %v", err)
+	}
+	custom, err := readZipPart(outputPath, "docProps/custom.xml")
+	if err != nil {
+		t.Fatalf("Failed to read custom properties: %v", err)
+	}
+	for _, want := range []string{`name="Quarter"><vt:i4>3</vt:i4>`, `name="Final"><vt:bool>true</vt:bool>`, `pid="6" name="Owner"><vt:lpwstr>Finance</vt:lpwstr>`} {
+		if !strings.Contains(custom, want) {
+			t.Errorf("Expected custom properties to contain %s, got:\n%s", want, custom)
+		}
+	}
+	if rels, _ := readZipPart(outputPath, "_rels/.rels"); strings.Count(rels, "custom-properties") != 1 {
+		t.Errorf("Expected a single custom properties relationship, got:\n%s", rels)
+	}
+
+	if err := xlsx.SetProperties(inputPath, outputPath, map[string]string{"Quarter": "Q3"}); err == nil {
+		t.Error("Expected an error for a value that doesn't fit the property's type")
+	}
+
+	t.Logf("\033[32m✓ Properties test passed\033[0m")
+}
+
+func TestScrubXlsx(t *testing.T) {
+	inputPath := "testdata/output/review.xlsx"
+	outputPath := "testdata/output/clean.xlsx"
+
+	if err := os.MkdirAll("testdata/output", 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	defer os.RemoveAll("testdata/output")
+
+	read := func(path, part string) string {
+		content, err := readZipPart(path, part)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", part, err)
+		}
+		return content
+	}
+
+	relType := "http://schemas.openxmlformats.org/officeDocument/2006/relationships/"
+	if err := writeTemplateWithParts("testdata/template.xlsx", inputPath, map[string]string{
+		"xl/worksheets/sheet1.xml": strings.Replace(read("testdata/template.xlsx", "xl/worksheets/sheet1.xml"), "</worksheet>",
+			`<legacyDrawing r:id="rId91"/></worksheet>`, 1),
+		"xl/comments1.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<comments xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><authors><author>Controller</author></authors>` +
+			`<commentList><comment ref="A1" authorId="0"><text><r><t>Verify the total</t></r></text></comment></commentList></comments>`,
+		"xl/drawings/vmlDrawing1.vml": `<xml xmlns:v="urn:schemas-microsoft-com:vml" xmlns:x="urn:schemas-microsoft-com:office:excel">` +
+			`<v:shape id="_x0000_s1025" type="#_x0000_t202"><x:ClientData ObjectType="Note"><x:Row>0</x:Row><x:Column>0</x:Column></x:ClientData></v:shape></xml>`,
+		"xl/worksheets/_rels/sheet1.xml.rels": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId90" Type="` + relType + `comments" Target="../comments1.xml"/>` +
+			`<Relationship Id="rId91" Type="` + relType + `vmlDrawing" Target="../drawings/vmlDrawing1.vml"/></Relationships>`,
+		"[Content_Types].xml": strings.Replace(read("testdata/template.xlsx", "[Content_Types].xml"), "</Types>",
+			`<Default Extension="vml" ContentType="application/vnd.openxmlformats-officedocument.vmlDrawing"/>`+
+				`<Override PartName="/xl/comments1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.comments+xml"/></Types>`, 1),
+	}); err != nil {
+		t.Fatalf("Failed to create workbook: %v", err)
+	}
+
+	report, err := xlsx.Scrub(inputPath, outputPath, xlsx.ScrubOptions{})
+	if err != nil {
+		t.Fatalf("Scrub failed: %v", err)
+	}
+	found := false
+	for _, item := range report.Items {
+		if item.What == "comments" {
+			found = true
+			if item.Count != 1 {
+				t.Errorf("Expected 1 comment, got %d", item.Count)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Expected the report to list comments, got:\n%s", report)
+	}
+
+	for _, part := range []string{"xl/comments1.xml", "xl/drawings/vmlDrawing1.vml"} {
+		if _, err := readZipPart(outputPath, part); err == nil {
+			t.Errorf("Expected %s to be removed", part)
+		}
+	}
+	sheet := read(outputPath, "xl/worksheets/sheet1.xml")
+	if err := checkWellFormedXML(sheet); err != nil {
+		t.Fatalf("Scrubbed sheet is not well-formed: %v", err)
+	}
+	if strings.Contains(sheet, "legacyDrawing") {
+		t.Error("Expected the legacy drawing reference to be removed")
+	}
+	if !strings.Contains(sheet, `<c r="A1" t="s"><v>0</v></c>`) {
+		t.Error("Expected the cells to be kept")
+	}
+	if strings.Contains(read(outputPath, "[Content_Types].xml"), "comments1.xml") {
+		t.Error("Expected the comments content type override to be removed")
+	}
+
+	t.Logf("\033[32m✓ Scrub test passed\033[0m")
+}
+
 // Benchmark tests
 func BenchmarkProcessXlsxSingle(b *testing.B) {
 	templatePath := "testdata/template.xlsx"
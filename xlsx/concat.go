@@ -0,0 +1,62 @@
+package xlsx
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/siliconcatalyst/officeforge/internal"
+)
+
+// Workbook is a workbook to concatenate with the sheets to take from it.
+type Workbook struct {
+	Input io.Reader
+
+	// Sheets names the sheets to take, in order. Nil takes all sheets.
+	Sheets []string
+}
+
+// Concat writes the sheets of the workbooks read from inputs, in order, as a single workbook
+// to w; see ConcatWorkbooks.
+func Concat(w io.Writer, inputs ...io.Reader) error {
+	books := make([]Workbook, len(inputs))
+	for i, input := range inputs {
+		books[i] = Workbook{Input: input}
+	}
+	return ConcatWorkbooks(w, books...)
+}
+
+// ConcatWorkbooks writes the selected sheets of each workbook, in order, as a single workbook
+// to w. Shared strings and styles are merged, with identical entries stored once, and the
+// cells of copied sheets remapped to them. A sheet named like one already in the workbook is
+// renamed "Name (2)" and so on, and formulas of its workbook referring to it follow. Tables,
+// drawings, comments and media come along; pivot tables are left out.
+func ConcatWorkbooks(w io.Writer, books ...Workbook) error {
+	if len(books) == 0 {
+		return fmt.Errorf("no workbooks to concatenate")
+	}
+
+	var merger *internal.XlsxMerger
+	var host *internal.Package
+	for i, book := range books {
+		pkg, err := internal.ReadPackage(book.Input)
+		if err != nil {
+			return fmt.Errorf("failed to open workbook %d: %v", i+1, err)
+		}
+
+		if merger == nil {
+			host = pkg
+			if merger, err = internal.NewXlsxMerger(pkg, book.Sheets); err != nil {
+				return fmt.Errorf("workbook 1: %v", err)
+			}
+			continue
+		}
+		if err := merger.Append(pkg, book.Sheets); err != nil {
+			return fmt.Errorf("failed to append workbook %d: %v", i+1, err)
+		}
+	}
+
+	if err := merger.Finish(); err != nil {
+		return err
+	}
+	return host.SaveTo(w)
+}
@@ -1,7 +1,6 @@
 package xlsx
 
 import (
-	"archive/zip"
 	"fmt"
 	"log"
 	"os"
@@ -10,65 +9,58 @@ import (
 	"github.com/siliconcatalyst/officeforge/internal"
 )
 
-// ProcessXlsxSingle performs a single keyword replacement in an XLSX file
-func ProcessXlsxSingle(inputPath, outputPath, keyword, replacement string) error {
-	reader, err := zip.OpenReader(inputPath)
-	if err != nil {
-		return fmt.Errorf("failed to open input file: %v", err)
-	}
-	defer reader.Close()
+// Options controls how templates are rendered.
+type Options = internal.Options
 
-	outputFile, err := os.Create(outputPath)
-	if err != nil {
-		return fmt.Errorf("failed to create output file: %v", err)
-	}
-	defer outputFile.Close()
+// Syntax describes how placeholders are written in templates.
+type Syntax = internal.Syntax
 
-	zipWriter := zip.NewWriter(outputFile)
-	defer zipWriter.Close()
+// FilterFunc formats a placeholder value, e.g. the currency filter in {{AMOUNT | currency:"EUR"}}.
+type FilterFunc = internal.FilterFunc
 
-	// Create replacements map
-	replacements := map[string]string{keyword: replacement}
+// FuncMap registers custom placeholder filters by name.
+type FuncMap = internal.FuncMap
 
-	// Process each file in the XLSX
-	for _, file := range reader.File {
-		err := internal.ProcessXlsxZipFile(file, zipWriter, replacements)
-		if err != nil {
-			return fmt.Errorf("failed to process file %s: %v", file.Name, err)
-		}
-	}
+// Data holds typed record values: strings, numbers, bools, nested objects addressed as
+// {{customer.address.city}} and arrays addressed as {{items[0].name}}.
+type Data = internal.Data
 
-	return nil
+// Link is a hyperlink value shown with the link filter, e.g. {{PORTAL | link}}.
+type Link = internal.Link
+
+// ProcessXlsxSingle performs a single keyword replacement in an XLSX file
+func ProcessXlsxSingle(inputPath, outputPath, keyword, replacement string) error {
+	// Create replacements map
+	replacements := map[string]string{keyword: replacement}
+	return ProcessXlsxMultiWithOptions(inputPath, outputPath, replacements, Options{})
 }
 
 // ProcessXlsxMulti performs multiple keyword replacements in an XLSX file
 func ProcessXlsxMulti(inputPath, outputPath string, replacements map[string]string) error {
-	reader, err := zip.OpenReader(inputPath)
-	if err != nil {
-		return fmt.Errorf("failed to open input file: %v", err)
+	return ProcessXlsxMultiWithOptions(inputPath, outputPath, replacements, Options{})
+}
+
+// ProcessXlsxMultiWithOptions performs multiple keyword replacements using the given options
+func ProcessXlsxMultiWithOptions(inputPath, outputPath string, replacements map[string]string, opts Options) error {
+	if err := opts.Validate(); err != nil {
+		return err
 	}
-	defer reader.Close()
 
-	outputFile, err := os.Create(outputPath)
+	pkg, err := internal.OpenPackage(inputPath)
 	if err != nil {
-		return fmt.Errorf("failed to create output file: %v", err)
+		return fmt.Errorf("failed to open input file: %v", err)
 	}
-	defer outputFile.Close()
+	defer pkg.Close()
+	pkg.SetCompressionLevel(opts.CompressionLevel)
 
-	// Create zip writer for output
-	zipWriter := zip.NewWriter(outputFile)
-	defer zipWriter.Close()
+	replacer := internal.NewReplacer(replacements, opts)
 
-	// Process each file in the XLSX
-	for _, file := range reader.File {
-		err := internal.ProcessXlsxZipFile(file, zipWriter, replacements)
-		if err != nil {
-			return fmt.Errorf("failed to process file %s: %v", file.Name, err)
-		}
+	if err := internal.ProcessXlsxPackage(pkg, replacer); err != nil {
+		return fmt.Errorf("failed to process workbook: %v", err)
 	}
 
 	// log.Printf("Successfully processed %d replacements in %s", len(replacements), outputPath)
-	return nil
+	return pkg.Save(outputPath)
 }
 
 // ProcessXlsxMultipleRecords generates multiple XLSX files using a naming pattern
@@ -77,27 +69,36 @@ func ProcessXlsxMulti(inputPath, outputPath string, replacements map[string]stri
 //   - Data-based: "{EMPLOYEE}_report.xlsx" (uses record fields)
 //   - Empty: defaults to "spreadsheet_%d.xlsx"
 func ProcessXlsxMultipleRecords(inputPath, outputDir string, records []map[string]string, fileNamePattern string) error {
+	return ProcessXlsxMultipleRecordsWithOptions(inputPath, outputDir, records, fileNamePattern, Options{})
+}
+
+// ProcessXlsxMultipleRecordsWithOptions generates multiple spreadsheets using a naming pattern and the given options
+func ProcessXlsxMultipleRecordsWithOptions(inputPath, outputDir string, records []map[string]string, fileNamePattern string, opts Options) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
 		return fmt.Errorf("failed to create output directory: %v", err)
 	}
 
 	// Validate pattern with first record
 	if len(records) > 0 && fileNamePattern != "" {
-		if err := internal.ValidatePattern(fileNamePattern, records[0]); err != nil {
+		if err := internal.ValidatePattern(fileNamePattern, records[0], opts); err != nil {
 			return fmt.Errorf("invalid pattern: %v", err)
 		}
 	}
 
 	// Create naming function based on pattern
-	nameFunc := internal.CreateXlsxNamingFunction(fileNamePattern)
+	nameFunc := internal.CreateXlsxNamingFunction(fileNamePattern, opts)
 
 	for i, record := range records {
 		// Generate filename using the naming function
 		fileName := nameFunc(record, i+1)
 		outputPath := filepath.Join(outputDir, fileName)
 
-		// Process the spreadsheet with this record's replacements
-		err := ProcessXlsxMulti(inputPath, outputPath, record)
+		// Process the spreadsheet with this record's replacements and locale
+		err := ProcessXlsxMultiWithOptions(inputPath, outputPath, record, opts.ForRecord(record))
 		if err != nil {
 			log.Printf("Failed to process record %d: %v", i+1, err)
 			continue
@@ -133,3 +134,17 @@ func ProcessXlsxMultipleRecordsWithNames(inputPath, outputDir string, records []
 	// log.Printf("Successfully processed %d records", len(records))
 	return nil
 }
+
+// ProcessXlsxData replaces placeholders with values from data, which may be nested and typed
+func ProcessXlsxData(inputPath, outputPath string, data Data, opts Options) error {
+	return ProcessXlsxMultiWithOptions(inputPath, outputPath, data.Flatten(opts.Syntax), opts)
+}
+
+// ProcessXlsxDataRecords generates one spreadsheet per record using a naming pattern; see ProcessXlsxMultipleRecords
+func ProcessXlsxDataRecords(inputPath, outputDir string, records []Data, fileNamePattern string, opts Options) error {
+	flat := make([]map[string]string, len(records))
+	for i, record := range records {
+		flat[i] = record.Flatten(opts.Syntax)
+	}
+	return ProcessXlsxMultipleRecordsWithOptions(inputPath, outputDir, flat, fileNamePattern, opts)
+}
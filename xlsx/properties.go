@@ -0,0 +1,40 @@
+package xlsx
+
+import (
+	"fmt"
+
+	"github.com/siliconcatalyst/officeforge/internal"
+)
+
+// Properties holds the document properties of a file: core properties such as title, creator
+// and keywords, extended properties such as Company and Manager, and custom properties.
+type Properties = internal.Properties
+
+// ReadProperties returns the properties of the workbook at inputPath.
+func ReadProperties(inputPath string) (Properties, error) {
+	pkg, err := internal.OpenPackage(inputPath)
+	if err != nil {
+		return Properties{}, fmt.Errorf("failed to open input file: %v", err)
+	}
+	defer pkg.Close()
+
+	return internal.ReadProperties(pkg)
+}
+
+// SetProperties writes a copy of the workbook at inputPath with the given properties, e.g.
+// {"title": "Contract 42", "Company": "Acme", "ContractId": "42"}. Names that are not core or
+// extended properties set custom properties; prefix a name with "core:", "app:" or "custom:" to
+// choose explicitly. An empty value removes the property. To set properties from record data
+// when rendering, use Options.Properties.
+func SetProperties(inputPath, outputPath string, props map[string]string) error {
+	pkg, err := internal.OpenPackage(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to open input file: %v", err)
+	}
+	defer pkg.Close()
+
+	if err := internal.SetProperties(pkg, props); err != nil {
+		return fmt.Errorf("failed to set properties: %v", err)
+	}
+	return pkg.Save(outputPath)
+}
@@ -0,0 +1,70 @@
+package xlsx
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/siliconcatalyst/officeforge/internal"
+)
+
+// ReadRows returns the cell values of a sheet of the workbook at inputPath by row, starting
+// with the sheet's first row and column. sheet names the sheet without regard to case; empty
+// reads the first sheet. Shared and inline strings are resolved, numbers are written in plain
+// decimal form and cells formatted as dates or times in ISO 8601, e.g. "2024-03-01".
+func ReadRows(inputPath, sheet string) ([][]string, error) {
+	rows, err := readSheet(inputPath, sheet)
+	if err != nil {
+		return nil, err
+	}
+	values := make([][]string, len(rows))
+	for i, row := range rows {
+		values[i] = make([]string, len(row))
+		for j, cell := range row {
+			values[i][j] = cell.Text
+		}
+	}
+	return values, nil
+}
+
+// ReadRecords reads a sheet as ReadRows does and returns a record per row, keyed by the
+// headings in the first row. Empty rows and columns without a heading are left out.
+func ReadRecords(inputPath, sheet string) ([]map[string]string, error) {
+	rows, err := readSheet(inputPath, sheet)
+	if err != nil {
+		return nil, err
+	}
+	return internal.SheetRecords(rows)
+}
+
+// ExportCSV writes a sheet of the workbook at inputPath to w as CSV; see ReadRows.
+func ExportCSV(w io.Writer, inputPath, sheet string) error {
+	rows, err := readSheet(inputPath, sheet)
+	if err != nil {
+		return err
+	}
+	return internal.WriteSheetCSV(w, rows)
+}
+
+// ExportJSON writes a sheet of the workbook at inputPath to w as a JSON array of records keyed
+// by the headings in the first row, in column order. Numbers and booleans stay JSON numbers
+// and booleans, dates are ISO 8601 strings and empty cells null.
+func ExportJSON(w io.Writer, inputPath, sheet string) error {
+	rows, err := readSheet(inputPath, sheet)
+	if err != nil {
+		return err
+	}
+	return internal.WriteSheetJSON(w, rows)
+}
+
+func readSheet(inputPath, sheet string) ([][]internal.SheetCell, error) {
+	pkg, err := internal.OpenPackage(inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open input file: %v", err)
+	}
+	defer pkg.Close()
+	rows, err := internal.ReadSheet(pkg, sheet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sheet: %v", err)
+	}
+	return rows, nil
+}
@@ -0,0 +1,33 @@
+package xlsx
+
+import (
+	"fmt"
+
+	"github.com/siliconcatalyst/officeforge/internal"
+)
+
+// ScrubOptions selects what Scrub leaves in a file; the zero value removes everything it can.
+type ScrubOptions = internal.ScrubOptions
+
+// ScrubReport lists what Scrub removed.
+type ScrubReport = internal.ScrubReport
+
+// ScrubItem is a kind of content Scrub removed, with how much of it.
+type ScrubItem = internal.ScrubItem
+
+// Scrub writes a copy of the workbook at inputPath without authoring traces: personal properties,
+// comments and their authors, the revision log of a shared workbook, custom XML data and the
+// thumbnail.
+func Scrub(inputPath, outputPath string, opts ScrubOptions) (ScrubReport, error) {
+	pkg, err := internal.OpenPackage(inputPath)
+	if err != nil {
+		return ScrubReport{}, fmt.Errorf("failed to open input file: %v", err)
+	}
+	defer pkg.Close()
+
+	report, err := internal.ScrubXlsx(pkg, opts)
+	if err != nil {
+		return report, fmt.Errorf("failed to scrub workbook: %v", err)
+	}
+	return report, pkg.Save(outputPath)
+}